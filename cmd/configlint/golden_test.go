@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+const testProgram = "./testdata/program"
+
+var (
+	testNamespacesOnce sync.Once
+	testNamespaces     []namespace
+	testNamespacesErr  error
+)
+
+// loadTestNamespaces loads the test program once, type-checking it takes a
+// few seconds
+func loadTestNamespaces(t *testing.T) []namespace {
+	t.Helper()
+	testNamespacesOnce.Do(func() {
+		testNamespaces, testNamespacesErr = loadNamespaces(testProgram)
+	})
+	if testNamespacesErr != nil {
+		t.Fatalf("failed to load %s: %v", testProgram, testNamespacesErr)
+	}
+	return testNamespaces
+}
+
+// checkGolden compares got to testdata/<name>, rewritten with `go test -update`
+func checkGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run `go test -update` to create it", err)
+	}
+	if got != string(want) {
+		t.Fatalf("%s differs, got:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type severity string
+
+const (
+	severityError   severity = "error"
+	severityWarning severity = "warning"
+)
+
+type issue struct {
+	severity severity
+	key      string
+	message  string
+}
+
+func (i issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.severity, i.key, i.message)
+}
+
+type stackConfig struct {
+	Config map[string]interface{} `yaml:"config"`
+}
+
+func loadStackConfig(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := stackConfig{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cfg.Config, nil
+}
+
+// isSecure tells whether the value was set via `pulumi config set --secret`
+func isSecure(value interface{}) bool {
+	dict, ok := value.(map[string]interface{})
+	if !ok || len(dict) != 1 {
+		return false
+	}
+	_, ok = dict["secure"]
+	return ok
+}
+
+type linter struct {
+	issues []issue
+}
+
+func (l *linter) errorf(key string, format string, args ...interface{}) {
+	l.issues = append(l.issues, issue{severityError, key, fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) warnf(key string, format string, args ...interface{}) {
+	l.issues = append(l.issues, issue{severityWarning, key, fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) hasErrors() bool {
+	return slices.ContainsFunc(l.issues, func(i issue) bool {
+		return i.severity == severityError
+	})
+}
+
+func (l *linter) lint(namespaces []namespace, config map[string]interface{}) {
+	// same namespace may be read into multiple structs
+	known := map[string][]field{}
+	order := []string{}
+	for _, ns := range namespaces {
+		if _, ok := known[ns.name]; !ok {
+			order = append(order, ns.name)
+		}
+		known[ns.name] = append(known[ns.name], ns.fields...)
+	}
+	for _, name := range order {
+		values := map[string]interface{}{}
+		for key, value := range config {
+			if field, ok := strings.CutPrefix(key, name+":"); ok {
+				values[field] = value
+			}
+		}
+		l.lintFields(name+":", known[name], values, true)
+	}
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if ns, _, ok := strings.Cut(key, ":"); ok && known[ns] == nil && !strings.Contains(ns, "/") && !isProviderNamespace(ns) {
+			l.warnf(key, "namespace '%s' is not read by the program", ns)
+		}
+	}
+}
+
+// provider plugins read their own namespaces, e.g. aws:region
+func isProviderNamespace(ns string) bool {
+	return slices.Contains([]string{"aws", "postgresql", "random", "pulumi", "vault", "kubernetes"}, ns)
+}
+
+func (l *linter) lintFields(prefix string, fields []field, values map[string]interface{}, topLevel bool) {
+	declared := map[string]bool{}
+	oneofs := map[string][]string{}
+	for _, f := range fields {
+		declared[f.name] = true
+		key := prefix + f.name
+		value, ok := values[f.name]
+		if !ok {
+			// nested fields can't be required, utils.ExtractConfig only checks the top-level ones
+			if f.required && topLevel {
+				l.errorf(key, "required config is missing")
+			}
+			continue
+		}
+		if f.oneof != "" {
+			oneofs[f.oneof] = append(oneofs[f.oneof], f.name)
+		}
+		if isSecure(value) {
+			if !f.secret && topLevel {
+				l.warnf(key, "value is a secret but the field is read as plaintext")
+			}
+			continue
+		}
+		if f.secret {
+			l.warnf(key, "value should be set with `pulumi config set --secret`")
+		}
+		l.lintValue(key, f, value)
+	}
+	groups := make([]string, 0, len(oneofs))
+	for group := range oneofs {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		if names := oneofs[group]; len(names) > 1 {
+			sort.Strings(names)
+			// the namespace itself for the top-level fields
+			key := strings.TrimSuffix(strings.TrimSuffix(prefix, "."), ":")
+			l.errorf(key, "only one of %s can be set (oneof group '%s')", strings.Join(names, ", "), group)
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !declared[name] {
+			l.warnf(prefix+name, "unknown config, it's ignored by the program")
+		}
+	}
+}
+
+func (l *linter) lintValue(key string, f field, value interface{}) {
+	// objects/arrays can also be set as JSON strings via the CLI
+	if s, ok := value.(string); ok && (f.kind == kindObject || f.kind == kindArray) {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(s), &parsed); err != nil {
+			l.errorf(key, "expected %s, got string", f.kind)
+			return
+		}
+		value = parsed
+	}
+	switch f.kind {
+	case kindBool:
+		if _, ok := value.(bool); !ok {
+			l.errorf(key, "expected bool, got %v", value)
+		}
+	case kindInt:
+		if _, ok := value.(int); !ok {
+			l.errorf(key, "expected int, got %v", value)
+		}
+	case kindFloat:
+		switch value.(type) {
+		case int, float64:
+		default:
+			l.errorf(key, "expected float, got %v", value)
+		}
+	case kindString:
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			l.errorf(key, "expected string, got %T", value)
+			return
+		}
+		if len(f.enum) > 0 && !slices.Contains(f.enum, fmt.Sprint(value)) {
+			l.errorf(key, "'%v' is not one of [%s]", value, strings.Join(f.enum, ", "))
+		}
+	case kindObject:
+		dict, ok := value.(map[string]interface{})
+		if !ok {
+			l.errorf(key, "expected object, got %T", value)
+			return
+		}
+		if f.union != "" {
+			if _, ok := dict["type"].(string); !ok {
+				l.errorf(key, "'type' is required to select the %s", f.union)
+			}
+		}
+		if f.children != nil {
+			l.lintFields(key+".", f.children, dict, false)
+		}
+	case kindArray:
+		arr, ok := value.([]interface{})
+		if !ok {
+			l.errorf(key, "expected array, got %T", value)
+			return
+		}
+		if f.children == nil {
+			return
+		}
+		for i, item := range arr {
+			itemKey := fmt.Sprintf("%s[%d]", key, i)
+			dict, ok := item.(map[string]interface{})
+			if !ok {
+				l.errorf(itemKey, "expected object, got %T", item)
+				continue
+			}
+			l.lintFields(itemKey+".", f.children, dict, false)
+		}
+	}
+}
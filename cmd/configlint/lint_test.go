@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	namespaces := loadTestNamespaces(t)
+	tests := []struct {
+		stack      string
+		wantErrors bool
+	}{
+		{stack: "valid"},
+		{stack: "invalid", wantErrors: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stack, func(t *testing.T) {
+			config, err := loadStackConfig(filepath.Join(testProgram, fmt.Sprintf("Pulumi.%s.yaml", tt.stack)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := &linter{}
+			l.lint(namespaces, config)
+			if l.hasErrors() != tt.wantErrors {
+				t.Errorf("got errors %t, want %t", l.hasErrors(), tt.wantErrors)
+			}
+			out := strings.Builder{}
+			for _, i := range l.issues {
+				fmt.Fprintln(&out, i)
+			}
+			checkGolden(t, fmt.Sprintf("lint-%s.golden", tt.stack), out.String())
+		})
+	}
+}
+
+func TestIsSecure(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  bool
+	}{
+		{name: "secure", value: map[string]interface{}{"secure": "v1:abc"}, want: true},
+		{name: "plaintext", value: "hunter2"},
+		{name: "object", value: map[string]interface{}{"secure": "v1:abc", "host": "db"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSecure(tt.value); got != tt.want {
+				t.Fatalf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// configlint statically checks a stack config against the config structs of
+// the program, before running any pulumi operation.
+//
+// Usage:
+//
+//	go run ./cmd/configlint -program ./programs/db-postgres-creds -stack dev
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	programDir := flag.String("program", ".", "directory of the pulumi program")
+	stack := flag.String("stack", "dev", "stack whose Pulumi.<stack>.yaml is linted")
+	configPath := flag.String("config", "", "path of the stack config, overrides -stack")
+	flag.Parse()
+
+	if *configPath == "" {
+		*configPath = filepath.Join(*programDir, fmt.Sprintf("Pulumi.%s.yaml", *stack))
+	}
+	namespaces, err := loadNamespaces(*programDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	config, err := loadStackConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	l := &linter{}
+	l.lint(namespaces, config)
+	for _, i := range l.issues {
+		fmt.Println(i)
+	}
+	if l.hasErrors() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	componentsPkg     = "github.com/shivanshs9/iac-pulumi/components"
+	extractConfigFunc = componentsPkg + "/utils.ExtractConfig"
+	pulumiPkg         = "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type valueKind string
+
+const (
+	kindBool   valueKind = "bool"
+	kindInt    valueKind = "int"
+	kindFloat  valueKind = "float"
+	kindString valueKind = "string"
+	kindObject valueKind = "object"
+	kindArray  valueKind = "array"
+)
+
+// field mirrors the struct tags read by utils.ExtractConfig
+type field struct {
+	name     string
+	kind     valueKind
+	required bool
+	secret   bool
+	enum     []string
+	oneof    string
+	// tagged union decoded by the `type` key of the object
+	union string
+	// for kindObject and kindArray of structs
+	children []field
+}
+
+// namespace is the struct passed to one utils.ExtractConfig call in the program
+type namespace struct {
+	name   string
+	fields []field
+}
+
+func fieldFromTag(tag reflect.StructTag) (field, bool) {
+	f := field{}
+	if name := tag.Get("config"); name != "" {
+		f.name = name
+	} else if name := tag.Get("json"); name != "" {
+		f.name = name
+	} else if name := tag.Get("secret"); name != "" {
+		f.name = name
+		f.secret = true
+	} else {
+		return f, false
+	}
+	_, f.required = tag.Lookup("required")
+	if enum := tag.Get("enum"); enum != "" {
+		f.enum = strings.Split(enum, ",")
+	}
+	f.oneof = tag.Get("oneof")
+	f.union = tag.Get("union")
+	return f, true
+}
+
+func structFields(st *types.Struct) []field {
+	fields := []field{}
+	for i := 0; i < st.NumFields(); i++ {
+		f, ok := fieldFromTag(reflect.StructTag(st.Tag(i)))
+		if !ok {
+			continue
+		}
+		f.kind, f.children = kindOf(st.Field(i).Type())
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func kindOf(t types.Type) (valueKind, []field) {
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == pulumiPkg {
+		switch named.Obj().Name() {
+		case "BoolInput":
+			return kindBool, nil
+		case "IntInput":
+			return kindInt, nil
+		case "Float64Input":
+			return kindFloat, nil
+		case "StringInput":
+			return kindString, nil
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return kindBool, nil
+		case u.Info()&types.IsInteger != 0:
+			return kindInt, nil
+		case u.Info()&types.IsFloat != 0:
+			return kindFloat, nil
+		default:
+			return kindString, nil
+		}
+	case *types.Pointer:
+		return kindOf(u.Elem())
+	case *types.Slice:
+		_, children := kindOf(u.Elem())
+		return kindArray, children
+	case *types.Array:
+		_, children := kindOf(u.Elem())
+		return kindArray, children
+	case *types.Struct:
+		return kindObject, structFields(u)
+	default:
+		return kindObject, nil
+	}
+}
+
+func findNamespaces(pkg *packages.Package, decl *ast.FuncDecl) []namespace {
+	namespaces := []namespace{}
+	ast.Inspect(decl, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 3 {
+			return true
+		}
+		// utils.ExtractConfig, or ExtractConfig in the utils package itself
+		var ident *ast.Ident
+		switch fun := call.Fun.(type) {
+		case *ast.SelectorExpr:
+			ident = fun.Sel
+		case *ast.Ident:
+			ident = fun
+		default:
+			return true
+		}
+		fn, ok := pkg.TypesInfo.Uses[ident].(*types.Func)
+		if !ok || fn.FullName() != extractConfigFunc {
+			return true
+		}
+		nsValue := pkg.TypesInfo.Types[call.Args[1]].Value
+		if nsValue == nil || nsValue.Kind() != constant.String {
+			return true
+		}
+		ptr, ok := pkg.TypesInfo.TypeOf(call.Args[2]).(*types.Pointer)
+		if !ok {
+			return true
+		}
+		st, ok := ptr.Elem().Underlying().(*types.Struct)
+		if !ok {
+			return true
+		}
+		namespaces = append(namespaces, namespace{
+			name:   constant.StringVal(nsValue),
+			fields: structFields(st),
+		})
+		return true
+	})
+	return namespaces
+}
+
+func loadPackage(dir string, pattern string) (*packages.Package, error) {
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:  dir,
+		Mode: packages.NeedName | packages.NeedImports | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedDeps,
+	}, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", pattern, err)
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package for %s, found %d", pattern, len(pkgs))
+	}
+	if len(pkgs[0].Errors) > 0 {
+		return nil, fmt.Errorf("failed to load %s: %v", pattern, pkgs[0].Errors[0])
+	}
+	return pkgs[0], nil
+}
+
+func funcDecls(pkg *packages.Package) []*ast.FuncDecl {
+	decls := []*ast.FuncDecl{}
+	for _, file := range pkg.Syntax {
+		for _, d := range file.Decls {
+			if decl, ok := d.(*ast.FuncDecl); ok {
+				decls = append(decls, decl)
+			}
+		}
+	}
+	return decls
+}
+
+// loadNamespaces finds all the utils.ExtractConfig calls in the program, and
+// in the component functions called by the program (e.g. utils.LoadStackMetadata),
+// and resolves the config structs passed to them.
+func loadNamespaces(programDir string) ([]namespace, error) {
+	program, err := loadPackage(programDir, ".")
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []namespace{}
+	for _, decl := range funcDecls(program) {
+		namespaces = append(namespaces, findNamespaces(program, decl)...)
+	}
+
+	usedFuncs := map[string]bool{}
+	for _, obj := range program.TypesInfo.Uses {
+		if fn, ok := obj.(*types.Func); ok {
+			usedFuncs[fn.FullName()] = true
+		}
+	}
+	for _, imported := range program.Types.Imports() {
+		if !strings.HasPrefix(imported.Path(), componentsPkg+"/") {
+			continue
+		}
+		pkg, err := loadPackage(programDir, imported.Path())
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range funcDecls(pkg) {
+			if fn, ok := pkg.TypesInfo.Defs[decl.Name].(*types.Func); ok && usedFuncs[fn.FullName()] {
+				namespaces = append(namespaces, findNamespaces(pkg, decl)...)
+			}
+		}
+	}
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("no utils.ExtractConfig calls found in %s", programDir)
+	}
+	return namespaces, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// dumpFields writes a field per line, the children indented under it
+func dumpFields(out *strings.Builder, fields []field, indent string) {
+	for _, f := range fields {
+		fmt.Fprintf(out, "%s%s %s", indent, f.name, f.kind)
+		if f.required {
+			out.WriteString(" required")
+		}
+		if f.secret {
+			out.WriteString(" secret")
+		}
+		if len(f.enum) > 0 {
+			fmt.Fprintf(out, " enum=%s", strings.Join(f.enum, ","))
+		}
+		if f.oneof != "" {
+			fmt.Fprintf(out, " oneof=%s", f.oneof)
+		}
+		if f.union != "" {
+			fmt.Fprintf(out, " union=%s", f.union)
+		}
+		out.WriteString("\n")
+		dumpFields(out, f.children, indent+"  ")
+	}
+}
+
+func TestLoadNamespaces(t *testing.T) {
+	out := strings.Builder{}
+	for _, ns := range loadTestNamespaces(t) {
+		fmt.Fprintf(&out, "%s:\n", ns.name)
+		dumpFields(&out, ns.fields, "  ")
+	}
+	checkGolden(t, "schema.golden", out.String())
+}
+
+func TestLoadNamespacesNoConfig(t *testing.T) {
+	if _, err := loadNamespaces("./testdata/noconfig"); err == nil || !strings.Contains(err.Error(), "no utils.ExtractConfig calls found") {
+		t.Fatalf("expected no config error, got %v", err)
+	}
+}
@@ -0,0 +1,4 @@
+// noconfig is a program reading no config, configlint has nothing to check
+package main
+
+func main() {}
@@ -0,0 +1,60 @@
+// A program reading every kind of config field configlint knows about, linted
+// by the golden tests of configlint.
+package main
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+type store interface {
+	Put(key string) error
+}
+
+type policyConfig struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command" enum:"ALL,SELECT"`
+	Roles   []string `json:"roles"`
+}
+
+type tableConfig struct {
+	Table    string         `json:"table"`
+	Force    bool           `json:"force"`
+	Policies []policyConfig `json:"policies"`
+}
+
+type poolConfig struct {
+	Size    int     `json:"size"`
+	Ratio   float64 `json:"ratio"`
+	Enabled bool    `json:"enabled"`
+}
+
+type appConfig struct {
+	Database   string             `json:"database" required:""`
+	Host       pulumi.StringInput `json:"host"`
+	Password   pulumi.StringInput `secret:"password"`
+	Port       int                `json:"port"`
+	ExportMode string             `json:"exportMode" enum:"perUser,consolidated"`
+	SecretId   string             `json:"secretId" oneof:"superuser"`
+	SecretArn  string             `json:"secretArn" oneof:"superuser"`
+	Pool       *poolConfig        `json:"pool"`
+	Tables     []tableConfig      `json:"tables"`
+	Tags       map[string]string  `json:"tags"`
+	Exporter   store              `json:"exporter" union:"store"`
+
+	internal string
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		cfg := &appConfig{}
+		if err := utils.ExtractConfig(ctx, "app", cfg); err != nil {
+			return err
+		}
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		return metadata.Export(ctx)
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// eventTypeAttributes let the subscribers filter the access changes only
+const eventTypeAttributes = `{"eventType":{"DataType":"String","StringValue":"access-change"}}`
+
+type accessChanges struct {
+	Roles  []string `json:"roles"`
+	Users  []string `json:"users"`
+	Grants []string `json:"grants"`
+}
+
+// same as the accessChangelog output of programs/db-postgres-creds
+type accessChangelog struct {
+	Added   accessChanges `json:"added"`
+	Removed accessChanges `json:"removed"`
+	Changed bool          `json:"changed"`
+}
+
+func readAccessChangelog(programDir string, stack string) (*accessChangelog, error) {
+	out, err := exec.Command("pulumi", "stack", "output", "accessChangelog", "--json", "--stack", stack, "--cwd", programDir).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("pulumi stack output failed: %s", exitErr.Stderr)
+		}
+		return nil, err
+	}
+	changelog := &accessChangelog{}
+	if err := json.Unmarshal(out, changelog); err != nil {
+		return nil, fmt.Errorf("failed to parse accessChangelog output of %s, is pg:accessChangelog set? %w", stack, err)
+	}
+	return changelog, nil
+}
+
+func formatChanges(verb string, changes accessChanges) []string {
+	lines := []string{}
+	for _, kind := range []struct {
+		name   string
+		values []string
+	}{{"roles", changes.Roles}, {"users", changes.Users}, {"grants", changes.Grants}} {
+		if len(kind.values) > 0 {
+			lines = append(lines, fmt.Sprintf("%s %s: %s", verb, kind.name, strings.Join(kind.values, ", ")))
+		}
+	}
+	return lines
+}
+
+// runNotifyAccessChanges is meant to run in CI after `pulumi up`
+func runNotifyAccessChanges(args []string) error {
+	flags := flag.NewFlagSet("notify-access-changes", flag.ExitOnError)
+	programDir := flags.String("program", ".", "directory of the pulumi program")
+	stack := flags.String("stack", "", "name of the stack, e.g. org/dev")
+	topicArn := flags.String("topic", "", "SNS topic the access changes are published to")
+	region := flags.String("region", "", "AWS region of the topic, defaults to the one of the AWS profile")
+	flags.Parse(args)
+
+	if *stack == "" || *topicArn == "" {
+		return fmt.Errorf("-stack and -topic are required")
+	}
+	changelog, err := readAccessChangelog(*programDir, *stack)
+	if err != nil {
+		return err
+	}
+	if !changelog.Changed {
+		fmt.Printf("no access changes in %s\n", *stack)
+		return nil
+	}
+	lines := append(formatChanges("added", changelog.Added), formatChanges("removed", changelog.Removed)...)
+	message := fmt.Sprintf("Access changes deployed to %s:\n%s", *stack, strings.Join(lines, "\n"))
+	if _, err := awsCLI(*region, "sns", "publish",
+		"--topic-arn", *topicArn,
+		"--subject", fmt.Sprintf("Access changes in %s", *stack),
+		"--message", message,
+		"--message-attributes", eventTypeAttributes); err != nil {
+		return err
+	}
+	fmt.Println(message)
+	return nil
+}
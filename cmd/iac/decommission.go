@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os/exec"
+)
+
+// same as the audit output of programs/db-decommission
+type decommissionAudit struct {
+	Database       string   `json:"database"`
+	Secrets        []string `json:"secrets"`
+	RecoveryWindow int      `json:"recoveryWindow"`
+}
+
+func readDecommissionAudit(programDir string, stack string) (*decommissionAudit, error) {
+	out, err := exec.Command("pulumi", "stack", "output", "audit", "--json", "--stack", stack, "--cwd", programDir).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("pulumi stack output failed: %s", exitErr.Stderr)
+		}
+		return nil, err
+	}
+	audit := &decommissionAudit{}
+	if err := json.Unmarshal(out, audit); err != nil {
+		return nil, fmt.Errorf("failed to parse audit output of %s: %w", stack, err)
+	}
+	return audit, nil
+}
+
+// runDecommissionSecrets is the last step of the decommission, once the
+// db-decommission stack is deployed. The secrets belong to the creds stack,
+// so they're deleted here instead of by the program.
+func runDecommissionSecrets(args []string) error {
+	flags := flag.NewFlagSet("decommission-secrets", flag.ExitOnError)
+	programDir := flags.String("program", "./programs/db-decommission", "directory of the db-decommission program")
+	stack := flags.String("stack", "", "name of the db-decommission stack, e.g. org/billing")
+	region := flags.String("region", "", "AWS region of the secrets, defaults to the one of the AWS profile")
+	dryRun := flags.Bool("dry-run", false, "only list the secrets")
+	flags.Parse(args)
+
+	if *stack == "" {
+		return fmt.Errorf("-stack is required")
+	}
+	audit, err := readDecommissionAudit(*programDir, *stack)
+	if err != nil {
+		return err
+	}
+	if len(audit.Secrets) == 0 {
+		fmt.Printf("database %s has no secrets\n", audit.Database)
+		return nil
+	}
+	for _, name := range audit.Secrets {
+		fmt.Printf("%s\t(database %s)\n", name, audit.Database)
+		if *dryRun {
+			continue
+		}
+		if err := scheduleDeletion(*region, name, audit.RecoveryWindow); err != nil {
+			return fmt.Errorf("failed to delete secret '%s': %w", name, err)
+		}
+		fmt.Printf("%s\tscheduled for deletion in %d days\n", name, audit.RecoveryWindow)
+	}
+	return nil
+}
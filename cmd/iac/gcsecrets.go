@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// findOrphanedSecrets returns the managed secrets whose owning stack no
+// longer exists. Secrets without the stack tag (created before it was
+// introduced) are skipped, since their owner can't be determined.
+func findOrphanedSecrets(secrets []managedSecret, exists func(stack string) (bool, error)) ([]managedSecret, error) {
+	checked := map[string]bool{}
+	orphans := []managedSecret{}
+	for _, s := range secrets {
+		if s.Stack == "" {
+			continue
+		}
+		found, ok := checked[s.Stack]
+		if !ok {
+			var err error
+			if found, err = exists(s.Stack); err != nil {
+				return nil, err
+			}
+			checked[s.Stack] = found
+		}
+		if !found {
+			orphans = append(orphans, s)
+		}
+	}
+	return orphans, nil
+}
+
+func runGCSecrets(args []string) error {
+	flags := flag.NewFlagSet("gc-secrets", flag.ExitOnError)
+	region := flags.String("region", "", "AWS region of the secrets, defaults to the one of the AWS profile")
+	del := flags.Bool("delete", false, "schedule deletion of the orphaned secrets, instead of only listing them")
+	recoveryDays := flags.Int("recovery-window", 30, "days (7-30) during which a deleted secret can be restored")
+	flags.Parse(args)
+
+	if *recoveryDays < 7 || *recoveryDays > 30 {
+		return fmt.Errorf("recovery window must be within 7 and 30 days, got %d", *recoveryDays)
+	}
+	client, err := newStackClient()
+	if err != nil {
+		return err
+	}
+	secrets, err := listManagedSecrets(*region)
+	if err != nil {
+		return err
+	}
+	orphans, err := findOrphanedSecrets(secrets, client.StackExists)
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		fmt.Println("no orphaned secrets found")
+		return nil
+	}
+	for _, s := range orphans {
+		fmt.Printf("%s\t(stack %s is gone)\n", s.Name, s.Stack)
+		if !*del {
+			continue
+		}
+		if err := scheduleDeletion(*region, s.Arn, *recoveryDays); err != nil {
+			return fmt.Errorf("failed to delete secret '%s': %w", s.Name, err)
+		}
+		fmt.Printf("%s\tscheduled for deletion in %d days\n", s.Name, *recoveryDays)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindOrphanedSecrets(t *testing.T) {
+	billing := managedSecret{Name: "db-creds-pg-billing-user-tom", Stack: "acme/db-postgres-creds/billing"}
+	billingAnn := managedSecret{Name: "db-creds-pg-billing-user-ann", Stack: "acme/db-postgres-creds/billing"}
+	catalog := managedSecret{Name: "db-creds-pg-catalog-user-bob", Stack: "acme/db-postgres-creds/catalog"}
+	untagged := managedSecret{Name: "db-creds-pg-legacy-user-zoe"}
+
+	tests := []struct {
+		name    string
+		secrets []managedSecret
+		// stacks that exist, any other one is gone
+		stacks map[string]bool
+		// stack whose lookup fails
+		failing   string
+		want      []managedSecret
+		wantCalls int
+		wantErr   string
+	}{
+		{
+			name:    "untagged secrets are skipped",
+			secrets: []managedSecret{untagged},
+			want:    []managedSecret{},
+		},
+		{
+			name:      "stack that exists",
+			secrets:   []managedSecret{billing, untagged},
+			stacks:    map[string]bool{billing.Stack: true},
+			want:      []managedSecret{},
+			wantCalls: 1,
+		},
+		{
+			name:      "stack that is gone, looked up once",
+			secrets:   []managedSecret{billing, catalog, billingAnn},
+			stacks:    map[string]bool{catalog.Stack: true},
+			want:      []managedSecret{billing, billingAnn},
+			wantCalls: 2,
+		},
+		{
+			name:      "error from exists",
+			secrets:   []managedSecret{billing, catalog},
+			stacks:    map[string]bool{billing.Stack: true},
+			failing:   catalog.Stack,
+			wantCalls: 2,
+			wantErr:   "failed to get stack acme/db-postgres-creds/catalog",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			exists := func(stack string) (bool, error) {
+				calls++
+				if stack == tt.failing {
+					return false, errors.New("failed to get stack " + stack)
+				}
+				return tt.stacks[stack], nil
+			}
+			got, err := findOrphanedSecrets(tt.secrets, exists)
+			if calls != tt.wantCalls {
+				t.Errorf("exists called %d times, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+)
+
+const defaultKMSAliasPrefix = "alias/pulumi-"
+
+// secretsProviderURL is the awskms secrets provider of the environment, so the
+// config & state secrets of its stacks are encrypted with the org keys.
+func secretsProviderURL(keyAlias string, region string) string {
+	providerURL := fmt.Sprintf("awskms://%s", keyAlias)
+	if region != "" {
+		providerURL = fmt.Sprintf("%s?region=%s", providerURL, url.QueryEscape(region))
+	}
+	return providerURL
+}
+
+func pulumiCLI(programDir string, args ...string) error {
+	cmd := exec.Command("pulumi", append(args, "--cwd", programDir)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pulumi %s failed: %w", args[0], err)
+	}
+	return nil
+}
+
+func runInitStack(args []string) error {
+	flags := flag.NewFlagSet("init-stack", flag.ExitOnError)
+	programDir := flags.String("program", ".", "directory of the pulumi program")
+	stack := flags.String("stack", "", "name of the stack, e.g. org/dev")
+	env := flags.String("env", "", "environment of the stack, the KMS key is resolved from it")
+	keyAlias := flags.String("kms-alias", "", "alias of the KMS key, overrides the one of the environment ("+defaultKMSAliasPrefix+"<env>)")
+	region := flags.String("region", "", "AWS region of the KMS key, defaults to the one of the AWS profile")
+	migrate := flags.Bool("migrate", false, "switch the secrets provider of an existing stack, instead of creating it")
+	flags.Parse(args)
+
+	if *stack == "" {
+		return fmt.Errorf("-stack is required")
+	}
+	if *keyAlias == "" {
+		if *env == "" {
+			return fmt.Errorf("either -env or -kms-alias is required")
+		}
+		*keyAlias = defaultKMSAliasPrefix + *env
+	}
+	providerURL := secretsProviderURL(*keyAlias, *region)
+	if *migrate {
+		// re-encrypts the existing secrets of the stack with the new provider
+		return pulumiCLI(*programDir, "stack", "change-secrets-provider", providerURL, "--stack", *stack)
+	}
+	return pulumiCLI(*programDir, "stack", "init", *stack, "--secrets-provider", providerURL)
+}
@@ -0,0 +1,56 @@
+// iac bundles the maintenance commands of the stacks managed by this repo.
+//
+// Usage:
+//
+//	go run ./cmd/iac gc-secrets [-delete]
+//	go run ./cmd/iac init-stack -program ./programs/db-postgres-creds -stack org/dev -env dev
+//	go run ./cmd/iac notify-access-changes -program ./programs/db-postgres-creds -stack org/dev -topic <arn>
+//	go run ./cmd/iac decommission-secrets -program ./programs/db-decommission -stack org/billing
+//	go run ./cmd/iac state-summary -program ./programs/db-postgres-creds -stack org/dev -format markdown
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{name: "gc-secrets", usage: "list (and delete) the secrets whose owning stack no longer exists", run: runGCSecrets},
+	{name: "init-stack", usage: "create a stack encrypting its secrets with the KMS key of its environment", run: runInitStack},
+	{name: "notify-access-changes", usage: "publish the access changes of the last deployment to SNS", run: runNotifyAccessChanges},
+	{name: "decommission-secrets", usage: "schedule deletion of the secrets of a decommissioned database", run: runDecommissionSecrets},
+	{name: "state-summary", usage: "render a redacted summary of the stack resources for reviews", run: runStateSummary},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: iac <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-22s %s\n", c.name, c.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command '%s'\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultBackendURL = "https://api.pulumi.com"
+
+// stackClient checks the existence of stacks via the Pulumi service API.
+type stackClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newStackClient() (*stackClient, error) {
+	token := os.Getenv("PULUMI_ACCESS_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("PULUMI_ACCESS_TOKEN is required to query the stacks")
+	}
+	baseURL := os.Getenv("PULUMI_BACKEND_URL")
+	if baseURL == "" {
+		baseURL = defaultBackendURL
+	}
+	return &stackClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// StackExists reports whether the fully qualified stack (org/project/stack)
+// still exists in the service.
+func (c *stackClient) StackExists(stack string) (bool, error) {
+	if len(strings.Split(stack, "/")) != 3 {
+		return false, fmt.Errorf("stack '%s' is not fully qualified as org/project/stack", stack)
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/stacks/%s", c.baseURL, stack), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Accept", "application/vnd.pulumi+8")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to query stack '%s': %w", stack, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to query stack '%s': %s", stack, resp.Status)
+	}
+}
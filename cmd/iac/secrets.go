@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// same tags as set by components/aws/secret
+const (
+	managedTag = "Pulumi"
+	stackTag   = "pulumi:stack"
+)
+
+type managedSecret struct {
+	Name  string
+	Arn   string
+	Stack string
+}
+
+// The AWS CLI is used instead of the SDK, so it picks up the same profiles
+// and SSO sessions as the pulumi operations.
+func awsCLI(region string, args ...string) ([]byte, error) {
+	if region != "" {
+		args = append(args, "--region", region)
+	}
+	args = append(args, "--output", "json")
+	out, err := exec.Command("aws", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("aws %s failed: %s", args[1], exitErr.Stderr)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// listManagedSecrets returns the secrets tagged Pulumi=true along with their
+// owning stack, if tagged.
+func listManagedSecrets(region string) ([]managedSecret, error) {
+	out, err := awsCLI(region, "secretsmanager", "list-secrets",
+		"--filters", fmt.Sprintf("Key=tag-key,Values=%s", managedTag), "Key=tag-value,Values=true")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		SecretList []struct {
+			Name string
+			ARN  string
+			Tags []struct {
+				Key   string
+				Value string
+			}
+		}
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse the secrets list: %w", err)
+	}
+	secrets := make([]managedSecret, 0, len(resp.SecretList))
+	for _, s := range resp.SecretList {
+		secret := managedSecret{Name: s.Name, Arn: s.ARN}
+		for _, tag := range s.Tags {
+			if tag.Key == stackTag {
+				secret.Stack = tag.Value
+			}
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// scheduleDeletion deletes the secret after the recovery window, during
+// which it can still be restored.
+func scheduleDeletion(region string, secretId string, recoveryDays int) error {
+	_, err := awsCLI(region, "secretsmanager", "delete-secret",
+		"--secret-id", secretId, "--recovery-window-in-days", strconv.Itoa(recoveryDays))
+	return err
+}
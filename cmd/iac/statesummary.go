@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// properties whose name hints at creds are left out, even if not marked secret
+var sensitiveProperty = regexp.MustCompile(`(?i)password|secret|token|private|credential|dsn|ciphertext`)
+
+// subset of the `pulumi stack export` deployment
+type stackExport struct {
+	Deployment struct {
+		Resources []struct {
+			URN     string                 `json:"urn"`
+			Type    string                 `json:"type"`
+			Parent  string                 `json:"parent"`
+			Protect bool                   `json:"protect"`
+			Outputs map[string]interface{} `json:"outputs"`
+		} `json:"resources"`
+	} `json:"deployment"`
+}
+
+type resourceSummary struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Parent     string            `json:"parent,omitempty"`
+	Protected  bool              `json:"protected,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type stackSummary struct {
+	Stack     string            `json:"stack"`
+	Counts    map[string]int    `json:"counts"`
+	Resources []resourceSummary `json:"resources"`
+}
+
+func readStackExport(programDir string, stack string) (*stackExport, error) {
+	out, err := exec.Command("pulumi", "stack", "export", "--stack", stack, "--cwd", programDir).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("pulumi stack export failed: %s", exitErr.Stderr)
+		}
+		return nil, err
+	}
+	export := &stackExport{}
+	if err := json.Unmarshal(out, export); err != nil {
+		return nil, fmt.Errorf("failed to parse the state of %s: %w", stack, err)
+	}
+	return export, nil
+}
+
+// urnName is the logical name, the last part of urn:pulumi:stack::project::type::name
+func urnName(urn string) string {
+	parts := strings.Split(urn, "::")
+	return parts[len(parts)-1]
+}
+
+// keyProperties keeps the scalar outputs. The secrets are objects holding
+// the ciphertext (the export runs without --show-secrets), so they're left
+// out with the nested ones (policies, tags), which are too noisy for a review.
+func keyProperties(outputs map[string]interface{}) map[string]string {
+	props := map[string]string{}
+	for key, value := range outputs {
+		if strings.HasPrefix(key, "__") || sensitiveProperty.MatchString(key) {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			if v != "" {
+				props[key] = v
+			}
+		case bool, float64:
+			props[key] = fmt.Sprint(v)
+		}
+	}
+	return props
+}
+
+func summarize(stack string, export *stackExport) *stackSummary {
+	summary := &stackSummary{Stack: stack, Counts: map[string]int{}}
+	for _, res := range export.Deployment.Resources {
+		// providers hold the superuser creds of the programs
+		if res.Type == "pulumi:pulumi:Stack" || strings.HasPrefix(res.Type, "pulumi:providers:") {
+			continue
+		}
+		summary.Counts[res.Type]++
+		resSummary := resourceSummary{
+			Name:       urnName(res.URN),
+			Type:       res.Type,
+			Protected:  res.Protect,
+			Properties: keyProperties(res.Outputs),
+		}
+		if res.Parent != "" && !strings.HasSuffix(res.Parent, "::pulumi:pulumi:Stack") {
+			resSummary.Parent = urnName(res.Parent)
+		}
+		summary.Resources = append(summary.Resources, resSummary)
+	}
+	sort.Slice(summary.Resources, func(i, j int) bool {
+		a, b := summary.Resources[i], summary.Resources[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	return summary
+}
+
+func markdownCell(value string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(value, "|", `\|`), "\n", " ")
+}
+
+func (s *stackSummary) markdown() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# Stack %s\n\n", s.Stack)
+	types := make([]string, 0, len(s.Counts))
+	for t := range s.Counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	b.WriteString("| Type | Count |\n| --- | --- |\n")
+	for _, t := range types {
+		fmt.Fprintf(b, "| `%s` | %d |\n", t, s.Counts[t])
+	}
+	b.WriteString("\n## Resources\n\n| Name | Type | Parent | Protected | Properties |\n| --- | --- | --- | --- | --- |\n")
+	for _, res := range s.Resources {
+		keys := make([]string, 0, len(res.Properties))
+		for k := range res.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		props := make([]string, len(keys))
+		for i, k := range keys {
+			props[i] = fmt.Sprintf("%s: %s", k, markdownCell(res.Properties[k]))
+		}
+		protected := ""
+		if res.Protected {
+			protected = "yes"
+		}
+		fmt.Fprintf(b, "| %s | `%s` | %s | %s | %s |\n", markdownCell(res.Name), res.Type, markdownCell(res.Parent), protected, strings.Join(props, "<br>"))
+	}
+	return b.String()
+}
+
+// runStateSummary renders the resources of the stack for the reviewers who
+// can't access the Pulumi console. Secrets are never decrypted.
+func runStateSummary(args []string) error {
+	flags := flag.NewFlagSet("state-summary", flag.ExitOnError)
+	programDir := flags.String("program", ".", "directory of the pulumi program")
+	stack := flags.String("stack", "", "name of the stack, e.g. org/dev")
+	format := flags.String("format", "markdown", "markdown or json")
+	outFile := flags.String("out", "", "file the summary is written to, stdout if not set")
+	flags.Parse(args)
+
+	if *stack == "" {
+		return fmt.Errorf("-stack is required")
+	}
+	if *format != "markdown" && *format != "json" {
+		return fmt.Errorf("invalid format '%s', expected markdown or json", *format)
+	}
+	export, err := readStackExport(*programDir, *stack)
+	if err != nil {
+		return err
+	}
+	summary := summarize(*stack, export)
+	var out string
+	if *format == "json" {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = string(data) + "\n"
+	} else {
+		out = summary.markdown()
+	}
+	if *outFile == "" {
+		fmt.Print(out)
+		return nil
+	}
+	return os.WriteFile(*outFile, []byte(out), 0o644)
+}
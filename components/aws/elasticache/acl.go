@@ -0,0 +1,261 @@
+package elasticache
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/elasticache"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	defaultPort = 6379
+	// elasticache accepts passwords of 16 to 128 characters
+	passwordLength = 32
+	// longest user id of elasticache
+	maxUserIdLength = 40
+)
+
+var (
+	// the user id is <component>-<username>, it needs to begin with a letter
+	// and can't have consecutive or trailing hyphens
+	userIdRegex = regexp.MustCompile(`^[A-Za-z](-?[A-Za-z0-9])*$`)
+	// +@read, -flushall, +config|get...
+	commandRegex = regexp.MustCompile(`^[+-]@?[a-z0-9|_-]+$`)
+	// the default commands of the users, all but the dangerous ones, e.g.
+	// FLUSHALL, KEYS or CONFIG
+	defaultCommands = []string{"+@all", "-@dangerous"}
+)
+
+type ElastiCacheACLUserProps struct {
+	Username string `json:"username"`
+	// Key patterns the user can access, e.g. billing:*. Only the keys
+	// prefixed by the username (<username>:*) if not set.
+	Keys []string `json:"keys"`
+	// Command rules, e.g. +@read or -flushall, +@all -@dangerous if not set
+	Commands []string `json:"commands"`
+	// Pub/Sub channel patterns the user can access, none if not set
+	Channels []string `json:"channels"`
+	// Bump it to rotate the generated password
+	RotationTrigger string `json:"rotationTrigger"`
+}
+
+type ElastiCacheACLProps struct {
+	// User group of the replication group the users are added to
+	UserGroupId string `json:"userGroupId"`
+	// Endpoint of the replication group, for the secrets
+	Host pulumi.StringInput `json:"-"`
+	// 6379 if not set
+	Port  int                       `json:"port"`
+	Users []ElastiCacheACLUserProps `json:"users"`
+	// Extra tags of the secrets
+	Tags map[string]string `json:"tags"`
+	// Only exposes the passwords, e.g. to export them as stack outputs,
+	// instead of storing the creds in secrets
+	NoSecrets bool `json:"noSecrets"`
+}
+
+type ElastiCacheACLResource struct {
+	pulumi.ResourceState
+
+	// Keyed by username
+	Users map[string]*elasticache.User
+	// Generated passwords, keyed by username
+	Passwords map[string]pulumi.StringOutput
+	// Secrets of the redis creds, keyed by username, empty with NoSecrets
+	Secrets map[string]*secret.AWSSecret
+	// Errors of the failed users, keyed by username
+	FailedUsers map[string]error
+}
+
+func (props *ElastiCacheACLProps) validate() error {
+	if props.UserGroupId == "" {
+		return fmt.Errorf("userGroupId is required")
+	}
+	if props.Host == nil {
+		return fmt.Errorf("host of the replication group is required")
+	}
+	if props.Port == 0 {
+		props.Port = defaultPort
+	}
+	return nil
+}
+
+// validatePatterns rejects the patterns breaking the access string
+func validatePatterns(kind string, patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" || strings.ContainsAny(pattern, " \t\"") {
+			return fmt.Errorf("invalid %s pattern '%s', spaces and quotes aren't allowed", kind, pattern)
+		}
+	}
+	return nil
+}
+
+func (user *ElastiCacheACLUserProps) validate(name string) error {
+	if user.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	// the default user of the user group is managed with the replication group
+	if user.Username == "default" {
+		return fmt.Errorf("username 'default' is reserved")
+	}
+	userId := fmt.Sprintf("%s-%s", name, user.Username)
+	if !userIdRegex.MatchString(userId) || len(userId) > maxUserIdLength {
+		return fmt.Errorf("invalid user id '%s', expected up to %d letters, digits and single hyphens", userId, maxUserIdLength)
+	}
+	if len(user.Keys) == 0 {
+		user.Keys = []string{user.Username + ":*"}
+	}
+	if len(user.Commands) == 0 {
+		user.Commands = defaultCommands
+	}
+	for _, command := range user.Commands {
+		if !commandRegex.MatchString(strings.ToLower(command)) {
+			return fmt.Errorf("invalid command rule '%s', expected e.g. +@read or -flushall", command)
+		}
+	}
+	if err := validatePatterns("key", user.Keys); err != nil {
+		return err
+	}
+	return validatePatterns("channel", user.Channels)
+}
+
+// accessString builds the ACL rules of the user, e.g. on ~billing:* +@all -@dangerous
+func (user *ElastiCacheACLUserProps) accessString() string {
+	rules := []string{"on"}
+	for _, key := range user.Keys {
+		rules = append(rules, "~"+key)
+	}
+	for _, channel := range user.Channels {
+		rules = append(rules, "&"+channel)
+	}
+	rules = append(rules, user.Commands...)
+	return strings.Join(rules, " ")
+}
+
+func (r *ElastiCacheACLResource) provision(ctx *pulumi.Context, name string, props *ElastiCacheACLProps, user *ElastiCacheACLUserProps, opts ...pulumi.ResourceOption) (*elasticache.UserGroupAssociation, error) {
+	if err := user.validate(name); err != nil {
+		return nil, err
+	}
+	resName := fmt.Sprintf("%s-%s", name, user.Username)
+	keepers := map[string]string{}
+	if user.RotationTrigger != "" {
+		keepers["rotationTrigger"] = user.RotationTrigger
+	}
+	password, err := utils.NewRotatingPassword(ctx, fmt.Sprintf("%s-password", resName), passwordLength, keepers, pulumi.Parent(r))
+	if err != nil {
+		return nil, err
+	}
+	// ACL SETUSER $USER on >$PASSWORD ~$KEYS +@all -@dangerous
+	redisUser, err := elasticache.NewUser(ctx, resName, &elasticache.UserArgs{
+		UserId:       pulumi.String(resName),
+		UserName:     pulumi.String(user.Username),
+		Engine:       pulumi.String("REDIS"),
+		AccessString: pulumi.String(user.accessString()),
+		AuthenticationMode: &elasticache.UserAuthenticationModeArgs{
+			Type:      pulumi.String("password"),
+			Passwords: pulumi.StringArray{password},
+		},
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	}, pulumi.Parent(r))
+	if err != nil {
+		return nil, err
+	}
+	association, err := elasticache.NewUserGroupAssociation(ctx, fmt.Sprintf("%s-group", resName), &elasticache.UserGroupAssociationArgs{
+		UserGroupId: pulumi.String(props.UserGroupId),
+		UserId:      redisUser.UserId,
+	}, append(opts, pulumi.Parent(r))...)
+	if err != nil {
+		return nil, err
+	}
+	r.Users[user.Username] = redisUser
+	r.Passwords[user.Username] = password
+	if props.NoSecrets {
+		return association, nil
+	}
+
+	tags := map[string]string{
+		"redis:acl":  name,
+		"redis:user": user.Username,
+	}
+	for k, v := range props.Tags {
+		tags[k] = v
+	}
+	secretRes, err := secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+		Name: fmt.Sprintf("%s-user-%s", name, user.Username),
+		Type: secret.RedisCreds,
+		InitialValue: pulumi.StringMap{
+			"username": pulumi.String(user.Username),
+			"password": password,
+			"host":     props.Host,
+			"port":     pulumi.Sprintf("%d", props.Port),
+			// the RBAC of elasticache needs the in-transit encryption
+			"tls": pulumi.String("true"),
+		},
+		Tags: tags,
+	}, pulumi.Parent(r), pulumi.DependsOn([]pulumi.Resource{association}))
+	if err != nil {
+		return association, fmt.Errorf("failed to create secret: %w", err)
+	}
+	r.Secrets[user.Username] = secretRes
+	return association, nil
+}
+
+// NewElastiCacheACL creates the ACL users (Redis 6+) of the services sharing an
+// ElastiCache replication group, with random passwords, and adds them to its
+// user group. The creds of each user are stored in a secret of the redis type,
+// unless NoSecrets is set.
+//
+// It's ElastiCache only: the users are managed by the ElastiCache API, not by
+// ACL SETUSER on the server, so a self-hosted Redis (or MemoryDB) isn't
+// supported. The type token is still the one of the former NewRedisACL so the
+// existing stacks keep their users.
+func NewElastiCacheACL(ctx *pulumi.Context, name string, props ElastiCacheACLProps, opts ...pulumi.ResourceOption) (*ElastiCacheACLResource, error) {
+	if err := props.validate(); err != nil {
+		return nil, err
+	}
+	resource := &ElastiCacheACLResource{
+		Users:       map[string]*elasticache.User{},
+		Passwords:   map[string]pulumi.StringOutput{},
+		Secrets:     map[string]*secret.AWSSecret{},
+		FailedUsers: map[string]error{},
+	}
+	if err := ctx.RegisterComponentResource("ss9:aws:elasticache:redisacl", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	// a failed user doesn't hold back the others
+	errs := []error{}
+	usernames := pulumi.StringArray{}
+	var previous pulumi.Resource
+	for i := range props.Users {
+		user := &props.Users[i]
+		// elasticache refuses to modify a user group while it's modifying, so
+		// the users are added one after the other
+		assocOpts := []pulumi.ResourceOption{}
+		if previous != nil {
+			assocOpts = append(assocOpts, pulumi.DependsOn([]pulumi.Resource{previous}))
+		}
+		association, err := resource.provision(ctx, name, &props, user, assocOpts...)
+		if association != nil {
+			previous = association
+		}
+		if err != nil {
+			resource.FailedUsers[user.Username] = err
+			errs = append(errs, fmt.Errorf("user %s: %w", user.Username, err))
+			continue
+		}
+		usernames = append(usernames, pulumi.String(user.Username))
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"userGroupId": pulumi.String(props.UserGroupId),
+		"usernames":   usernames,
+	})
+	return resource, errors.Join(errs...)
+}
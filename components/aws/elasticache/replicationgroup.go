@@ -0,0 +1,243 @@
+package elasticache
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/elasticache"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	defaultNodeType      = "cache.t4g.micro"
+	defaultEngineVersion = "7.1"
+	// longest replication group id of elasticache
+	maxClusterIdLength = 40
+)
+
+// elasticache lowercases the ids, so only the lowercase ones are accepted
+var clusterIdRegex = regexp.MustCompile(`^[a-z](-?[a-z0-9])*$`)
+
+type RedisClusterProps struct {
+	// Id of the replication group, and name of its subnet & parameter groups
+	Name string `json:"name"`
+	// Private subnets of the nodes, in different AZs for the replicas
+	SubnetIds        []string `json:"subnetIds"`
+	SecurityGroupIds []string `json:"securityGroupIds"`
+	// cache.t4g.micro if not set
+	NodeType string `json:"nodeType"`
+	// 6.x or 7.x, 7.1 if not set
+	EngineVersion string `json:"engineVersion"`
+	// 6379 if not set
+	Port int `json:"port"`
+	// Replicas of the primary, the automatic failover and multi-AZ are
+	// enabled if there's any
+	Replicas int `json:"replicas"`
+	// Parameters of the parameter group, e.g. maxmemory-policy: allkeys-lru
+	Parameters map[string]string `json:"parameters"`
+	// KMS key of the encryption at rest, the elasticache one if not set
+	KmsKeyId string `json:"kmsKeyId"`
+	// Days the daily snapshots are kept, no snapshot if not set
+	SnapshotRetentionDays int `json:"snapshotRetentionDays"`
+	// User group of the ACL users (see NewElastiCacheACL), instead of the auth
+	// token. ElastiCache doesn't allow both.
+	UserGroupId string `json:"userGroupId"`
+	// Bump it to rotate the auth token. ElastiCache keeps the previous one
+	// valid until the next rotation, so the clients can switch meanwhile.
+	AuthTokenRotationTrigger string `json:"authTokenRotationTrigger"`
+	// Fails any delete of the replication group, e.g. in a destroy
+	Protect bool `json:"protect"`
+}
+
+type RedisClusterResource struct {
+	pulumi.ResourceState
+
+	ReplicationGroup *elasticache.ReplicationGroup
+	// Endpoints of the primary and of the replicas
+	PrimaryEndpoint pulumi.StringOutput
+	ReaderEndpoint  pulumi.StringOutput
+	Port            int
+	// Secret of the auth token, nil if the cluster uses a user group
+	AuthTokenSecret *secret.AWSSecret
+}
+
+func (props *RedisClusterProps) validate() error {
+	if !clusterIdRegex.MatchString(props.Name) || len(props.Name) > maxClusterIdLength {
+		return fmt.Errorf("invalid name '%s', expected up to %d lowercase letters, digits and single hyphens", props.Name, maxClusterIdLength)
+	}
+	if len(props.SubnetIds) == 0 {
+		return fmt.Errorf("at least one subnet is required")
+	}
+	if props.Replicas < 0 || props.Replicas > 5 {
+		return fmt.Errorf("replicas of %s need to be between 0 and 5, got %d", props.Name, props.Replicas)
+	}
+	if props.NodeType == "" {
+		props.NodeType = defaultNodeType
+	}
+	if props.EngineVersion == "" {
+		props.EngineVersion = defaultEngineVersion
+	}
+	if props.Port == 0 {
+		props.Port = defaultPort
+	}
+	return nil
+}
+
+// parameterGroupFamily is the family of the engine version, e.g. redis7 for 7.1
+func parameterGroupFamily(engineVersion string) (string, error) {
+	major, _, _ := strings.Cut(engineVersion, ".")
+	switch major {
+	case "6":
+		return "redis6.x", nil
+	case "7":
+		return "redis7", nil
+	}
+	return "", fmt.Errorf("unsupported redis version '%s', expected 6.x or 7.x", engineVersion)
+}
+
+func (r *RedisClusterResource) provision(ctx *pulumi.Context, props *RedisClusterProps) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	family, err := parameterGroupFamily(props.EngineVersion)
+	if err != nil {
+		return err
+	}
+	destroyProtected, err := utils.DestroyProtected(ctx)
+	if err != nil {
+		return err
+	}
+	tags := pulumi.StringMap{
+		"Pulumi": pulumi.String("true"),
+	}
+	subnetGroup, err := elasticache.NewSubnetGroup(ctx, props.Name, &elasticache.SubnetGroupArgs{
+		Name:        pulumi.String(props.Name),
+		Description: pulumi.Sprintf("Subnets of the redis cluster %s", props.Name),
+		SubnetIds:   pulumi.ToStringArray(props.SubnetIds),
+		Tags:        tags,
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	// sorted, so the parameters don't show a diff on every preview
+	keys := make([]string, 0, len(props.Parameters))
+	for key := range props.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parameters := elasticache.ParameterGroupParameterArray{}
+	for _, key := range keys {
+		parameters = append(parameters, elasticache.ParameterGroupParameterArgs{
+			Name:  pulumi.String(key),
+			Value: pulumi.String(props.Parameters[key]),
+		})
+	}
+	parameterGroup, err := elasticache.NewParameterGroup(ctx, props.Name, &elasticache.ParameterGroupArgs{
+		Name:        pulumi.String(props.Name),
+		Family:      pulumi.String(family),
+		Description: pulumi.Sprintf("Parameters of the redis cluster %s", props.Name),
+		Parameters:  parameters,
+		Tags:        tags,
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+
+	args := &elasticache.ReplicationGroupArgs{
+		ReplicationGroupId:       pulumi.String(props.Name),
+		Description:              pulumi.Sprintf("Redis cluster %s", props.Name),
+		Engine:                   pulumi.String("redis"),
+		EngineVersion:            pulumi.String(props.EngineVersion),
+		NodeType:                 pulumi.String(props.NodeType),
+		Port:                     pulumi.Int(props.Port),
+		NumCacheClusters:         pulumi.Int(props.Replicas + 1),
+		AutomaticFailoverEnabled: pulumi.Bool(props.Replicas > 0),
+		MultiAzEnabled:           pulumi.Bool(props.Replicas > 0),
+		SubnetGroupName:          subnetGroup.Name,
+		ParameterGroupName:       parameterGroup.Name,
+		SecurityGroupIds:         pulumi.ToStringArray(props.SecurityGroupIds),
+		AtRestEncryptionEnabled:  pulumi.Bool(true),
+		// the auth token and the ACL users need it
+		TransitEncryptionEnabled: pulumi.Bool(true),
+		SnapshotRetentionLimit:   pulumi.Int(props.SnapshotRetentionDays),
+		FinalSnapshotIdentifier:  pulumi.Sprintf("%s-final", props.Name),
+		Tags:                     tags,
+	}
+	if props.KmsKeyId != "" {
+		args.KmsKeyId = pulumi.String(props.KmsKeyId)
+	}
+	var authToken pulumi.StringOutput
+	if props.UserGroupId != "" {
+		args.UserGroupIds = pulumi.StringArray{pulumi.String(props.UserGroupId)}
+	} else {
+		// elasticache refuses the auth tokens with @ " or /, none is generated
+		keepers := map[string]string{}
+		if props.AuthTokenRotationTrigger != "" {
+			keepers["rotationTrigger"] = props.AuthTokenRotationTrigger
+		}
+		authToken, err = utils.NewRotatingPassword(ctx, fmt.Sprintf("%s-auth-token", props.Name), passwordLength, keepers, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		args.AuthToken = authToken
+		// ROTATE (unlike SET) accepts both tokens until the next rotation
+		args.AuthTokenUpdateStrategy = pulumi.String("ROTATE")
+	}
+	groupOpts := []pulumi.ResourceOption{pulumi.Parent(r)}
+	if props.Protect || destroyProtected {
+		groupOpts = append(groupOpts, pulumi.Protect(true))
+	}
+	r.ReplicationGroup, err = elasticache.NewReplicationGroup(ctx, props.Name, args, groupOpts...)
+	if err != nil {
+		return err
+	}
+	r.PrimaryEndpoint = r.ReplicationGroup.PrimaryEndpointAddress
+	r.ReaderEndpoint = r.ReplicationGroup.ReaderEndpointAddress
+	r.Port = props.Port
+	if props.UserGroupId != "" {
+		return nil
+	}
+
+	r.AuthTokenSecret, err = secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+		Name: fmt.Sprintf("%s-auth-token", props.Name),
+		Type: secret.RedisCreds,
+		InitialValue: pulumi.StringMap{
+			"host":       r.PrimaryEndpoint,
+			"readerHost": r.ReaderEndpoint,
+			"port":       pulumi.Sprintf("%d", props.Port),
+			"password":   authToken,
+			"tls":        pulumi.String("true"),
+		},
+		Tags: map[string]string{
+			"redis:cluster": props.Name,
+		},
+	}, pulumi.Parent(r))
+	if err != nil {
+		return fmt.Errorf("failed to create secret of the auth token: %w", err)
+	}
+	return nil
+}
+
+// NewRedisCluster creates an ElastiCache replication group of redis, encrypted
+// at rest & in transit, with its subnet & parameter groups. Its auth token is
+// generated and stored in a secret of the redis type, with the endpoints.
+func NewRedisCluster(ctx *pulumi.Context, props RedisClusterProps, opts ...pulumi.ResourceOption) (*RedisClusterResource, error) {
+	resource := &RedisClusterResource{}
+	if err := ctx.RegisterComponentResource("ss9:aws:elasticache:redis", props.Name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"primaryEndpoint": resource.PrimaryEndpoint,
+		"readerEndpoint":  resource.ReaderEndpoint,
+		"port":            pulumi.Int(resource.Port),
+	})
+	return resource, nil
+}
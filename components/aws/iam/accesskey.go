@@ -0,0 +1,95 @@
+package iam
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+)
+
+// AWS allows at most 2 access keys per IAM user
+const accessKeySlots = 2
+
+type AccessKeyProps struct {
+	Name string
+	// IAM user owning the access keys
+	User pulumi.StringInput
+	// Bump it to rotate the key which isn't currently active
+	RotationKeeper int
+	// Optional store to expose the active key
+	Store secret.SecretStore
+}
+
+type AccessKeyResource struct {
+	pulumi.ResourceState
+
+	Keys      []*iam.AccessKey
+	ActiveKey *iam.AccessKey
+	SecretId  pulumi.StringOutput
+}
+
+// slotKeeper returns the generation of the key in given slot.
+// The slots regenerate alternately, i.e. every keeper change replaces only
+// one key and the other one stays valid till the consumers switch over.
+func slotKeeper(slot int, keeper int) int {
+	return (keeper + accessKeySlots - 1 - slot) / accessKeySlots
+}
+
+// activeSlot is the slot which was regenerated most recently.
+func activeSlot(keeper int) int {
+	return (keeper + 1) % accessKeySlots
+}
+
+func (r *AccessKeyResource) provision(ctx *pulumi.Context, props *AccessKeyProps) error {
+	if props.RotationKeeper < 0 {
+		return fmt.Errorf("rotation keeper must not be negative, got %d", props.RotationKeeper)
+	}
+	r.Keys = make([]*iam.AccessKey, accessKeySlots)
+	for slot := range r.Keys {
+		key, err := iam.NewAccessKey(ctx, fmt.Sprintf("%s-key%d-%d", props.Name, slot, slotKeeper(slot, props.RotationKeeper)), &iam.AccessKeyArgs{
+			User: props.User,
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.Keys[slot] = key
+	}
+	r.ActiveKey = r.Keys[activeSlot(props.RotationKeeper)]
+
+	if props.Store != nil {
+		secretId, err := props.Store.Store(ctx, props.Name, secret.IAMCreds, r.credsMap(props), pulumi.Parent(r))
+		if err != nil {
+			return fmt.Errorf("failed to store access key: %w", err)
+		}
+		r.SecretId = secretId
+	}
+	return nil
+}
+
+func (r *AccessKeyResource) credsMap(props *AccessKeyProps) pulumi.StringMap {
+	return pulumi.StringMap{
+		"username":        props.User,
+		"accessKeyId":     r.ActiveKey.ID().ToStringOutput(),
+		"secretAccessKey": r.ActiveKey.Secret,
+	}
+}
+
+func NewAccessKey(ctx *pulumi.Context, props AccessKeyProps, opts ...pulumi.ResourceOption) (*AccessKeyResource, error) {
+	resource := &AccessKeyResource{}
+	if err := ctx.RegisterComponentResource("ss9:aws:iam:accesskey", props.Name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, &props); err != nil {
+		return resource, err
+	}
+
+	outputs := pulumi.Map{
+		"accessKeyId": resource.ActiveKey.ID(),
+	}
+	if props.Store != nil {
+		outputs["secretId"] = resource.SecretId
+	}
+	ctx.RegisterResourceOutputs(resource, outputs)
+	return resource, nil
+}
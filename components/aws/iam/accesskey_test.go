@@ -0,0 +1,46 @@
+package iam
+
+import "testing"
+
+func TestSlotKeeper(t *testing.T) {
+	tests := []struct {
+		keeper int
+		// generations of slot 0 & 1
+		want       [accessKeySlots]int
+		wantActive int
+	}{
+		{keeper: 0, want: [accessKeySlots]int{0, 0}, wantActive: 1},
+		{keeper: 1, want: [accessKeySlots]int{1, 0}, wantActive: 0},
+		{keeper: 2, want: [accessKeySlots]int{1, 1}, wantActive: 1},
+		{keeper: 3, want: [accessKeySlots]int{2, 1}, wantActive: 0},
+		{keeper: 10, want: [accessKeySlots]int{5, 5}, wantActive: 1},
+	}
+	for _, tt := range tests {
+		got := [accessKeySlots]int{}
+		for slot := range got {
+			got[slot] = slotKeeper(slot, tt.keeper)
+		}
+		if got != tt.want {
+			t.Errorf("keeper %d: got generations %v, want %v", tt.keeper, got, tt.want)
+		}
+		if active := activeSlot(tt.keeper); active != tt.wantActive {
+			t.Errorf("keeper %d: got active slot %d, want %d", tt.keeper, active, tt.wantActive)
+		}
+	}
+}
+
+// every bump of the keeper replaces the key of a single slot, the one
+// becoming active, so the other key stays valid during the switch over
+func TestSlotKeeperReplacesOneKey(t *testing.T) {
+	for keeper := 1; keeper <= 20; keeper++ {
+		replaced := []int{}
+		for slot := 0; slot < accessKeySlots; slot++ {
+			if slotKeeper(slot, keeper) != slotKeeper(slot, keeper-1) {
+				replaced = append(replaced, slot)
+			}
+		}
+		if len(replaced) != 1 || replaced[0] != activeSlot(keeper) {
+			t.Errorf("keeper %d: replaced slots %v, want only the active slot %d", keeper, replaced, activeSlot(keeper))
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package keyspaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/keyspaces"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	// keyspaces only accepts the TLS connections, on 9142
+	cqlPort     = 9142
+	serviceName = "cassandra.amazonaws.com"
+	// longest IAM user name
+	maxUserNameLength = 64
+)
+
+var (
+	// 1 to 48 letters, digits and underscores, beginning with a letter or digit
+	nameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_]{0,47}$`)
+	// IAM actions of the services on their tables, as the rw/ro roles of the
+	// postgres components
+	permissionActions = map[postgres.PostgresUserPermission][]string{
+		postgres.ReadWrite: {"cassandra:Select", "cassandra:Modify"},
+		postgres.ReadOnly:  {"cassandra:Select"},
+	}
+)
+
+type KeyspaceColumnProps struct {
+	Name string `json:"name"`
+	// CQL type, e.g. text, bigint or map<text, int>
+	Type string `json:"type"`
+}
+
+type KeyspaceTableProps struct {
+	Name    string                `json:"name"`
+	Columns []KeyspaceColumnProps `json:"columns"`
+	// Columns of the partition key, in order
+	PartitionKeys []string `json:"partitionKeys"`
+	// Columns of the clustering key, in order, sorted ascending
+	ClusteringKeys []string `json:"clusteringKeys"`
+	// Seconds after which the rows expire, never if not set
+	DefaultTTL int `json:"defaultTTL"`
+	// Continuous backups of the last 35 days
+	PointInTimeRecovery bool `json:"pointInTimeRecovery"`
+}
+
+type KeyspaceServiceProps struct {
+	// Name of the service, its IAM user is keyspaces-<keyspace>-<username>
+	Username string `json:"username"`
+	// Select (ro) or Select & Modify (rw) on the tables, rw if not set
+	Permission postgres.PostgresUserPermission `json:"permission"`
+	// Tables the service can access, all the tables of the keyspace if not set
+	Tables []string `json:"tables"`
+}
+
+type KeyspaceProps struct {
+	Name string `json:"name"`
+	// Tables of the keyspace, on-demand capacity and encrypted with the
+	// keyspaces key
+	Tables   []KeyspaceTableProps   `json:"tables"`
+	Services []KeyspaceServiceProps `json:"services"`
+	// Store of the creds of the services, AWS secrets if not set
+	Store secret.SecretStore `json:"-"`
+	// Fails any delete of the keyspace and its tables, e.g. in a destroy
+	Protect bool `json:"protect"`
+}
+
+type KeyspaceResource struct {
+	pulumi.ResourceState
+
+	Keyspace *keyspaces.Keyspace
+	// Keyed by table name
+	Tables map[string]*keyspaces.Table
+	// IAM users of the services, keyed by username
+	Users map[string]*iam.User
+	// Ids of the stored creds, keyed by username
+	SecretIds map[string]pulumi.StringOutput
+	// Errors of the failed services, keyed by username
+	FailedServices map[string]error
+	// Regional endpoint of keyspaces, e.g. cassandra.us-east-1.amazonaws.com
+	Host string
+}
+
+func validateName(kind string, name string) error {
+	if !nameRegex.MatchString(name) {
+		return fmt.Errorf("invalid %s name '%s', expected 1 to 48 letters, digits and underscores", kind, name)
+	}
+	return nil
+}
+
+func (table *KeyspaceTableProps) validate() error {
+	if err := validateName("table", table.Name); err != nil {
+		return err
+	}
+	if len(table.PartitionKeys) == 0 {
+		return fmt.Errorf("table %s: at least one partition key is required", table.Name)
+	}
+	columns := map[string]bool{}
+	for _, column := range table.Columns {
+		if column.Name == "" || column.Type == "" {
+			return fmt.Errorf("table %s: name and type of the columns are required", table.Name)
+		}
+		columns[column.Name] = true
+	}
+	for _, keys := range [][]string{table.PartitionKeys, table.ClusteringKeys} {
+		for _, key := range keys {
+			if !columns[key] {
+				return fmt.Errorf("table %s: key %s isn't a column", table.Name, key)
+			}
+		}
+	}
+	return nil
+}
+
+func (props *KeyspaceProps) validate() error {
+	if err := validateName("keyspace", props.Name); err != nil {
+		return err
+	}
+	for i := range props.Tables {
+		if err := props.Tables[i].validate(); err != nil {
+			return err
+		}
+	}
+	for i := range props.Services {
+		service := &props.Services[i]
+		if service.Permission == "" {
+			service.Permission = postgres.ReadWrite
+		}
+		if _, ok := permissionActions[service.Permission]; !ok {
+			return fmt.Errorf("service %s: permission '%s' isn't supported by keyspaces, expected %s or %s", service.Username, service.Permission, postgres.ReadWrite, postgres.ReadOnly)
+		}
+		// the tables may be created by the migrations of the services
+		for _, table := range service.Tables {
+			if err := validateName("table", table); err != nil {
+				return fmt.Errorf("service %s: %w", service.Username, err)
+			}
+		}
+	}
+	if props.Store == nil {
+		props.Store = secret.AWSSecretStore{}
+	}
+	return nil
+}
+
+func (table *KeyspaceTableProps) schema() *keyspaces.TableSchemaDefinitionArgs {
+	schema := &keyspaces.TableSchemaDefinitionArgs{}
+	columns := keyspaces.TableSchemaDefinitionColumnArray{}
+	for _, column := range table.Columns {
+		columns = append(columns, keyspaces.TableSchemaDefinitionColumnArgs{
+			Name: pulumi.String(column.Name),
+			Type: pulumi.String(column.Type),
+		})
+	}
+	schema.Columns = columns
+	partitionKeys := keyspaces.TableSchemaDefinitionPartitionKeyArray{}
+	for _, key := range table.PartitionKeys {
+		partitionKeys = append(partitionKeys, keyspaces.TableSchemaDefinitionPartitionKeyArgs{
+			Name: pulumi.String(key),
+		})
+	}
+	schema.PartitionKeys = partitionKeys
+	clusteringKeys := keyspaces.TableSchemaDefinitionClusteringKeyArray{}
+	for _, key := range table.ClusteringKeys {
+		clusteringKeys = append(clusteringKeys, keyspaces.TableSchemaDefinitionClusteringKeyArgs{
+			Name:    pulumi.String(key),
+			OrderBy: pulumi.String("ASC"),
+		})
+	}
+	schema.ClusteringKeys = clusteringKeys
+	return schema
+}
+
+func (r *KeyspaceResource) provisionTable(ctx *pulumi.Context, props *KeyspaceProps, table *KeyspaceTableProps, opts ...pulumi.ResourceOption) error {
+	args := &keyspaces.TableArgs{
+		KeyspaceName:     r.Keyspace.Name,
+		TableName:        pulumi.String(table.Name),
+		SchemaDefinition: table.schema(),
+		CapacitySpecification: &keyspaces.TableCapacitySpecificationArgs{
+			ThroughputMode: pulumi.String("PAY_PER_REQUEST"),
+		},
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	}
+	if table.DefaultTTL > 0 {
+		args.DefaultTimeToLive = pulumi.Int(table.DefaultTTL)
+		// the default TTL needs the TTL of the table enabled
+		args.Ttl = &keyspaces.TableTtlArgs{Status: pulumi.String("ENABLED")}
+	}
+	if table.PointInTimeRecovery {
+		args.PointInTimeRecovery = &keyspaces.TablePointInTimeRecoveryArgs{
+			Status: pulumi.String("ENABLED"),
+		}
+	}
+	res, err := keyspaces.NewTable(ctx, fmt.Sprintf("%s-%s", props.Name, table.Name), args, opts...)
+	if err != nil {
+		return err
+	}
+	r.Tables[table.Name] = res
+	return nil
+}
+
+// servicePolicy allows the actions of the permission on the tables, and the
+// reads of the system keyspaces the drivers need to connect
+func servicePolicy(keyspaceArn string, service *KeyspaceServiceProps) (string, error) {
+	tables := []string{fmt.Sprintf("%stable/*", keyspaceArn)}
+	if len(service.Tables) > 0 {
+		tables = []string{}
+		for _, table := range service.Tables {
+			tables = append(tables, fmt.Sprintf("%stable/%s", keyspaceArn, table))
+		}
+	}
+	// arn:aws:cassandra:<region>:<account>:/keyspace/<name>/
+	prefix, _, _ := strings.Cut(keyspaceArn, "/keyspace/")
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":   "Allow",
+				"Action":   permissionActions[service.Permission],
+				"Resource": append(tables, keyspaceArn),
+			},
+			{
+				"Effect":   "Allow",
+				"Action":   "cassandra:Select",
+				"Resource": fmt.Sprintf("%s/keyspace/system*", prefix),
+			},
+		},
+	})
+	return string(policy), err
+}
+
+func (r *KeyspaceResource) provisionService(ctx *pulumi.Context, props *KeyspaceProps, service *KeyspaceServiceProps) error {
+	userName := fmt.Sprintf("keyspaces-%s-%s", props.Name, service.Username)
+	if service.Username == "" || len(userName) > maxUserNameLength {
+		return fmt.Errorf("invalid username '%s', the IAM user %s is limited to %d characters", service.Username, userName, maxUserNameLength)
+	}
+	resName := fmt.Sprintf("%s-%s", props.Name, service.Username)
+	user, err := iam.NewUser(ctx, resName, &iam.UserArgs{
+		Name: pulumi.String(userName),
+		Tags: pulumi.StringMap{
+			"Pulumi":            pulumi.String("true"),
+			"keyspaces:service": pulumi.String(service.Username),
+		},
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	policy := r.Keyspace.Arn.ApplyT(func(arn string) (string, error) {
+		return servicePolicy(arn, service)
+	}).(pulumi.StringOutput)
+	if _, err := iam.NewUserPolicy(ctx, resName, &iam.UserPolicyArgs{
+		User:   user.Name,
+		Policy: policy,
+	}, pulumi.Parent(r)); err != nil {
+		return err
+	}
+	// the CQL drivers authenticate with a service-specific credential, i.e.
+	// a username & password, instead of the SigV4 plugin
+	credential, err := iam.NewServiceSpecificCredential(ctx, resName, &iam.ServiceSpecificCredentialArgs{
+		ServiceName: pulumi.String(serviceName),
+		UserName:    user.Name,
+	}, pulumi.Parent(r), pulumi.AdditionalSecretOutputs([]string{"servicePassword"}))
+	if err != nil {
+		return err
+	}
+	r.Users[service.Username] = user
+
+	secretId, err := props.Store.Store(ctx, fmt.Sprintf("keyspaces-%s-%s", props.Name, service.Username), secret.CassandraCreds, pulumi.StringMap{
+		"username": credential.ServiceUserName,
+		"password": credential.ServicePassword,
+		"host":     pulumi.String(r.Host),
+		"port":     pulumi.Sprintf("%d", cqlPort),
+		"keyspace": pulumi.String(props.Name),
+		"tls":      pulumi.String("true"),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return fmt.Errorf("failed to store creds: %w", err)
+	}
+	r.SecretIds[service.Username] = secretId
+	return nil
+}
+
+func (r *KeyspaceResource) provision(ctx *pulumi.Context, props *KeyspaceProps) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	destroyProtected, err := utils.DestroyProtected(ctx)
+	if err != nil {
+		return err
+	}
+	region, err := aws.GetRegion(ctx, nil)
+	if err != nil {
+		return err
+	}
+	r.Host = fmt.Sprintf("cassandra.%s.amazonaws.com", region.Name)
+	opts := []pulumi.ResourceOption{pulumi.Parent(r)}
+	if props.Protect || destroyProtected {
+		opts = append(opts, pulumi.Protect(true))
+	}
+	r.Keyspace, err = keyspaces.NewKeyspace(ctx, props.Name, &keyspaces.KeyspaceArgs{
+		Name: pulumi.String(props.Name),
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	for i := range props.Tables {
+		if err := r.provisionTable(ctx, props, &props.Tables[i], opts...); err != nil {
+			return fmt.Errorf("table %s: %w", props.Tables[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// NewKeyspace creates an Amazon Keyspaces keyspace and its tables, and an IAM
+// user per service with the rw or ro actions on its tables. The
+// service-specific credential of each service is stored with the regional
+// endpoint, in AWS secrets unless another Store is set.
+func NewKeyspace(ctx *pulumi.Context, props KeyspaceProps, opts ...pulumi.ResourceOption) (*KeyspaceResource, error) {
+	resource := &KeyspaceResource{
+		Tables:         map[string]*keyspaces.Table{},
+		Users:          map[string]*iam.User{},
+		SecretIds:      map[string]pulumi.StringOutput{},
+		FailedServices: map[string]error{},
+	}
+	if err := ctx.RegisterComponentResource("ss9:aws:keyspaces:keyspace", props.Name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, &props); err != nil {
+		return resource, err
+	}
+	// a failed service doesn't hold back the others
+	errs := []error{}
+	secretIds := pulumi.StringMap{}
+	for i := range props.Services {
+		service := &props.Services[i]
+		if err := resource.provisionService(ctx, &props, service); err != nil {
+			resource.FailedServices[service.Username] = err
+			errs = append(errs, fmt.Errorf("service %s: %w", service.Username, err))
+			continue
+		}
+		secretIds[service.Username] = resource.SecretIds[service.Username]
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"keyspace":  resource.Keyspace.Name,
+		"host":      pulumi.String(resource.Host),
+		"secretIds": secretIds,
+	})
+	return resource, errors.Join(errs...)
+}
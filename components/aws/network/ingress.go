@@ -0,0 +1,100 @@
+package network
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type IngressRuleProps struct {
+	// Identifies the rule in the name and description, e.g. the username
+	Name  string
+	Cidrs []string
+}
+
+type IngressProps struct {
+	Name            string
+	SecurityGroupId pulumi.StringInput
+	Port            int
+	Rules           []IngressRuleProps
+}
+
+type IngressResource struct {
+	pulumi.ResourceState
+
+	Rules []*ec2.SecurityGroupRule
+}
+
+// ValidateCidrs checks every CIDR is a valid IPv4 or IPv6 block.
+func ValidateCidrs(cidrs []string) error {
+	for _, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid CIDR '%s': %w", cidr, err)
+		}
+	}
+	return nil
+}
+
+func splitCidrs(cidrs []string) (ipv4 []string, ipv6 []string) {
+	for _, cidr := range cidrs {
+		ip, _, _ := net.ParseCIDR(cidr)
+		if ip.To4() != nil {
+			ipv4 = append(ipv4, cidr)
+		} else {
+			ipv6 = append(ipv6, cidr)
+		}
+	}
+	return
+}
+
+func (r *IngressResource) provision(ctx *pulumi.Context, props *IngressProps) error {
+	if props.Port <= 0 {
+		return fmt.Errorf("port is required to allow the ingress")
+	}
+	for _, rule := range props.Rules {
+		if len(rule.Cidrs) == 0 {
+			continue
+		}
+		if err := ValidateCidrs(rule.Cidrs); err != nil {
+			return fmt.Errorf("rule '%s': %w", rule.Name, err)
+		}
+		ipv4, ipv6 := splitCidrs(rule.Cidrs)
+		args := &ec2.SecurityGroupRuleArgs{
+			Type:            pulumi.String("ingress"),
+			Protocol:        pulumi.String("tcp"),
+			FromPort:        pulumi.Int(props.Port),
+			ToPort:          pulumi.Int(props.Port),
+			SecurityGroupId: props.SecurityGroupId,
+			Description:     pulumi.Sprintf("%s: %s", props.Name, rule.Name),
+		}
+		if len(ipv4) > 0 {
+			args.CidrBlocks = pulumi.ToStringArray(ipv4)
+		}
+		if len(ipv6) > 0 {
+			args.Ipv6CidrBlocks = pulumi.ToStringArray(ipv6)
+		}
+		sgRule, err := ec2.NewSecurityGroupRule(ctx, fmt.Sprintf("%s-ingress-%s", props.Name, rule.Name), args, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.Rules = append(r.Rules, sgRule)
+	}
+	return nil
+}
+
+// NewIngress allows each rule's CIDRs to reach the port in the security group.
+func NewIngress(ctx *pulumi.Context, props IngressProps, opts ...pulumi.ResourceOption) (*IngressResource, error) {
+	resource := &IngressResource{}
+	if err := ctx.RegisterComponentResource("ss9:aws:network:ingress", props.Name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, &props); err != nil {
+		return resource, err
+	}
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"rules": pulumi.Int(len(resource.Rules)),
+	})
+	return resource, nil
+}
@@ -0,0 +1,222 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/opensearch"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	defaultEngineVersion = "OpenSearch_2.11"
+	defaultInstanceType  = "t3.small.search"
+	defaultVolumeSize    = 10
+	masterUsername       = "admin"
+)
+
+// 3 to 28 lowercase letters, digits and hyphens, beginning with a letter
+var domainNameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]{2,27}$`)
+
+type OpenSearchDomainProps struct {
+	Name string `json:"name"`
+	// OpenSearch_2.11 if not set
+	EngineVersion string `json:"engineVersion"`
+	// t3.small.search if not set
+	InstanceType string `json:"instanceType"`
+	// Data nodes, spread over the AZs of the subnets. 1 if not set.
+	InstanceCount int `json:"instanceCount"`
+	// GiB of the EBS volume of each node, 10 if not set
+	VolumeSize int `json:"volumeSize"`
+	// Private subnets of the domain, one per AZ
+	SubnetIds        []string `json:"subnetIds"`
+	SecurityGroupIds []string `json:"securityGroupIds"`
+	// KMS key of the encryption at rest, the opensearch one if not set
+	KmsKeyId string `json:"kmsKeyId"`
+	// IAM principals allowed to send requests to the domain, any if not set.
+	// The requests are authorized by the internal users anyway.
+	PrincipalArns []string `json:"principalArns"`
+	// Fails any delete of the domain, e.g. in a destroy
+	Protect bool `json:"protect"`
+}
+
+type OpenSearchDomainResource struct {
+	pulumi.ResourceState
+
+	Domain *opensearch.Domain
+	// https://<endpoint> of the domain, e.g. for NewProvider
+	Url            pulumi.StringOutput
+	MasterUsername string
+	MasterPassword pulumi.StringOutput
+	// Secret of the master user creds
+	MasterSecret *secret.AWSSecret
+}
+
+func (props *OpenSearchDomainProps) validate() error {
+	if !domainNameRegex.MatchString(props.Name) {
+		return fmt.Errorf("invalid domain name '%s', expected 3 to 28 lowercase letters, digits and hyphens", props.Name)
+	}
+	if len(props.SubnetIds) == 0 {
+		return fmt.Errorf("at least one subnet is required")
+	}
+	if props.EngineVersion == "" {
+		props.EngineVersion = defaultEngineVersion
+	}
+	if props.InstanceType == "" {
+		props.InstanceType = defaultInstanceType
+	}
+	if props.InstanceCount == 0 {
+		props.InstanceCount = 1
+	}
+	if props.VolumeSize == 0 {
+		props.VolumeSize = defaultVolumeSize
+	}
+	if len(props.PrincipalArns) == 0 {
+		props.PrincipalArns = []string{"*"}
+	}
+	return nil
+}
+
+// accessPolicy allows the principals to send any request to the domain, the
+// fine-grained access control authorizes them
+func accessPolicy(domainArn string, principalArns []string) (string, error) {
+	var principal interface{} = map[string]interface{}{"AWS": principalArns}
+	if len(principalArns) == 1 && principalArns[0] == "*" {
+		principal = map[string]interface{}{"AWS": "*"}
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": principal,
+			"Action":    "es:ESHttp*",
+			"Resource":  fmt.Sprintf("%s/*", domainArn),
+		}},
+	})
+	return string(policy), err
+}
+
+func (r *OpenSearchDomainResource) provision(ctx *pulumi.Context, props *OpenSearchDomainProps) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	destroyProtected, err := utils.DestroyProtected(ctx)
+	if err != nil {
+		return err
+	}
+	r.MasterUsername = masterUsername
+	// the internal users need upper & lowercase letters, digits and special characters
+	r.MasterPassword, err = utils.NewComplexPassword(ctx, fmt.Sprintf("%s-master-password", props.Name), 32, nil, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	encryptAtRest := &opensearch.DomainEncryptAtRestArgs{
+		Enabled: pulumi.Bool(true),
+	}
+	if props.KmsKeyId != "" {
+		encryptAtRest.KmsKeyId = pulumi.String(props.KmsKeyId)
+	}
+	clusterConfig := &opensearch.DomainClusterConfigArgs{
+		InstanceType:  pulumi.String(props.InstanceType),
+		InstanceCount: pulumi.Int(props.InstanceCount),
+	}
+	// the nodes are spread over the AZs of the subnets
+	if len(props.SubnetIds) > 1 {
+		clusterConfig.ZoneAwarenessEnabled = pulumi.Bool(true)
+		clusterConfig.ZoneAwarenessConfig = &opensearch.DomainClusterConfigZoneAwarenessConfigArgs{
+			AvailabilityZoneCount: pulumi.Int(len(props.SubnetIds)),
+		}
+	}
+	domainOpts := []pulumi.ResourceOption{pulumi.Parent(r)}
+	if props.Protect || destroyProtected {
+		domainOpts = append(domainOpts, pulumi.Protect(true))
+	}
+	r.Domain, err = opensearch.NewDomain(ctx, props.Name, &opensearch.DomainArgs{
+		DomainName:    pulumi.String(props.Name),
+		EngineVersion: pulumi.String(props.EngineVersion),
+		ClusterConfig: clusterConfig,
+		EbsOptions: &opensearch.DomainEbsOptionsArgs{
+			EbsEnabled: pulumi.Bool(true),
+			VolumeSize: pulumi.Int(props.VolumeSize),
+			VolumeType: pulumi.String("gp3"),
+		},
+		VpcOptions: &opensearch.DomainVpcOptionsArgs{
+			SubnetIds:        pulumi.ToStringArray(props.SubnetIds),
+			SecurityGroupIds: pulumi.ToStringArray(props.SecurityGroupIds),
+		},
+		EncryptAtRest: encryptAtRest,
+		// the fine-grained access control needs both
+		NodeToNodeEncryption: &opensearch.DomainNodeToNodeEncryptionArgs{
+			Enabled: pulumi.Bool(true),
+		},
+		DomainEndpointOptions: &opensearch.DomainDomainEndpointOptionsArgs{
+			EnforceHttps:      pulumi.Bool(true),
+			TlsSecurityPolicy: pulumi.String("Policy-Min-TLS-1-2-2019-07"),
+		},
+		AdvancedSecurityOptions: &opensearch.DomainAdvancedSecurityOptionsArgs{
+			Enabled:                     pulumi.Bool(true),
+			InternalUserDatabaseEnabled: pulumi.Bool(true),
+			MasterUserOptions: &opensearch.DomainAdvancedSecurityOptionsMasterUserOptionsArgs{
+				MasterUserName:     pulumi.String(r.MasterUsername),
+				MasterUserPassword: r.MasterPassword,
+			},
+		},
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	}, domainOpts...)
+	if err != nil {
+		return err
+	}
+	// the policy refers to the ARN of the domain, so it's a resource of its own
+	policy := r.Domain.Arn.ApplyT(func(arn string) (string, error) {
+		return accessPolicy(arn, props.PrincipalArns)
+	}).(pulumi.StringOutput)
+	if _, err := opensearch.NewDomainPolicy(ctx, props.Name, &opensearch.DomainPolicyArgs{
+		DomainName:     r.Domain.DomainName,
+		AccessPolicies: policy,
+	}, pulumi.Parent(r)); err != nil {
+		return err
+	}
+	r.Url = pulumi.Sprintf("https://%s", r.Domain.Endpoint)
+
+	r.MasterSecret, err = secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+		Name: fmt.Sprintf("%s-master", props.Name),
+		Type: secret.SearchCreds,
+		InitialValue: pulumi.StringMap{
+			"username": pulumi.String(r.MasterUsername),
+			"password": r.MasterPassword,
+			"endpoint": r.Url,
+		},
+		Tags: map[string]string{
+			"opensearch:domain": props.Name,
+			"opensearch:user":   r.MasterUsername,
+		},
+	}, pulumi.Parent(r))
+	if err != nil {
+		return fmt.Errorf("failed to create secret of the master user: %w", err)
+	}
+	return nil
+}
+
+// NewOpenSearchDomain creates an OpenSearch domain in the VPC, encrypted at
+// rest & in transit, with the fine-grained access control of its internal
+// users. The creds of the master user are stored in a secret.
+func NewOpenSearchDomain(ctx *pulumi.Context, props OpenSearchDomainProps, opts ...pulumi.ResourceOption) (*OpenSearchDomainResource, error) {
+	resource := &OpenSearchDomainResource{}
+	if err := ctx.RegisterComponentResource("ss9:aws:opensearch:domain", props.Name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"url":      resource.Url,
+		"masterId": resource.MasterSecret.Secret.ID(),
+	})
+	return resource, nil
+}
@@ -0,0 +1,98 @@
+package opensearch
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+// The security resources of the domain (internal users, roles and their
+// mappings) aren't in the aws provider, they're registered by type token with
+// the opensearch provider (`pulumi plugin install resource opensearch`). Only
+// the properties the components set are declared. Unlike mysql or kafka, the
+// opensearch provider has no published Go SDK; one can only be generated
+// locally from the terraform provider (`pulumi package add terraform-provider
+// opensearch-project/opensearch`), which the components would then import.
+const (
+	providerType     = "pulumi:providers:opensearch"
+	userType         = "opensearch:index/user:User"
+	roleType         = "opensearch:index/role:Role"
+	rolesMappingType = "opensearch:index/rolesMapping:RolesMapping"
+)
+
+type Provider struct {
+	pulumi.ProviderResourceState
+}
+
+type ProviderArgs struct {
+	// https://<endpoint> of the domain
+	Url pulumi.StringInput
+	// Master user of the domain
+	Username pulumi.StringInput
+	Password pulumi.StringInput
+}
+
+// NewProvider configures the domain the users are provisioned in, with the
+// basic auth of its master user instead of signed requests.
+// The opensearch plugin runs the version of plugins:opensearch if set.
+func NewProvider(ctx *pulumi.Context, name string, args ProviderArgs, opts ...pulumi.ResourceOption) (*Provider, error) {
+	opts, err := utils.PinPlugin(ctx, "opensearch", opts)
+	if err != nil {
+		return nil, err
+	}
+	provider := &Provider{}
+	if err := ctx.RegisterResource(providerType, name, pulumi.Map{
+		"url":             args.Url,
+		"username":        args.Username,
+		"password":        pulumi.ToSecret(args.Password),
+		"signAwsRequests": pulumi.String("false"),
+		"healthcheck":     pulumi.String("false"),
+	}, provider, opts...); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+type User struct {
+	pulumi.CustomResourceState
+
+	Username pulumi.StringOutput `pulumi:"username"`
+	Password pulumi.StringOutput `pulumi:"password"`
+}
+
+type Role struct {
+	pulumi.CustomResourceState
+
+	RoleName pulumi.StringOutput `pulumi:"roleName"`
+}
+
+type RolesMapping struct {
+	pulumi.CustomResourceState
+
+	RoleName pulumi.StringOutput      `pulumi:"roleName"`
+	Users    pulumi.StringArrayOutput `pulumi:"users"`
+}
+
+func newUser(ctx *pulumi.Context, name string, props pulumi.Map, opts ...pulumi.ResourceOption) (*User, error) {
+	res := &User{}
+	opts = append(opts, pulumi.AdditionalSecretOutputs([]string{"password"}))
+	if err := ctx.RegisterResource(userType, name, props, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func newRole(ctx *pulumi.Context, name string, props pulumi.Map, opts ...pulumi.ResourceOption) (*Role, error) {
+	res := &Role{}
+	if err := ctx.RegisterResource(roleType, name, props, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func newRolesMapping(ctx *pulumi.Context, name string, props pulumi.Map, opts ...pulumi.ResourceOption) (*RolesMapping, error) {
+	res := &RolesMapping{}
+	if err := ctx.RegisterResource(rolesMappingType, name, props, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,178 @@
+package opensearch
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+type rolePermissions struct {
+	cluster []string
+	index   []string
+}
+
+var (
+	usernameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	// action groups of the roles of the apps on their indices, as the rw/ro
+	// roles of the postgres components
+	permissionActionGroups = map[postgres.PostgresUserPermission]rolePermissions{
+		postgres.ReadWrite: {
+			cluster: []string{"cluster_composite_ops", "cluster_monitor"},
+			index:   []string{"crud", "create_index", "manage_aliases", "indices_monitor"},
+		},
+		postgres.ReadOnly: {
+			cluster: []string{"cluster_composite_ops_ro"},
+			index:   []string{"read"},
+		},
+	}
+)
+
+type OpenSearchUserProps struct {
+	// Internal user of the app, and name of its role
+	Username string `json:"username"`
+	// rw or ro action groups on the indices, rw if not set
+	Permission postgres.PostgresUserPermission `json:"permission"`
+	// Index patterns of the app, e.g. billing-*
+	IndexPatterns []string `json:"indexPatterns"`
+	// Bump it to rotate the generated password
+	RotationTrigger string `json:"rotationTrigger"`
+}
+
+type OpenSearchUsersProps struct {
+	// Domain the users are created in, stored with the creds
+	Domain string                `json:"domain"`
+	Url    pulumi.StringInput    `json:"-"`
+	Users  []OpenSearchUserProps `json:"users"`
+}
+
+type OpenSearchUsersResource struct {
+	pulumi.ResourceState
+
+	// Keyed by username
+	Users   map[string]*User
+	Secrets map[string]*secret.AWSSecret
+	// Errors of the failed users, keyed by username
+	FailedUsers map[string]error
+}
+
+func (props *OpenSearchUserProps) validate() error {
+	if !usernameRegex.MatchString(props.Username) {
+		return fmt.Errorf("invalid username '%s', only letters, digits and _ . - are allowed", props.Username)
+	}
+	if props.Username == masterUsername {
+		return fmt.Errorf("username '%s' is the master user of the domain", props.Username)
+	}
+	if props.Permission == "" {
+		props.Permission = postgres.ReadWrite
+	}
+	if _, ok := permissionActionGroups[props.Permission]; !ok {
+		return fmt.Errorf("permission '%s' isn't supported by opensearch, expected %s or %s", props.Permission, postgres.ReadWrite, postgres.ReadOnly)
+	}
+	if len(props.IndexPatterns) == 0 {
+		return fmt.Errorf("index patterns of user %s are required", props.Username)
+	}
+	return nil
+}
+
+func (r *OpenSearchUsersResource) provision(ctx *pulumi.Context, name string, props *OpenSearchUsersProps, user *OpenSearchUserProps) error {
+	if err := user.validate(); err != nil {
+		return err
+	}
+	resName := fmt.Sprintf("%s-%s", name, user.Username)
+	keepers := map[string]string{}
+	if user.RotationTrigger != "" {
+		keepers["rotationTrigger"] = user.RotationTrigger
+	}
+	password, err := utils.NewComplexPassword(ctx, fmt.Sprintf("%s-password", resName), 32, keepers, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	// PUT _plugins/_security/api/internalusers/$USER
+	searchUser, err := newUser(ctx, resName, pulumi.Map{
+		"username": pulumi.String(user.Username),
+		"password": pulumi.ToSecret(password),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	groups := permissionActionGroups[user.Permission]
+	// PUT _plugins/_security/api/roles/$USER
+	role, err := newRole(ctx, resName, pulumi.Map{
+		"roleName":           pulumi.String(user.Username),
+		"clusterPermissions": pulumi.ToStringArray(groups.cluster),
+		"indexPermissions": pulumi.Array{pulumi.Map{
+			"indexPatterns":  pulumi.ToStringArray(user.IndexPatterns),
+			"allowedActions": pulumi.ToStringArray(groups.index),
+		}},
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	// PUT _plugins/_security/api/rolesmapping/$USER
+	if _, err := newRolesMapping(ctx, resName, pulumi.Map{
+		"roleName": role.RoleName,
+		"users":    pulumi.StringArray{searchUser.Username},
+	}, pulumi.Parent(r)); err != nil {
+		return err
+	}
+	r.Users[user.Username] = searchUser
+
+	secretRes, err := secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+		Name: fmt.Sprintf("%s-user-%s", props.Domain, user.Username),
+		Type: secret.SearchCreds,
+		InitialValue: pulumi.StringMap{
+			"username": pulumi.String(user.Username),
+			"password": pulumi.ToSecret(password).(pulumi.StringOutput),
+			"endpoint": props.Url,
+		},
+		Tags: map[string]string{
+			"opensearch:domain": props.Domain,
+			"opensearch:user":   user.Username,
+		},
+	}, pulumi.Parent(r))
+	if err != nil {
+		return fmt.Errorf("failed to create secret: %w", err)
+	}
+	r.Secrets[user.Username] = secretRes
+	return nil
+}
+
+// NewOpenSearchUsers creates the internal users of the apps in a domain with
+// the fine-grained access control, each with its role on the index patterns
+// of the app. The creds of each user are stored in a secret.
+func NewOpenSearchUsers(ctx *pulumi.Context, name string, props OpenSearchUsersProps, opts ...pulumi.ResourceOption) (*OpenSearchUsersResource, error) {
+	if props.Domain == "" || props.Url == nil {
+		return nil, fmt.Errorf("domain and its url are required")
+	}
+	resource := &OpenSearchUsersResource{
+		Users:       map[string]*User{},
+		Secrets:     map[string]*secret.AWSSecret{},
+		FailedUsers: map[string]error{},
+	}
+	if err := ctx.RegisterComponentResource("ss9:aws:opensearch:users", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	// a failed user doesn't hold back the others
+	errs := []error{}
+	usernames := pulumi.StringArray{}
+	for i := range props.Users {
+		user := &props.Users[i]
+		if err := resource.provision(ctx, name, &props, user); err != nil {
+			resource.FailedUsers[user.Username] = err
+			errs = append(errs, fmt.Errorf("user %s: %w", user.Username, err))
+			continue
+		}
+		usernames = append(usernames, pulumi.String(user.Username))
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"domain":    pulumi.String(props.Domain),
+		"usernames": usernames,
+	})
+	return resource, errors.Join(errs...)
+}
@@ -0,0 +1,95 @@
+package rds
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type IamAuthPolicyProps struct {
+	Name string
+	// RDS instance the users connect to
+	InstanceId string
+	// DB users authenticating with IAM tokens, i.e. granted rds_iam
+	Usernames []string
+}
+
+type IamAuthPolicyResource struct {
+	pulumi.ResourceState
+
+	Policy *iam.Policy
+}
+
+// dbUserArns builds the rds-db:connect resources of the users, from the ARN
+// (arn:aws:rds:$REGION:$ACCOUNT:db:$ID) and the resource id of the instance.
+func dbUserArns(instanceArn string, resourceId string, usernames []string) ([]string, error) {
+	parts := strings.Split(instanceArn, ":")
+	if len(parts) < 6 {
+		return nil, fmt.Errorf("invalid RDS instance ARN '%s'", instanceArn)
+	}
+	partition, region, account := parts[1], parts[3], parts[4]
+	arns := make([]string, len(usernames))
+	for i, username := range usernames {
+		arns[i] = fmt.Sprintf("arn:%s:rds-db:%s:%s:dbuser:%s/%s", partition, region, account, resourceId, username)
+	}
+	return arns, nil
+}
+
+func (r *IamAuthPolicyResource) provision(ctx *pulumi.Context, props *IamAuthPolicyProps) error {
+	if props.InstanceId == "" {
+		return fmt.Errorf("instance id is required for the IAM auth policy")
+	}
+	if len(props.Usernames) == 0 {
+		return fmt.Errorf("at least one username is required for the IAM auth policy")
+	}
+	instance, err := rds.LookupInstance(ctx, &rds.LookupInstanceArgs{
+		DbInstanceIdentifier: &props.InstanceId,
+	}, pulumi.Parent(r))
+	if err != nil {
+		return fmt.Errorf("failed to lookup RDS instance %s: %w", props.InstanceId, err)
+	}
+	resources, err := dbUserArns(instance.DbInstanceArn, instance.ResourceId, props.Usernames)
+	if err != nil {
+		return err
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":   "Allow",
+			"Action":   "rds-db:connect",
+			"Resource": resources,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	r.Policy, err = iam.NewPolicy(ctx, fmt.Sprintf("%s-iam-auth", props.Name), &iam.PolicyArgs{
+		Description: pulumi.Sprintf("Connects to RDS instance %s as the IAM auth users of %s", props.InstanceId, props.Name),
+		Policy:      pulumi.String(string(policy)),
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	}, pulumi.Parent(r))
+	return err
+}
+
+// NewIamAuthPolicy creates the IAM policy allowing to connect as the users
+// granted rds_iam, to be attached to the roles of the workloads.
+func NewIamAuthPolicy(ctx *pulumi.Context, props IamAuthPolicyProps, opts ...pulumi.ResourceOption) (*IamAuthPolicyResource, error) {
+	resource := &IamAuthPolicyResource{}
+	if err := ctx.RegisterComponentResource("ss9:aws:rds:iamauthpolicy", props.Name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"policyArn": resource.Policy.Arn,
+	})
+	return resource, nil
+}
@@ -0,0 +1,165 @@
+package rds
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	failedLoginMetricNamespace = "ss9/Postgres"
+	defaultAlarmPeriod         = 300
+	defaultAlarmThreshold      = 5
+)
+
+type LoginAlertProps struct {
+	Name string
+	// RDS instance whose postgresql logs are exported to CloudWatch
+	InstanceId string
+	// Overrides the log group derived from InstanceId
+	LogGroupName string
+	// If set, a parameter group of this family (e.g. postgres15) is created
+	// with log_connections & log_disconnections turned on. It needs to be
+	// attached to the instance separately.
+	ParameterGroupFamily string
+	// Managed users whose failed logins are counted
+	Usernames []string
+	// Failed logins within the period which trigger the alarm
+	Threshold int
+	// Period of the alarm in seconds
+	PeriodSeconds int
+	// ARNs notified when the alarm goes off, e.g. SNS topics
+	AlarmActions []string
+}
+
+type LoginAlertResource struct {
+	pulumi.ResourceState
+
+	ParameterGroup *rds.ParameterGroup
+	MetricFilters  []*cloudwatch.LogMetricFilter
+	Alarm          *cloudwatch.MetricAlarm
+}
+
+func (props *LoginAlertProps) fillRuntimeInputs() error {
+	if props.LogGroupName == "" {
+		if props.InstanceId == "" {
+			return fmt.Errorf("either instance id or log group name is required")
+		}
+		props.LogGroupName = fmt.Sprintf("/aws/rds/instance/%s/postgresql", props.InstanceId)
+	}
+	if len(props.Usernames) == 0 {
+		return fmt.Errorf("at least one username is required to watch the failed logins")
+	}
+	if props.Threshold <= 0 {
+		props.Threshold = defaultAlarmThreshold
+	}
+	if props.PeriodSeconds <= 0 {
+		props.PeriodSeconds = defaultAlarmPeriod
+	}
+	return nil
+}
+
+func (r *LoginAlertResource) provisionParameterGroup(ctx *pulumi.Context, props *LoginAlertProps) (*rds.ParameterGroup, error) {
+	return rds.NewParameterGroup(ctx, fmt.Sprintf("%s-log-connections", props.Name), &rds.ParameterGroupArgs{
+		Family:      pulumi.String(props.ParameterGroupFamily),
+		Description: pulumi.String("Logs connection attempts to detect failed logins"),
+		Parameters: rds.ParameterGroupParameterArray{
+			rds.ParameterGroupParameterArgs{
+				Name:  pulumi.String("log_connections"),
+				Value: pulumi.String("1"),
+			},
+			rds.ParameterGroupParameterArgs{
+				Name:  pulumi.String("log_disconnections"),
+				Value: pulumi.String("1"),
+			},
+		},
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	}, pulumi.Parent(r))
+}
+
+func (r *LoginAlertResource) provision(ctx *pulumi.Context, props *LoginAlertProps) error {
+	if err := props.fillRuntimeInputs(); err != nil {
+		return err
+	}
+	if props.ParameterGroupFamily != "" {
+		paramGroup, err := r.provisionParameterGroup(ctx, props)
+		if err != nil {
+			return err
+		}
+		r.ParameterGroup = paramGroup
+	}
+
+	metricName := fmt.Sprintf("%s-failed-logins", props.Name)
+	// every user gets its own filter, all of them publish to the same metric
+	for _, username := range props.Usernames {
+		filter, err := cloudwatch.NewLogMetricFilter(ctx, fmt.Sprintf("%s-failed-login-%s", props.Name, username), &cloudwatch.LogMetricFilterArgs{
+			LogGroupName: pulumi.String(props.LogGroupName),
+			// FATAL:  password authentication failed for user "tom"
+			Pattern: pulumi.Sprintf(`"password authentication failed for user" "%s"`, username),
+			MetricTransformation: cloudwatch.LogMetricFilterMetricTransformationArgs{
+				Name:         pulumi.String(metricName),
+				Namespace:    pulumi.String(failedLoginMetricNamespace),
+				Value:        pulumi.String("1"),
+				DefaultValue: pulumi.String("0"),
+			},
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.MetricFilters = append(r.MetricFilters, filter)
+	}
+
+	alarm, err := cloudwatch.NewMetricAlarm(ctx, metricName, &cloudwatch.MetricAlarmArgs{
+		AlarmDescription:   pulumi.Sprintf("Failed logins of the users managed by %s", props.Name),
+		Namespace:          pulumi.String(failedLoginMetricNamespace),
+		MetricName:         pulumi.String(metricName),
+		Statistic:          pulumi.String("Sum"),
+		Period:             pulumi.Int(props.PeriodSeconds),
+		EvaluationPeriods:  pulumi.Int(1),
+		Threshold:          pulumi.Float64(props.Threshold),
+		ComparisonOperator: pulumi.String("GreaterThanOrEqualToThreshold"),
+		TreatMissingData:   pulumi.String("notBreaching"),
+		AlarmActions:       pulumi.ToArray(toInterfaces(props.AlarmActions)),
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Alarm = alarm
+	return nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	res := make([]interface{}, len(values))
+	for i, v := range values {
+		res[i] = v
+	}
+	return res
+}
+
+// NewLoginAlert raises a CloudWatch alarm on failed logins of the managed
+// postgres users, based on the connection logs exported by RDS.
+func NewLoginAlert(ctx *pulumi.Context, props LoginAlertProps, opts ...pulumi.ResourceOption) (*LoginAlertResource, error) {
+	resource := &LoginAlertResource{}
+	if err := ctx.RegisterComponentResource("ss9:aws:rds:loginalert", props.Name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, &props); err != nil {
+		return resource, err
+	}
+
+	outputs := pulumi.Map{
+		"alarmArn": resource.Alarm.Arn,
+	}
+	if resource.ParameterGroup != nil {
+		outputs["parameterGroup"] = resource.ParameterGroup.Name
+	}
+	ctx.RegisterResourceOutputs(resource, outputs)
+	return resource, nil
+}
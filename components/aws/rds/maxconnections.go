@@ -0,0 +1,40 @@
+package rds
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	// RDS postgres default: LEAST({DBInstanceClassMemory/9531392}, 5000)
+	bytesPerConnection = 9531392
+	maxConnectionsCap  = 5000
+)
+
+// LookupDefaultMaxConnections estimates max_connections of the RDS instance
+// from the memory of its instance class, as per the default parameter group.
+// It doesn't account for max_connections overridden in a custom parameter group.
+func LookupDefaultMaxConnections(ctx *pulumi.Context, instanceId string) (int, error) {
+	instance, err := rds.LookupInstance(ctx, &rds.LookupInstanceArgs{
+		DbInstanceIdentifier: &instanceId,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to lookup RDS instance %s: %w", instanceId, err)
+	}
+	instanceType, err := ec2.GetInstanceType(ctx, &ec2.GetInstanceTypeArgs{
+		InstanceType: strings.TrimPrefix(instance.DbInstanceClass, "db."),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to lookup memory of instance class %s: %w", instance.DbInstanceClass, err)
+	}
+	// memory size is in MiB
+	maxConnections := instanceType.MemorySize * 1024 * 1024 / bytesPerConnection
+	if maxConnections > maxConnectionsCap {
+		maxConnections = maxConnectionsCap
+	}
+	return maxConnections, nil
+}
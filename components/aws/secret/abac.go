@@ -0,0 +1,131 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	// tags matched against the same tags of the IAM principals reading the
+	// secrets, see NewAbacPolicy
+	AccessTeamTag = "access-team"
+	AccessAppTag  = "access-app"
+)
+
+// SecretAccess tags every secret of the stack with the team and app allowed
+// to read it. It's read from the `secret` config namespace.
+type SecretAccess struct {
+	AccessTeam string `json:"accessTeam"`
+	AccessApp  string `json:"accessApp"`
+}
+
+func LoadSecretAccess(ctx *pulumi.Context) (*SecretAccess, error) {
+	access := &SecretAccess{}
+	if err := utils.ExtractConfig(ctx, "secret", access); err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+func (a *SecretAccess) tags() map[string]string {
+	tags := map[string]string{}
+	if a.AccessTeam != "" {
+		tags[AccessTeamTag] = a.AccessTeam
+	}
+	if a.AccessApp != "" {
+		tags[AccessAppTag] = a.AccessApp
+	}
+	return tags
+}
+
+type AbacPolicyProps struct {
+	Name string
+}
+
+type AbacPolicyResource struct {
+	pulumi.ResourceState
+
+	Policy *iam.Policy
+}
+
+// abacStatement allows reading the secrets whose tag matches the one of the
+// principal. Principals or secrets without the tag never match.
+func abacStatement(sid string, tag string) map[string]interface{} {
+	return map[string]interface{}{
+		"Sid":      sid,
+		"Effect":   "Allow",
+		"Action":   []string{"secretsmanager:GetSecretValue", "secretsmanager:DescribeSecret"},
+		"Resource": "*",
+		"Condition": map[string]interface{}{
+			"StringEquals": map[string]string{
+				fmt.Sprintf("secretsmanager:ResourceTag/%s", tag): fmt.Sprintf("${aws:PrincipalTag/%s}", tag),
+			},
+			"Null": map[string]string{
+				fmt.Sprintf("aws:PrincipalTag/%s", tag): "false",
+			},
+		},
+	}
+}
+
+func abacPolicy() (string, error) {
+	out, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			abacStatement("ReadTeamSecrets", AccessTeamTag),
+			abacStatement("ReadAppSecrets", AccessAppTag),
+			{
+				// secrets encrypted with a customer managed key, e.g. secret:kms_alias
+				"Sid":      "DecryptSecrets",
+				"Effect":   "Allow",
+				"Action":   "kms:Decrypt",
+				"Resource": "*",
+				"Condition": map[string]interface{}{
+					"StringLike": map[string]string{
+						"kms:ViaService": "secretsmanager.*.amazonaws.com",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (r *AbacPolicyResource) provision(ctx *pulumi.Context, props *AbacPolicyProps) error {
+	policy, err := abacPolicy()
+	if err != nil {
+		return err
+	}
+	r.Policy, err = iam.NewPolicy(ctx, fmt.Sprintf("%s-secrets-abac", props.Name), &iam.PolicyArgs{
+		Description: pulumi.Sprintf("Reads the secrets tagged with the %s or %s of the principal", AccessTeamTag, AccessAppTag),
+		Policy:      pulumi.String(policy),
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	}, pulumi.Parent(r))
+	return err
+}
+
+// NewAbacPolicy creates the IAM policy allowing to read the secrets tagged
+// with the access-team or access-app of the principal. It doesn't refer to
+// any secret, so the same policy can be attached to all the consuming roles.
+func NewAbacPolicy(ctx *pulumi.Context, props AbacPolicyProps, opts ...pulumi.ResourceOption) (*AbacPolicyResource, error) {
+	resource := &AbacPolicyResource{}
+	if err := ctx.RegisterComponentResource("ss9:aws:secretmanager:abacpolicy", props.Name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"policyArn": resource.Policy.Arn,
+	})
+	return resource, nil
+}
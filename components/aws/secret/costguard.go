@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+var (
+	countsMu sync.Mutex
+	// secrets created by the program, per type
+	secretCounts = map[SecretType]int{}
+)
+
+func countSecret(secretType SecretType) {
+	countsMu.Lock()
+	defer countsMu.Unlock()
+	secretCounts[secretType]++
+}
+
+// CostGuard warns when the stack creates more secrets than expected, since
+// Secret Manager charges per secret. It's read from the `secret` config namespace.
+type CostGuard struct {
+	// Max secrets in the stack, 0 disables the check
+	MaxSecrets int `json:"maxSecrets"`
+	// Max secrets of the same type, beyond which they'd better be consolidated
+	// (e.g. creds:exportMode consolidated), 0 disables the check
+	MaxSecretsPerType int `json:"maxSecretsPerType"`
+	// Fail the deployment instead of warning
+	EnforceCostGuard bool `json:"enforceCostGuard"`
+}
+
+func LoadCostGuard(ctx *pulumi.Context) (*CostGuard, error) {
+	guard := &CostGuard{}
+	if err := utils.ExtractConfig(ctx, "secret", guard); err != nil {
+		return nil, err
+	}
+	return guard, nil
+}
+
+func (g *CostGuard) violations() []string {
+	countsMu.Lock()
+	defer countsMu.Unlock()
+	violations := []string{}
+	total := 0
+	types := make([]string, 0, len(secretCounts))
+	for secretType, count := range secretCounts {
+		total += count
+		types = append(types, string(secretType))
+	}
+	if g.MaxSecrets > 0 && total > g.MaxSecrets {
+		violations = append(violations, fmt.Sprintf("stack creates %d secrets, more than %d allowed", total, g.MaxSecrets))
+	}
+	sort.Strings(types)
+	for _, secretType := range types {
+		count := secretCounts[SecretType(secretType)]
+		if g.MaxSecretsPerType > 0 && count > g.MaxSecretsPerType {
+			violations = append(violations, fmt.Sprintf("stack creates %d secrets of type %s, consider consolidating them into one", count, secretType))
+		}
+	}
+	return violations
+}
+
+// Check is meant to be called once all the secrets of the program are created.
+func (g *CostGuard) Check(ctx *pulumi.Context) error {
+	violations := g.violations()
+	if len(violations) == 0 {
+		return nil
+	}
+	if g.EnforceCostGuard {
+		return fmt.Errorf("secret cost guard: %s", violations[0])
+	}
+	for _, violation := range violations {
+		ctx.Log.Warn(fmt.Sprintf("secret cost guard: %s", violation), nil)
+	}
+	return nil
+}
+
+// CheckCostGuard loads the cost guard from config and checks the secrets created so far.
+func CheckCostGuard(ctx *pulumi.Context) error {
+	guard, err := LoadCostGuard(ctx)
+	if err != nil {
+		return err
+	}
+	return guard.Check(ctx)
+}
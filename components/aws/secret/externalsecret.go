@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultRefreshInterval = "1h"
+
+// ExternalSecretProps configures the ExternalSecret manifest which syncs an
+// AWSSecret into a k8s secret, via external-secrets operator.
+type ExternalSecretProps struct {
+	// Name of the ExternalSecret and the k8s secret created by it
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// (Cluster)SecretStore pointing to the Secret Manager of the account
+	SecretStore string `json:"secretStore"`
+	// Whether SecretStore is namespaced, instead of a ClusterSecretStore
+	NamespacedStore bool   `json:"namespacedStore"`
+	RefreshInterval string `json:"refreshInterval"`
+}
+
+type externalSecretRef struct {
+	Name string `yaml:"name"`
+	Kind string `yaml:"kind"`
+}
+
+type externalSecretManifest struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace,omitempty"`
+	} `yaml:"metadata"`
+	Spec struct {
+		RefreshInterval string            `yaml:"refreshInterval"`
+		SecretStoreRef  externalSecretRef `yaml:"secretStoreRef"`
+		Target          struct {
+			Name           string `yaml:"name"`
+			CreationPolicy string `yaml:"creationPolicy"`
+		} `yaml:"target"`
+		DataFrom []map[string]map[string]string `yaml:"dataFrom"`
+	} `yaml:"spec"`
+}
+
+func renderExternalSecret(props ExternalSecretProps, secretName string) (string, error) {
+	manifest := externalSecretManifest{
+		APIVersion: "external-secrets.io/v1beta1",
+		Kind:       "ExternalSecret",
+	}
+	manifest.Metadata.Name = props.Name
+	manifest.Metadata.Namespace = props.Namespace
+	manifest.Spec.RefreshInterval = props.RefreshInterval
+	manifest.Spec.SecretStoreRef = externalSecretRef{Name: props.SecretStore, Kind: "ClusterSecretStore"}
+	if props.NamespacedStore {
+		manifest.Spec.SecretStoreRef.Kind = "SecretStore"
+	}
+	manifest.Spec.Target.Name = props.Name
+	manifest.Spec.Target.CreationPolicy = "Owner"
+	// every key of the secret payload becomes a key of the k8s secret
+	manifest.Spec.DataFrom = []map[string]map[string]string{
+		{"extract": {"key": secretName}},
+	}
+	out, err := yaml.Marshal(manifest)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal external secret manifest: %w", err)
+	}
+	return string(out), nil
+}
+
+// ExternalSecretManifest renders the ExternalSecret YAML referencing this
+// secret, to be handed off to GitOps managed app deployments. It only
+// refers to the secret by name, so it's safe to commit.
+func (s *AWSSecret) ExternalSecretManifest(props ExternalSecretProps) (pulumi.StringOutput, error) {
+	if props.Name == "" {
+		return pulumi.StringOutput{}, fmt.Errorf("name is required for the external secret")
+	}
+	if props.SecretStore == "" {
+		return pulumi.StringOutput{}, fmt.Errorf("secret store is required for the external secret '%s'", props.Name)
+	}
+	if props.RefreshInterval == "" {
+		props.RefreshInterval = defaultRefreshInterval
+	}
+	return s.Secret.Name.ApplyT(func(secretName string) (string, error) {
+		return renderExternalSecret(props, secretName)
+	}).(pulumi.StringOutput), nil
+}
@@ -0,0 +1,84 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// LookupPassword reads a password owned by another team or stack from an
+// existing Secret Manager secret: the password key of a JSON payload (e.g. of
+// DB creds type), or the whole secret string otherwise. The result stays secret.
+func LookupPassword(ctx *pulumi.Context, secretId string, opts ...pulumi.InvokeOption) pulumi.StringOutput {
+	sourceSecret := secretsmanager.LookupSecretVersionOutput(ctx, secretsmanager.LookupSecretVersionOutputArgs{
+		SecretId: pulumi.String(secretId),
+	}, opts...)
+	password := sourceSecret.SecretString().ApplyT(func(secretString string) (string, error) {
+		payload := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(secretString), &payload); err != nil {
+			// plain text secret
+			if secretString == "" {
+				return "", fmt.Errorf("secret '%s' is empty", secretId)
+			}
+			return secretString, nil
+		}
+		password, ok := payload["password"].(string)
+		if !ok || password == "" {
+			return "", fmt.Errorf("secret '%s' has no password", secretId)
+		}
+		return password, nil
+	}).(pulumi.StringOutput)
+	return pulumi.ToSecret(password).(pulumi.StringOutput)
+}
+
+// ReadDBCreds reads the creds of an existing Secret Manager secret while the
+// program runs, unlike LookupDBCreds, so they can configure a provider. Numbers
+// (e.g. the port of the secrets managed by RDS) are read as strings. The
+// values aren't secret, the caller has to wrap the password.
+func ReadDBCreds(ctx *pulumi.Context, secretId string, opts ...pulumi.InvokeOption) (map[string]string, error) {
+	sourceSecret, err := secretsmanager.LookupSecretVersion(ctx, &secretsmanager.LookupSecretVersionArgs{
+		SecretId: secretId,
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret '%s': %w", secretId, err)
+	}
+	payload := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(sourceSecret.SecretString), &payload); err != nil {
+		return nil, fmt.Errorf("secret '%s' isn't a JSON of the creds: %w", secretId, err)
+	}
+	creds := map[string]string{}
+	for key, value := range payload {
+		switch v := value.(type) {
+		case string:
+			creds[key] = v
+		case float64:
+			creds[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	if err := DBCreds.ValidatePayload(creds); err != nil {
+		return nil, fmt.Errorf("invalid creds in the secret '%s': %w", secretId, err)
+	}
+	return creds, nil
+}
+
+// LookupDBCreds reads the creds from an existing Secret Manager secret of DB
+// creds type, e.g. of another server. The result stays secret.
+func LookupDBCreds(ctx *pulumi.Context, secretId string, opts ...pulumi.InvokeOption) pulumi.StringMapOutput {
+	sourceSecret := secretsmanager.LookupSecretVersionOutput(ctx, secretsmanager.LookupSecretVersionOutputArgs{
+		SecretId: pulumi.String(secretId),
+	}, opts...)
+	creds := sourceSecret.SecretString().ApplyT(func(secretString string) (map[string]string, error) {
+		creds := map[string]string{}
+		if err := json.Unmarshal([]byte(secretString), &creds); err != nil {
+			return nil, fmt.Errorf("secret '%s' isn't a JSON of the creds: %w", secretId, err)
+		}
+		if err := DBCreds.ValidatePayload(creds); err != nil {
+			return nil, fmt.Errorf("invalid creds in the secret '%s': %w", secretId, err)
+		}
+		return creds, nil
+	}).(pulumi.StringMapOutput)
+	return pulumi.ToSecret(creds).(pulumi.StringMapOutput)
+}
@@ -0,0 +1,177 @@
+package secret
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+var (
+	regionProvidersMu sync.Mutex
+	// providers of the replica regions, shared by the secrets of the stack
+	regionProviders = map[string]*aws.Provider{}
+)
+
+// SecretReplication is the default replication of the secrets, read from the
+// `secret` config namespace
+type SecretReplication struct {
+	// Regions the secrets are replicated to, e.g. the DR ones, unless their
+	// ReplicaRegions is set
+	ReplicaRegions []string `json:"replicaRegions"`
+}
+
+func LoadSecretReplication(ctx *pulumi.Context) (*SecretReplication, error) {
+	replication := &SecretReplication{}
+	if err := utils.ExtractConfig(ctx, "secret", replication); err != nil {
+		return nil, err
+	}
+	return replication, nil
+}
+
+// awsAuth is how the default aws provider authenticates, read from the `aws`
+// config namespace, so the providers of the replica regions do the same. The
+// credentials of the environment (AWS_ACCESS_KEY_ID...) apply to all of them.
+type awsAuth struct {
+	Profile                   string                        `json:"profile"`
+	SharedConfigFiles         []string                      `json:"sharedConfigFiles"`
+	SharedCredentialsFiles    []string                      `json:"sharedCredentialsFiles"`
+	AssumeRole                *awsAssumeRole                `json:"assumeRole"`
+	AssumeRoleWithWebIdentity *awsAssumeRoleWithWebIdentity `json:"assumeRoleWithWebIdentity"`
+}
+
+type awsAssumeRole struct {
+	RoleArn           string            `json:"roleArn"`
+	SessionName       string            `json:"sessionName"`
+	ExternalId        string            `json:"externalId"`
+	Duration          string            `json:"duration"`
+	Policy            string            `json:"policy"`
+	PolicyArns        []string          `json:"policyArns"`
+	SourceIdentity    string            `json:"sourceIdentity"`
+	Tags              map[string]string `json:"tags"`
+	TransitiveTagKeys []string          `json:"transitiveTagKeys"`
+}
+
+type awsAssumeRoleWithWebIdentity struct {
+	RoleArn              string   `json:"roleArn"`
+	SessionName          string   `json:"sessionName"`
+	WebIdentityToken     string   `json:"webIdentityToken"`
+	WebIdentityTokenFile string   `json:"webIdentityTokenFile"`
+	Duration             string   `json:"duration"`
+	Policy               string   `json:"policy"`
+	PolicyArns           []string `json:"policyArns"`
+}
+
+// optional strings of the provider args, unset if empty
+func stringPtr(value string) pulumi.StringPtrInput {
+	if value == "" {
+		return nil
+	}
+	return pulumi.StringPtr(value)
+}
+
+func (auth *awsAuth) providerArgs(region string) *aws.ProviderArgs {
+	args := &aws.ProviderArgs{
+		Region:                 pulumi.String(region),
+		Profile:                stringPtr(auth.Profile),
+		SharedConfigFiles:      pulumi.ToStringArray(auth.SharedConfigFiles),
+		SharedCredentialsFiles: pulumi.ToStringArray(auth.SharedCredentialsFiles),
+	}
+	if role := auth.AssumeRole; role != nil {
+		args.AssumeRole = &aws.ProviderAssumeRoleArgs{
+			RoleArn:           stringPtr(role.RoleArn),
+			SessionName:       stringPtr(role.SessionName),
+			ExternalId:        stringPtr(role.ExternalId),
+			Duration:          stringPtr(role.Duration),
+			Policy:            stringPtr(role.Policy),
+			PolicyArns:        pulumi.ToStringArray(role.PolicyArns),
+			SourceIdentity:    stringPtr(role.SourceIdentity),
+			Tags:              pulumi.ToStringMap(role.Tags),
+			TransitiveTagKeys: pulumi.ToStringArray(role.TransitiveTagKeys),
+		}
+	}
+	if role := auth.AssumeRoleWithWebIdentity; role != nil {
+		args.AssumeRoleWithWebIdentity = &aws.ProviderAssumeRoleWithWebIdentityArgs{
+			RoleArn:              stringPtr(role.RoleArn),
+			SessionName:          stringPtr(role.SessionName),
+			WebIdentityToken:     stringPtr(role.WebIdentityToken),
+			WebIdentityTokenFile: stringPtr(role.WebIdentityTokenFile),
+			Duration:             stringPtr(role.Duration),
+			Policy:               stringPtr(role.Policy),
+			PolicyArns:           pulumi.ToStringArray(role.PolicyArns),
+		}
+	}
+	return args
+}
+
+// regionProvider is the aws provider of the region, to look up its KMS key.
+// It authenticates as the default provider, only the region differs.
+func regionProvider(ctx *pulumi.Context, region string) (*aws.Provider, error) {
+	regionProvidersMu.Lock()
+	defer regionProvidersMu.Unlock()
+	if provider, ok := regionProviders[region]; ok {
+		return provider, nil
+	}
+	auth := &awsAuth{}
+	if err := utils.ExtractConfig(ctx, "aws", auth); err != nil {
+		return nil, err
+	}
+	provider, err := aws.NewProvider(ctx, fmt.Sprintf("secret-replica-%s", region), auth.providerArgs(region))
+	if err != nil {
+		return nil, err
+	}
+	regionProviders[region] = provider
+	return provider, nil
+}
+
+// replicas of the secret in its replica regions, encrypted with the key of
+// secret:kms_alias in each region if set, the aws/secretsmanager one otherwise
+func replicas(ctx *pulumi.Context, props *AWSSecretProps, kmsKeyAlias string) (secretsmanager.SecretReplicaArray, error) {
+	regions := props.ReplicaRegions
+	if regions == nil {
+		replication, err := LoadSecretReplication(ctx)
+		if err != nil {
+			return nil, err
+		}
+		regions = replication.ReplicaRegions
+	}
+	if len(regions) == 0 {
+		return nil, nil
+	}
+	// the region of the default provider, whether set by aws:region, the
+	// profile or AWS_REGION
+	primary, err := aws.GetRegion(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	replicaArgs := secretsmanager.SecretReplicaArray{}
+	for _, region := range regions {
+		if region == "" || region == primary.Name || seen[region] {
+			return nil, fmt.Errorf("invalid replica region '%s' of secret %s, expected distinct regions other than its own", region, props.Name)
+		}
+		seen[region] = true
+		replica := secretsmanager.SecretReplicaArgs{
+			Region: pulumi.String(region),
+		}
+		if kmsKeyAlias != "" {
+			provider, err := regionProvider(ctx, region)
+			if err != nil {
+				return nil, err
+			}
+			kmsKey, err := kms.LookupAlias(ctx, &kms.LookupAliasArgs{
+				Name: kmsKeyAlias,
+			}, pulumi.Provider(provider))
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up %s in %s: %w", kmsKeyAlias, region, err)
+			}
+			replica.KmsKeyId = pulumi.String(kmsKey.TargetKeyArn)
+		}
+		replicaArgs = append(replicaArgs, replica)
+	}
+	return replicaArgs, nil
+}
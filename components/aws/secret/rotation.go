@@ -0,0 +1,143 @@
+package secret
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/serverlessrepository"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	// single-user rotation function of Secret Manager for postgres, published
+	// in the serverless application repository
+	postgresRotationApp = "arn:aws:serverlessrepo:us-east-1:297356227824:applications/SecretsManagerRDSPostgreSQLRotationSingleUser"
+	// longest name of a lambda function
+	maxFunctionNameLength = 64
+)
+
+type RotationRules struct {
+	// Days between the rotations, unless ScheduleExpression is set
+	AutomaticallyAfterDays int
+	// cron() or rate() expression of the rotations, e.g. rate(10 days)
+	ScheduleExpression string
+	// Window of the rotations, e.g. 3h, the whole day if not set
+	Duration string
+}
+
+// RotationVpc places the rotation function in the VPC of the database, it
+// also needs to reach Secret Manager, e.g. through a VPC endpoint
+type RotationVpc struct {
+	SubnetIds        []string
+	SecurityGroupIds []string
+}
+
+func (props *AWSSecretProps) rotates() bool {
+	return props.RotationLambdaArn != nil || props.RotatePostgres
+}
+
+func (props *AWSSecretProps) validateRotation() error {
+	if !props.rotates() {
+		if props.RotationRules != nil {
+			return fmt.Errorf("secret %s has rotation rules but no rotation function", props.Name)
+		}
+		return nil
+	}
+	if props.RotationLambdaArn != nil && props.RotatePostgres {
+		return fmt.Errorf("secret %s can't have both a rotation lambda and the postgres rotation", props.Name)
+	}
+	if props.RotatePostgres && props.Type != DBCreds {
+		return fmt.Errorf("the postgres rotation of secret %s needs the %s type, got %s", props.Name, DBCreds, props.Type)
+	}
+	if props.InitialValue == nil {
+		return fmt.Errorf("rotation of secret %s needs an initial value", props.Name)
+	}
+	rules := props.RotationRules
+	if rules == nil || (rules.AutomaticallyAfterDays == 0) == (rules.ScheduleExpression == "") {
+		return fmt.Errorf("rotation rules of secret %s need either automaticallyAfterDays or scheduleExpression", props.Name)
+	}
+	if rules.AutomaticallyAfterDays < 0 || rules.AutomaticallyAfterDays > 1000 {
+		return fmt.Errorf("rotation of secret %s must be every 1 to 1000 days, got %d", props.Name, rules.AutomaticallyAfterDays)
+	}
+	return nil
+}
+
+// rotationPayload adds the keys the postgres rotation function reads, engine
+// and dbname, to the DB creds
+func rotationPayload(payload map[string]string) map[string]string {
+	rotated := map[string]string{}
+	for k, v := range payload {
+		rotated[k] = v
+	}
+	if rotated["engine"] == "" {
+		rotated["engine"] = "postgres"
+	}
+	if rotated["dbname"] == "" && rotated["database"] != "" {
+		rotated["dbname"] = rotated["database"]
+	}
+	return rotated
+}
+
+// postgresRotationLambda deploys the rotation function of Secret Manager,
+// which changes the password of the user of the secret with its own creds
+func (s *AWSSecret) postgresRotationLambda(ctx *pulumi.Context, props *AWSSecretProps) (pulumi.StringOutput, error) {
+	functionName := fmt.Sprintf("rotate-%s", s.Name)
+	if len(functionName) > maxFunctionNameLength {
+		return pulumi.StringOutput{}, fmt.Errorf("name of the rotation function %s is longer than %d characters", functionName, maxFunctionNameLength)
+	}
+	region, err := aws.GetRegion(ctx, nil)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	params := pulumi.StringMap{
+		"endpoint":     pulumi.Sprintf("https://secretsmanager.%s.amazonaws.com", region.Name),
+		"functionName": pulumi.String(functionName),
+	}
+	if props.RotationVpc != nil {
+		params["vpcSubnetIds"] = pulumi.String(strings.Join(props.RotationVpc.SubnetIds, ","))
+		params["vpcSecurityGroupIds"] = pulumi.String(strings.Join(props.RotationVpc.SecurityGroupIds, ","))
+	}
+	stack, err := serverlessrepository.NewCloudFormationStack(ctx, fmt.Sprintf("rotation-%s", props.Name), &serverlessrepository.CloudFormationStackArgs{
+		Name:          pulumi.String(functionName),
+		ApplicationId: pulumi.String(postgresRotationApp),
+		Capabilities:  pulumi.ToStringArray([]string{"CAPABILITY_IAM", "CAPABILITY_RESOURCE_POLICY"}),
+		Parameters:    params,
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	}, pulumi.Parent(s))
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	return stack.Outputs.MapIndex(pulumi.String("RotationLambdaARN")), nil
+}
+
+// newRotation schedules the rotations of the secret, the first one runs once
+// it's created
+func (s *AWSSecret) newRotation(ctx *pulumi.Context, props *AWSSecretProps, secret *secretsmanager.Secret, version pulumi.Resource) (*secretsmanager.SecretRotation, error) {
+	lambdaArn := props.RotationLambdaArn
+	if props.RotatePostgres {
+		arn, err := s.postgresRotationLambda(ctx, props)
+		if err != nil {
+			return nil, err
+		}
+		lambdaArn = arn
+	}
+	rules := &secretsmanager.SecretRotationRotationRulesArgs{}
+	if props.RotationRules.AutomaticallyAfterDays > 0 {
+		rules.AutomaticallyAfterDays = pulumi.Int(props.RotationRules.AutomaticallyAfterDays)
+	}
+	if props.RotationRules.ScheduleExpression != "" {
+		rules.ScheduleExpression = pulumi.String(props.RotationRules.ScheduleExpression)
+	}
+	if props.RotationRules.Duration != "" {
+		rules.Duration = pulumi.String(props.RotationRules.Duration)
+	}
+	return secretsmanager.NewSecretRotation(ctx, fmt.Sprintf("secretrotation-%s", props.Name), &secretsmanager.SecretRotationArgs{
+		SecretId:          secret.ID(),
+		RotationLambdaArn: lambdaArn,
+		RotationRules:     rules,
+	}, pulumi.Parent(s), pulumi.DependsOn([]pulumi.Resource{version}))
+}
@@ -7,39 +7,73 @@ import (
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
 )
 
-type SecretType string
-
-const (
-	DBCreds    SecretType = "db"
-	MongoCreds SecretType = "mongo"
-)
+// StackTag is set on every secret with the fully qualified name
+// (org/project/stack) of the stack managing it.
+const StackTag = "pulumi:stack"
 
 type AWSSecretProps struct {
 	Name         string
 	Type         SecretType
 	InitialValue pulumi.StringMapInput
+	// Extra tags on the secret, they can't override the managed ones
+	Tags map[string]string
+	// KMS key of the secret, overrides secret:kms_alias
+	KmsKeyId pulumi.StringInput
+	// If set, only these IAM principals (ARNs, wildcards allowed) can read
+	// the secret value, whatever their IAM policies allow. The deployer needs
+	// to be one of them, to refresh the secret.
+	ReaderArns []string
+	// Time-limited read access for the developers, refused in prod. It
+	// can't be combined with ReaderArns.
+	Shares []SecretShare
+	// Lambda rotating the secret on the RotationRules schedule
+	RotationLambdaArn pulumi.StringInput
+	// Deploys the postgres single-user rotation function of Secret Manager
+	// instead of RotationLambdaArn, for the secrets of DB creds type
+	RotatePostgres bool
+	// VPC of the postgres rotation function, to reach a private database
+	RotationVpc   *RotationVpc
+	RotationRules *RotationRules
+	// Regions the secret is replicated to, secret:replicaRegions if nil. The
+	// replicas are encrypted with the secret:kms_alias key of their region.
+	ReplicaRegions []string
 }
 
 func (props AWSSecretProps) String() string {
-	descriptionType := map[SecretType]string{
-		DBCreds:    "database credentials",
-		MongoCreds: "mongo connection details",
-	}
-	return fmt.Sprintf("Secret %s to store %s", props.Name, descriptionType[props.Type])
+	return fmt.Sprintf("Secret %s to store %s", props.Name, props.Type.Description())
 }
 
 type AWSSecret struct {
 	pulumi.ResourceState
 
 	Secret *secretsmanager.Secret
+	// Name of the secret in Secret Manager, known before it's created
+	Name string
+}
+
+// SecretName is the name of the secret in Secret Manager
+func SecretName(secretType SecretType, name string) string {
+	return fmt.Sprintf("%s-%s", secretType, name)
 }
 
 func (s *AWSSecret) newSecret(ctx *pulumi.Context, props *AWSSecretProps) (*secretsmanager.Secret, error) {
-	tags := pulumi.StringMap{
-		"Pulumi": pulumi.String("true"),
+	tags := pulumi.StringMap{}
+	for k, v := range props.Tags {
+		tags[k] = pulumi.String(v)
 	}
+	access, err := LoadSecretAccess(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range access.tags() {
+		tags[k] = pulumi.String(v)
+	}
+	tags["Pulumi"] = pulumi.String("true")
+	// owning stack, to find the orphaned secrets once it's removed
+	tags[StackTag] = pulumi.String(fmt.Sprintf("%s/%s/%s", ctx.Organization(), ctx.Project(), ctx.Stack()))
 	var kmsKeyId string
 	kmsKeyAlias, ok := ctx.GetConfig("secret:kms_alias")
 	if ok {
@@ -54,46 +88,139 @@ func (s *AWSSecret) newSecret(ctx *pulumi.Context, props *AWSSecretProps) (*secr
 	tags["secret:type"] = pulumi.String(props.Type)
 
 	args := &secretsmanager.SecretArgs{
-		Name:        pulumi.Sprintf("%s-%s", props.Type, props.Name),
+		Name:        pulumi.String(SecretName(props.Type, props.Name)),
 		Description: pulumi.String(props.String()),
 		Tags:        tags,
 	}
 	if kmsKeyId != "" {
 		args.KmsKeyId = pulumi.String(kmsKeyId)
 	}
-	secret, err := secretsmanager.NewSecret(ctx, fmt.Sprintf("secret-%s", props.Name), args, pulumi.Parent(s))
+	if props.KmsKeyId != nil {
+		args.KmsKeyId = props.KmsKeyId
+	}
+	replicaArgs, err := replicas(ctx, props, kmsKeyAlias)
 	if err != nil {
 		return nil, err
 	}
+	if len(replicaArgs) > 0 {
+		args.Replicas = replicaArgs
+	}
+	opts := []pulumi.ResourceOption{pulumi.Parent(s)}
+	if props.Type == DBCreds || props.Type == DBCredsBundle {
+		protected, err := utils.DestroyProtected(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, pulumi.Protect(protected))
+	}
+	secret, err := secretsmanager.NewSecret(ctx, fmt.Sprintf("secret-%s", props.Name), args, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// each replica is charged as a secret
+	for range replicaArgs {
+		countSecret(props.Type)
+	}
+	policy := ""
+	if len(props.ReaderArns) > 0 {
+		policy = readersPolicy(props.ReaderArns)
+	} else if len(props.Shares) > 0 {
+		shares, err := activeShares(ctx, props.Shares)
+		if err != nil {
+			return nil, err
+		}
+		if len(shares) > 0 {
+			policy = sharesPolicy(shares)
+		}
+	}
+	if policy != "" {
+		if _, err := secretsmanager.NewSecretPolicy(ctx, fmt.Sprintf("secretpolicy-%s", props.Name), &secretsmanager.SecretPolicyArgs{
+			SecretArn: secret.Arn,
+			Policy:    pulumi.String(policy),
+		}, pulumi.Parent(s)); err != nil {
+			return nil, err
+		}
+	}
 	return secret, nil
 }
 
+// readersPolicy denies reading the secret value to every principal but the readers
+func readersPolicy(readerArns []string) string {
+	policy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Sid":       "RestrictReaders",
+			"Effect":    "Deny",
+			"Principal": "*",
+			"Action":    "secretsmanager:GetSecretValue",
+			"Resource":  "*",
+			"Condition": map[string]interface{}{
+				"StringNotLike": map[string]interface{}{
+					"aws:PrincipalArn": readerArns,
+				},
+			},
+		}},
+	}
+	out, _ := json.Marshal(policy)
+	return string(out)
+}
+
 func (s *AWSSecret) provision(ctx *pulumi.Context, props *AWSSecretProps) error {
+	if _, ok := LookupSecretType(props.Type); !ok {
+		return fmt.Errorf("secret type '%s' is not registered", props.Type)
+	}
+	if len(props.ReaderArns) > 0 && len(props.Shares) > 0 {
+		return fmt.Errorf("secret %s can't have both readers and shares", props.Name)
+	}
+	if err := props.validateRotation(); err != nil {
+		return err
+	}
 	secret, err := s.newSecret(ctx, props)
 	if err != nil {
 		return err
 	}
+	countSecret(props.Type)
 
 	s.Secret = secret
+	s.Name = SecretName(props.Type, props.Name)
 	outputs := pulumi.Map{
 		"secretArn": secret.Arn,
 	}
 	if props.InitialValue != nil {
-		secVersion := props.InitialValue.ToStringMapOutput().ApplyT(func(val map[string]string) (pulumi.StringOutput, error) {
+		secretString := props.InitialValue.ToStringMapOutput().ApplyT(func(val map[string]string) (string, error) {
+			if err := props.Type.ValidatePayload(val); err != nil {
+				return "", err
+			}
+			if props.RotatePostgres {
+				val = rotationPayload(val)
+			}
 			secretDict, err := json.Marshal(val)
 			if err != nil {
-				return pulumi.StringOutput{}, fmt.Errorf("failed to marshal secret data into json: %w", err)
+				return "", fmt.Errorf("failed to marshal secret data into json: %w", err)
 			}
-			secVersion, err := secretsmanager.NewSecretVersion(ctx, fmt.Sprintf("secretversion-initial-%s", props.Name), &secretsmanager.SecretVersionArgs{
-				SecretId:     secret.Arn,
-				SecretString: pulumi.String(string(secretDict)),
-			}, pulumi.Parent(s))
+			return string(secretDict), nil
+		}).(pulumi.StringOutput)
+		versionOpts := []pulumi.ResourceOption{pulumi.Parent(s)}
+		if props.rotates() {
+			// the rotations own the value, it isn't written back on updates
+			versionOpts = append(versionOpts, pulumi.IgnoreChanges([]string{"secretString"}))
+		}
+		// registered outside of the apply, so it's in the preview and can be targeted
+		secVersion, err := secretsmanager.NewSecretVersion(ctx, fmt.Sprintf("secretversion-initial-%s", props.Name), &secretsmanager.SecretVersionArgs{
+			SecretId:     secret.Arn,
+			SecretString: pulumi.ToSecret(secretString).(pulumi.StringOutput),
+		}, versionOpts...)
+		if err != nil {
+			return err
+		}
+		outputs["secretVersion"] = secVersion.VersionId
+		if props.rotates() {
+			rotation, err := s.newRotation(ctx, props, secret, secVersion)
 			if err != nil {
-				return pulumi.StringOutput{}, err
+				return fmt.Errorf("failed to set up rotation of secret %s: %w", props.Name, err)
 			}
-			return secVersion.VersionId, nil
-		}).(pulumi.StringOutput)
-		outputs["secretVersion"] = secVersion
+			outputs["rotationEnabled"] = rotation.RotationEnabled
+		}
 	}
 	ctx.RegisterResourceOutputs(s, outputs)
 	return nil
@@ -0,0 +1,78 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+// shares are meant for one-off handoffs, not as standing access
+const maxShareDuration = 7 * 24 * time.Hour
+
+// SecretShare lets a developer read the secret until the expiry, e.g. to
+// hand off the creds of a dev database instead of DMing the password.
+type SecretShare struct {
+	// IAM user or role the secret is shared with
+	PrincipalArn string `json:"principalArn"`
+	// RFC3339 timestamp after which the share stops working
+	Until string `json:"until"`
+}
+
+// activeShares validates the shares and drops the expired ones, so they're
+// removed from the policy on the next deploy.
+func activeShares(ctx *pulumi.Context, shares []SecretShare) ([]SecretShare, error) {
+	policy, err := utils.LoadExportPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Profile == "prod" {
+		return nil, fmt.Errorf("secrets can't be shared in stacks with the prod export profile")
+	}
+	now := time.Now()
+	active := []SecretShare{}
+	for _, share := range shares {
+		if share.PrincipalArn == "" {
+			return nil, fmt.Errorf("principal ARN is required to share a secret")
+		}
+		until, err := time.Parse(time.RFC3339, share.Until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry of the share with %s: %w", share.PrincipalArn, err)
+		}
+		if until.Sub(now) > maxShareDuration {
+			return nil, fmt.Errorf("share with %s can last %s at most", share.PrincipalArn, maxShareDuration)
+		}
+		if !until.After(now) {
+			ctx.Log.Warn(fmt.Sprintf("share with %s expired at %s, it's removed", share.PrincipalArn, share.Until), nil)
+			continue
+		}
+		active = append(active, share)
+	}
+	return active, nil
+}
+
+// sharesPolicy allows each principal to read the secret value until its expiry
+func sharesPolicy(shares []SecretShare) string {
+	statements := make([]map[string]interface{}, len(shares))
+	for i, share := range shares {
+		statements[i] = map[string]interface{}{
+			"Sid":       fmt.Sprintf("Share%d", i),
+			"Effect":    "Allow",
+			"Principal": map[string]string{"AWS": share.PrincipalArn},
+			"Action":    "secretsmanager:GetSecretValue",
+			"Resource":  "*",
+			"Condition": map[string]interface{}{
+				"DateLessThan": map[string]string{
+					"aws:CurrentTime": share.Until,
+				},
+			},
+		}
+	}
+	out, _ := json.Marshal(map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	})
+	return string(out)
+}
@@ -0,0 +1,162 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi-vault/sdk/v6/go/vault/kv"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+// names of the k8s secrets, DNS subdomains
+var k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+
+// SecretStoreUnion is the tagged union of the stores, so a store can be picked
+// in the config, e.g. `exporter: {type: vault, mount: kv}`:
+//
+//	Exporter secret.SecretStore `json:"exporter" union:"secretStore"`
+//
+// or by name only, e.g. `exportTargets: [aws, vault]`
+const SecretStoreUnion = "secretStore"
+
+// SecretStore persists a credentials payload in some secret backend and
+// returns the identifier consumers should use to look it up.
+type SecretStore interface {
+	Store(ctx *pulumi.Context, name string, secretType SecretType, value pulumi.StringMapInput, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error)
+}
+
+// AWSSecretStore stores the payload as an AWSSecret in Secret Manager.
+type AWSSecretStore struct {
+	// Extra tags of the stored secrets
+	Tags map[string]string `json:"tags"`
+	// KMS key of the stored secrets, overrides secret:kms_alias, e.g. for a
+	// store in another account
+	KmsKeyId string `json:"kmsKeyId"`
+}
+
+func (s AWSSecretStore) Store(ctx *pulumi.Context, name string, secretType SecretType, value pulumi.StringMapInput, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
+	props := AWSSecretProps{
+		Name:         name,
+		Type:         secretType,
+		InitialValue: value,
+		Tags:         s.Tags,
+	}
+	if s.KmsKeyId != "" {
+		props.KmsKeyId = pulumi.String(s.KmsKeyId)
+	}
+	res, err := NewAWSSecret(ctx, props, opts...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	return res.Secret.ID().ToStringOutput(), nil
+}
+
+// VaultSecretStore stores the payload in a KV v2 secrets engine of Vault, the
+// default vault provider reads the address from vault:address and the token
+// from VAULT_TOKEN.
+type VaultSecretStore struct {
+	// Mount of the KV v2 engine, secret if not set
+	Mount string `json:"mount"`
+	// Prefix of the paths of the stored secrets, e.g. databases/
+	PathPrefix string `json:"pathPrefix"`
+}
+
+func (s VaultSecretStore) Store(ctx *pulumi.Context, name string, secretType SecretType, value pulumi.StringMapInput, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
+	mount := s.Mount
+	if mount == "" {
+		mount = "secret"
+	}
+	secret, err := kv.NewSecretV2(ctx, fmt.Sprintf("vault-%s", name), &kv.SecretV2Args{
+		Mount:    pulumi.String(mount),
+		Name:     pulumi.String(s.PathPrefix + name),
+		DataJson: pulumi.ToSecret(pulumi.JSONMarshal(value.ToStringMapOutput())).(pulumi.StringOutput),
+		CustomMetadata: &kv.SecretV2CustomMetadataArgs{
+			Data: pulumi.StringMap{
+				"type": pulumi.String(secretType),
+			},
+		},
+	}, opts...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	return secret.Path, nil
+}
+
+// KubernetesSecretStore stores the payload in an Opaque secret of the cluster
+// of the default kubernetes provider, one key per payload key.
+type KubernetesSecretStore struct {
+	// Namespace of the stored secrets, default if not set
+	Namespace string `json:"namespace"`
+	// Extra labels of the stored secrets
+	Labels map[string]string `json:"labels"`
+}
+
+func (s KubernetesSecretStore) Store(ctx *pulumi.Context, name string, secretType SecretType, value pulumi.StringMapInput, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
+	if !k8sNamePattern.MatchString(name) {
+		return pulumi.StringOutput{}, fmt.Errorf("'%s' isn't a valid name of a k8s secret", name)
+	}
+	namespace := s.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	labels := pulumi.StringMap{}
+	for k, v := range s.Labels {
+		labels[k] = pulumi.String(v)
+	}
+	labels["app.kubernetes.io/managed-by"] = pulumi.String("pulumi")
+	if _, err := corev1.NewSecret(ctx, fmt.Sprintf("k8s-%s", name), &corev1.SecretArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:      pulumi.String(name),
+			Namespace: pulumi.String(namespace),
+			Labels:    labels,
+			Annotations: pulumi.StringMap{
+				"secret-type": pulumi.String(secretType),
+			},
+		},
+		Type:       pulumi.String("Opaque"),
+		StringData: pulumi.ToSecret(value).(pulumi.StringMapOutput),
+	}, opts...); err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	return pulumi.Sprintf("%s/%s", namespace, name), nil
+}
+
+// LookupSecretStore returns the store registered in the secretStore union as
+// typeName, e.g. to publish the creds to several stores of `exportTargets`.
+// The settings, if any, are decoded into the store, e.g. {mount: kv} of vault.
+func LookupSecretStore(typeName string, settings map[string]interface{}) (SecretStore, error) {
+	member, err := utils.NewUnionMember(SecretStoreUnion, typeName)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := member.(SecretStore)
+	if !ok {
+		return nil, fmt.Errorf("%s type '%s' (%T) isn't a secret store", SecretStoreUnion, typeName, member)
+	}
+	if len(settings) > 0 {
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, member); err != nil {
+			return nil, fmt.Errorf("invalid settings of the %s store: %w", typeName, err)
+		}
+	}
+	return store, nil
+}
+
+func init() {
+	utils.MustRegisterUnionType(SecretStoreUnion, "aws", func() interface{} {
+		return &AWSSecretStore{}
+	})
+	utils.MustRegisterUnionType(SecretStoreUnion, "vault", func() interface{} {
+		return &VaultSecretStore{}
+	})
+	utils.MustRegisterUnionType(SecretStoreUnion, "k8s", func() interface{} {
+		return &KubernetesSecretStore{}
+	})
+}
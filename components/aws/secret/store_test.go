@@ -0,0 +1,114 @@
+package secret
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+func TestLookupSecretStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		typeName string
+		settings map[string]interface{}
+		want     SecretStore
+		wantErr  string
+	}{
+		{name: "aws", typeName: "aws", want: &AWSSecretStore{}},
+		{
+			name:     "aws with settings",
+			typeName: "aws",
+			settings: map[string]interface{}{"kmsKeyId": "alias/shared", "tags": map[string]interface{}{"team": "billing"}},
+			want:     &AWSSecretStore{KmsKeyId: "alias/shared", Tags: map[string]string{"team": "billing"}},
+		},
+		{
+			name:     "vault with settings",
+			typeName: "vault",
+			settings: map[string]interface{}{"mount": "kv", "pathPrefix": "databases/"},
+			want:     &VaultSecretStore{Mount: "kv", PathPrefix: "databases/"},
+		},
+		{name: "k8s", typeName: "k8s", want: &KubernetesSecretStore{}},
+		{name: "unknown type", typeName: "gcp", wantErr: "unknown secretStore type 'gcp', expected one of [aws, k8s, vault]"},
+		{
+			name:     "invalid settings",
+			typeName: "k8s",
+			settings: map[string]interface{}{"namespace": 42},
+			wantErr:  "invalid settings of the k8s store",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := LookupSecretStore(tt.typeName, tt.settings)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(store, tt.want) {
+				t.Fatalf("got store %#v, want %#v", store, tt.want)
+			}
+		})
+	}
+}
+
+type storeTestMocks struct{}
+
+func (storeTestMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	return args.Name, args.Inputs, nil
+}
+
+func (storeTestMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+func TestExtractConfigSecretStore(t *testing.T) {
+	type exportConfig struct {
+		Exporter SecretStore `json:"exporter" union:"secretStore"`
+	}
+	tests := []struct {
+		name     string
+		exporter string
+		want     SecretStore
+		wantErr  string
+	}{
+		{name: "vault", exporter: `{"type": "vault", "mount": "kv", "pathPrefix": "databases/"}`, want: &VaultSecretStore{Mount: "kv", PathPrefix: "databases/"}},
+		{name: "aws", exporter: `{"type": "aws", "kmsKeyId": "alias/shared", "tags": {"team": "billing"}}`, want: &AWSSecretStore{KmsKeyId: "alias/shared", Tags: map[string]string{"team": "billing"}}},
+		{name: "k8s", exporter: `{"type": "k8s", "namespace": "billing"}`, want: &KubernetesSecretStore{Namespace: "billing"}},
+		{name: "unknown type", exporter: `{"type": "gcp"}`, wantErr: "unknown secretStore type 'gcp', expected one of [aws, k8s, vault]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := json.Marshal(map[string]string{"test:exporter": tt.exporter})
+			t.Setenv(pulumi.EnvConfig, string(data))
+			got := exportConfig{}
+			var err error
+			if runErr := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				err = utils.ExtractConfig(ctx, "test", &got)
+				return nil
+			}, pulumi.WithMocks("project", "stack", storeTestMocks{})); runErr != nil {
+				t.Fatal(runErr)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Exporter, tt.want) {
+				t.Fatalf("got store %#v, want %#v", got.Exporter, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+type SecretType string
+
+const (
+	DBCreds    SecretType = "db"
+	MongoCreds SecretType = "mongo"
+	IAMCreds   SecretType = "iam"
+	SMTPCreds  SecretType = "smtp"
+	APIKey     SecretType = "apikey"
+	RedisCreds SecretType = "redis"
+	KafkaCreds SecretType = "kafka"
+	AMQPCreds  SecretType = "amqp"
+	HelmValues SecretType = "helm"
+	// DB creds of multiple users, keyed by username
+	DBCredsBundle SecretType = "dbs"
+	// Connection strings of a DB user, e.g. DATABASE_URL
+	DBConnection SecretType = "dburl"
+	// userlist.txt & [databases] entry of a pgbouncer
+	PgBouncerConfig SecretType = "pgbouncer"
+	// Internal user of an OpenSearch domain, with its endpoint
+	SearchCreds SecretType = "opensearch"
+	// Service-specific credential of Amazon Keyspaces, with its keyspace
+	CassandraCreds SecretType = "cassandra"
+)
+
+// SecretTypeSpec describes the payload stored by secrets of a type.
+type SecretTypeSpec struct {
+	Description string
+	// Keys which must be present (and non-empty) in the payload
+	RequiredKeys []string
+	// Optional extra check on the payload, run after the required keys
+	Validate func(payload map[string]string) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[SecretType]SecretTypeSpec{}
+)
+
+// RegisterSecretType makes a new secret type usable by AWSSecret and
+// validates the payloads stored against its spec.
+func RegisterSecretType(secretType SecretType, spec SecretTypeSpec) error {
+	if secretType == "" {
+		return fmt.Errorf("secret type must not be empty")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[secretType]; ok {
+		return fmt.Errorf("secret type '%s' is already registered", secretType)
+	}
+	registry[secretType] = spec
+	return nil
+}
+
+// MustRegisterSecretType is like RegisterSecretType but panics on error, for use in init().
+func MustRegisterSecretType(secretType SecretType, spec SecretTypeSpec) {
+	if err := RegisterSecretType(secretType, spec); err != nil {
+		panic(err)
+	}
+}
+
+func LookupSecretType(secretType SecretType) (SecretTypeSpec, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	spec, ok := registry[secretType]
+	return spec, ok
+}
+
+func (t SecretType) Description() string {
+	if spec, ok := LookupSecretType(t); ok && spec.Description != "" {
+		return spec.Description
+	}
+	return string(t)
+}
+
+func (t SecretType) ValidatePayload(payload map[string]string) error {
+	spec, ok := LookupSecretType(t)
+	if !ok {
+		return fmt.Errorf("secret type '%s' is not registered", t)
+	}
+	for _, key := range spec.RequiredKeys {
+		if payload[key] == "" {
+			return fmt.Errorf("missing '%s' in payload of secret type %s", key, t)
+		}
+	}
+	if spec.Validate != nil {
+		if err := spec.Validate(payload); err != nil {
+			return fmt.Errorf("invalid payload of secret type %s: %w", t, err)
+		}
+	}
+	return nil
+}
+
+func validatePort(payload map[string]string) error {
+	port, ok := payload["port"]
+	if !ok {
+		return nil
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("port '%s' is not a valid number", port)
+	}
+	return nil
+}
+
+// validateCredsBundle checks every value is the JSON of DBCreds payload
+func validateCredsBundle(payload map[string]string) error {
+	if len(payload) == 0 {
+		return fmt.Errorf("no credentials in the bundle")
+	}
+	for username, value := range payload {
+		creds := map[string]string{}
+		if err := json.Unmarshal([]byte(value), &creds); err != nil {
+			return fmt.Errorf("credentials of '%s' are not valid JSON: %w", username, err)
+		}
+		if err := DBCreds.ValidatePayload(creds); err != nil {
+			return fmt.Errorf("credentials of '%s': %w", username, err)
+		}
+	}
+	return nil
+}
+
+// validateDBCreds requires the password, unless the user authenticates with IAM tokens
+func validateDBCreds(payload map[string]string) error {
+	if payload["password"] == "" && payload["authentication"] != "iam" {
+		return fmt.Errorf("missing 'password', required unless authentication is iam")
+	}
+	return validatePort(payload)
+}
+
+// validateDBConnection requires at least one connection string
+func validateDBConnection(payload map[string]string) error {
+	for _, value := range payload {
+		if value != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("no connection string in the payload")
+}
+
+func init() {
+	MustRegisterSecretType(DBConnection, SecretTypeSpec{
+		Description: "database connection strings",
+		Validate:    validateDBConnection,
+	})
+	MustRegisterSecretType(DBCreds, SecretTypeSpec{
+		Description:  "database credentials",
+		RequiredKeys: []string{"username"},
+		Validate:     validateDBCreds,
+	})
+	MustRegisterSecretType(MongoCreds, SecretTypeSpec{
+		Description: "mongo connection details",
+		Validate:    validatePort,
+	})
+	MustRegisterSecretType(IAMCreds, SecretTypeSpec{
+		Description:  "IAM access keys",
+		RequiredKeys: []string{"accessKeyId", "secretAccessKey"},
+	})
+	MustRegisterSecretType(SMTPCreds, SecretTypeSpec{
+		Description:  "SES SMTP credentials",
+		RequiredKeys: []string{"username", "password", "host", "port"},
+		Validate:     validatePort,
+	})
+	MustRegisterSecretType(APIKey, SecretTypeSpec{
+		Description:  "third-party API key",
+		RequiredKeys: []string{"apiKey"},
+	})
+	MustRegisterSecretType(RedisCreds, SecretTypeSpec{
+		Description:  "redis credentials",
+		RequiredKeys: []string{"host", "port"},
+		Validate:     validatePort,
+	})
+	MustRegisterSecretType(KafkaCreds, SecretTypeSpec{
+		Description:  "kafka credentials",
+		RequiredKeys: []string{"username", "password", "bootstrapServers"},
+	})
+	MustRegisterSecretType(SearchCreds, SecretTypeSpec{
+		Description:  "OpenSearch credentials",
+		RequiredKeys: []string{"username", "password", "endpoint"},
+	})
+	MustRegisterSecretType(CassandraCreds, SecretTypeSpec{
+		Description:  "cassandra credentials",
+		RequiredKeys: []string{"username", "password", "host", "port", "keyspace"},
+		Validate:     validatePort,
+	})
+	MustRegisterSecretType(AMQPCreds, SecretTypeSpec{
+		Description:  "AMQP broker credentials",
+		RequiredKeys: []string{"username", "password", "host", "port", "vhost"},
+		Validate:     validatePort,
+	})
+	MustRegisterSecretType(DBCredsBundle, SecretTypeSpec{
+		Description: "database credentials of multiple users",
+		Validate:    validateCredsBundle,
+	})
+	MustRegisterSecretType(HelmValues, SecretTypeSpec{
+		Description:  "helm values fragment",
+		RequiredKeys: []string{"values.yaml"},
+	})
+	MustRegisterSecretType(PgBouncerConfig, SecretTypeSpec{
+		Description:  "pgbouncer userlist and databases",
+		RequiredKeys: []string{"userlist.txt", "databases"},
+	})
+}
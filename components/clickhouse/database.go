@@ -0,0 +1,279 @@
+package clickhouse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+var (
+	identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	// privileges of the users on the database, as the rw/ro roles of the
+	// postgres components
+	permissionPrivileges = map[postgres.PostgresUserPermission][]string{
+		postgres.ReadWrite: {"SELECT", "INSERT", "ALTER", "CREATE TABLE", "CREATE VIEW", "DROP TABLE", "DROP VIEW", "TRUNCATE", "OPTIMIZE"},
+		postgres.ReadOnly:  {"SELECT"},
+	}
+	// databases of the server itself
+	reservedDatabases = map[string]bool{"default": true, "system": true, "information_schema": true, "INFORMATION_SCHEMA": true}
+)
+
+type ClickHouseProfileProps struct {
+	Name string `json:"name"`
+	// Settings of the profile, e.g. max_memory_usage: "10000000000" or
+	// max_execution_time: "300"
+	Settings map[string]string `json:"settings"`
+}
+
+type ClickHouseUserProps struct {
+	Username string `json:"username"`
+	// rw or ro privileges on the database, rw if not set
+	Permission postgres.PostgresUserPermission `json:"permission"`
+	// Settings profile of the component the user gets, e.g. to limit the
+	// memory of the queries of the analysts
+	Profile string `json:"profile"`
+	// Bump it to rotate the generated password
+	RotationTrigger string `json:"rotationTrigger"`
+}
+
+type ClickHouseDatabaseProps struct {
+	Database string                   `json:"database"`
+	Comment  string                   `json:"comment"`
+	Profiles []ClickHouseProfileProps `json:"profiles"`
+	Users    []ClickHouseUserProps    `json:"users"`
+	// Fails any delete of the database, e.g. in a destroy
+	Protect bool `json:"protect"`
+
+	Connection *ClientConnection `json:"-"`
+}
+
+type ClickHouseDatabaseResource struct {
+	pulumi.ResourceState
+
+	DB *Query
+	// Keyed by name
+	Profiles map[string]*Query
+	// Keyed by username
+	Users     map[string]*Query
+	Passwords map[string]pulumi.StringOutput
+	// Errors of the failed users, keyed by username
+	FailedUsers map[string]error
+}
+
+func validateName(kind string, name string) error {
+	if !identifierRegex.MatchString(name) {
+		return fmt.Errorf("invalid %s name '%s', only letters, digits and _ - are allowed", kind, name)
+	}
+	return nil
+}
+
+func (props *ClickHouseDatabaseProps) validate() error {
+	if props.Connection == nil {
+		return fmt.Errorf("the clickhouse-client connection is required")
+	}
+	if err := validateName("database", props.Database); err != nil {
+		return err
+	}
+	if reservedDatabases[props.Database] {
+		return fmt.Errorf("database name '%s' is reserved", props.Database)
+	}
+	seen := map[string]bool{}
+	for _, profile := range props.Profiles {
+		if err := validateName("profile", profile.Name); err != nil {
+			return err
+		}
+		if seen[profile.Name] {
+			return fmt.Errorf("duplicate profile %s", profile.Name)
+		}
+		seen[profile.Name] = true
+		for setting := range profile.Settings {
+			if err := validateName("setting", setting); err != nil {
+				return fmt.Errorf("profile %s: %w", profile.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (props *ClickHouseDatabaseProps) validateUser(user *ClickHouseUserProps) error {
+	if err := validateName("user", user.Username); err != nil {
+		return err
+	}
+	if user.Permission == "" {
+		user.Permission = postgres.ReadWrite
+	}
+	if _, ok := permissionPrivileges[user.Permission]; !ok {
+		return fmt.Errorf("permission '%s' isn't supported by clickhouse, expected %s or %s", user.Permission, postgres.ReadWrite, postgres.ReadOnly)
+	}
+	if user.Profile == "" {
+		return nil
+	}
+	for _, profile := range props.Profiles {
+		if profile.Name == user.Profile {
+			return nil
+		}
+	}
+	return fmt.Errorf("profile %s isn't one of the component", user.Profile)
+}
+
+// settingValue keeps the numbers as is, e.g. max_memory_usage, and quotes the
+// others, e.g. load_balancing
+func settingValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return quoteLiteral(value)
+}
+
+// sha256Hex is the hash of the password the server stores, the password
+// itself isn't sent to it
+func sha256Hex(password pulumi.StringOutput) pulumi.StringOutput {
+	return password.ApplyT(func(password string) string {
+		hash := sha256.Sum256([]byte(password))
+		return hex.EncodeToString(hash[:])
+	}).(pulumi.StringOutput)
+}
+
+func (r *ClickHouseDatabaseResource) provisionProfile(ctx *pulumi.Context, name string, props *ClickHouseDatabaseProps, profile *ClickHouseProfileProps) error {
+	keys := make([]string, 0, len(profile.Settings))
+	for key := range profile.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	statements := []string{
+		fmt.Sprintf("CREATE SETTINGS PROFILE IF NOT EXISTS %s;", quoteIdentifier(profile.Name)),
+	}
+	if len(keys) > 0 {
+		settings := make([]string, 0, len(keys))
+		for _, key := range keys {
+			settings = append(settings, fmt.Sprintf("%s = %s", key, settingValue(profile.Settings[key])))
+		}
+		statements = append(statements, fmt.Sprintf("ALTER SETTINGS PROFILE %s SETTINGS %s;", quoteIdentifier(profile.Name), strings.Join(settings, ", ")))
+	}
+	query, err := newQuery(ctx, fmt.Sprintf("%s-profile-%s", name, profile.Name), props.Connection,
+		pulumi.String(strings.Join(statements, "\n")),
+		fmt.Sprintf("DROP SETTINGS PROFILE IF EXISTS %s;", quoteIdentifier(profile.Name)),
+		pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Profiles[profile.Name] = query
+	return nil
+}
+
+// userStatements creates the user, or sets its password if it exists, and
+// grants it the privileges of its permission on the database only
+func userStatements(database string, user *ClickHouseUserProps, hash string) string {
+	username := quoteIdentifier(user.Username)
+	statements := []string{
+		fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED WITH sha256_hash BY %s;", username, quoteLiteral(hash)),
+		fmt.Sprintf("ALTER USER %s IDENTIFIED WITH sha256_hash BY %s;", username, quoteLiteral(hash)),
+		fmt.Sprintf("REVOKE ALL ON %s.* FROM %s;", quoteIdentifier(database), username),
+		fmt.Sprintf("GRANT %s ON %s.* TO %s;", strings.Join(permissionPrivileges[user.Permission], ", "), quoteIdentifier(database), username),
+	}
+	if user.Profile != "" {
+		statements = append(statements, fmt.Sprintf("ALTER USER %s SETTINGS PROFILE %s;", username, quoteLiteral(user.Profile)))
+	}
+	return strings.Join(statements, "\n")
+}
+
+func (r *ClickHouseDatabaseResource) provisionUser(ctx *pulumi.Context, name string, props *ClickHouseDatabaseProps, user *ClickHouseUserProps) error {
+	if err := props.validateUser(user); err != nil {
+		return err
+	}
+	dependsOn := []pulumi.Resource{r.DB}
+	if user.Profile != "" {
+		if r.Profiles[user.Profile] == nil {
+			return fmt.Errorf("profile %s failed", user.Profile)
+		}
+		dependsOn = append(dependsOn, r.Profiles[user.Profile])
+	}
+	resName := fmt.Sprintf("%s-%s", name, user.Username)
+	keepers := map[string]string{}
+	if user.RotationTrigger != "" {
+		keepers["rotationTrigger"] = user.RotationTrigger
+	}
+	password, err := utils.NewRotatingPassword(ctx, fmt.Sprintf("%s-password", resName), 16, keepers, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	statements := sha256Hex(password).ApplyT(func(hash string) string {
+		return userStatements(props.Database, user, hash)
+	}).(pulumi.StringOutput)
+	query, err := newQuery(ctx, resName, props.Connection,
+		pulumi.ToSecret(statements).(pulumi.StringOutput),
+		fmt.Sprintf("DROP USER IF EXISTS %s;", quoteIdentifier(user.Username)),
+		pulumi.Parent(r), pulumi.DependsOn(dependsOn))
+	if err != nil {
+		return err
+	}
+	r.Users[user.Username] = query
+	r.Passwords[user.Username] = pulumi.ToSecret(password).(pulumi.StringOutput)
+	return nil
+}
+
+// NewClickHouseDatabase creates the database, its settings profiles and its
+// users with random passwords and the rw or ro privileges on it.
+func NewClickHouseDatabase(ctx *pulumi.Context, name string, props ClickHouseDatabaseProps, opts ...pulumi.ResourceOption) (*ClickHouseDatabaseResource, error) {
+	if err := props.validate(); err != nil {
+		return nil, err
+	}
+	resource := &ClickHouseDatabaseResource{
+		Profiles:    map[string]*Query{},
+		Users:       map[string]*Query{},
+		Passwords:   map[string]pulumi.StringOutput{},
+		FailedUsers: map[string]error{},
+	}
+	if err := ctx.RegisterComponentResource("ss9:clickhouse:database", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	destroyProtected, err := utils.DestroyProtected(ctx)
+	if err != nil {
+		return resource, err
+	}
+	// the comment is only set when the database is created
+	create := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", quoteIdentifier(props.Database))
+	if props.Comment != "" {
+		create += " COMMENT " + quoteLiteral(props.Comment)
+	}
+	resource.DB, err = newQuery(ctx, fmt.Sprintf("%s-db", name), props.Connection,
+		pulumi.String(create+";"),
+		fmt.Sprintf("DROP DATABASE IF EXISTS %s;", quoteIdentifier(props.Database)),
+		pulumi.Parent(resource), pulumi.Protect(props.Protect || destroyProtected))
+	if err != nil {
+		return resource, err
+	}
+	// a failed profile or user doesn't hold back the others
+	errs := []error{}
+	for i := range props.Profiles {
+		profile := &props.Profiles[i]
+		if err := resource.provisionProfile(ctx, name, &props, profile); err != nil {
+			errs = append(errs, fmt.Errorf("profile %s: %w", profile.Name, err))
+		}
+	}
+	usernames := pulumi.StringArray{}
+	for i := range props.Users {
+		user := &props.Users[i]
+		if err := resource.provisionUser(ctx, name, &props, user); err != nil {
+			resource.FailedUsers[user.Username] = err
+			errs = append(errs, fmt.Errorf("user %s: %w", user.Username, err))
+			continue
+		}
+		usernames = append(usernames, pulumi.String(user.Username))
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"database":  pulumi.String(props.Database),
+		"usernames": usernames,
+	})
+	return resource, errors.Join(errs...)
+}
@@ -0,0 +1,59 @@
+package clickhouse
+
+import (
+	"testing"
+
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+)
+
+func TestUserStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		user ClickHouseUserProps
+		want string
+	}{
+		{
+			name: "ro",
+			user: ClickHouseUserProps{Username: "analyst", Permission: postgres.ReadOnly},
+			want: "CREATE USER IF NOT EXISTS `analyst` IDENTIFIED WITH sha256_hash BY 'ab12';\n" +
+				"ALTER USER `analyst` IDENTIFIED WITH sha256_hash BY 'ab12';\n" +
+				"REVOKE ALL ON `events`.* FROM `analyst`;\n" +
+				"GRANT SELECT ON `events`.* TO `analyst`;",
+		},
+		{
+			name: "rw with a profile",
+			user: ClickHouseUserProps{Username: "events-ingest", Permission: postgres.ReadWrite, Profile: "ingest"},
+			want: "CREATE USER IF NOT EXISTS `events-ingest` IDENTIFIED WITH sha256_hash BY 'ab12';\n" +
+				"ALTER USER `events-ingest` IDENTIFIED WITH sha256_hash BY 'ab12';\n" +
+				"REVOKE ALL ON `events`.* FROM `events-ingest`;\n" +
+				"GRANT SELECT, INSERT, ALTER, CREATE TABLE, CREATE VIEW, DROP TABLE, DROP VIEW, TRUNCATE, OPTIMIZE ON `events`.* TO `events-ingest`;\n" +
+				"ALTER USER `events-ingest` SETTINGS PROFILE 'ingest';",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := userStatements("events", &tt.user, "ab12"); got != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettingValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "10000000000", want: "10000000000"},
+		{value: "0.5", want: "0.5"},
+		{value: "random", want: "'random'"},
+		{value: "it's", want: `'it\'s'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := settingValue(tt.value); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+// ClickHouse has no Pulumi provider, the statements are run with
+// clickhouse-client in a command of the command provider, registered by type
+// token like the psql ones of the postgres components (`pulumi plugin install
+// resource command`).
+const commandType = "command:local:Command"
+
+// ClientTool is the CLI the queries run, to pass to utils.CheckPluginVersions
+// by the programs using them
+const ClientTool = "clickhouse-client"
+
+// the connection is read from the environment of the command, the password
+// from CLICKHOUSE_PASSWORD so it isn't in the arguments of the process
+const clientCommand = `clickhouse-client --host "$CLICKHOUSE_HOST" --port "$CLICKHOUSE_PORT" --user "$CLICKHOUSE_USER" $CLICKHOUSE_SECURE --multiquery`
+
+// the delete statements are passed in the environment, stdin is only given
+// to the create
+const clientDeleteCommand = clientCommand + ` --query "$CLICKHOUSE_DELETE_QUERY"`
+
+// ClientConnection is how clickhouse-client reaches the server. It runs where
+// `pulumi up` does, so clickhouse-client needs to be installed there.
+type ClientConnection struct {
+	Host pulumi.StringInput
+	// Native protocol port, 9440 over TLS and 9000 without
+	Port     int
+	Username pulumi.StringInput
+	Password pulumi.StringInput
+	// Native protocol over TLS (--secure), as ClickHouse Cloud
+	Secure bool
+}
+
+// Query runs statements on the server with clickhouse-client, again whenever
+// they change, and the statements dropping what they created on delete
+type Query struct {
+	pulumi.CustomResourceState
+
+	Stdout pulumi.StringOutput `pulumi:"stdout"`
+}
+
+// quoteIdentifier quotes a name checked by validateName
+func quoteIdentifier(name string) string {
+	return "`" + name + "`"
+}
+
+func quoteLiteral(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// newQuery runs the create statements once, and again as the update when they
+// change, so they need to be idempotent. The delete statements are run when
+// the resource is deleted, or replaced because they changed (a rename): the
+// old object is dropped before the new one is created.
+func newQuery(ctx *pulumi.Context, name string, conn *ClientConnection, create pulumi.StringInput, delete string, opts ...pulumi.ResourceOption) (*Query, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("the clickhouse-client connection is required")
+	}
+	opts, err := utils.PinPlugin(ctx, "command", opts)
+	if err != nil {
+		return nil, err
+	}
+	secure := ""
+	if conn.Secure {
+		secure = "--secure"
+	}
+	query := &Query{}
+	if err := ctx.RegisterResource(commandType, name, pulumi.Map{
+		"create": pulumi.String(clientCommand),
+		"update": pulumi.String(clientCommand),
+		"delete": pulumi.String(clientDeleteCommand),
+		"stdin":  create,
+		"environment": pulumi.StringMap{
+			"CLICKHOUSE_HOST":         conn.Host,
+			"CLICKHOUSE_PORT":         pulumi.Sprintf("%d", conn.Port),
+			"CLICKHOUSE_USER":         conn.Username,
+			"CLICKHOUSE_PASSWORD":     pulumi.ToSecret(conn.Password).(pulumi.StringOutput),
+			"CLICKHOUSE_SECURE":       pulumi.String(secure),
+			"CLICKHOUSE_DELETE_QUERY": pulumi.String(delete),
+		},
+	}, query, append(opts,
+		pulumi.ReplaceOnChanges([]string{"environment.CLICKHOUSE_DELETE_QUERY"}),
+		pulumi.DeleteBeforeReplace(true),
+	)...); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
@@ -0,0 +1,121 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"text/template"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/s3"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+)
+
+type HelmValuesProps struct {
+	Name string
+	// Go template of the values fragment, e.g.:
+	//	database:
+	//	  host: {{ .host }}
+	//	  password: {{ quote .password }}
+	Template string
+	// Provisioned creds and endpoints the template is rendered with
+	Values pulumi.StringMapInput
+	// Optional store to expose the rendered fragment
+	Store secret.SecretStore
+	// Optional S3 location to write the rendered fragment to
+	Bucket string
+	Key    string
+}
+
+type HelmValuesResource struct {
+	pulumi.ResourceState
+
+	Rendered pulumi.StringOutput
+	SecretId pulumi.StringOutput
+	Object   *s3.BucketObjectv2
+}
+
+var templateFuncs = template.FuncMap{
+	// double-quoted strings are valid YAML scalars
+	"quote": strconv.Quote,
+}
+
+func render(name string, tmpl string, values map[string]string) (string, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse helm values template: %w", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, values); err != nil {
+		return "", fmt.Errorf("failed to render helm values: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func (r *HelmValuesResource) provision(ctx *pulumi.Context, props *HelmValuesProps) error {
+	if props.Template == "" {
+		return fmt.Errorf("template is required to render helm values")
+	}
+	// fail early on a broken template, before any value is resolved
+	if _, err := template.New(props.Name).Funcs(templateFuncs).Parse(props.Template); err != nil {
+		return fmt.Errorf("failed to parse helm values template: %w", err)
+	}
+	if props.Bucket != "" && props.Key == "" {
+		props.Key = fmt.Sprintf("%s/values.yaml", props.Name)
+	}
+
+	r.Rendered = pulumi.ToSecret(props.Values.ToStringMapOutput().ApplyT(func(values map[string]string) (string, error) {
+		return render(props.Name, props.Template, values)
+	})).(pulumi.StringOutput)
+
+	if props.Store != nil {
+		secretId, err := props.Store.Store(ctx, props.Name, secret.HelmValues, pulumi.StringMap{
+			"values.yaml": r.Rendered,
+		}, pulumi.Parent(r))
+		if err != nil {
+			return fmt.Errorf("failed to store helm values: %w", err)
+		}
+		r.SecretId = secretId
+	}
+	if props.Bucket != "" {
+		obj, err := s3.NewBucketObjectv2(ctx, fmt.Sprintf("%s-helm-values", props.Name), &s3.BucketObjectv2Args{
+			Bucket:               pulumi.String(props.Bucket),
+			Key:                  pulumi.String(props.Key),
+			Content:              r.Rendered,
+			ContentType:          pulumi.String("application/yaml"),
+			ServerSideEncryption: pulumi.String("aws:kms"),
+			Tags: pulumi.StringMap{
+				"Pulumi": pulumi.String("true"),
+			},
+		}, pulumi.Parent(r))
+		if err != nil {
+			return fmt.Errorf("failed to write helm values to s3://%s/%s: %w", props.Bucket, props.Key, err)
+		}
+		r.Object = obj
+	}
+	return nil
+}
+
+// NewHelmValues renders a helm values fragment from the provisioned creds,
+// so the pipelines deploying the charts can pull it as is.
+func NewHelmValues(ctx *pulumi.Context, props HelmValuesProps, opts ...pulumi.ResourceOption) (*HelmValuesResource, error) {
+	resource := &HelmValuesResource{}
+	if err := ctx.RegisterComponentResource("ss9:helm:values", props.Name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, &props); err != nil {
+		return resource, err
+	}
+
+	outputs := pulumi.Map{
+		"rendered": resource.Rendered,
+	}
+	if props.Store != nil {
+		outputs["secretId"] = resource.SecretId
+	}
+	if resource.Object != nil {
+		outputs["s3Key"] = resource.Object.Key
+	}
+	ctx.RegisterResourceOutputs(resource, outputs)
+	return resource, nil
+}
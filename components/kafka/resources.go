@@ -0,0 +1,35 @@
+package kafka
+
+import (
+	kafkasdk "github.com/pulumi/pulumi-kafka/sdk/v3/go/kafka"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ProviderArgs struct {
+	BootstrapServers []string
+	// SASL/SCRAM admin of the cluster
+	Username pulumi.StringInput
+	Password pulumi.StringInput
+	// scram-sha512 if not set
+	SaslMechanism string
+	// Connects to the brokers over TLS, e.g. for MSK
+	Tls bool
+}
+
+// NewProvider configures the cluster the components provision, as
+// postgresql.NewProvider does for postgres. The plugin runs the version of
+// the pulumi-kafka SDK, checked against plugins:kafka by
+// utils.CheckPluginVersions.
+func NewProvider(ctx *pulumi.Context, name string, args ProviderArgs, opts ...pulumi.ResourceOption) (*kafkasdk.Provider, error) {
+	mechanism := args.SaslMechanism
+	if mechanism == "" {
+		mechanism = "scram-sha512"
+	}
+	return kafkasdk.NewProvider(ctx, name, &kafkasdk.ProviderArgs{
+		BootstrapServers: pulumi.ToStringArray(args.BootstrapServers),
+		SaslUsername:     args.Username.ToStringOutput(),
+		SaslPassword:     pulumi.ToSecret(args.Password).(pulumi.StringOutput),
+		SaslMechanism:    pulumi.String(mechanism),
+		TlsEnabled:       pulumi.Bool(args.Tls),
+	}, opts...)
+}
@@ -0,0 +1,306 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	kafkasdk "github.com/pulumi/pulumi-kafka/sdk/v3/go/kafka"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	defaultPartitions        = 3
+	defaultReplicationFactor = 3
+	scramMechanism           = "SCRAM-SHA-512"
+)
+
+var (
+	// kafka rejects the longer names, and the other characters
+	topicRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,249}$`)
+	// cleanup.policy values of the topics
+	cleanupPolicies = map[string]bool{"delete": true, "compact": true, "compact,delete": true}
+	// operations granted on the topics, as the rw/ro roles of the postgres
+	// components
+	permissionOperations = map[postgres.PostgresUserPermission][]string{
+		postgres.ReadWrite: {"Describe", "Read", "Write"},
+		postgres.ReadOnly:  {"Describe", "Read"},
+	}
+)
+
+type KafkaTopicProps struct {
+	Name string `json:"name"`
+	// 3 if not set
+	Partitions int `json:"partitions"`
+	// 3 if not set, it can't exceed the brokers of the cluster
+	ReplicationFactor int `json:"replicationFactor"`
+	// retention.ms in hours, the broker's default if not set, -1 keeps the
+	// messages forever
+	RetentionHours int `json:"retentionHours"`
+	// delete, compact or compact,delete, the broker's default if not set
+	CleanupPolicy string `json:"cleanupPolicy"`
+	// Any other config of the topic, e.g. max.message.bytes
+	Config map[string]string `json:"config"`
+}
+
+type KafkaServiceProps struct {
+	// SASL/SCRAM user of the service, its principal is User:<username>
+	Username string `json:"username"`
+	// rw (produces & consumes) or ro (consumes), rw if not set
+	Permission postgres.PostgresUserPermission `json:"permission"`
+	// Topics the service is granted the permission on, all the ones of the
+	// component if not set
+	Topics []string `json:"topics"`
+	// Prefix of the consumer groups of the service, its username if not set
+	ConsumerGroup string `json:"consumerGroup"`
+	// Bump it to rotate the generated password
+	RotationTrigger string `json:"rotationTrigger"`
+}
+
+type KafkaTopicsProps struct {
+	Topics   []KafkaTopicProps   `json:"topics"`
+	Services []KafkaServiceProps `json:"services"`
+	// Bootstrap servers (host:port,...) of the SASL listener, stored with the creds
+	BootstrapServers pulumi.StringInput `json:"-"`
+	// Store of the creds, AWS secrets if not set
+	Store secret.SecretStore `json:"-"`
+}
+
+type KafkaTopicsResource struct {
+	pulumi.ResourceState
+
+	// Indexed as the props
+	Topics []*kafkasdk.Topic
+	// SCRAM creds of the services, keyed by username
+	Users     map[string]*kafkasdk.UserScramCredential
+	Passwords map[string]pulumi.StringOutput
+	// Ids of the stored creds, keyed by username
+	SecretIds map[string]pulumi.StringOutput
+	// Errors of the failed services, keyed by username
+	FailedServices map[string]error
+}
+
+func (topic *KafkaTopicProps) validate() error {
+	if !topicRegex.MatchString(topic.Name) {
+		return fmt.Errorf("invalid topic name '%s', expected up to 249 letters, digits and . _ -", topic.Name)
+	}
+	if topic.Partitions == 0 {
+		topic.Partitions = defaultPartitions
+	}
+	if topic.ReplicationFactor == 0 {
+		topic.ReplicationFactor = defaultReplicationFactor
+	}
+	if topic.Partitions < 0 || topic.ReplicationFactor < 0 {
+		return fmt.Errorf("partitions and replication factor of topic %s need to be positive", topic.Name)
+	}
+	if topic.RetentionHours < -1 {
+		return fmt.Errorf("invalid retention of topic %s, expected hours or -1", topic.Name)
+	}
+	if topic.CleanupPolicy != "" && !cleanupPolicies[topic.CleanupPolicy] {
+		return fmt.Errorf("invalid cleanup policy '%s' of topic %s, expected delete, compact or compact,delete", topic.CleanupPolicy, topic.Name)
+	}
+	for _, key := range []string{"retention.ms", "cleanup.policy"} {
+		if _, ok := topic.Config[key]; ok {
+			return fmt.Errorf("%s of topic %s is set by retentionHours or cleanupPolicy", key, topic.Name)
+		}
+	}
+	return nil
+}
+
+// config is the config of the topic with the retention & cleanup policy
+func (topic *KafkaTopicProps) config() pulumi.StringMap {
+	config := pulumi.StringMap{}
+	for key, value := range topic.Config {
+		config[key] = pulumi.String(value)
+	}
+	if topic.RetentionHours == -1 {
+		config["retention.ms"] = pulumi.String("-1")
+	} else if topic.RetentionHours > 0 {
+		config["retention.ms"] = pulumi.Sprintf("%d", int64(topic.RetentionHours)*3600*1000)
+	}
+	if topic.CleanupPolicy != "" {
+		config["cleanup.policy"] = pulumi.String(topic.CleanupPolicy)
+	}
+	return config
+}
+
+func (props *KafkaTopicsProps) validate() error {
+	if len(props.Topics) == 0 {
+		return fmt.Errorf("at least one topic is required")
+	}
+	seen := map[string]bool{}
+	for i := range props.Topics {
+		topic := &props.Topics[i]
+		if err := topic.validate(); err != nil {
+			return err
+		}
+		if seen[topic.Name] {
+			return fmt.Errorf("duplicate topic %s", topic.Name)
+		}
+		seen[topic.Name] = true
+	}
+	if len(props.Services) > 0 && props.BootstrapServers == nil {
+		return fmt.Errorf("bootstrap servers are required to store the creds of the services")
+	}
+	if props.Store == nil {
+		props.Store = secret.AWSSecretStore{}
+	}
+	return nil
+}
+
+func (props *KafkaTopicsProps) validateService(service *KafkaServiceProps) error {
+	if service.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if strings.ContainsAny(service.Username, " ,=") {
+		return fmt.Errorf("invalid username '%s', spaces , and = aren't allowed", service.Username)
+	}
+	if service.Permission == "" {
+		service.Permission = postgres.ReadWrite
+	}
+	if _, ok := permissionOperations[service.Permission]; !ok {
+		return fmt.Errorf("permission '%s' isn't supported by kafka, expected %s or %s", service.Permission, postgres.ReadWrite, postgres.ReadOnly)
+	}
+	if len(service.Topics) == 0 {
+		for _, topic := range props.Topics {
+			service.Topics = append(service.Topics, topic.Name)
+		}
+	}
+	known := map[string]bool{}
+	for _, topic := range props.Topics {
+		known[topic.Name] = true
+	}
+	for _, topic := range service.Topics {
+		if !known[topic] {
+			return fmt.Errorf("topic %s isn't one of the component", topic)
+		}
+	}
+	if service.ConsumerGroup == "" {
+		service.ConsumerGroup = service.Username
+	}
+	return nil
+}
+
+// allow adds the ACL allowing the operation on the resource to the principal,
+// from any host
+func (r *KafkaTopicsResource) allow(ctx *pulumi.Context, name string, principal string, operation string, resourceType string, resourceName string, patternType string) error {
+	_, err := kafkasdk.NewAcl(ctx, fmt.Sprintf("%s-%s-%s", name, strings.ToLower(resourceType), operation), &kafkasdk.AclArgs{
+		AclPrincipal:              pulumi.String(principal),
+		AclHost:                   pulumi.String("*"),
+		AclOperation:              pulumi.String(operation),
+		AclPermissionType:         pulumi.String("Allow"),
+		AclResourceType:           pulumi.String(resourceType),
+		AclResourceName:           pulumi.String(resourceName),
+		ResourcePatternTypeFilter: pulumi.String(patternType),
+	}, pulumi.Parent(r))
+	return err
+}
+
+func (r *KafkaTopicsResource) provisionService(ctx *pulumi.Context, name string, props *KafkaTopicsProps, service *KafkaServiceProps) error {
+	if err := props.validateService(service); err != nil {
+		return err
+	}
+	resName := fmt.Sprintf("%s-%s", name, service.Username)
+	keepers := map[string]string{}
+	if service.RotationTrigger != "" {
+		keepers["rotationTrigger"] = service.RotationTrigger
+	}
+	password, err := utils.NewRotatingPassword(ctx, fmt.Sprintf("%s-password", resName), 16, keepers, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	// kafka-configs --alter --add-config 'SCRAM-SHA-512=[password=$PASSWORD]' --entity-type users --entity-name $USER
+	user, err := kafkasdk.NewUserScramCredential(ctx, resName, &kafkasdk.UserScramCredentialArgs{
+		Username:       pulumi.String(service.Username),
+		ScramMechanism: pulumi.String(scramMechanism),
+		Password:       pulumi.ToSecret(password).(pulumi.StringOutput),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	principal := fmt.Sprintf("User:%s", service.Username)
+	// kafka-acls --add --allow-principal User:$USER --operation Read --topic $TOPIC
+	for _, topic := range service.Topics {
+		for _, operation := range permissionOperations[service.Permission] {
+			if err := r.allow(ctx, fmt.Sprintf("%s-%s", resName, topic), principal, operation, "Topic", topic, "Literal"); err != nil {
+				return err
+			}
+		}
+	}
+	// kafka-acls --add --allow-principal User:$USER --operation Read --group $GROUP --resource-pattern-type prefixed
+	if err := r.allow(ctx, resName, principal, "Read", "Group", service.ConsumerGroup, "Prefixed"); err != nil {
+		return err
+	}
+	r.Users[service.Username] = user
+	r.Passwords[service.Username] = pulumi.ToSecret(password).(pulumi.StringOutput)
+
+	secretId, err := props.Store.Store(ctx, fmt.Sprintf("%s-user-%s", name, service.Username), secret.KafkaCreds, pulumi.StringMap{
+		"username":         pulumi.String(service.Username),
+		"password":         r.Passwords[service.Username],
+		"bootstrapServers": props.BootstrapServers,
+		"saslMechanism":    pulumi.String(scramMechanism),
+		"consumerGroup":    pulumi.String(service.ConsumerGroup),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return fmt.Errorf("failed to store the creds: %w", err)
+	}
+	r.SecretIds[service.Username] = secretId
+	return nil
+}
+
+// NewKafkaTopics creates the topics of a cluster, and the SASL/SCRAM users of
+// the services using them with random passwords and the ACLs of the rw or ro
+// permission. The creds of each service are stored as the KafkaCreds secret type.
+func NewKafkaTopics(ctx *pulumi.Context, name string, props KafkaTopicsProps, opts ...pulumi.ResourceOption) (*KafkaTopicsResource, error) {
+	if err := props.validate(); err != nil {
+		return nil, err
+	}
+	resource := &KafkaTopicsResource{
+		Users:          map[string]*kafkasdk.UserScramCredential{},
+		Passwords:      map[string]pulumi.StringOutput{},
+		SecretIds:      map[string]pulumi.StringOutput{},
+		FailedServices: map[string]error{},
+	}
+	if err := ctx.RegisterComponentResource("ss9:kafka:topics", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	for _, topicProps := range props.Topics {
+		// kafka-topics --create --topic $TOPIC --partitions 3 --replication-factor 3 --config ...
+		topic, err := kafkasdk.NewTopic(ctx, fmt.Sprintf("%s-%s", name, topicProps.Name), &kafkasdk.TopicArgs{
+			Name:              pulumi.String(topicProps.Name),
+			Partitions:        pulumi.Int(topicProps.Partitions),
+			ReplicationFactor: pulumi.Int(topicProps.ReplicationFactor),
+			Config:            topicProps.config(),
+		}, pulumi.Parent(resource))
+		if err != nil {
+			return resource, err
+		}
+		resource.Topics = append(resource.Topics, topic)
+	}
+	// a failed service doesn't hold back the others
+	errs := []error{}
+	usernames := pulumi.StringArray{}
+	for i := range props.Services {
+		service := &props.Services[i]
+		if err := resource.provisionService(ctx, name, &props, service); err != nil {
+			resource.FailedServices[service.Username] = err
+			errs = append(errs, fmt.Errorf("service %s: %w", service.Username, err))
+			continue
+		}
+		usernames = append(usernames, pulumi.String(service.Username))
+	}
+
+	topics := make([]string, len(props.Topics))
+	for i, topic := range props.Topics {
+		topics[i] = topic.Name
+	}
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"topics":    pulumi.ToStringArray(topics),
+		"usernames": usernames,
+	})
+	return resource, errors.Join(errs...)
+}
@@ -0,0 +1,22 @@
+package mongo
+
+import (
+	"github.com/pulumi/pulumi-mongodbatlas/sdk/v3/go/mongodbatlas"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ProviderArgs struct {
+	// Programmatic API key of the Atlas organization or project
+	PublicKey  pulumi.StringInput
+	PrivateKey pulumi.StringInput
+}
+
+// NewProvider configures the Atlas API the components provision with. The
+// plugin runs the version of the pulumi-mongodbatlas SDK, checked against
+// plugins:mongodbatlas by utils.CheckPluginVersions.
+func NewProvider(ctx *pulumi.Context, name string, args ProviderArgs, opts ...pulumi.ResourceOption) (*mongodbatlas.Provider, error) {
+	return mongodbatlas.NewProvider(ctx, name, &mongodbatlas.ProviderArgs{
+		PublicKey:  args.PublicKey.ToStringOutput(),
+		PrivateKey: pulumi.ToSecret(args.PrivateKey).(pulumi.StringOutput),
+	}, opts...)
+}
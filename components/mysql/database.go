@@ -0,0 +1,212 @@
+package mysql
+
+import (
+	"fmt"
+	"regexp"
+
+	mysqlsdk "github.com/pulumi/pulumi-mysql/sdk/v3/go/mysql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	// longer names are rejected by the server
+	maxDatabaseLength = 64
+
+	defaultCharacterSet = "utf8mb4"
+)
+
+var (
+	identifierRegex = regexp.MustCompile(`^[A-Za-z0-9_$-]+$`)
+	// privileges of the roles on all the tables of the database, on any
+	// flavor
+	permissionPrivileges = map[postgres.PostgresUserPermission][]string{
+		postgres.ReadWrite: {"SELECT", "INSERT", "UPDATE", "DELETE", "EXECUTE", "CREATE TEMPORARY TABLES", "LOCK TABLES"},
+		postgres.ReadOnly:  {"SELECT", "SHOW VIEW"},
+	}
+)
+
+// MySQLDbProps is the database & its roles, with the permission model of
+// the postgres component, so the programs can be backend-agnostic.
+type MySQLDbProps struct {
+	Database string `json:"database"`
+	// mysql or mariadb, mysql if not set
+	Flavor MySQLFlavor `json:"flavor"`
+	// Only rw & ro are supported (and existingRole), rw and ro if not set
+	DbRoles []postgres.PostgresDbRoleProps `json:"dbRoles"`
+	// utf8mb4 if not set
+	CharacterSet string `json:"characterSet"`
+	// Default collation of the character set if not set
+	Collation string `json:"collation"`
+	// Fails any delete of the database, e.g. in a destroy
+	Protect bool `json:"protect"`
+	// Keeps the database in the server when it's deleted from the stack
+	RetainOnDelete bool `json:"retainOnDelete"`
+}
+
+type MySQLDBResource struct {
+	pulumi.ResourceState
+
+	DB *mysqlsdk.Database
+	// Indexed as DbRoles, nil for the existing roles
+	Roles  []*mysqlsdk.Role
+	Grants []*mysqlsdk.Grant
+	// Names of the roles, known before they're created
+	RoleNames []string
+	// Resolves once the database, roles and all the grants are provisioned
+	Ready pulumi.BoolOutput
+}
+
+func validateName(kind string, name string, maxLength int) error {
+	if !identifierRegex.MatchString(name) {
+		return fmt.Errorf("invalid %s name '%s', only letters, digits and _ $ - are allowed", kind, name)
+	}
+	if len(name) > maxLength {
+		return fmt.Errorf("%s name '%s' is longer than %d characters", kind, name, maxLength)
+	}
+	return nil
+}
+
+// validateDbRole rejects the props of the postgres roles mysql has no equivalent of
+func validateDbRole(role *postgres.PostgresDbRoleProps) error {
+	if _, ok := permissionPrivileges[role.Permission]; !ok {
+		return fmt.Errorf("permission '%s' isn't supported by mysql, expected %s or %s", role.Permission, postgres.ReadWrite, postgres.ReadOnly)
+	}
+	if len(role.Schemas) > 0 || len(role.Tables) > 0 || len(role.FunctionSchemas) > 0 || len(role.Settings) > 0 {
+		return fmt.Errorf("role %s: schemas, tables, functionSchemas and settings aren't supported by mysql", role.Permission)
+	}
+	if role.ConnectionLimit != 0 || role.CreateDatabase || role.CreateRole || role.Superuser || role.Inherit != nil {
+		return fmt.Errorf("role %s: role attributes aren't supported by mysql", role.Permission)
+	}
+	return nil
+}
+
+func (props *MySQLDbProps) validate() (flavorSpec, error) {
+	spec, err := props.Flavor.spec()
+	if err != nil {
+		return spec, err
+	}
+	if err := validateName("database", props.Database, maxDatabaseLength); err != nil {
+		return spec, err
+	}
+	if len(props.DbRoles) == 0 {
+		props.DbRoles = []postgres.PostgresDbRoleProps{{Permission: postgres.ReadWrite}, {Permission: postgres.ReadOnly}}
+	}
+	seen := map[postgres.PostgresUserPermission]bool{}
+	for i := range props.DbRoles {
+		role := &props.DbRoles[i]
+		if err := validateDbRole(role); err != nil {
+			return spec, err
+		}
+		if seen[role.Permission] {
+			return spec, fmt.Errorf("duplicate %s role of database %s", role.Permission, props.Database)
+		}
+		seen[role.Permission] = true
+		if role.ExistingRole == "" {
+			if err := validateName("role", role.RoleName(props.Database), spec.maxRoleLength); err != nil {
+				return spec, err
+			}
+		}
+	}
+	if props.CharacterSet == "" {
+		props.CharacterSet = defaultCharacterSet
+	}
+	return spec, spec.validateCollation(props.Flavor, props.Collation)
+}
+
+func (r *MySQLDBResource) provision(ctx *pulumi.Context, namePrefix string, props *MySQLDbProps) error {
+	spec, err := props.validate()
+	if err != nil {
+		return err
+	}
+	destroyProtected, err := utils.DestroyProtected(ctx)
+	if err != nil {
+		return err
+	}
+	// CREATE DATABASE $DB CHARACTER SET $CHARSET COLLATE $COLLATION;
+	dbArgs := &mysqlsdk.DatabaseArgs{
+		Name:                pulumi.String(props.Database),
+		DefaultCharacterSet: pulumi.String(props.CharacterSet),
+	}
+	if props.Collation != "" {
+		dbArgs.DefaultCollation = pulumi.String(props.Collation)
+	}
+	dbOpts := []pulumi.ResourceOption{pulumi.Parent(r)}
+	if props.Protect || destroyProtected {
+		dbOpts = append(dbOpts, pulumi.Protect(true))
+	}
+	if props.RetainOnDelete {
+		dbOpts = append(dbOpts, pulumi.RetainOnDelete(true))
+	}
+	db, err := mysqlsdk.NewDatabase(ctx, fmt.Sprintf("%s-db", namePrefix), dbArgs, dbOpts...)
+	if err != nil {
+		return err
+	}
+	r.DB = db
+
+	resources := []pulumi.CustomResource{db}
+	r.Roles = make([]*mysqlsdk.Role, len(props.DbRoles))
+	for i, dbRole := range props.DbRoles {
+		roleName := dbRole.RoleName(props.Database)
+		var grantee pulumi.StringPtrInput = pulumi.String(roleName)
+		if dbRole.ExistingRole == "" {
+			// CREATE ROLE $ROLE;
+			role, err := mysqlsdk.NewRole(ctx, fmt.Sprintf("%s-%s", namePrefix, dbRole.Permission), &mysqlsdk.RoleArgs{
+				Name: pulumi.String(roleName),
+			}, pulumi.Parent(r))
+			if err != nil {
+				return err
+			}
+			r.Roles[i] = role
+			grantee = role.Name
+			resources = append(resources, role)
+		}
+		r.RoleNames = append(r.RoleNames, roleName)
+		// GRANT SELECT, INSERT, ... ON $DB.* TO $ROLE;
+		grant, err := mysqlsdk.NewGrant(ctx, fmt.Sprintf("%s-%s-grant", namePrefix, dbRole.Permission), &mysqlsdk.GrantArgs{
+			Role:       grantee,
+			Database:   db.Name,
+			Table:      pulumi.String("*"),
+			Privileges: pulumi.ToStringArray(spec.privileges(dbRole.Permission)),
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.Grants = append(r.Grants, grant)
+		resources = append(resources, grant)
+	}
+	r.Ready = readyAfter(resources...)
+	return nil
+}
+
+// NewMySQLDatabase creates the database, and its rw & ro roles granted on all
+// its tables. The roles need MySQL 8.0 or MariaDB 10.0.5+, the grants of a
+// role depend on the Flavor of the server.
+func NewMySQLDatabase(ctx *pulumi.Context, name string, props MySQLDbProps, opts ...pulumi.ResourceOption) (*MySQLDBResource, error) {
+	resource := &MySQLDBResource{}
+	if err := ctx.RegisterComponentResource("ss9:mysql:database", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"database": resource.DB.Name,
+		"roles":    pulumi.ToStringArray(resource.RoleNames),
+		"ready":    resource.Ready,
+	})
+	return resource, nil
+}
+
+// readyAfter resolves to true only after all the resources are created
+func readyAfter(resources ...pulumi.CustomResource) pulumi.BoolOutput {
+	ids := make([]interface{}, len(resources))
+	for i, res := range resources {
+		ids[i] = res.ID()
+	}
+	return pulumi.All(ids...).ApplyT(func(_ []interface{}) bool {
+		return true
+	}).(pulumi.BoolOutput)
+}
@@ -0,0 +1,89 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+)
+
+// MySQLFlavor is the server the components provision, MySQL or MariaDB (e.g.
+// RDS for MariaDB), their grants and authentication plugins differ.
+type MySQLFlavor string
+
+const (
+	MySQL   MySQLFlavor = "mysql"
+	MariaDB MySQLFlavor = "mariadb"
+
+	// RDS IAM authentication, the users log in with a token instead of a password
+	iamAuthPlugin = "AWSAuthenticationPlugin"
+)
+
+type flavorSpec struct {
+	// longest user & role names
+	maxUsernameLength int
+	maxRoleLength     int
+	// privileges the roles get besides the common ones
+	extraPrivileges map[postgres.PostgresUserPermission][]string
+	// plugins the users can authenticate with instead of a password
+	authPlugins []string
+	// prefix of the collations the servers of the flavor don't have
+	foreignCollations string
+}
+
+var flavorSpecs = map[MySQLFlavor]flavorSpec{
+	MySQL: {
+		// mysql.user.User is CHAR(32)
+		maxUsernameLength: 32,
+		maxRoleLength:     32,
+		authPlugins:       []string{iamAuthPlugin, "mysql_no_login"},
+		// the UCA 14.0.0 collations of MariaDB 10.10+
+		foreignCollations: "utf8mb4_uca1400_",
+	},
+	MariaDB: {
+		// mysql.user.User is CHAR(80) since MariaDB 10.0
+		maxUsernameLength: 80,
+		maxRoleLength:     80,
+		// the system-versioned tables need it to purge their history
+		extraPrivileges: map[postgres.PostgresUserPermission][]string{
+			postgres.ReadWrite: {"DELETE HISTORY"},
+		},
+		authPlugins: []string{iamAuthPlugin, "unix_socket"},
+		// the default collations of MySQL 8.0, missing in MariaDB
+		foreignCollations: "utf8mb4_0900_",
+	},
+}
+
+// spec of the flavor, mysql if not set
+func (flavor *MySQLFlavor) spec() (flavorSpec, error) {
+	if *flavor == "" {
+		*flavor = MySQL
+	}
+	spec, ok := flavorSpecs[*flavor]
+	if !ok {
+		return flavorSpec{}, fmt.Errorf("unknown flavor '%s', expected %s or %s", *flavor, MySQL, MariaDB)
+	}
+	return spec, nil
+}
+
+// privileges of the permission on the servers of the flavor
+func (spec flavorSpec) privileges(permission postgres.PostgresUserPermission) []string {
+	privileges := append([]string{}, permissionPrivileges[permission]...)
+	return append(privileges, spec.extraPrivileges[permission]...)
+}
+
+func (spec flavorSpec) validateCollation(flavor MySQLFlavor, collation string) error {
+	if strings.HasPrefix(collation, spec.foreignCollations) {
+		return fmt.Errorf("collation '%s' isn't supported by %s", collation, flavor)
+	}
+	return nil
+}
+
+func (spec flavorSpec) validateAuthPlugin(flavor MySQLFlavor, plugin string) error {
+	for _, supported := range spec.authPlugins {
+		if plugin == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("auth plugin '%s' isn't supported by %s, expected one of %s", plugin, flavor, strings.Join(spec.authPlugins, ", "))
+}
@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	mysqlsdk "github.com/pulumi/pulumi-mysql/sdk/v3/go/mysql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ProviderArgs struct {
+	// host:port of the server
+	Endpoint pulumi.StringInput
+	Username pulumi.StringInput
+	Password pulumi.StringInput
+	// Requires TLS to the server, e.g. for RDS with require_secure_transport
+	Tls bool
+}
+
+// NewProvider configures the server the components provision, as
+// postgresql.NewProvider does for postgres. The plugin runs the version of
+// the pulumi-mysql SDK, checked against plugins:mysql by
+// utils.CheckPluginVersions.
+func NewProvider(ctx *pulumi.Context, name string, args ProviderArgs, opts ...pulumi.ResourceOption) (*mysqlsdk.Provider, error) {
+	providerArgs := &mysqlsdk.ProviderArgs{
+		Endpoint: args.Endpoint,
+		Username: args.Username,
+		Password: args.Password.ToStringOutput(),
+	}
+	if args.Tls {
+		providerArgs.Tls = pulumi.String("true")
+	}
+	return mysqlsdk.NewProvider(ctx, name, providerArgs, opts...)
+}
@@ -0,0 +1,196 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	mysqlsdk "github.com/pulumi/pulumi-mysql/sdk/v3/go/mysql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+type MySQLUserProps struct {
+	Username string             `json:"username"`
+	Password pulumi.StringInput `json:"password"`
+	// Flavor of the server, as the one of the database, mysql if not set
+	Flavor MySQLFlavor `json:"flavor"`
+	// Plugin the user authenticates with instead of a password, e.g.
+	// AWSAuthenticationPlugin for the RDS IAM authentication
+	AuthPlugin string `json:"authPlugin"`
+	// rw or ro privileges on the database, rw if not set
+	Permission postgres.PostgresUserPermission `json:"permission"`
+	// Hosts the user can connect from: '%' (any, the default), a pattern
+	// like '10.0.%' or a CIDR. Each host is an account of its own in mysql.
+	Hosts []string `json:"hosts"`
+	// Bump it to rotate the generated password
+	RotationTrigger string `json:"rotationTrigger"`
+	// Fails any delete of the user, e.g. in a destroy
+	Protect bool `json:"protect"`
+}
+
+type MySQLUsersResource struct {
+	pulumi.ResourceState
+
+	// Accounts of the user, one per host, keyed by username
+	Accounts map[string][]*mysqlsdk.User
+	// Passwords keyed by username, shared by the accounts of the user. The
+	// users of an AuthPlugin have none.
+	Passwords map[string]pulumi.StringOutput
+	// Errors of the failed users, keyed by username
+	FailedUsers map[string]error
+	// Resolves once all the users are provisioned
+	Ready pulumi.BoolOutput
+}
+
+// accountHost is the host of the account, an IPv4 CIDR being written as
+// address/netmask which all the mysql versions understand
+func accountHost(host string) (string, error) {
+	if !strings.Contains(host, "/") {
+		return host, nil
+	}
+	ip, network, err := net.ParseCIDR(host)
+	if err != nil || ip.To4() == nil {
+		return "", fmt.Errorf("invalid host '%s', expected an IPv4 CIDR", host)
+	}
+	if !ip.Equal(network.IP) {
+		return "", fmt.Errorf("host '%s' isn't the address of its network, did you mean %s?", host, network)
+	}
+	return fmt.Sprintf("%s/%s", network.IP, net.IP(network.Mask)), nil
+}
+
+func (props *MySQLUserProps) validate() (flavorSpec, error) {
+	spec, err := props.Flavor.spec()
+	if err != nil {
+		return spec, err
+	}
+	if props.Username == "" || len(props.Username) > spec.maxUsernameLength {
+		return spec, fmt.Errorf("invalid username '%s', expected 1 to %d characters", props.Username, spec.maxUsernameLength)
+	}
+	if strings.ContainsAny(props.Username, "'`\"@") {
+		return spec, fmt.Errorf("invalid username '%s', quotes and @ aren't allowed", props.Username)
+	}
+	if props.Permission == "" {
+		props.Permission = postgres.ReadWrite
+	}
+	if _, ok := permissionPrivileges[props.Permission]; !ok {
+		return spec, fmt.Errorf("permission '%s' of user %s isn't supported by mysql, expected %s or %s", props.Permission, props.Username, postgres.ReadWrite, postgres.ReadOnly)
+	}
+	if len(props.Hosts) == 0 {
+		props.Hosts = []string{"%"}
+	}
+	if props.Password != nil && props.RotationTrigger != "" {
+		return spec, fmt.Errorf("password of user %s is set, it can't be rotated by the stack", props.Username)
+	}
+	if props.AuthPlugin == "" {
+		return spec, nil
+	}
+	if props.Password != nil || props.RotationTrigger != "" {
+		return spec, fmt.Errorf("user %s authenticates with %s, it has no password", props.Username, props.AuthPlugin)
+	}
+	return spec, spec.validateAuthPlugin(props.Flavor, props.AuthPlugin)
+}
+
+// authArgs sets how the user authenticates, with the password or the plugin
+func (props *MySQLUserProps) authArgs(args *mysqlsdk.UserArgs) {
+	if props.AuthPlugin != "" {
+		// CREATE USER ... IDENTIFIED WITH $PLUGIN, i.e. VIA in MariaDB
+		args.AuthPlugin = pulumi.String(props.AuthPlugin)
+		return
+	}
+	args.PlaintextPassword = pulumi.ToSecret(props.Password).(pulumi.StringOutput)
+}
+
+func (r *MySQLUsersResource) provision(ctx *pulumi.Context, database string, props *MySQLUserProps) error {
+	spec, err := props.validate()
+	if err != nil {
+		return err
+	}
+	if props.Password == nil && props.AuthPlugin == "" {
+		keepers := map[string]string{}
+		if props.RotationTrigger != "" {
+			keepers["rotationTrigger"] = props.RotationTrigger
+		}
+		password, err := utils.NewRotatingPassword(ctx, fmt.Sprintf("%s-%s-password", database, props.Username), 16, keepers, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		props.Password = password
+	}
+	accounts := []*mysqlsdk.User{}
+	for _, host := range props.Hosts {
+		mysqlHost, err := accountHost(host)
+		if err != nil {
+			return err
+		}
+		resName := fmt.Sprintf("%s-%s", database, props.Username)
+		if host != "%" {
+			resName = fmt.Sprintf("%s@%s", resName, host)
+		}
+		// CREATE USER '$USER'@'$HOST' IDENTIFIED BY '$PASSWORD';
+		userArgs := &mysqlsdk.UserArgs{
+			User: pulumi.String(props.Username),
+			Host: pulumi.String(mysqlHost),
+		}
+		props.authArgs(userArgs)
+		user, err := mysqlsdk.NewUser(ctx, resName, userArgs, pulumi.Parent(r), pulumi.Protect(props.Protect))
+		if err != nil {
+			return err
+		}
+		// the privileges are granted directly, the roles of the database are
+		// only active after SET ROLE unless they're default roles
+		// GRANT SELECT, INSERT, ... ON $DB.* TO '$USER'@'$HOST';
+		if _, err := mysqlsdk.NewGrant(ctx, fmt.Sprintf("%s-grant", resName), &mysqlsdk.GrantArgs{
+			User:       user.User,
+			Host:       user.Host,
+			Database:   pulumi.String(database),
+			Table:      pulumi.String("*"),
+			Privileges: pulumi.ToStringArray(spec.privileges(props.Permission)),
+		}, pulumi.Parent(r)); err != nil {
+			return err
+		}
+		accounts = append(accounts, user)
+	}
+	r.Accounts[props.Username] = accounts
+	if props.Password != nil {
+		r.Passwords[props.Username] = pulumi.ToSecret(props.Password).(pulumi.StringOutput)
+	}
+	return nil
+}
+
+// NewMySQLUsers creates the login users of the database, named as the
+// component, with the privileges of their permission.
+func NewMySQLUsers(ctx *pulumi.Context, name string, props []MySQLUserProps, opts ...pulumi.ResourceOption) (*MySQLUsersResource, error) {
+	resource := &MySQLUsersResource{
+		Accounts:    map[string][]*mysqlsdk.User{},
+		Passwords:   map[string]pulumi.StringOutput{},
+		FailedUsers: map[string]error{},
+	}
+	if err := ctx.RegisterComponentResource("ss9:mysql:users", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	// a failed user doesn't hold back the others
+	errs := []error{}
+	usernames := pulumi.StringArray{}
+	accounts := []pulumi.CustomResource{}
+	for _, prop := range props {
+		if err := resource.provision(ctx, name, &prop); err != nil {
+			resource.FailedUsers[prop.Username] = err
+			errs = append(errs, fmt.Errorf("user %s: %w", prop.Username, err))
+			continue
+		}
+		usernames = append(usernames, pulumi.String(prop.Username))
+		for _, user := range resource.Accounts[prop.Username] {
+			accounts = append(accounts, user)
+		}
+	}
+	resource.Ready = readyAfter(accounts...)
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"usernames": usernames,
+		"ready":     resource.Ready,
+	})
+	return resource, errors.Join(errs...)
+}
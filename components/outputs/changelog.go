@@ -0,0 +1,75 @@
+package outputs
+
+import "sort"
+
+type AccessChanges struct {
+	Roles  []string `json:"roles"`
+	Users  []string `json:"users"`
+	Grants []string `json:"grants"`
+}
+
+func (c AccessChanges) empty() bool {
+	return len(c.Roles) == 0 && len(c.Users) == 0 && len(c.Grants) == 0
+}
+
+// ToMap converts the changes to plain values, to be exported as output
+func (c AccessChanges) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"roles":  c.Roles,
+		"users":  c.Users,
+		"grants": c.Grants,
+	}
+}
+
+// AccessChangelog is the diff of the plans of two deployments
+type AccessChangelog struct {
+	Added   AccessChanges `json:"added"`
+	Removed AccessChanges `json:"removed"`
+	Changed bool          `json:"changed"`
+}
+
+// difference returns the values of a missing in b, sorted
+func difference(a []string, b []string) []string {
+	inB := map[string]bool{}
+	for _, v := range b {
+		inB[v] = true
+	}
+	res := []string{}
+	for _, v := range a {
+		if !inB[v] {
+			res = append(res, v)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// DiffPlans compares the roles, users and grants of the previous plan (empty
+// on the first deployment) with the current one.
+func DiffPlans(previous PlanOutput, current PlanOutput) AccessChangelog {
+	changelog := AccessChangelog{
+		Added: AccessChanges{
+			Roles:  difference(current.Roles, previous.Roles),
+			Users:  difference(current.Users, previous.Users),
+			Grants: difference(current.Grants, previous.Grants),
+		},
+		Removed: AccessChanges{
+			Roles:  difference(previous.Roles, current.Roles),
+			Users:  difference(previous.Users, current.Users),
+			Grants: difference(previous.Grants, current.Grants),
+		},
+	}
+	changelog.Changed = !changelog.Added.empty() || !changelog.Removed.empty()
+	return changelog
+}
+
+// ParsePlan parses the plan output of programs/db-postgres-creds, a missing
+// one (e.g. before the first deployment) gives an empty plan.
+func ParsePlan(value interface{}) (PlanOutput, error) {
+	plan := PlanOutput{}
+	if value == nil {
+		return plan, nil
+	}
+	err := decode("plan", value, &plan)
+	return plan, err
+}
@@ -0,0 +1,110 @@
+package outputs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffPlans(t *testing.T) {
+	none := AccessChanges{Roles: []string{}, Users: []string{}, Grants: []string{}}
+	tests := []struct {
+		name     string
+		previous PlanOutput
+		current  PlanOutput
+		want     AccessChangelog
+	}{
+		{
+			name:     "first deployment",
+			previous: PlanOutput{},
+			current:  PlanOutput{Roles: []string{"billing-rw"}, Users: []string{"tom", "ann"}, Grants: []string{"billing-connectDatabase"}},
+			want: AccessChangelog{
+				Added:   AccessChanges{Roles: []string{"billing-rw"}, Users: []string{"ann", "tom"}, Grants: []string{"billing-connectDatabase"}},
+				Removed: none,
+				Changed: true,
+			},
+		},
+		{
+			name:     "same plan",
+			previous: PlanOutput{Roles: []string{"billing-rw"}, Users: []string{"tom"}},
+			current:  PlanOutput{Roles: []string{"billing-rw"}, Users: []string{"tom"}},
+			want:     AccessChangelog{Added: none, Removed: none},
+		},
+		{
+			name:     "added and removed, sorted",
+			previous: PlanOutput{Users: []string{"tom", "zoe", "bob"}, Grants: []string{"g1"}},
+			current:  PlanOutput{Users: []string{"tom", "ann", "carl"}, Grants: []string{"g1"}},
+			want: AccessChangelog{
+				Added:   AccessChanges{Roles: []string{}, Users: []string{"ann", "carl"}, Grants: []string{}},
+				Removed: AccessChanges{Roles: []string{}, Users: []string{"bob", "zoe"}, Grants: []string{}},
+				Changed: true,
+			},
+		},
+		{
+			name:     "only the secrets changed",
+			previous: PlanOutput{Users: []string{"tom"}, Secrets: []string{"old"}},
+			current:  PlanOutput{Users: []string{"tom"}, Secrets: []string{"new"}},
+			want:     AccessChangelog{Added: none, Removed: none},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffPlans(tt.previous, tt.current)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    PlanOutput
+		wantErr string
+	}{
+		{name: "missing output", value: nil, want: PlanOutput{}},
+		{
+			name: "stack output",
+			value: map[string]interface{}{
+				"database":    "billing",
+				"roles":       []interface{}{"billing-rw"},
+				"users":       []interface{}{"tom"},
+				"grants":      []interface{}{"billing-connectDatabase"},
+				"secrets":     []interface{}{"db-creds-pg-billing-user-tom"},
+				"roleSecrets": map[string]interface{}{"tom": "db-creds-pg-billing-user-tom"},
+			},
+			want: PlanOutput{
+				Database:    "billing",
+				Roles:       []string{"billing-rw"},
+				Users:       []string{"tom"},
+				Grants:      []string{"billing-connectDatabase"},
+				Secrets:     []string{"db-creds-pg-billing-user-tom"},
+				RoleSecrets: map[string]string{"tom": "db-creds-pg-billing-user-tom"},
+			},
+		},
+		{
+			name:    "invalid output",
+			value:   map[string]interface{}{"users": "tom"},
+			wantErr: "failed to parse output 'plan'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePlan(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package outputs
+
+import "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+// DbMongoCredsOutputs are the outputs of programs/db-mongo-creds
+type DbMongoCredsOutputs struct {
+	// IDs of the secrets holding the creds & URI of the users, keyed by
+	// service then username
+	Secrets  map[string]map[string]string
+	Metadata StackMetadataOutput
+}
+
+func ParseDbMongoCredsOutputs(outputs map[string]interface{}) (*DbMongoCredsOutputs, error) {
+	res := &DbMongoCredsOutputs{
+		Secrets: map[string]map[string]string{},
+	}
+	metadata, err := parseMetadata(outputs)
+	if err != nil {
+		return nil, err
+	}
+	res.Metadata = metadata
+	if value, ok := outputs["secrets"]; ok {
+		if err := decode("secrets", value, &res.Secrets); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// DbMongoCredsOutputsFrom resolves to *DbMongoCredsOutputs of the referenced stack
+func DbMongoCredsOutputsFrom(ref *pulumi.StackReference) pulumi.AnyOutput {
+	return fromStackReference(ref, ParseDbMongoCredsOutputs)
+}
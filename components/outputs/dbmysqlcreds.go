@@ -0,0 +1,68 @@
+package outputs
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type MySQLCredsOutput struct {
+	Username string `json:"username"`
+	// Unset if the user connects with IAM tokens
+	Password string `json:"password"`
+	Database string `json:"database"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	// mysql or mariadb
+	Engine string `json:"engine"`
+	// iam if the user connects with IAM tokens, instead of the password
+	Authentication string `json:"authentication"`
+}
+
+// DbMySQLCredsOutputs are the outputs of programs/db-mysql-creds.
+// All the maps are keyed by username.
+type DbMySQLCredsOutputs struct {
+	Database string
+	// Roles of the database, e.g. billing-rw
+	Roles []string
+	// Creds of the users, only if they aren't exported as secret
+	Users map[string]MySQLCredsOutput
+	// Secret of each user, with exportAsSecret
+	Secrets  map[string]SecretRefOutput
+	Metadata StackMetadataOutput
+}
+
+func ParseDbMySQLCredsOutputs(outputs map[string]interface{}) (*DbMySQLCredsOutputs, error) {
+	res := &DbMySQLCredsOutputs{
+		Users:   map[string]MySQLCredsOutput{},
+		Secrets: map[string]SecretRefOutput{},
+	}
+	metadata, err := parseMetadata(outputs)
+	if err != nil {
+		return nil, err
+	}
+	res.Metadata = metadata
+	for key, value := range outputs {
+		switch {
+		case key == "database":
+			err = decode(key, value, &res.Database)
+		case key == "roles":
+			err = decode(key, value, &res.Roles)
+		case key == "users":
+			err = decode(key, value, &res.Users)
+		case strings.HasPrefix(key, "secret-"):
+			ref := SecretRefOutput{}
+			err = decode(key, value, &ref)
+			res.Secrets[strings.TrimPrefix(key, "secret-")] = ref
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// DbMySQLCredsOutputsFrom resolves to *DbMySQLCredsOutputs of the referenced stack
+func DbMySQLCredsOutputsFrom(ref *pulumi.StackReference) pulumi.AnyOutput {
+	return fromStackReference(ref, ParseDbMySQLCredsOutputs)
+}
@@ -0,0 +1,62 @@
+package outputs
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type RedisCredsOutput struct {
+	// ACL user (<name>-<username>), or default with the auth token
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	// Always true, ElastiCache needs the in-transit encryption for the auth
+	Tls string `json:"tls"`
+}
+
+// DbRedisCredsOutputs are the outputs of programs/db-redis-creds.
+// All the maps are keyed by the username of the service.
+type DbRedisCredsOutputs struct {
+	// Endpoint of the replication group
+	Host string
+	// Creds of the services, only if they aren't exported as secret
+	Users map[string]RedisCredsOutput
+	// Secret of each service, with exportAsSecret
+	Secrets  map[string]SecretRefOutput
+	Metadata StackMetadataOutput
+}
+
+func ParseDbRedisCredsOutputs(outputs map[string]interface{}) (*DbRedisCredsOutputs, error) {
+	res := &DbRedisCredsOutputs{
+		Users:   map[string]RedisCredsOutput{},
+		Secrets: map[string]SecretRefOutput{},
+	}
+	metadata, err := parseMetadata(outputs)
+	if err != nil {
+		return nil, err
+	}
+	res.Metadata = metadata
+	for key, value := range outputs {
+		switch {
+		case key == "host":
+			err = decode(key, value, &res.Host)
+		case key == "users":
+			err = decode(key, value, &res.Users)
+		case strings.HasPrefix(key, "secret-"):
+			ref := SecretRefOutput{}
+			err = decode(key, value, &ref)
+			res.Secrets[strings.TrimPrefix(key, "secret-")] = ref
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// DbRedisCredsOutputsFrom resolves to *DbRedisCredsOutputs of the referenced stack
+func DbRedisCredsOutputsFrom(ref *pulumi.StackReference) pulumi.AnyOutput {
+	return fromStackReference(ref, ParseDbRedisCredsOutputs)
+}
@@ -0,0 +1,32 @@
+package outputs
+
+import "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+// DbSuperuserSecretOutputs are the outputs of programs/db-superuser-secret
+type DbSuperuserSecretOutputs struct {
+	Secret struct {
+		SecretId string `json:"secretId"`
+		KeyArn   string `json:"keyArn"`
+	}
+	Metadata StackMetadataOutput
+}
+
+func ParseDbSuperuserSecretOutputs(outputs map[string]interface{}) (*DbSuperuserSecretOutputs, error) {
+	res := &DbSuperuserSecretOutputs{}
+	metadata, err := parseMetadata(outputs)
+	if err != nil {
+		return nil, err
+	}
+	res.Metadata = metadata
+	if value, ok := outputs["secret"]; ok {
+		if err := decode("secret", value, &res.Secret); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// DbSuperuserSecretOutputsFrom resolves to *DbSuperuserSecretOutputs of the referenced stack
+func DbSuperuserSecretOutputsFrom(ref *pulumi.StackReference) pulumi.AnyOutput {
+	return fromStackReference(ref, ParseDbSuperuserSecretOutputs)
+}
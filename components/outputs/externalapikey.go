@@ -0,0 +1,48 @@
+package outputs
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// ExternalApiKeyOutputs are the outputs of programs/external-api-key.
+// All the maps are keyed by the name of the key.
+type ExternalApiKeyOutputs struct {
+	Secrets   map[string]SecretRefOutput
+	Manifests map[string]string
+	Metadata  StackMetadataOutput
+}
+
+func ParseExternalApiKeyOutputs(outputs map[string]interface{}) (*ExternalApiKeyOutputs, error) {
+	res := &ExternalApiKeyOutputs{
+		Secrets:   map[string]SecretRefOutput{},
+		Manifests: map[string]string{},
+	}
+	metadata, err := parseMetadata(outputs)
+	if err != nil {
+		return nil, err
+	}
+	res.Metadata = metadata
+	for key, value := range outputs {
+		switch {
+		case strings.HasPrefix(key, "secret-"):
+			ref := SecretRefOutput{}
+			err = decode(key, value, &ref)
+			res.Secrets[strings.TrimPrefix(key, "secret-")] = ref
+		case strings.HasPrefix(key, "manifest-"):
+			var manifest string
+			err = decode(key, value, &manifest)
+			res.Manifests[strings.TrimPrefix(key, "manifest-")] = manifest
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// ExternalApiKeyOutputsFrom resolves to *ExternalApiKeyOutputs of the referenced stack
+func ExternalApiKeyOutputsFrom(ref *pulumi.StackReference) pulumi.AnyOutput {
+	return fromStackReference(ref, ParseExternalApiKeyOutputs)
+}
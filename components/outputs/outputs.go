@@ -0,0 +1,56 @@
+// Package outputs defines the typed outputs of the programs in this repo, so
+// downstream Go stacks can consume them with compile-time checked access,
+// instead of indexing the raw outputs of a StackReference.
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type SecretRefOutput struct {
+	SecretId string `json:"secretId"`
+}
+
+// StackMetadataOutput is the envelope exported by every program under `metadata`
+type StackMetadataOutput struct {
+	Version           string            `json:"version"`
+	Project           string            `json:"project"`
+	Stack             string            `json:"stack"`
+	Service           string            `json:"service"`
+	Owner             string            `json:"owner"`
+	Databases         []string          `json:"databases"`
+	ComponentVersions map[string]string `json:"componentVersions"`
+}
+
+// decode converts a raw output value into its typed struct
+func decode(key string, value interface{}, target interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal output '%s': %w", key, err)
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return fmt.Errorf("failed to parse output '%s': %w", key, err)
+	}
+	return nil
+}
+
+func parseMetadata(outputs map[string]interface{}) (StackMetadataOutput, error) {
+	metadata := StackMetadataOutput{}
+	value, ok := outputs["metadata"]
+	if !ok {
+		return metadata, nil
+	}
+	err := decode("metadata", value, &metadata)
+	return metadata, err
+}
+
+// fromStackReference parses the outputs of the referenced stack once they
+// resolve. The result stays secret if any of the outputs is.
+func fromStackReference[T any](ref *pulumi.StackReference, parse func(map[string]interface{}) (*T, error)) pulumi.AnyOutput {
+	return ref.Outputs.ApplyT(func(outputs map[string]interface{}) (*T, error) {
+		return parse(outputs)
+	}).(pulumi.AnyOutput)
+}
@@ -0,0 +1,52 @@
+package outputs
+
+import "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+type SmtpCredsOutput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	IamUser  string `json:"iamUser"`
+}
+
+// SesSmtpCredsOutputs are the outputs of programs/ses-smtp-creds
+type SesSmtpCredsOutputs struct {
+	// Creds, only if they aren't exported as secret
+	Smtp     *SmtpCredsOutput
+	Secret   *SecretRefOutput
+	Manifest string
+	Metadata StackMetadataOutput
+}
+
+func ParseSesSmtpCredsOutputs(outputs map[string]interface{}) (*SesSmtpCredsOutputs, error) {
+	res := &SesSmtpCredsOutputs{}
+	metadata, err := parseMetadata(outputs)
+	if err != nil {
+		return nil, err
+	}
+	res.Metadata = metadata
+	if value, ok := outputs["smtp"]; ok {
+		res.Smtp = &SmtpCredsOutput{}
+		if err := decode("smtp", value, res.Smtp); err != nil {
+			return nil, err
+		}
+	}
+	if value, ok := outputs["secret"]; ok {
+		res.Secret = &SecretRefOutput{}
+		if err := decode("secret", value, res.Secret); err != nil {
+			return nil, err
+		}
+	}
+	if value, ok := outputs["manifest"]; ok {
+		if err := decode("manifest", value, &res.Manifest); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// SesSmtpCredsOutputsFrom resolves to *SesSmtpCredsOutputs of the referenced stack
+func SesSmtpCredsOutputsFrom(ref *pulumi.StackReference) pulumi.AnyOutput {
+	return fromStackReference(ref, ParseSesSmtpCredsOutputs)
+}
@@ -0,0 +1,21 @@
+package postgres
+
+import "strings"
+
+// bootstrapSQL renders the statements run once the database is provisioned,
+// e.g. seeding reference tables or creating custom types. The postgresql
+// provider can't run arbitrary SQL, so psql runs them in a SQLCommand.
+func bootstrapSQL(statements []string) string {
+	lines := []string{}
+	for _, statement := range statements {
+		statement = strings.TrimSpace(statement)
+		if statement == "" {
+			continue
+		}
+		if !strings.HasSuffix(statement, ";") {
+			statement += ";"
+		}
+		lines = append(lines, statement)
+	}
+	return strings.Join(lines, "\n")
+}
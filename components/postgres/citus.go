@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type CitusWorker struct {
+	Name string
+	// Provider connected to the worker node, with the same superuser
+	Provider pulumi.ProviderResource
+}
+
+// PostgresCitusProps distributes a database of the coordinator (e.g. created
+// by NewPostgresDatabase with the coordinator provider) over the workers.
+type PostgresCitusProps struct {
+	Database string
+	Workers  []CitusWorker
+	// Set for Citus 12.1+ with citus.enable_create_database_propagation,
+	// which creates the database on the workers already
+	SkipWorkerDatabases bool
+	// Distribution column of the distributed tables, keyed by table
+	DistributedTables map[string]string
+	// Tables replicated on every worker, e.g. small lookup tables
+	ReferenceTables []string
+	// Where psql distributes the tables on the coordinator, required with
+	// them. The tables need to exist already, e.g. created by the bootstrap
+	// SQL of the database.
+	Connection *SQLConnection
+}
+
+type PostgresCitusResource struct {
+	pulumi.ResourceState
+
+	Extension        *postgresql.Extension
+	WorkerDatabases  []*postgresql.Database
+	WorkerExtensions []*postgresql.Extension
+	// SQL distributing the tables, and the command running it if there are
+	// tables. The provider can't run arbitrary SQL, psql does.
+	TablesSQL string
+	Tables    *SQLCommand
+}
+
+// quoteLiteral quotes the value as SQL string literal
+func quoteLiteral(value string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(value, "'", "''"))
+}
+
+// unlessDistributed skips the call on the tables citus has already
+// distributed, which it refuses to distribute again
+func unlessDistributed(call string, table string) string {
+	return fmt.Sprintf("SELECT %s WHERE NOT EXISTS (SELECT 1 FROM pg_dist_partition WHERE logicalrelid = %s::regclass);", call, quoteLiteral(table))
+}
+
+// DistributedTableSQL shards the table over the workers by the column
+func DistributedTableSQL(table string, column string) string {
+	return unlessDistributed(fmt.Sprintf("create_distributed_table(%s, %s)", quoteLiteral(table), quoteLiteral(column)), table)
+}
+
+// ReferenceTableSQL replicates the table on every worker
+func ReferenceTableSQL(table string) string {
+	return unlessDistributed(fmt.Sprintf("create_reference_table(%s)", quoteLiteral(table)), table)
+}
+
+func citusTablesSQL(props *PostgresCitusProps) string {
+	// reference tables first, since the distributed ones may refer to them
+	statements := []string{}
+	for _, table := range props.ReferenceTables {
+		statements = append(statements, ReferenceTableSQL(table))
+	}
+	tables := make([]string, 0, len(props.DistributedTables))
+	for table := range props.DistributedTables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		statements = append(statements, DistributedTableSQL(table, props.DistributedTables[table]))
+	}
+	return strings.Join(statements, "\n")
+}
+
+func (r *PostgresCitusResource) provision(ctx *pulumi.Context, name string, props *PostgresCitusProps) error {
+	if props.Database == "" {
+		return fmt.Errorf("database is required to distribute it with citus")
+	}
+	if len(props.Workers) == 0 {
+		return fmt.Errorf("at least one worker is required for database %s", props.Database)
+	}
+	for table, column := range props.DistributedTables {
+		if column == "" {
+			return fmt.Errorf("distribution column is required for table %s", table)
+		}
+	}
+	if (len(props.DistributedTables) > 0 || len(props.ReferenceTables) > 0) && props.Connection == nil {
+		return fmt.Errorf("connection is required to distribute the tables of database %s", props.Database)
+	}
+
+	// CREATE EXTENSION citus; on the coordinator
+	ext, err := postgresql.NewExtension(ctx, fmt.Sprintf("%s-citus", name), &postgresql.ExtensionArgs{
+		Database: pulumi.String(props.Database),
+		Name:     pulumi.String("citus"),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Extension = ext
+
+	for _, worker := range props.Workers {
+		if worker.Name == "" || worker.Provider == nil {
+			return fmt.Errorf("name and provider are required for the workers of database %s", props.Database)
+		}
+		workerOpts := []pulumi.ResourceOption{pulumi.Parent(r), pulumi.Provider(worker.Provider)}
+		database := pulumi.String(props.Database).ToStringOutput()
+		if !props.SkipWorkerDatabases {
+			// CREATE DATABASE $DB; on the worker, the roles are synced by
+			// citus once the worker is added to the coordinator
+			db, err := postgresql.NewDatabase(ctx, fmt.Sprintf("%s-%s-db", name, worker.Name), &postgresql.DatabaseArgs{
+				Name: pulumi.String(props.Database),
+			}, workerOpts...)
+			if err != nil {
+				return err
+			}
+			r.WorkerDatabases = append(r.WorkerDatabases, db)
+			database = db.Name
+		}
+		// CREATE EXTENSION citus; on the worker
+		workerExt, err := postgresql.NewExtension(ctx, fmt.Sprintf("%s-%s-citus", name, worker.Name), &postgresql.ExtensionArgs{
+			Database: database,
+			Name:     pulumi.String("citus"),
+		}, workerOpts...)
+		if err != nil {
+			return err
+		}
+		r.WorkerExtensions = append(r.WorkerExtensions, workerExt)
+	}
+	r.TablesSQL = citusTablesSQL(props)
+	if r.TablesSQL == "" {
+		return nil
+	}
+	// the shards are placed on the workers with citus installed
+	dependsOn := []pulumi.Resource{ext}
+	for _, workerExt := range r.WorkerExtensions {
+		dependsOn = append(dependsOn, workerExt)
+	}
+	// create-only, the tables stay distributed (undistribute_table copies them
+	// back to the coordinator, which is left to the operator)
+	r.Tables, err = NewSQLCommand(ctx, fmt.Sprintf("%s-citus-tables", name), props.Connection, pulumi.String(props.Database), pulumi.String(r.TablesSQL), "",
+		pulumi.Parent(r), pulumi.DependsOn(dependsOn))
+	return err
+}
+
+// NewPostgresCitus makes a database of a Citus cluster usable: the citus
+// extension is created in it on the coordinator (the provider of the
+// component) and on the workers, and the tables are distributed. Adding the
+// workers to the coordinator (citus_add_node) is left to the cluster setup.
+func NewPostgresCitus(ctx *pulumi.Context, name string, props PostgresCitusProps, opts ...pulumi.ResourceOption) (*PostgresCitusResource, error) {
+	resource := &PostgresCitusResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:citus", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"extension": resource.Extension.Name,
+		"workers":   pulumi.Int(len(props.Workers)),
+		"tablesSql": pulumi.String(resource.TablesSQL),
+	})
+	return resource, nil
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// catalogs of the commented objects, by object type
+var commentCatalogs = map[string]string{
+	"ROLE":     "pg_roles WHERE rolname",
+	"DATABASE": "pg_database WHERE datname",
+}
+
+// commentSQL renders COMMENT ON $TYPE $NAME IS '$COMMENT'; the postgresql
+// provider can't manage comments, so psql runs it in a SQLCommand.
+func commentSQL(objectType string, name string, comment string) string {
+	return fmt.Sprintf("COMMENT ON %s %s IS %s;", objectType, quoteIdentifier(name), quoteLiteral(comment))
+}
+
+// uncommentSQL removes the COMMENT when the command is deleted, if the object
+// still exists: the role linked to its secret may be dropped first.
+func uncommentSQL(objectType string, name string) string {
+	return fmt.Sprintf("DO $$ BEGIN IF EXISTS (SELECT FROM %s = %s) THEN COMMENT ON %s %s IS NULL; END IF; END $$;",
+		commentCatalogs[objectType], quoteLiteral(name), objectType, quoteIdentifier(name))
+}
+
+// NewRoleComment sets the COMMENT of the role with psql once the comment
+// resolves, e.g. to the ARN of the secret of its creds
+func NewRoleComment(ctx *pulumi.Context, name string, conn *SQLConnection, role string, comment pulumi.StringInput, opts ...pulumi.ResourceOption) (*SQLCommand, error) {
+	sql := comment.ToStringOutput().ApplyT(func(comment string) string {
+		return commentSQL("ROLE", role, comment)
+	}).(pulumi.StringOutput)
+	// the roles belong to the server, any database does
+	return NewSQLCommand(ctx, name, conn, pulumi.String("postgres"), sql, uncommentSQL("ROLE", role), opts...)
+}
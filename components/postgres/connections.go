@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConnectionBudget sums up the connection limits of the users. The users
+// without any limit can open up to max_connections, so they're returned
+// separately.
+func ConnectionBudget(users []PostgresUserProps) (total int, unbounded []string) {
+	for _, user := range users {
+		if user.ConnectionLimit > 0 {
+			total += user.ConnectionLimit
+		} else {
+			unbounded = append(unbounded, user.Username)
+		}
+	}
+	return
+}
+
+// CheckConnectionBudget fails if the users together can open more connections
+// than the server allows, excluding the ones reserved for superusers.
+func CheckConnectionBudget(users []PostgresUserProps, maxConnections int, reserved int) error {
+	available := maxConnections - reserved
+	total, unbounded := ConnectionBudget(users)
+	if len(unbounded) > 0 {
+		return fmt.Errorf("users %s have no connection limit, they can use all %d connections", strings.Join(unbounded, ", "), available)
+	}
+	if total > available {
+		return fmt.Errorf("users can open %d connections, but only %d are available (max_connections %d, reserved %d)", total, available, maxConnections, reserved)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
 )
 
 type PostgresUserPermission string
@@ -13,15 +14,110 @@ type PostgresUserPermission string
 const (
 	ReadWrite PostgresUserPermission = "rw"
 	ReadOnly  PostgresUserPermission = "ro"
+	// Migration role, it can change the schema but the read-write role owns the data
+	DDL PostgresUserPermission = "ddl"
+	// Role owning the database and its objects, to run the migrations as.
+	// The read-write role then only reads & writes the data, it can't DROP it.
+	Owner PostgresUserPermission = "owner"
+	// Role with the privileges set in PostgresDbRoleProps.Privileges
+	Custom PostgresUserPermission = "custom"
 )
 
 type PostgresDbRoleProps struct {
 	Permission PostgresUserPermission `json:"permission"`
+	// Name of the custom role, it's suffixed to the DB name like the permission
+	Name string `json:"name"`
+	// Privileges of the custom role
+	Privileges PostgresPrivilegesProps `json:"privileges"`
+	// Opts out of the default privileges of the read-only role, i.e. the
+	// objects created later by the owner won't be readable by it.
+	SkipDefaultPrivileges bool `json:"skipDefaultPrivileges"`
+	// Restricts the read-only role to these tables ("table" in public or
+	// "schema.table"), instead of all the tables in the schemas. The
+	// sequences and the tables created later aren't granted then.
+	Tables []string `json:"tables"`
+	// Schemas in which the read-only or custom role can execute all the
+	// functions & procedures
+	FunctionSchemas []string `json:"functionSchemas"`
+	// Max concurrent connections of the role, unlimited if not set
+	ConnectionLimit int  `json:"connectionLimit"`
+	CreateDatabase  bool `json:"createDatabase"`
+	CreateRole      bool `json:"createRole"`
+	Superuser       bool `json:"superuser"`
+	// Whether the privileges of the granted roles are inherited, true if not set
+	Inherit *bool `json:"inherit"`
+	// Role-level parameters: search_path, statement_timeout & idle_in_transaction_session_timeout
+	Settings map[string]string `json:"settings"`
+	// Schemas the read-only, ddl or custom role is granted access on, i.e.
+	// the per-service schemas. All the schemas of the database if not set.
+	Schemas []string `json:"schemas"`
+	// Role created outside of the stack (e.g. by an auth system) which gets
+	// the grants of the permission, instead of creating <db>-<permission>.
+	// It's only read, never altered nor dropped.
+	ExistingRole string `json:"existingRole"`
+}
+
+func (props *PostgresDbRoleProps) attributes() roleAttributes {
+	return roleAttributes{
+		connectionLimit: props.ConnectionLimit,
+		createDatabase:  props.CreateDatabase,
+		createRole:      props.CreateRole,
+		superuser:       props.Superuser,
+		inherit:         props.Inherit,
+		settings:        props.Settings,
+	}
 }
 
 type PostgresDbProps struct {
 	Database string                `json:"database"`
 	DbRoles  []PostgresDbRoleProps `json:"dbRoles"`
+	// Default tablespace of the database, one of Tablespaces or an existing one
+	Tablespace string `json:"tablespace"`
+	// Tablespaces created with psql before the database, and assigned to roles
+	Tablespaces []PostgresTablespaceProps `json:"tablespaces"`
+	// Schemas created besides public, the DB roles get access on all of them
+	// unless narrowed by the schemas of the role
+	Schemas []PostgresSchemaProps `json:"schemas"`
+	// Existing role (e.g. an admin) owning the database, instead of the role
+	// owning the schemas, i.e. the rw or owner role
+	DatabaseOwner string `json:"databaseOwner"`
+	// Extensions created in the database
+	Extensions []PostgresExtensionProps `json:"extensions"`
+	// Marks the database as template (datistemplate), to be cloned by NewDatabaseFromTemplate
+	TemplateDatabase bool `json:"templateDatabase"`
+	// e.g. UTF8, the server default if not set
+	Encoding  string `json:"encoding"`
+	LcCollate string `json:"lcCollate"`
+	LcCtype   string `json:"lcCtype"`
+	// Database it's created from, template1 if not set. Use template0 for an
+	// encoding or locale different from template1.
+	Template string `json:"template"`
+	// Max concurrent connections to the database, unlimited if not set
+	ConnectionLimit int `json:"connectionLimit"`
+	// Adopts the database and the DB roles created by hand, instead of
+	// creating them. Their settings need to match the props, else the import fails.
+	ImportExisting bool `json:"importExisting"`
+	// Fails any delete of the database and the DB roles, e.g. in a destroy
+	Protect bool `json:"protect"`
+	// Keeps the database and the DB roles in the server when they're deleted from the stack
+	RetainOnDelete bool `json:"retainOnDelete"`
+	// Creates the auth_query function and the lookup role of pgbouncer
+	PgBouncerAuth *PgBouncerAuthProps `json:"pgbouncerAuth"`
+	// Where the clients connect to, the DSNs of the login roles are exposed if set
+	Endpoint *ConnectionEndpoint `json:"-"`
+	// Ownership or team metadata, shown by \l+. The provider can't set it,
+	// psql does through Connection.
+	Comment string `json:"comment"`
+	// Revokes CREATE on schema public and CONNECT on the database from
+	// PUBLIC, so only the roles granted CONNECT can log into the database
+	HardenPublicSchema bool `json:"hardenPublicSchema"`
+	// Statements run with psql once the database is provisioned, e.g. seeding
+	// the reference tables. They're run again whenever they change, so they
+	// need to be idempotent.
+	BootstrapSQL []string `json:"bootstrapSql"`
+	// Where psql sets Comment, runs BootstrapSQL and creates Tablespaces,
+	// required with them
+	Connection *SQLConnection `json:"-"`
 }
 
 func (i PostgresDbProps) String() string {
@@ -33,7 +129,19 @@ func (i PostgresDbProps) String() string {
 }
 
 func (props *PostgresDbRoleProps) fillRuntimeInputs(ctx *pulumi.Context, res *PostgresDBResource) (err error) {
-	if props.Permission != ReadOnly && props.Permission != ReadWrite {
+	switch props.Permission {
+	case ReadOnly, ReadWrite, DDL, Owner:
+		if props.Name != "" {
+			return fmt.Errorf("name can only be set for custom roles")
+		}
+	case Custom:
+		if props.Name == "" {
+			return fmt.Errorf("name is required for custom roles")
+		}
+		if props.Name == string(ReadOnly) || props.Name == string(ReadWrite) || props.Name == string(DDL) || props.Name == string(Owner) {
+			return fmt.Errorf("custom role can't be named '%s'", props.Name)
+		}
+	default:
 		return fmt.Errorf("invalid permission %s", props.Permission)
 	}
 	return
@@ -43,30 +151,109 @@ func (props *PostgresDbProps) fillRuntimeInputs(ctx *pulumi.Context, res *Postgr
 	if len(props.DbRoles) == 0 {
 		props.DbRoles = []PostgresDbRoleProps{{Permission: ReadWrite}}
 	}
-	if len(props.DbRoles) > 2 {
-		return fmt.Errorf("only 2 roles are supported, one read-write and other read-only")
-	}
 	for i := range props.DbRoles {
 		if err := props.DbRoles[i].fillRuntimeInputs(ctx, res); err != nil {
 			return err
 		}
 	}
-	return nil
+	if err := props.validateRoles(); err != nil {
+		return err
+	}
+	if err := props.validateSchemas(); err != nil {
+		return err
+	}
+	if err := props.validateRoleTables(); err != nil {
+		return err
+	}
+	if err := props.validateRoleSchemas(); err != nil {
+		return err
+	}
+	if err := props.validateFunctionSchemas(); err != nil {
+		return err
+	}
+	if (len(props.BootstrapSQL) > 0 || props.Comment != "") && props.Connection == nil {
+		return fmt.Errorf("connection is required to comment on database %s and run its bootstrap SQL", props.Database)
+	}
+	if err := props.validateTablespaces(); err != nil {
+		return err
+	}
+	return props.validateExtensions()
 }
 
 type PostgresDBResource struct {
 	pulumi.ResourceState
 
-	Roles []*postgresql.Role
-	DB    *postgresql.Database
+	Roles      []*postgresql.Role
+	DB         *postgresql.Database
+	Grants     []*postgresql.Grant
+	RoleGrants []*postgresql.GrantRole
+	Schemas    []*postgresql.Schema
+	Extensions []*postgresql.Extension
+	// Default privileges on the objects created later by the owner
+	DefaultPrivileges []*postgresql.DefaultPrivileges
+	// Names of the roles and the grant resources, known before they're created
+	RoleNames  []string
+	GrantNames []string
+	// Role inheritance and grants, known before they're created
+	AccessGraph *AccessGraph
+	// Lookup role & function of pgbouncer auth_query, if PgBouncerAuth is set
+	PgBouncerRole         *postgresql.Role
+	PgBouncerAuthFunction *postgresql.Function
+	// Connection strings of the login roles keyed by role name, if Endpoint is set
+	DSNs map[string]pulumi.StringOutput
+	// COMMENT ON DATABASE of Comment, and the command running it if set
+	CommentsSQL string
+	Comment     *SQLCommand
+	// Statements of BootstrapSQL, and the command running them if set
+	BootstrapSQL string
+	Bootstrap    *SQLCommand
+	// SQL creating and assigning the tablespaces, and the command running it if set
+	TablespacesSQL string
+	Tablespaces    *SQLCommand
+	// Set once provisioned, also registered as the outputs of the component
+	Outputs *PostgresDBOutputs
+	// Resolves once the database, roles and all the grants are provisioned
+	Ready pulumi.BoolOutput
 }
 
 func (r *PostgresDBResource) provisionDB(ctx *pulumi.Context, namePrefix string, roleName pulumi.StringInput, props *PostgresDbProps) (db *postgresql.Database, err error) {
 	// CREATE DATABASE $DB;
-	db, err = postgresql.NewDatabase(ctx, fmt.Sprintf("%s-db", namePrefix), &postgresql.DatabaseArgs{
+	args := &postgresql.DatabaseArgs{
 		Name:  pulumi.String(props.Database),
 		Owner: roleName,
-	}, pulumi.Parent(r))
+	}
+	if props.Tablespace != "" {
+		// CREATE DATABASE $DB TABLESPACE $TABLESPACE;
+		args.TablespaceName = pulumi.String(props.Tablespace)
+	}
+	if props.TemplateDatabase {
+		args.IsTemplate = pulumi.BoolPtr(true)
+	}
+	// CREATE DATABASE $DB ENCODING $ENCODING LC_COLLATE $LC_COLLATE LC_CTYPE $LC_CTYPE TEMPLATE $TEMPLATE CONNECTION LIMIT $LIMIT;
+	if props.Encoding != "" {
+		args.Encoding = pulumi.String(props.Encoding)
+	}
+	if props.LcCollate != "" {
+		args.LcCollate = pulumi.String(props.LcCollate)
+	}
+	if props.LcCtype != "" {
+		args.LcCtype = pulumi.String(props.LcCtype)
+	}
+	if props.Template != "" {
+		args.Template = pulumi.String(props.Template)
+	}
+	if props.ConnectionLimit > 0 {
+		args.ConnectionLimit = pulumi.IntPtr(props.ConnectionLimit)
+	}
+	destroyProtected, err := utils.DestroyProtected(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := withImport(props.ImportExisting, props.Database, pulumi.Parent(r))
+	if r.Tablespaces != nil {
+		opts = append(opts, pulumi.DependsOn([]pulumi.Resource{r.Tablespaces}))
+	}
+	db, err = postgresql.NewDatabase(ctx, fmt.Sprintf("%s-db", namePrefix), args, withProtection(props.Protect || destroyProtected, props.RetainOnDelete, opts...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -78,98 +265,305 @@ func (r *PostgresDBResource) provision(ctx *pulumi.Context, namePrefix string, p
 		return err
 	}
 	var owner pulumi.StringInput = pulumi.String("postgres")
+	var ownerRole *postgresql.Role
 	r.Roles = make([]*postgresql.Role, len(props.DbRoles))
 	for i, user := range props.DbRoles {
-		role, err := r.provisionUser(ctx, namePrefix, user)
+		role, err := r.provisionUser(ctx, namePrefix, user, props)
 		if err != nil {
 			return err
 		}
 		r.Roles[i] = role
-		if user.Permission == ReadWrite {
+		if user.Permission == props.ownerPermission() {
 			owner = role.Name
+			ownerRole = role
 		}
 	}
-	db, err := r.provisionDB(ctx, namePrefix, owner, props)
+	if err := r.provisionTablespaces(ctx, namePrefix, props); err != nil {
+		return err
+	}
+	dbOwner := owner
+	if props.DatabaseOwner != "" {
+		dbOwner = pulumi.String(props.DatabaseOwner)
+	}
+	db, err := r.provisionDB(ctx, namePrefix, dbOwner, props)
 	if err != nil {
 		return err
 	}
 	r.DB = db
+	if err := r.provisionSchemas(ctx, namePrefix, owner, props); err != nil {
+		return err
+	}
+	if err := r.provisionExtensions(ctx, namePrefix, props); err != nil {
+		return err
+	}
+	if props.PgBouncerAuth != nil {
+		if err := r.provisionPgBouncerAuth(ctx, namePrefix, props.PgBouncerAuth); err != nil {
+			return err
+		}
+	}
+	r.DSNs = map[string]pulumi.StringOutput{}
+	// the DB roles can't login, only the pgbouncer lookup role
+	if props.Endpoint != nil && r.PgBouncerRole != nil {
+		endpoint := *props.Endpoint
+		if endpoint.Database == "" {
+			endpoint.Database = props.Database
+		}
+		r.DSNs[props.PgBouncerAuth.Username] = endpoint.DSN(r.PgBouncerRole.Name, r.PgBouncerRole.Password.Elem())
+	}
 	for i, role := range r.Roles {
-		if err := r.grantDBAccess(ctx, namePrefix, role.Name, props.DbRoles[i]); err != nil {
+		if err := r.grantDBAccess(ctx, namePrefix, role.Name, owner, props.DbRoles[i], props); err != nil {
+			return err
+		}
+		if err := r.grantExecute(ctx, namePrefix, role.Name, owner, props.DbRoles[i], props); err != nil {
+			return err
+		}
+	}
+	if props.HardenPublicSchema {
+		if err := r.hardenPublicSchema(ctx, namePrefix); err != nil {
+			return err
+		}
+	}
+	if ownerRole != nil && (props.HardenPublicSchema || props.DatabaseOwner != "") {
+		if err := r.grantDataOwner(ctx, namePrefix, ownerRole, props); err != nil {
 			return err
 		}
 	}
+	resources := []pulumi.CustomResource{r.DB}
+	if r.Tablespaces != nil {
+		resources = append(resources, r.Tablespaces)
+	}
+	for _, role := range r.Roles {
+		resources = append(resources, role)
+	}
+	for _, schema := range r.Schemas {
+		resources = append(resources, schema)
+	}
+	for _, ext := range r.Extensions {
+		resources = append(resources, ext)
+	}
+	if r.PgBouncerAuthFunction != nil {
+		resources = append(resources, r.PgBouncerAuthFunction)
+	}
+	if r.PgBouncerRole != nil {
+		resources = append(resources, r.PgBouncerRole)
+	}
+	for _, defaultPrivileges := range r.DefaultPrivileges {
+		resources = append(resources, defaultPrivileges)
+	}
+	for _, grant := range r.Grants {
+		resources = append(resources, grant)
+	}
+	for _, roleGrant := range r.RoleGrants {
+		resources = append(resources, roleGrant)
+	}
+	r.Ready = readyAfter(resources...)
+	r.AccessGraph = r.buildAccessGraph(namePrefix, props)
+	return nil
+}
+
+func (r *PostgresDBResource) newGrant(ctx *pulumi.Context, name string, args *postgresql.GrantArgs, opts ...pulumi.ResourceOption) error {
+	grant, err := postgresql.NewGrant(ctx, name, args, append(opts, pulumi.Parent(r))...)
+	if err != nil {
+		return err
+	}
+	r.GrantNames = append(r.GrantNames, name)
+	r.Grants = append(r.Grants, grant)
 	return nil
 }
 
-func (r *PostgresDBResource) grantDBAccess(ctx *pulumi.Context, namePrefix string, roleName pulumi.StringOutput, userProps PostgresDbRoleProps) error {
+func (r *PostgresDBResource) newDefaultPrivileges(ctx *pulumi.Context, name string, args *postgresql.DefaultPrivilegesArgs) error {
+	defaultPrivileges, err := postgresql.NewDefaultPrivileges(ctx, name, args, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.GrantNames = append(r.GrantNames, name)
+	r.DefaultPrivileges = append(r.DefaultPrivileges, defaultPrivileges)
+	return nil
+}
+
+func (r *PostgresDBResource) grantDBAccess(ctx *pulumi.Context, namePrefix string, roleName pulumi.StringOutput, owner pulumi.StringInput, userProps PostgresDbRoleProps, props *PostgresDbProps) error {
 	database := r.DB.Name
-	if userProps.Permission == ReadOnly {
-		// GRANT SELECT ON ALL TABLES IN SCHEMA public TO rouser
-		if _, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-readOnlyTables", namePrefix), &postgresql.GrantArgs{
-			Database:   database,
-			ObjectType: pulumi.String("table"),
-			Objects:    pulumi.StringArray{},
-			Privileges: pulumi.StringArray{pulumi.String("SELECT")},
-			Role:       roleName,
-			Schema:     pulumi.String("public"),
-		}, pulumi.Parent(r)); err != nil {
-			return err
-		}
-		// GRANT SELECT ON ALL SEQUENCES IN SCHEMA public TO rouser;
-		if _, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-readOnlySequences", namePrefix), &postgresql.GrantArgs{
-			Database:   database,
-			ObjectType: pulumi.String("sequence"),
-			Objects:    pulumi.StringArray{},
-			Privileges: pulumi.StringArray{pulumi.String("SELECT")},
-			Role:       roleName,
-			Schema:     pulumi.String("public"),
-		}, pulumi.Parent(r)); err != nil {
-			return err
+	switch userProps.Permission {
+	case DDL:
+		return r.grantDDLAccess(ctx, namePrefix, roleName, owner, userProps, props)
+	case Custom:
+		return r.grantCustomAccess(ctx, namePrefix, roleName, owner, userProps, props)
+	case Owner:
+		return nil
+	case ReadWrite:
+		if props.ownerPermission() == Owner {
+			return r.grantReadWriteAccess(ctx, namePrefix, roleName, owner, userProps, props)
 		}
+	}
+	if userProps.Permission == ReadOnly {
 		// GRANT CONNECT ON DATABASE $DB TO rouser;
-		if _, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-connectDatabase", namePrefix), &postgresql.GrantArgs{
+		if err := r.newGrant(ctx, fmt.Sprintf("%s-connectDatabase", namePrefix), &postgresql.GrantArgs{
 			Database:   database,
 			ObjectType: pulumi.String("database"),
 			Privileges: pulumi.StringArray{pulumi.String("CONNECT")},
 			Role:       roleName,
-		}, pulumi.Parent(r)); err != nil {
+		}); err != nil {
 			return err
 		}
-		// GRANT USAGE ON SCHEMA public TO rouser;
-		if _, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-usageSchema", namePrefix), &postgresql.GrantArgs{
-			Database:   database,
-			ObjectType: pulumi.String("schema"),
-			Privileges: pulumi.StringArray{pulumi.String("USAGE")},
-			Role:       roleName,
-			Schema:     pulumi.String("public"),
-		}, pulumi.Parent(r)); err != nil {
-			return err
+		scoped := len(userProps.Tables) > 0
+		scopedTables := tablesBySchema(userProps.Tables)
+		for _, schema := range r.roleSchemaRefs(props, userProps) {
+			prefix := grantPrefix(namePrefix, schema.name)
+			if scoped {
+				tables, ok := scopedTables[schema.name]
+				if !ok {
+					continue
+				}
+				// GRANT SELECT ON $TABLES TO rouser
+				if err := r.newGrant(ctx, fmt.Sprintf("%s-readOnlyScopedTables", prefix), &postgresql.GrantArgs{
+					Database:   database,
+					ObjectType: pulumi.String("table"),
+					Objects:    pulumi.ToStringArray(tables),
+					Privileges: pulumi.StringArray{pulumi.String("SELECT")},
+					Role:       roleName,
+					Schema:     schema.input,
+				}); err != nil {
+					return err
+				}
+				// GRANT USAGE ON SCHEMA $SCHEMA TO rouser;
+				if err := r.newGrant(ctx, fmt.Sprintf("%s-usageSchema", prefix), &postgresql.GrantArgs{
+					Database:   database,
+					ObjectType: pulumi.String("schema"),
+					Privileges: pulumi.StringArray{pulumi.String("USAGE")},
+					Role:       roleName,
+					Schema:     schema.input,
+				}); err != nil {
+					return err
+				}
+				continue
+			}
+			// GRANT SELECT ON ALL TABLES IN SCHEMA $SCHEMA TO rouser
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-readOnlyTables", prefix), &postgresql.GrantArgs{
+				Database:   database,
+				ObjectType: pulumi.String("table"),
+				Objects:    pulumi.StringArray{},
+				Privileges: pulumi.StringArray{pulumi.String("SELECT")},
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+			// GRANT SELECT ON ALL SEQUENCES IN SCHEMA $SCHEMA TO rouser;
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-readOnlySequences", prefix), &postgresql.GrantArgs{
+				Database:   database,
+				ObjectType: pulumi.String("sequence"),
+				Objects:    pulumi.StringArray{},
+				Privileges: pulumi.StringArray{pulumi.String("SELECT")},
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+			// GRANT USAGE ON SCHEMA $SCHEMA TO rouser;
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-usageSchema", prefix), &postgresql.GrantArgs{
+				Database:   database,
+				ObjectType: pulumi.String("schema"),
+				Privileges: pulumi.StringArray{pulumi.String("USAGE")},
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+			if userProps.SkipDefaultPrivileges {
+				continue
+			}
+			// ALTER DEFAULT PRIVILEGES FOR ROLE rwuser IN SCHEMA $SCHEMA GRANT SELECT ON TABLES TO rouser;
+			if err := r.newDefaultPrivileges(ctx, fmt.Sprintf("%s-readOnlyDefaultTables", prefix), &postgresql.DefaultPrivilegesArgs{
+				Database:   database,
+				Owner:      owner,
+				ObjectType: pulumi.String("table"),
+				Privileges: pulumi.StringArray{pulumi.String("SELECT")},
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+			// ALTER DEFAULT PRIVILEGES FOR ROLE rwuser IN SCHEMA $SCHEMA GRANT SELECT ON SEQUENCES TO rouser;
+			if err := r.newDefaultPrivileges(ctx, fmt.Sprintf("%s-readOnlyDefaultSequences", prefix), &postgresql.DefaultPrivilegesArgs{
+				Database:   database,
+				Owner:      owner,
+				ObjectType: pulumi.String("sequence"),
+				Privileges: pulumi.StringArray{pulumi.String("SELECT")},
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+		}
+	} else {
+		// rw role owns the DB and the schemas, except the ones owned by other roles
+		for i, schemaProps := range props.Schemas {
+			if schemaProps.Owner == "" {
+				continue
+			}
+			schema := r.Schemas[i].Name
+			prefix := grantPrefix(namePrefix, schemaProps.Name)
+			// GRANT USAGE, CREATE ON SCHEMA $SCHEMA TO rwuser;
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-usageCreateSchema", prefix), &postgresql.GrantArgs{
+				Database:   database,
+				ObjectType: pulumi.String("schema"),
+				Privileges: pulumi.StringArray{pulumi.String("USAGE"), pulumi.String("CREATE")},
+				Role:       roleName,
+				Schema:     schema,
+			}); err != nil {
+				return err
+			}
+			// GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA $SCHEMA TO rwuser;
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-readWriteTables", prefix), &postgresql.GrantArgs{
+				Database:   database,
+				ObjectType: pulumi.String("table"),
+				Objects:    pulumi.StringArray{},
+				Privileges: pulumi.ToStringArray([]string{"SELECT", "INSERT", "UPDATE", "DELETE"}),
+				Role:       roleName,
+				Schema:     schema,
+			}); err != nil {
+				return err
+			}
+			// GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA $SCHEMA TO rwuser;
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-readWriteSequences", prefix), &postgresql.GrantArgs{
+				Database:   database,
+				ObjectType: pulumi.String("sequence"),
+				Objects:    pulumi.StringArray{},
+				Privileges: pulumi.ToStringArray([]string{"USAGE", "SELECT", "UPDATE"}),
+				Role:       roleName,
+				Schema:     schema,
+			}); err != nil {
+				return err
+			}
 		}
-		// REVOKE CREATE ON SCHEMA public FROM PUBLIC;
-		// _, err = postgresql.NewGrant(ctx, "revokePublic", &postgresql.GrantArgs{
-		// 	Database:   pulumi.String(database),
-		// 	ObjectType: pulumi.String("schema"),
-		// 	Privileges: pulumi.StringArray{},
-		// 	Role:       pulumi.String(roUsername),
-		// 	Schema:     pulumi.String("public"),
-		// })
-		// if err != nil {
-		// 	return nil, err
-		// }
 	}
 	return nil
 }
 
-func (r *PostgresDBResource) provisionUser(ctx *pulumi.Context, name string, props PostgresDbRoleProps) (*postgresql.Role, error) {
-	roleName := fmt.Sprintf("%s-%s", name, props.Permission)
-	role, err := postgresql.NewRole(ctx, roleName, &postgresql.RoleArgs{
+func (r *PostgresDBResource) provisionUser(ctx *pulumi.Context, name string, props PostgresDbRoleProps, dbProps *PostgresDbProps) (*postgresql.Role, error) {
+	if props.ExistingRole != "" {
+		// the ID of a role is its name, a missing role fails the read
+		role, err := postgresql.GetRole(ctx, fmt.Sprintf("%s-%s", name, props.roleSuffix()), pulumi.ID(props.ExistingRole), nil, pulumi.Parent(r))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing role %s: %w", props.ExistingRole, err)
+		}
+		r.RoleNames = append(r.RoleNames, props.ExistingRole)
+		return role, nil
+	}
+	roleName := props.RoleName(name)
+	args := &postgresql.RoleArgs{
 		Name:  pulumi.String(roleName),
 		Login: pulumi.BoolPtr(false),
-	}, pulumi.Parent(r))
+	}
+	if err := props.attributes().apply(args); err != nil {
+		return nil, fmt.Errorf("role %s: %w", roleName, err)
+	}
+	opts := withImport(dbProps.ImportExisting, roleName, pulumi.Parent(r))
+	role, err := postgresql.NewRole(ctx, roleName, args, withProtection(dbProps.Protect, dbProps.RetainOnDelete, opts...)...)
 	if err != nil {
 		return nil, err
 	}
+	r.RoleNames = append(r.RoleNames, roleName)
 	return role, nil
 }
 
@@ -181,6 +575,9 @@ func NewPostgresDatabase(ctx *pulumi.Context, name string, props PostgresDbProps
 	* postgresql:username - (required) Username for the server connection. The default is postgres. Can also be specified with the PGUSER environment variable.
 	* postgresql:password - (optional) Password for the server connection. Can also be specified with the PGPASSWORD environment variable.
 	 */
+	if err := props.validateIdentifiers(name); err != nil {
+		return nil, err
+	}
 	resource := &PostgresDBResource{}
 	if err := ctx.RegisterComponentResource("ss9:postgres:database", name, resource, opts...); err != nil {
 		return nil, err
@@ -190,15 +587,43 @@ func NewPostgresDatabase(ctx *pulumi.Context, name string, props PostgresDbProps
 		return resource, err
 	}
 
-	outputRoles := make([]pulumi.MapInput, len(resource.Roles))
+	roleNames := make(pulumi.StringArray, 0, len(resource.Roles))
 	for _, role := range resource.Roles {
-		outputRoles = append(outputRoles, pulumi.Map{
-			"username": role.Name,
-		})
-	}
-	ctx.RegisterResourceOutputs(resource, pulumi.Map{
-		"database": resource.DB.Name,
-		"users":    pulumi.MapArray(outputRoles),
-	})
+		roleNames = append(roleNames, role.Name)
+	}
+	if props.Comment != "" {
+		resource.CommentsSQL = commentSQL("DATABASE", props.Database, props.Comment)
+		resource.Comment, err = NewSQLCommand(ctx, fmt.Sprintf("%s-comment", name), props.Connection, resource.DB.Name, pulumi.String(resource.CommentsSQL),
+			uncommentSQL("DATABASE", props.Database), pulumi.Parent(resource), pulumi.DependsOn([]pulumi.Resource{resource.DB}))
+		if err != nil {
+			return resource, err
+		}
+	}
+	resource.BootstrapSQL = bootstrapSQL(props.BootstrapSQL)
+	if resource.BootstrapSQL != "" {
+		// the statements may use the schemas and extensions of the database
+		dependsOn := []pulumi.Resource{resource.DB}
+		for _, schema := range resource.Schemas {
+			dependsOn = append(dependsOn, schema)
+		}
+		for _, ext := range resource.Extensions {
+			dependsOn = append(dependsOn, ext)
+		}
+		// create-only, the statements are the application's to undo
+		resource.Bootstrap, err = NewSQLCommand(ctx, fmt.Sprintf("%s-bootstrap", name), props.Connection, resource.DB.Name, pulumi.String(resource.BootstrapSQL), "",
+			pulumi.Parent(resource), pulumi.DependsOn(dependsOn))
+		if err != nil {
+			return resource, err
+		}
+	}
+	resource.Outputs = &PostgresDBOutputs{
+		Database:     resource.DB.Name,
+		Roles:        roleNames.ToStringArrayOutput(),
+		DSNs:         toStringMapOutput(resource.DSNs),
+		CommentsSQL:  resource.CommentsSQL,
+		BootstrapSQL: resource.BootstrapSQL,
+		Ready:        resource.Ready,
+	}
+	ctx.RegisterResourceOutputs(resource, resource.Outputs.resourceOutputs())
 	return resource, nil
 }
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type PostgresDatabasesResource struct {
+	pulumi.ResourceState
+
+	// Keyed by the database name
+	Databases      map[string]*PostgresDBResource
+	FailedDatabase string
+	// Resolves once all the databases are provisioned
+	Ready pulumi.BoolOutput
+}
+
+func validateDatabases(props []PostgresDbProps) error {
+	seen := map[string]bool{}
+	for _, db := range props {
+		if db.Database == "" {
+			return fmt.Errorf("database name is required")
+		}
+		if seen[db.Database] {
+			return fmt.Errorf("database %s is declared more than once", db.Database)
+		}
+		seen[db.Database] = true
+		if err := db.validateIdentifiers(db.Database); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewPostgresDatabases provisions several databases of the same cluster, each
+// one with its roles, as done by NewPostgresDatabase. They're named after the
+// database (e.g. billing-rw), so a database can be moved in or out of it
+// with an alias only.
+func NewPostgresDatabases(ctx *pulumi.Context, name string, props []PostgresDbProps, opts ...pulumi.ResourceOption) (*PostgresDatabasesResource, error) {
+	if err := validateDatabases(props); err != nil {
+		return nil, err
+	}
+	resource := &PostgresDatabasesResource{Databases: map[string]*PostgresDBResource{}}
+	if err := ctx.RegisterComponentResource("ss9:postgres:databases", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	ready := make([]interface{}, len(props))
+	for i, dbProps := range props {
+		db, err := NewPostgresDatabase(ctx, dbProps.Database, dbProps, pulumi.Parent(resource))
+		if err != nil {
+			resource.FailedDatabase = dbProps.Database
+			return resource, err
+		}
+		resource.Databases[dbProps.Database] = db
+		ready[i] = db.Ready
+	}
+	resource.Ready = pulumi.All(ready...).ApplyT(func(_ []interface{}) bool {
+		return true
+	}).(pulumi.BoolOutput)
+
+	databases := pulumi.StringArray{}
+	for _, dbProps := range props {
+		databases = append(databases, resource.Databases[dbProps.Database].DB.Name)
+	}
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"databases": databases,
+		"ready":     resource.Ready,
+	})
+	return resource, nil
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	// SELECT on the tables & sequences
+	DefaultPrivilegesRead = "read"
+	// read, plus INSERT, UPDATE, DELETE on the tables and USAGE, UPDATE on the sequences
+	DefaultPrivilegesWrite = "write"
+	// the privileges of PostgresDefaultPrivilegesProps.Privileges
+	DefaultPrivilegesCustom = "custom"
+)
+
+var defaultPrivilegesPresets = map[string]map[string][]string{
+	DefaultPrivilegesRead: {
+		"table":    {"SELECT"},
+		"sequence": {"SELECT"},
+	},
+	DefaultPrivilegesWrite: {
+		"table":    {"SELECT", "INSERT", "UPDATE", "DELETE"},
+		"sequence": {"USAGE", "SELECT", "UPDATE"},
+	},
+}
+
+// object types of ALTER DEFAULT PRIVILEGES, as named by the provider
+var defaultPrivilegesObjectTypes = map[string]string{
+	"table":    "Tables",
+	"sequence": "Sequences",
+	"function": "Functions",
+	"type":     "Types",
+}
+
+type PostgresDefaultPrivilegesProps struct {
+	// Database of the schemas, e.g. one not managed by NewPostgresDatabase
+	Database string `json:"database"`
+	// Role creating the objects, e.g. the one the migrations run as
+	Owner string `json:"owner"`
+	// Role granted the privileges on the objects created later by the owner
+	Role string `json:"role"`
+	// Schemas of the objects, public if not set
+	Schemas []string `json:"schemas"`
+	// read, write or custom, read if not set
+	Preset string `json:"preset"`
+	// Privileges keyed by object type (table, sequence, function or type),
+	// for the custom preset
+	Privileges map[string][]string `json:"privileges"`
+}
+
+type PostgresDefaultPrivilegesResource struct {
+	pulumi.ResourceState
+
+	DefaultPrivileges []*postgresql.DefaultPrivileges
+	// Names of the default privileges resources, known before they're created
+	Names []string
+	// Edges of the role, to be merged in the graph of the database
+	AccessGraph *AccessGraph
+}
+
+func (props *PostgresDefaultPrivilegesProps) validate() error {
+	if err := validateIdentifier("database", props.Database); err != nil {
+		return err
+	}
+	for _, role := range []string{props.Owner, props.Role} {
+		if err := validateRoleName(role); err != nil {
+			return err
+		}
+	}
+	if len(props.Schemas) == 0 {
+		props.Schemas = []string{publicSchema}
+	}
+	for _, schema := range props.Schemas {
+		if err := validateIdentifier("schema", schema); err != nil {
+			return err
+		}
+	}
+	switch props.Preset {
+	case "":
+		props.Preset = DefaultPrivilegesRead
+		fallthrough
+	case DefaultPrivilegesRead, DefaultPrivilegesWrite:
+		if len(props.Privileges) > 0 {
+			return fmt.Errorf("privileges can only be set with the %s preset", DefaultPrivilegesCustom)
+		}
+		props.Privileges = defaultPrivilegesPresets[props.Preset]
+	case DefaultPrivilegesCustom:
+		if len(props.Privileges) == 0 {
+			return fmt.Errorf("privileges are required with the %s preset", DefaultPrivilegesCustom)
+		}
+		for objectType := range props.Privileges {
+			if _, ok := defaultPrivilegesObjectTypes[objectType]; !ok {
+				return fmt.Errorf("invalid object type '%s' of the default privileges, expected table, sequence, function or type", objectType)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid default privileges preset '%s', expected %s, %s or %s", props.Preset, DefaultPrivilegesRead, DefaultPrivilegesWrite, DefaultPrivilegesCustom)
+	}
+	return nil
+}
+
+func (r *PostgresDefaultPrivilegesResource) provision(ctx *pulumi.Context, name string, props *PostgresDefaultPrivilegesProps) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	objectTypes := make([]string, 0, len(props.Privileges))
+	for objectType := range props.Privileges {
+		objectTypes = append(objectTypes, objectType)
+	}
+	sort.Strings(objectTypes)
+	r.AccessGraph = &AccessGraph{}
+	for _, schema := range props.Schemas {
+		prefix := grantPrefix(name, schema)
+		for _, objectType := range objectTypes {
+			resName := fmt.Sprintf("%s-default%s", prefix, defaultPrivilegesObjectTypes[objectType])
+			// ALTER DEFAULT PRIVILEGES FOR ROLE $OWNER IN SCHEMA $SCHEMA GRANT SELECT ON TABLES TO $ROLE;
+			defaultPrivileges, err := postgresql.NewDefaultPrivileges(ctx, resName, &postgresql.DefaultPrivilegesArgs{
+				Database:   pulumi.String(props.Database),
+				Owner:      pulumi.String(props.Owner),
+				ObjectType: pulumi.String(objectType),
+				Privileges: pulumi.ToStringArray(props.Privileges[objectType]),
+				Role:       pulumi.String(props.Role),
+				Schema:     pulumi.String(schema),
+			}, pulumi.Parent(r))
+			if err != nil {
+				return err
+			}
+			r.DefaultPrivileges = append(r.DefaultPrivileges, defaultPrivileges)
+			r.Names = append(r.Names, resName)
+			r.AccessGraph.AddPrivileges(props.Role, fmt.Sprintf("future %ss of %s in %s.%s", objectType, props.Owner, props.Database, schema), props.Privileges[objectType]...)
+		}
+	}
+	return nil
+}
+
+// NewPostgresDefaultPrivileges grants a role the privileges on the objects
+// the owner role creates later, in any database of the server. Objects which
+// exist already aren't granted, see postgresql.Grant.
+func NewPostgresDefaultPrivileges(ctx *pulumi.Context, name string, props PostgresDefaultPrivilegesProps, opts ...pulumi.ResourceOption) (*PostgresDefaultPrivilegesResource, error) {
+	resource := &PostgresDefaultPrivilegesResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:defaultprivileges", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"owner":   pulumi.String(props.Owner),
+		"role":    pulumi.String(props.Role),
+		"schemas": pulumi.ToStringArray(props.Schemas),
+		"preset":  pulumi.String(props.Preset),
+	})
+	return resource, nil
+}
@@ -0,0 +1,340 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	// tags cross-linking the secrets to the roles whose creds they store
+	roleTag     = "postgres:role"
+	databaseTag = "postgres:database"
+)
+
+const (
+	// one secret for each user
+	ExportPerUser = "perUser"
+	// one secret for all the users, keyed by username
+	ExportConsolidated = "consolidated"
+	// Secret Manager secrets, the default export target
+	AWSExportTarget = "aws"
+)
+
+// CredsExportProps is where the creds of the roles of a database go, the
+// stack outputs, or the secrets of the export targets with ExportAsSecret.
+type CredsExportProps struct {
+	Database string
+	// Server of the roles, its host & port are stored with the creds once
+	// the superuser is loaded, i.e. they may be read from its secret
+	Provider *ProviderConfig
+	// Stores the creds in secrets, only referenced by the outputs
+	ExportAsSecret bool
+	// ExportPerUser or ExportConsolidated, ExportPerUser if not set
+	Mode string
+	// Secret stores the creds are published to with ExportAsSecret, aws if not set
+	Targets []string
+	// Settings of the stores of the targets other than aws, keyed by target
+	StoreSettings map[string]map[string]interface{}
+	// ExternalSecret manifests are exported for the aws secrets if set
+	ExternalSecret *secret.ExternalSecretProps
+}
+
+// CredsExporter exports the creds of the roles, and keeps track of the
+// secrets created for them, for the plan and the comments of the roles.
+type CredsExporter struct {
+	props CredsExportProps
+	// stores of the export targets other than aws
+	stores map[string]secret.SecretStore
+	// Names of the created secrets
+	SecretNames []string
+	// Secret names keyed by the role whose creds they store
+	RoleSecrets    map[string]string
+	roleSecretArns map[string]pulumi.StringOutput
+}
+
+// ExportedUser is the creds payload of a user, see ExportUsers
+type ExportedUser struct {
+	Username string
+	// DBCreds if not set, e.g. DBConnection for connection strings
+	Type    secret.SecretType
+	Payload pulumi.StringMapInput
+	// Developers allowed to read the secret of the user, in ExportPerUser mode
+	Shares []secret.SecretShare
+	// Secret Manager rotates the password in the secret of the user if set
+	RotationRules *secret.RotationRules
+	RotationVpc   *secret.RotationVpc
+}
+
+// NewCredsExporter validates the export mode, and resolves the stores of the
+// export targets. The aws target is handled by the exporter itself, for the
+// tags, shares & manifests.
+func NewCredsExporter(props CredsExportProps) (*CredsExporter, error) {
+	switch props.Mode {
+	case "":
+		props.Mode = ExportPerUser
+	case ExportPerUser, ExportConsolidated:
+	default:
+		return nil, fmt.Errorf("invalid export mode '%s', expected %s or %s", props.Mode, ExportPerUser, ExportConsolidated)
+	}
+	if len(props.Targets) > 0 && !props.ExportAsSecret {
+		return nil, fmt.Errorf("exportTargets needs exportAsSecret")
+	}
+	if len(props.Targets) == 0 {
+		props.Targets = []string{AWSExportTarget}
+	}
+	e := &CredsExporter{
+		props:          props,
+		stores:         map[string]secret.SecretStore{},
+		RoleSecrets:    map[string]string{},
+		roleSecretArns: map[string]pulumi.StringOutput{},
+	}
+	seen := map[string]bool{}
+	for _, target := range props.Targets {
+		if seen[target] {
+			return nil, fmt.Errorf("duplicate export target '%s'", target)
+		}
+		seen[target] = true
+		if target == AWSExportTarget {
+			continue
+		}
+		store, err := secret.LookupSecretStore(target, props.StoreSettings[target])
+		if err != nil {
+			return nil, fmt.Errorf("invalid export target: %w", err)
+		}
+		e.stores[target] = store
+	}
+	for target := range props.StoreSettings {
+		if !seen[target] || target == AWSExportTarget {
+			return nil, fmt.Errorf("exportStores has settings of '%s', which isn't one of the other export targets", target)
+		}
+	}
+	return e, nil
+}
+
+func (e *CredsExporter) Mode() string {
+	return e.props.Mode
+}
+
+// Targets are the export targets, aws if none was set
+func (e *CredsExporter) Targets() []string {
+	return e.props.Targets
+}
+
+func (e *CredsExporter) ExportsToAWS() bool {
+	if !e.props.ExportAsSecret {
+		return false
+	}
+	for _, target := range e.props.Targets {
+		if target == AWSExportTarget {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags links the secret to the roles whose creds it stores. Tag values
+// can't have commas, so the roles are separated by spaces.
+func (e *CredsExporter) Tags(roles ...string) map[string]string {
+	return map[string]string{
+		roleTag:     strings.Join(roles, " "),
+		databaseTag: e.props.Database,
+	}
+}
+
+// AddSecret records the secret for the plan, and links it to the roles. The
+// ID of a Secret Manager secret is its ARN.
+func (e *CredsExporter) AddSecret(name string, arn pulumi.StringOutput, roles ...string) {
+	e.SecretNames = append(e.SecretNames, name)
+	for _, role := range roles {
+		e.RoleSecrets[role] = name
+		e.roleSecretArns[role] = arn
+	}
+}
+
+// newSecret creates the Secret Manager secret of the creds of the roles,
+// tagged with them, and records it.
+func (e *CredsExporter) newSecret(ctx *pulumi.Context, props secret.AWSSecretProps, roles ...string) (*secret.AWSSecret, error) {
+	if len(roles) > 0 {
+		props.Tags = e.Tags(roles...)
+	}
+	res, err := secret.NewAWSSecret(ctx, props)
+	if err != nil {
+		return nil, err
+	}
+	e.AddSecret(res.Name, res.Secret.ID().ToStringOutput(), roles...)
+	return res, nil
+}
+
+func (e *CredsExporter) exportExternalSecret(ctx *pulumi.Context, res *secret.AWSSecret, name string, outputName string) error {
+	if e.props.ExternalSecret == nil {
+		return nil
+	}
+	props := *e.props.ExternalSecret
+	props.Name = name
+	manifest, err := res.ExternalSecretManifest(props)
+	if err != nil {
+		return err
+	}
+	return utils.Export(ctx, utils.OutputManifest, outputName, manifest)
+}
+
+// storeInTargets publishes the same creds to the stores of the other export targets
+func (e *CredsExporter) storeInTargets(ctx *pulumi.Context, name string, secretType secret.SecretType, creds pulumi.StringMapInput, ids map[string]pulumi.StringOutput) error {
+	for _, target := range e.props.Targets {
+		store, ok := e.stores[target]
+		if !ok {
+			continue
+		}
+		id, err := store.Store(ctx, name, secretType, creds)
+		if err != nil {
+			return fmt.Errorf("failed to store %s in export target %s: %w", name, target, err)
+		}
+		ids[target] = id
+	}
+	return nil
+}
+
+// secretRefs references the secret of the first export target as secretId,
+// and with several targets, each one's as <target>SecretId too.
+func (e *CredsExporter) secretRefs(ids map[string]pulumi.StringOutput) pulumi.StringMap {
+	refs := pulumi.StringMap{
+		"secretId": ids[e.props.Targets[0]],
+	}
+	if len(e.props.Targets) > 1 {
+		for target, id := range ids {
+			refs[fmt.Sprintf("%sSecretId", target)] = id
+		}
+	}
+	return refs
+}
+
+// ExportUsers exports the creds of the users as outputs, or in secrets once
+// ready resolves, i.e. the access is fully provisioned.
+func (e *CredsExporter) ExportUsers(ctx *pulumi.Context, users []ExportedUser, ready pulumi.ArrayOutput) error {
+	if !e.props.ExportAsSecret {
+		for _, user := range users {
+			if err := utils.Export(ctx, utils.OutputCreds, user.Username, user.Payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if e.props.Mode == ExportConsolidated {
+		return e.exportConsolidatedSecret(ctx, users, ready)
+	}
+	for _, user := range users {
+		if err := e.exportUserSecret(ctx, user, ready); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportUserSecret exposes the user creds in an independent secret
+func (e *CredsExporter) exportUserSecret(ctx *pulumi.Context, user ExportedUser, ready pulumi.ArrayOutput) error {
+	name := fmt.Sprintf("pg-%s-user-%s", e.props.Database, user.Username)
+	secretType := user.Type
+	if secretType == "" {
+		secretType = secret.DBCreds
+	}
+	// built once, so every target gets the same payload
+	creds := afterReady(ready, user.Payload)
+	ids := map[string]pulumi.StringOutput{}
+	if e.ExportsToAWS() {
+		res, err := e.newSecret(ctx, secret.AWSSecretProps{
+			Name:           name,
+			Type:           secretType,
+			InitialValue:   creds,
+			Shares:         user.Shares,
+			RotatePostgres: user.RotationRules != nil,
+			RotationRules:  user.RotationRules,
+			RotationVpc:    user.RotationVpc,
+		}, user.Username)
+		if err != nil {
+			return fmt.Errorf("failed to create secret for user %s: %w", user.Username, err)
+		}
+		ids[AWSExportTarget] = res.Secret.ID().ToStringOutput()
+		if err := e.exportExternalSecret(ctx, res, fmt.Sprintf("%s-%s", e.props.Database, user.Username), fmt.Sprintf("manifest-%s", user.Username)); err != nil {
+			return err
+		}
+	}
+	if err := e.storeInTargets(ctx, name, secretType, creds, ids); err != nil {
+		return err
+	}
+	return utils.Export(ctx, utils.OutputReference, fmt.Sprintf("secret-%s", user.Username), e.secretRefs(ids))
+}
+
+// exportConsolidatedSecret exposes all the users creds in a single secret,
+// each user's creds are stored as JSON under its username.
+func (e *CredsExporter) exportConsolidatedSecret(ctx *pulumi.Context, users []ExportedUser, ready pulumi.ArrayOutput) error {
+	creds := pulumi.StringMap{}
+	usernames := make([]string, len(users))
+	for i, user := range users {
+		creds[user.Username] = pulumi.JSONMarshal(user.Payload)
+		usernames[i] = user.Username
+	}
+	name := fmt.Sprintf("pg-%s-users", e.props.Database)
+	bundle := afterReady(ready, creds)
+	ids := map[string]pulumi.StringOutput{}
+	if e.ExportsToAWS() {
+		res, err := e.newSecret(ctx, secret.AWSSecretProps{
+			Name:         name,
+			Type:         secret.DBCredsBundle,
+			InitialValue: bundle,
+		}, usernames...)
+		if err != nil {
+			return fmt.Errorf("failed to create consolidated secret: %w", err)
+		}
+		ids[AWSExportTarget] = res.Secret.ID().ToStringOutput()
+		if err := e.exportExternalSecret(ctx, res, fmt.Sprintf("%s-users", e.props.Database), "manifest"); err != nil {
+			return err
+		}
+	}
+	if err := e.storeInTargets(ctx, name, secret.DBCredsBundle, bundle, ids); err != nil {
+		return err
+	}
+	return utils.Export(ctx, utils.OutputReference, "secret", e.secretRefs(ids))
+}
+
+// roleCreds are the creds of a role, as the db secret types expect them
+func (e *CredsExporter) roleCreds(username pulumi.StringInput, password pulumi.StringOutput, database string) pulumi.StringMap {
+	return pulumi.StringMap{
+		"username": username,
+		"password": pulumi.ToSecret(password).(pulumi.StringOutput),
+		"database": pulumi.String(database),
+		"host":     e.props.Provider.Host,
+		"port":     pulumi.Sprintf("%d", e.props.Provider.Port),
+	}
+}
+
+// CommentRoleSecrets writes the ARN of the secret of each role into its
+// COMMENT, after the comment of the role in comments if set
+func (e *CredsExporter) CommentRoleSecrets(ctx *pulumi.Context, conn *SQLConnection, comments map[string]string) error {
+	roles := make([]string, 0, len(e.roleSecretArns))
+	for role := range e.roleSecretArns {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		comment := pulumi.Sprintf("secret: %s", e.roleSecretArns[role])
+		if roleComment := comments[role]; roleComment != "" {
+			comment = pulumi.Sprintf("%s\nsecret: %s", roleComment, e.roleSecretArns[role])
+		}
+		if _, err := NewRoleComment(ctx, fmt.Sprintf("%s-%s-secret", e.props.Database, role), conn, role, comment); err != nil {
+			return fmt.Errorf("failed to link role %s to its secret: %w", role, err)
+		}
+	}
+	return nil
+}
+
+// afterReady resolves the creds only after ready does
+func afterReady(ready pulumi.ArrayOutput, creds pulumi.StringMapInput) pulumi.StringMapOutput {
+	return pulumi.All(ready, creds).ApplyT(func(args []interface{}) map[string]string {
+		return args[1].(map[string]string)
+	}).(pulumi.StringMapOutput)
+}
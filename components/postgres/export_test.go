@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewCredsExporter(t *testing.T) {
+	tests := []struct {
+		name        string
+		props       CredsExportProps
+		wantMode    string
+		wantTargets []string
+		wantAWS     bool
+		wantErr     string
+	}{
+		{name: "outputs", props: CredsExportProps{}, wantMode: ExportPerUser, wantTargets: []string{AWSExportTarget}},
+		{
+			name:        "aws by default",
+			props:       CredsExportProps{ExportAsSecret: true, Mode: ExportConsolidated},
+			wantMode:    ExportConsolidated,
+			wantTargets: []string{AWSExportTarget},
+			wantAWS:     true,
+		},
+		{
+			name: "other targets",
+			props: CredsExportProps{
+				ExportAsSecret: true,
+				Targets:        []string{"vault", AWSExportTarget},
+				StoreSettings:  map[string]map[string]interface{}{"vault": {"mount": "kv"}},
+			},
+			wantMode:    ExportPerUser,
+			wantTargets: []string{"vault", AWSExportTarget},
+			wantAWS:     true,
+		},
+		{
+			name:        "without aws",
+			props:       CredsExportProps{ExportAsSecret: true, Targets: []string{"k8s"}},
+			wantMode:    ExportPerUser,
+			wantTargets: []string{"k8s"},
+		},
+		{name: "invalid mode", props: CredsExportProps{Mode: "perRole"}, wantErr: "invalid export mode 'perRole'"},
+		{name: "targets without exportAsSecret", props: CredsExportProps{Targets: []string{"vault"}}, wantErr: "exportTargets needs exportAsSecret"},
+		{
+			name:    "duplicate target",
+			props:   CredsExportProps{ExportAsSecret: true, Targets: []string{"vault", "vault"}},
+			wantErr: "duplicate export target 'vault'",
+		},
+		{
+			name:    "unknown target",
+			props:   CredsExportProps{ExportAsSecret: true, Targets: []string{"gcp"}},
+			wantErr: "invalid export target: unknown secretStore type 'gcp'",
+		},
+		{
+			name: "settings of the aws target",
+			props: CredsExportProps{
+				ExportAsSecret: true,
+				StoreSettings:  map[string]map[string]interface{}{AWSExportTarget: {"kmsKeyId": "alias/shared"}},
+			},
+			wantErr: "exportStores has settings of 'aws'",
+		},
+		{
+			name: "settings of no target",
+			props: CredsExportProps{
+				ExportAsSecret: true,
+				Targets:        []string{"vault"},
+				StoreSettings:  map[string]map[string]interface{}{"k8s": {"namespace": "apps"}},
+			},
+			wantErr: "exportStores has settings of 'k8s'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCredsExporter(tt.props)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Mode() != tt.wantMode {
+				t.Fatalf("got mode %s, want %s", got.Mode(), tt.wantMode)
+			}
+			if !reflect.DeepEqual(got.Targets(), tt.wantTargets) {
+				t.Fatalf("got targets %v, want %v", got.Targets(), tt.wantTargets)
+			}
+			if got.ExportsToAWS() != tt.wantAWS {
+				t.Fatalf("got exportsToAWS %t, want %t", got.ExportsToAWS(), tt.wantAWS)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"fmt"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type PostgresExtensionProps struct {
+	// e.g. uuid-ossp, pg_trgm, postgis
+	Name string `json:"name"`
+	// Pins the version, defaults to the one packaged with the server
+	Version string `json:"version"`
+	// Schema the extension objects are created in, it can be one of the declared schemas
+	Schema string `json:"schema"`
+	// Also creates the extensions it depends on
+	CreateCascade bool `json:"createCascade"`
+}
+
+func (props *PostgresDbProps) validateExtensions() error {
+	seen := map[string]bool{}
+	for _, ext := range props.Extensions {
+		if ext.Name == "" {
+			return fmt.Errorf("extension name is required")
+		}
+		if seen[ext.Name] {
+			return fmt.Errorf("extension '%s' is declared more than once", ext.Name)
+		}
+		seen[ext.Name] = true
+	}
+	return nil
+}
+
+// schemaInput resolves the schema after it's created, if it's one of the declared ones
+func (r *PostgresDBResource) schemaInput(props *PostgresDbProps, name string) pulumi.StringInput {
+	for _, schema := range r.schemaRefs(props) {
+		if schema.name == name {
+			return schema.input
+		}
+	}
+	return pulumi.String(name)
+}
+
+func (r *PostgresDBResource) provisionExtensions(ctx *pulumi.Context, namePrefix string, props *PostgresDbProps) error {
+	for _, extProps := range props.Extensions {
+		// CREATE EXTENSION $EXT SCHEMA $SCHEMA VERSION $VERSION;
+		args := &postgresql.ExtensionArgs{
+			Database: r.DB.Name,
+			Name:     pulumi.String(extProps.Name),
+		}
+		if extProps.Version != "" {
+			args.Version = pulumi.String(extProps.Version)
+		}
+		if extProps.Schema != "" {
+			args.Schema = r.schemaInput(props, extProps.Schema)
+		}
+		if extProps.CreateCascade {
+			args.CreateCascade = pulumi.BoolPtr(true)
+		}
+		ext, err := postgresql.NewExtension(ctx, fmt.Sprintf("%s-extension-%s", namePrefix, extProps.Name), args, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.Extensions = append(r.Extensions, ext)
+	}
+	return nil
+}
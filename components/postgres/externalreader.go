@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+type PostgresExternalReaderProps struct {
+	// Team granted the access, the user is named <database>-reader-<team>
+	Team string `json:"team"`
+	// Schemas whose tables the team can read, public if not set
+	Schemas []string `json:"schemas"`
+	// RFC3339 timestamp after which the user can't login anymore
+	ExpiresAt string `json:"expiresAt"`
+	// Database the team reads from, the name of the component if not set
+	Database string `json:"-"`
+	// Endpoint of the server, stored with the creds
+	Host pulumi.StringInput `json:"-"`
+	Port int                `json:"-"`
+	// Store of the creds, e.g. in the account of the team
+	Store secret.SecretStore `json:"-"`
+	// <name>-reader-<team> if not set
+	SecretName string `json:"-"`
+}
+
+type PostgresExternalReaderResource struct {
+	pulumi.ResourceState
+
+	Role     *postgresql.Role
+	Password pulumi.StringOutput
+	SecretId pulumi.StringOutput
+	// Grants of the user, to be merged in the graph of the database
+	AccessGraph *AccessGraph
+}
+
+// cross-team access is renewed by bumping the expiry, not granted for good
+const MaxExternalReaderDuration = 90 * 24 * time.Hour
+
+// ExternalReaderUsername is the login role of the team reading the database
+func ExternalReaderUsername(database string, team string) string {
+	return fmt.Sprintf("%s-reader-%s", database, team)
+}
+
+// Active checks the expiry is at most MaxExternalReaderDuration away, and
+// tells whether it's still ahead of now. The expired readers are meant to be
+// dropped, so their role, grants and secret are removed on the next deploy.
+func (props *PostgresExternalReaderProps) Active(now time.Time) (bool, error) {
+	expiresAt, err := time.Parse(time.RFC3339, props.ExpiresAt)
+	if err != nil {
+		return false, fmt.Errorf("invalid expiry of external reader %s: %w", props.Team, err)
+	}
+	if expiresAt.Sub(now) > MaxExternalReaderDuration {
+		return false, fmt.Errorf("access of external reader %s can last %s at most", props.Team, MaxExternalReaderDuration)
+	}
+	return expiresAt.After(now), nil
+}
+
+func (props *PostgresExternalReaderProps) validate(name string) error {
+	if props.Team == "" {
+		return fmt.Errorf("team is required for the external reader")
+	}
+	if props.Database == "" {
+		props.Database = name
+	}
+	if err := validateRoleName(ExternalReaderUsername(props.Database, props.Team)); err != nil {
+		return err
+	}
+	if _, err := time.Parse(time.RFC3339, props.ExpiresAt); err != nil {
+		return fmt.Errorf("invalid expiry of the external reader %s: %w", props.Team, err)
+	}
+	if len(props.Schemas) == 0 {
+		props.Schemas = []string{publicSchema}
+	}
+	for _, schema := range props.Schemas {
+		if err := validateIdentifier("schema", schema); err != nil {
+			return fmt.Errorf("external reader %s: %w", props.Team, err)
+		}
+	}
+	if props.Store != nil && props.Host == nil {
+		return fmt.Errorf("host is required to store the creds of external reader %s", props.Team)
+	}
+	return nil
+}
+
+func (r *PostgresExternalReaderResource) provision(ctx *pulumi.Context, name string, props *PostgresExternalReaderProps) error {
+	if err := props.validate(name); err != nil {
+		return err
+	}
+	username := ExternalReaderUsername(props.Database, props.Team)
+	resName := fmt.Sprintf("%s-reader-%s", name, props.Team)
+	// a renewed access gets new creds
+	password, err := utils.NewRotatingPassword(ctx, fmt.Sprintf("%s-password", resName), 16, map[string]string{
+		"expiresAt": props.ExpiresAt,
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Password = password
+
+	// CREATE ROLE $USER LOGIN PASSWORD '$PASSWORD' VALID UNTIL '$EXPIRES_AT';
+	role, err := postgresql.NewRole(ctx, resName, &postgresql.RoleArgs{
+		Name:       pulumi.String(username),
+		Password:   password,
+		Login:      pulumi.BoolPtr(true),
+		ValidUntil: pulumi.String(props.ExpiresAt),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Role = role
+	r.AccessGraph = &AccessGraph{}
+
+	// GRANT CONNECT ON DATABASE $DB TO $USER;
+	if _, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-connectDatabase", resName), &postgresql.GrantArgs{
+		Database:   pulumi.String(props.Database),
+		ObjectType: pulumi.String("database"),
+		Privileges: pulumi.StringArray{pulumi.String("CONNECT")},
+		Role:       role.Name,
+	}, pulumi.Parent(r)); err != nil {
+		return err
+	}
+	r.AccessGraph.AddPrivileges(username, fmt.Sprintf("database %s", props.Database), "CONNECT")
+	for _, schema := range props.Schemas {
+		prefix := grantPrefix(resName, schema)
+		// GRANT USAGE ON SCHEMA $SCHEMA TO $USER;
+		if _, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-usageSchema", prefix), &postgresql.GrantArgs{
+			Database:   pulumi.String(props.Database),
+			ObjectType: pulumi.String("schema"),
+			Privileges: pulumi.StringArray{pulumi.String("USAGE")},
+			Role:       role.Name,
+			Schema:     pulumi.String(schema),
+		}, pulumi.Parent(r)); err != nil {
+			return err
+		}
+		// GRANT SELECT ON ALL TABLES IN SCHEMA $SCHEMA TO $USER;
+		if _, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-readOnlyTables", prefix), &postgresql.GrantArgs{
+			Database:   pulumi.String(props.Database),
+			ObjectType: pulumi.String("table"),
+			Objects:    pulumi.StringArray{},
+			Privileges: pulumi.StringArray{pulumi.String("SELECT")},
+			Role:       role.Name,
+			Schema:     pulumi.String(schema),
+		}, pulumi.Parent(r)); err != nil {
+			return err
+		}
+		// same nodes as buildAccessGraph, so the edges land on the schemas of the database
+		r.AccessGraph.AddPrivileges(username, fmt.Sprintf("schema %s.%s", props.Database, schema), "USAGE")
+		r.AccessGraph.AddPrivileges(username, fmt.Sprintf("all tables in %s.%s", props.Database, schema), "SELECT")
+	}
+
+	if props.Store != nil {
+		if props.SecretName == "" {
+			props.SecretName = resName
+		}
+		creds := pulumi.StringMap{
+			"username":  role.Name,
+			"password":  pulumi.ToSecret(password).(pulumi.StringOutput),
+			"database":  pulumi.String(props.Database),
+			"host":      props.Host,
+			"port":      pulumi.Sprintf("%d", props.Port),
+			"expiresAt": pulumi.String(props.ExpiresAt),
+		}
+		secretId, err := props.Store.Store(ctx, props.SecretName, secret.DBCreds, creds, pulumi.Parent(r), pulumi.DependsOn([]pulumi.Resource{role}))
+		if err != nil {
+			return fmt.Errorf("failed to store creds of external reader %s: %w", props.Team, err)
+		}
+		r.SecretId = secretId
+	}
+	return nil
+}
+
+// NewPostgresExternalReader creates a time-boxed login role of another team,
+// which can only read the tables of the requested schemas.
+func NewPostgresExternalReader(ctx *pulumi.Context, name string, props PostgresExternalReaderProps, opts ...pulumi.ResourceOption) (*PostgresExternalReaderResource, error) {
+	resource := &PostgresExternalReaderResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:externalreader", fmt.Sprintf("%s-%s", name, props.Team), resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+
+	outputs := pulumi.Map{
+		"username":  resource.Role.Name,
+		"expiresAt": pulumi.String(props.ExpiresAt),
+	}
+	if props.Store != nil {
+		outputs["secretId"] = resource.SecretId
+	}
+	ctx.RegisterResourceOutputs(resource, outputs)
+	return resource, nil
+}
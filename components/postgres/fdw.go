@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"fmt"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+)
+
+type PostgresFdwProps struct {
+	// Local database the foreign server is declared in. The postgresql
+	// provider must connect to it too, since the server is created in the
+	// database of the provider.
+	Database string `json:"database"`
+	// Name of the foreign server
+	ServerName string `json:"serverName"`
+	// Secret Manager secret (of DB creds type) of the remote database, its
+	// creds are used for all the user mappings
+	SourceSecretId string `json:"sourceSecretId"`
+	// Local roles mapped to the remote user
+	Users []string `json:"users"`
+	// Set if postgres_fdw is declared already, e.g. in PostgresDbProps.Extensions
+	SkipExtension bool `json:"skipExtension"`
+}
+
+type PostgresFdwResource struct {
+	pulumi.ResourceState
+
+	Extension    *postgresql.Extension
+	Server       *postgresql.Server
+	UserMappings []*postgresql.UserMapping
+}
+
+func (r *PostgresFdwResource) provision(ctx *pulumi.Context, name string, props *PostgresFdwProps) error {
+	if props.Database == "" || props.ServerName == "" || props.SourceSecretId == "" {
+		return fmt.Errorf("database, server name and source secret are required for the foreign server")
+	}
+	if len(props.Users) == 0 {
+		return fmt.Errorf("at least one user is required to map to the foreign server")
+	}
+	creds := secret.LookupDBCreds(ctx, props.SourceSecretId, pulumi.Parent(r))
+
+	serverOpts := []pulumi.ResourceOption{pulumi.Parent(r)}
+	if !props.SkipExtension {
+		// CREATE EXTENSION postgres_fdw;
+		ext, err := postgresql.NewExtension(ctx, fmt.Sprintf("%s-postgres_fdw", name), &postgresql.ExtensionArgs{
+			Database: pulumi.String(props.Database),
+			Name:     pulumi.String("postgres_fdw"),
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.Extension = ext
+		serverOpts = append(serverOpts, pulumi.DependsOn([]pulumi.Resource{ext}))
+	}
+
+	// CREATE SERVER $SERVER FOREIGN DATA WRAPPER postgres_fdw OPTIONS (host '$HOST', port '$PORT', dbname '$DB');
+	server, err := postgresql.NewServer(ctx, fmt.Sprintf("%s-server", name), &postgresql.ServerArgs{
+		ServerName: pulumi.String(props.ServerName),
+		FdwName:    pulumi.String("postgres_fdw"),
+		Options: pulumi.StringMap{
+			"host":   creds.MapIndex(pulumi.String("host")),
+			"port":   creds.MapIndex(pulumi.String("port")),
+			"dbname": creds.MapIndex(pulumi.String("database")),
+		},
+	}, serverOpts...)
+	if err != nil {
+		return err
+	}
+	r.Server = server
+
+	for _, user := range props.Users {
+		// CREATE USER MAPPING FOR $USER SERVER $SERVER OPTIONS (user '$REMOTE_USER', password '$REMOTE_PASSWORD');
+		mapping, err := postgresql.NewUserMapping(ctx, fmt.Sprintf("%s-mapping-%s", name, user), &postgresql.UserMappingArgs{
+			ServerName: server.ServerName,
+			UserName:   pulumi.String(user),
+			Options: pulumi.StringMap{
+				"user":     creds.MapIndex(pulumi.String("username")),
+				"password": creds.MapIndex(pulumi.String("password")),
+			},
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.UserMappings = append(r.UserMappings, mapping)
+	}
+	return nil
+}
+
+// NewPostgresFdw declares a remote database as postgres_fdw foreign server,
+// e.g. for a reporting DB reading from the app DB. The foreign tables are
+// left to the migrations (IMPORT FOREIGN SCHEMA).
+func NewPostgresFdw(ctx *pulumi.Context, name string, props PostgresFdwProps, opts ...pulumi.ResourceOption) (*PostgresFdwResource, error) {
+	resource := &PostgresFdwResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:fdw", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"server": resource.Server.ServerName,
+	})
+	return resource, nil
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"fmt"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func (props *PostgresDbProps) validateFunctionSchemas() error {
+	schemas := map[string]bool{publicSchema: true}
+	for _, schema := range props.Schemas {
+		schemas[schema.Name] = true
+	}
+	for _, role := range props.DbRoles {
+		if len(role.FunctionSchemas) == 0 {
+			continue
+		}
+		if role.Permission != ReadOnly && role.Permission != Custom {
+			return fmt.Errorf("functions can only be granted to the read-only and custom roles, the read-write role owns them")
+		}
+		for _, schema := range role.FunctionSchemas {
+			if !schemas[schema] {
+				return fmt.Errorf("function schema '%s' is not declared in the database", schema)
+			}
+		}
+	}
+	return nil
+}
+
+// grantExecute lets the role call the functions & procedures of the schemas,
+// e.g. the stored procedures of the reporting tools.
+func (r *PostgresDBResource) grantExecute(ctx *pulumi.Context, namePrefix string, roleName pulumi.StringOutput, owner pulumi.StringInput, userProps PostgresDbRoleProps, props *PostgresDbProps) error {
+	functionSchemas := map[string]bool{}
+	for _, schema := range userProps.FunctionSchemas {
+		functionSchemas[schema] = true
+	}
+	rolePrefix := fmt.Sprintf("%s-%s", namePrefix, userProps.roleSuffix())
+	for _, schema := range r.schemaRefs(props) {
+		if !functionSchemas[schema.name] {
+			continue
+		}
+		prefix := grantPrefix(rolePrefix, schema.name)
+		// GRANT EXECUTE ON ALL FUNCTIONS IN SCHEMA $SCHEMA TO $ROLE;
+		// GRANT EXECUTE ON ALL PROCEDURES IN SCHEMA $SCHEMA TO $ROLE;
+		objects := []struct {
+			objectType string
+			grantName  string
+		}{{"function", "executeFunctions"}, {"procedure", "executeProcedures"}}
+		for _, object := range objects {
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-%s", prefix, object.grantName), &postgresql.GrantArgs{
+				Database:   r.DB.Name,
+				ObjectType: pulumi.String(object.objectType),
+				Objects:    pulumi.StringArray{},
+				Privileges: pulumi.StringArray{pulumi.String("EXECUTE")},
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+		}
+		if userProps.SkipDefaultPrivileges {
+			continue
+		}
+		// ALTER DEFAULT PRIVILEGES FOR ROLE rwuser IN SCHEMA $SCHEMA GRANT EXECUTE ON FUNCTIONS TO $ROLE;
+		// it covers the procedures too
+		if err := r.newDefaultPrivileges(ctx, fmt.Sprintf("%s-executeDefaultFunctions", prefix), &postgresql.DefaultPrivilegesArgs{
+			Database:   r.DB.Name,
+			Owner:      owner,
+			ObjectType: pulumi.String("function"),
+			Privileges: pulumi.StringArray{pulumi.String("EXECUTE")},
+			Role:       roleName,
+			Schema:     schema.input,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
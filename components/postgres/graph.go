@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AccessEdge is either a role membership (no privileges) or the privileges
+// of a role on an object.
+type AccessEdge struct {
+	From       string
+	To         string
+	Privileges []string
+}
+
+// AccessGraph is the role inheritance and grant topology provisioned by the
+// components, for the DBAs and auditors.
+type AccessGraph struct {
+	Edges []AccessEdge
+}
+
+func (g *AccessGraph) AddMembership(member string, role string) {
+	g.Edges = append(g.Edges, AccessEdge{From: member, To: role})
+}
+
+func (g *AccessGraph) AddPrivileges(role string, object string, privileges ...string) {
+	g.Edges = append(g.Edges, AccessEdge{From: role, To: object, Privileges: privileges})
+}
+
+func (g *AccessGraph) Merge(other *AccessGraph) {
+	if other != nil {
+		g.Edges = append(g.Edges, other.Edges...)
+	}
+}
+
+// DOT renders the graph for Graphviz, roles as ellipses and objects as boxes.
+func (g *AccessGraph) DOT() string {
+	roles, objects := map[string]bool{}, map[string]bool{}
+	for _, e := range g.Edges {
+		roles[e.From] = true
+		if e.Privileges == nil {
+			roles[e.To] = true
+		} else {
+			objects[e.To] = true
+		}
+	}
+	b := &strings.Builder{}
+	b.WriteString("digraph access {\n\trankdir=LR;\n")
+	for _, node := range sortedKeys(roles) {
+		fmt.Fprintf(b, "\t%q [shape=ellipse];\n", node)
+	}
+	for _, node := range sortedKeys(objects) {
+		fmt.Fprintf(b, "\t%q [shape=box];\n", node)
+	}
+	for _, e := range g.Edges {
+		if e.Privileges == nil {
+			fmt.Fprintf(b, "\t%q -> %q [style=dashed, label=\"member of\"];\n", e.From, e.To)
+		} else {
+			fmt.Fprintf(b, "\t%q -> %q [label=%q];\n", e.From, e.To, strings.Join(e.Privileges, ", "))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// buildAccessGraph mirrors the grants of grantDBAccess
+func (r *PostgresDBResource) buildAccessGraph(namePrefix string, props *PostgresDbProps) *AccessGraph {
+	g := &AccessGraph{}
+	database := fmt.Sprintf("database %s", props.Database)
+	schemaNode := func(schema string) string { return fmt.Sprintf("schema %s.%s", props.Database, schema) }
+	objectsNode := func(schema string, objects string) string {
+		return fmt.Sprintf("%s in %s.%s", objects, props.Database, schema)
+	}
+	schemas := []string{publicSchema}
+	schemaOwners := map[string]string{}
+	for _, schema := range props.Schemas {
+		schemas = append(schemas, schema.Name)
+		schemaOwners[schema.Name] = schema.Owner
+	}
+	owner := "postgres"
+	ownerPermission := props.ownerPermission()
+	for _, role := range props.DbRoles {
+		if role.Permission == ownerPermission {
+			owner = role.RoleName(namePrefix)
+		}
+	}
+
+	for _, role := range props.DbRoles {
+		roleName := role.RoleName(namePrefix)
+		switch role.Permission {
+		case ReadWrite, Owner:
+			if role.Permission != ownerPermission {
+				// the data of the owner role, see grantReadWriteAccess
+				g.AddPrivileges(roleName, database, "CONNECT", "TEMPORARY")
+				for _, schema := range schemas {
+					if role.grantsSchema(schema) {
+						g.AddPrivileges(roleName, schemaNode(schema), "USAGE")
+						g.AddPrivileges(roleName, objectsNode(schema, "all tables"), "SELECT", "INSERT", "UPDATE", "DELETE")
+						g.AddPrivileges(roleName, objectsNode(schema, "all sequences"), "USAGE", "SELECT", "UPDATE")
+					}
+				}
+				continue
+			}
+			if props.DatabaseOwner != "" {
+				g.AddPrivileges(props.DatabaseOwner, database, "OWNER")
+				g.AddPrivileges(roleName, database, "CONNECT", "TEMPORARY")
+			} else {
+				g.AddPrivileges(roleName, database, "OWNER")
+			}
+			for _, schema := range schemas {
+				if schemaOwners[schema] == "" {
+					g.AddPrivileges(roleName, schemaNode(schema), "OWNER")
+					continue
+				}
+				g.AddPrivileges(schemaOwners[schema], schemaNode(schema), "OWNER")
+				g.AddPrivileges(roleName, schemaNode(schema), "USAGE", "CREATE")
+				g.AddPrivileges(roleName, objectsNode(schema, "all tables"), "SELECT", "INSERT", "UPDATE", "DELETE")
+				g.AddPrivileges(roleName, objectsNode(schema, "all sequences"), "USAGE", "SELECT", "UPDATE")
+			}
+		case ReadOnly:
+			g.AddPrivileges(roleName, database, "CONNECT")
+			scopedTables := tablesBySchema(role.Tables)
+			for _, schema := range schemas {
+				if !role.grantsSchema(schema) {
+					continue
+				}
+				if len(role.Tables) > 0 {
+					tables, ok := scopedTables[schema]
+					if !ok {
+						continue
+					}
+					g.AddPrivileges(roleName, schemaNode(schema), "USAGE")
+					for _, table := range tables {
+						g.AddPrivileges(roleName, fmt.Sprintf("table %s.%s.%s", props.Database, schema, table), "SELECT")
+					}
+					continue
+				}
+				g.AddPrivileges(roleName, schemaNode(schema), "USAGE")
+				g.AddPrivileges(roleName, objectsNode(schema, "all tables"), "SELECT")
+				g.AddPrivileges(roleName, objectsNode(schema, "all sequences"), "SELECT")
+			}
+		case DDL:
+			g.AddMembership(roleName, owner)
+			g.AddPrivileges(roleName, database, "CONNECT", "CREATE", "TEMPORARY")
+			for _, schema := range schemas {
+				if role.grantsSchema(schema) {
+					g.AddPrivileges(roleName, schemaNode(schema), "USAGE", "CREATE")
+				}
+			}
+		case Custom:
+			if len(role.Privileges.Database) > 0 {
+				g.AddPrivileges(roleName, database, role.Privileges.Database...)
+			}
+			for _, schema := range schemas {
+				if !role.grantsSchema(schema) {
+					continue
+				}
+				if len(role.Privileges.Schema) > 0 {
+					g.AddPrivileges(roleName, schemaNode(schema), role.Privileges.Schema...)
+				}
+				if len(role.Privileges.Tables) > 0 {
+					g.AddPrivileges(roleName, objectsNode(schema, "all tables"), role.Privileges.Tables...)
+				}
+				if len(role.Privileges.Sequences) > 0 {
+					g.AddPrivileges(roleName, objectsNode(schema, "all sequences"), role.Privileges.Sequences...)
+				}
+			}
+		}
+	}
+	for _, role := range props.DbRoles {
+		roleName := role.RoleName(namePrefix)
+		for _, schema := range role.FunctionSchemas {
+			g.AddPrivileges(roleName, objectsNode(schema, "all functions"), "EXECUTE")
+		}
+	}
+	return g
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NAMEDATALEN-1, longer identifiers are silently truncated by postgres
+const maxIdentifierLength = 63
+
+var (
+	// the provider quotes the identifiers, but the names end up in
+	// connection strings, grants and the SQL of the migrations too
+	identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$-]*$`)
+	// role names can also be emails, e.g. for the IAM authenticated users
+	roleNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$.@-]*$`)
+)
+
+// reserved keywords of postgres, which can't be used unquoted
+var reservedKeywords = map[string]bool{}
+
+func init() {
+	for _, keyword := range strings.Fields(`all analyse analyze and any array as asc asymmetric both
+		case cast check collate column constraint create current_catalog current_date current_role
+		current_time current_timestamp current_user default deferrable desc distinct do else end
+		except false fetch for foreign from grant group having in initially intersect into lateral
+		leading limit localtime localtimestamp not null offset on only or order placing primary
+		references returning select session_user some symmetric system_user table then to trailing
+		true union unique user using variadic when where window with`) {
+		reservedKeywords[keyword] = true
+	}
+}
+
+func validateIdentifier(kind string, name string) error {
+	return validateName(kind, name, identifierRegex, "letters, digits and _ $ -")
+}
+
+func validateRoleName(name string) error {
+	if err := validateName("role", name, roleNameRegex, "letters, digits and _ $ . @ -"); err != nil {
+		return err
+	}
+	// reserved for the predefined roles, or special in GRANT
+	if strings.HasPrefix(strings.ToLower(name), "pg_") || strings.EqualFold(name, "public") || strings.EqualFold(name, "none") {
+		return fmt.Errorf("role name '%s' is reserved", name)
+	}
+	return nil
+}
+
+func validateName(kind string, name string, regex *regexp.Regexp, allowed string) error {
+	if len(name) > maxIdentifierLength {
+		return fmt.Errorf("%s name '%s' is %d bytes long, more than %d allowed", kind, name, len(name), maxIdentifierLength)
+	}
+	if !regex.MatchString(name) {
+		return fmt.Errorf("%s name '%s' is invalid, it must start with a letter or underscore and contain only %s", kind, name, allowed)
+	}
+	if reservedKeywords[strings.ToLower(name)] {
+		return fmt.Errorf("%s name '%s' is a reserved keyword", kind, name)
+	}
+	return nil
+}
+
+// validateIdentifiers checks the names of the database, its roles and
+// schemas, before any resource is registered.
+func (props *PostgresDbProps) validateIdentifiers(namePrefix string) error {
+	if err := validateIdentifier("database", props.Database); err != nil {
+		return err
+	}
+	roles := props.DbRoles
+	if len(roles) == 0 {
+		roles = []PostgresDbRoleProps{{Permission: ReadWrite}}
+	}
+	for _, role := range roles {
+		if err := validateRoleName(role.RoleName(namePrefix)); err != nil {
+			return err
+		}
+	}
+	if props.DatabaseOwner != "" {
+		if err := validateRoleName(props.DatabaseOwner); err != nil {
+			return err
+		}
+	}
+	for _, schema := range props.Schemas {
+		if err := validateIdentifier("schema", schema.Name); err != nil {
+			return err
+		}
+		if schema.Owner != "" {
+			if err := validateRoleName(schema.Owner); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
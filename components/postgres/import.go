@@ -0,0 +1,13 @@
+package postgres
+
+import "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+// withImport adopts the existing object of the given name (the import ID of
+// databases and roles) instead of creating it. It's a no-op once the
+// resource is in the state, so the flag can be left on.
+func withImport(importExisting bool, id string, opts ...pulumi.ResourceOption) []pulumi.ResourceOption {
+	if importExisting {
+		opts = append(opts, pulumi.Import(pulumi.ID(id)))
+	}
+	return opts
+}
@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const DefaultMonitoringUser = "monitoring"
+
+// predefined roles reading the stats, pg_monitor being all of them
+var monitoringRoles = map[string]bool{
+	"pg_monitor":           true,
+	"pg_read_all_stats":    true,
+	"pg_read_all_settings": true,
+	"pg_stat_scan_tables":  true,
+}
+
+type PostgresMonitoringUserProps struct {
+	// monitoring if not set
+	Username string `json:"username"`
+	// Predefined roles granted to the user, pg_monitor if not set
+	Roles []string `json:"roles"`
+	// Max concurrent connections of the agent, unlimited if not set
+	ConnectionLimit int `json:"connectionLimit"`
+	// Bump it to rotate the generated password
+	RotationTrigger string `json:"rotationTrigger"`
+	// Database the agent connects to, granted CONNECT if set
+	Database string `json:"database"`
+	// Endpoint of the server, stored with the creds
+	Host pulumi.StringInput `json:"-"`
+	Port int                `json:"-"`
+	// Optional store to expose the creds, e.g. to the Datadog agent
+	Store secret.SecretStore `json:"-"`
+	// <name>-<username> if not set
+	SecretName string `json:"-"`
+}
+
+type PostgresMonitoringUserResource struct {
+	pulumi.ResourceState
+
+	Role     *postgresql.Role
+	Password pulumi.StringOutput
+	SecretId pulumi.StringOutput
+	// Memberships & grants of the user, to be merged in the graph of the database
+	AccessGraph *AccessGraph
+}
+
+func (props *PostgresMonitoringUserProps) validate() error {
+	if props.Username == "" {
+		props.Username = DefaultMonitoringUser
+	}
+	if err := validateRoleName(props.Username); err != nil {
+		return err
+	}
+	if len(props.Roles) == 0 {
+		props.Roles = []string{"pg_monitor"}
+	}
+	for _, role := range props.Roles {
+		if !monitoringRoles[role] {
+			names := []string{}
+			for name := range monitoringRoles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("role %s of monitoring user %s isn't a monitoring role, expected one of [%s]", role, props.Username, strings.Join(names, ", "))
+		}
+	}
+	if props.Store != nil && props.Host == nil {
+		return fmt.Errorf("host is required to store the creds of monitoring user %s", props.Username)
+	}
+	return nil
+}
+
+func (r *PostgresMonitoringUserResource) provision(ctx *pulumi.Context, name string, props *PostgresMonitoringUserProps) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	resName := fmt.Sprintf("%s-%s", name, props.Username)
+	keepers := map[string]string{}
+	if props.RotationTrigger != "" {
+		keepers["rotationTrigger"] = props.RotationTrigger
+	}
+	password, err := utils.NewRotatingPassword(ctx, fmt.Sprintf("%s-password", resName), 16, keepers, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Password = password
+
+	// CREATE ROLE monitoring LOGIN PASSWORD '$PASSWORD' IN ROLE pg_monitor;
+	args := &postgresql.RoleArgs{
+		Name:     pulumi.String(props.Username),
+		Password: password,
+		Login:    pulumi.BoolPtr(true),
+		Roles:    pulumi.ToStringArray(props.Roles),
+	}
+	if err := (roleAttributes{connectionLimit: props.ConnectionLimit}).apply(args); err != nil {
+		return err
+	}
+	role, err := postgresql.NewRole(ctx, resName, args, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Role = role
+	r.AccessGraph = &AccessGraph{}
+	for _, member := range props.Roles {
+		r.AccessGraph.AddMembership(props.Username, member)
+	}
+
+	if props.Database != "" {
+		// GRANT CONNECT ON DATABASE $DB TO monitoring;
+		_, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-connectDatabase", resName), &postgresql.GrantArgs{
+			Database:   pulumi.String(props.Database),
+			ObjectType: pulumi.String("database"),
+			Privileges: pulumi.StringArray{pulumi.String("CONNECT")},
+			Role:       role.Name,
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.AccessGraph.AddPrivileges(props.Username, fmt.Sprintf("database %s", props.Database), "CONNECT")
+	}
+
+	if props.Store != nil {
+		if props.SecretName == "" {
+			props.SecretName = resName
+		}
+		creds := pulumi.StringMap{
+			"username": role.Name,
+			"password": pulumi.ToSecret(password).(pulumi.StringOutput),
+			"host":     props.Host,
+			"port":     pulumi.Sprintf("%d", props.Port),
+		}
+		if props.Database != "" {
+			creds["database"] = pulumi.String(props.Database)
+		}
+		secretId, err := props.Store.Store(ctx, props.SecretName, secret.DBCreds, creds, pulumi.Parent(r), pulumi.DependsOn([]pulumi.Resource{role}))
+		if err != nil {
+			return fmt.Errorf("failed to store creds of monitoring user %s: %w", props.Username, err)
+		}
+		r.SecretId = secretId
+	}
+	return nil
+}
+
+// NewPostgresMonitoringUser creates a login role granted the predefined
+// monitoring roles, for agents like Datadog or postgres_exporter.
+func NewPostgresMonitoringUser(ctx *pulumi.Context, name string, props PostgresMonitoringUserProps, opts ...pulumi.ResourceOption) (*PostgresMonitoringUserResource, error) {
+	resource := &PostgresMonitoringUserResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:monitoringuser", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+
+	outputs := pulumi.Map{
+		"username": resource.Role.Name,
+	}
+	if props.Store != nil {
+		outputs["secretId"] = resource.SecretId
+	}
+	ctx.RegisterResourceOutputs(resource, outputs)
+	return resource, nil
+}
+
+// MonitoringUserStore stores the creds of the monitoring user in a secret
+// tagged with its role, with ExportAsSecret
+func (e *CredsExporter) MonitoringUserStore(props *PostgresMonitoringUserProps) {
+	if !e.props.ExportAsSecret {
+		return
+	}
+	props.Store = secret.AWSSecretStore{Tags: e.Tags(props.Username)}
+	props.SecretName = fmt.Sprintf("pg-%s-monitoring", e.props.Database)
+}
+
+// ExportMonitoringUser exposes the creds of the monitoring user as
+// monitoringUser, only its secret with ExportAsSecret
+func (e *CredsExporter) ExportMonitoringUser(ctx *pulumi.Context, res *PostgresMonitoringUserResource, props PostgresMonitoringUserProps) error {
+	if e.props.ExportAsSecret {
+		e.AddSecret(secret.SecretName(secret.DBCreds, props.SecretName), res.SecretId, props.Username)
+		return utils.Export(ctx, utils.OutputReference, "monitoringUser", pulumi.StringMap{
+			"secretId": res.SecretId,
+		})
+	}
+	return utils.Export(ctx, utils.OutputCreds, "monitoringUser", e.roleCreds(res.Role.Name, res.Password, props.Database))
+}
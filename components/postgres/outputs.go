@@ -0,0 +1,57 @@
+package postgres
+
+import "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+// PostgresDBOutputs are the outputs of NewPostgresDatabase, registered as
+// the outputs of the component too.
+type PostgresDBOutputs struct {
+	Database pulumi.StringOutput
+	// Names of the DB roles, in the order of the props
+	Roles pulumi.StringArrayOutput
+	// Connection strings of the login roles keyed by role name, if Endpoint is set
+	DSNs         pulumi.StringMapOutput
+	CommentsSQL  string
+	BootstrapSQL string
+	Ready        pulumi.BoolOutput
+}
+
+func (o *PostgresDBOutputs) resourceOutputs() pulumi.Map {
+	return pulumi.Map{
+		"database":     o.Database,
+		"roles":        o.Roles,
+		"dsns":         o.DSNs,
+		"commentsSql":  pulumi.String(o.CommentsSQL),
+		"bootstrapSql": pulumi.String(o.BootstrapSQL),
+		"ready":        o.Ready,
+	}
+}
+
+// PostgresUsersOutputs are the outputs of NewPostgresUsers, registered as
+// the outputs of the component too. The failed users are left out.
+type PostgresUsersOutputs struct {
+	Usernames pulumi.StringArrayOutput
+	// Passwords keyed by username, secret. The IAM auth users have none.
+	Passwords pulumi.StringMapOutput
+	// Connection strings keyed by username, of the users with an Endpoint
+	DSNs        pulumi.StringMapOutput
+	CommentsSQL string
+	Ready       pulumi.BoolOutput
+}
+
+func (o *PostgresUsersOutputs) resourceOutputs() pulumi.Map {
+	return pulumi.Map{
+		"usernames":   o.Usernames,
+		"passwords":   o.Passwords,
+		"dsns":        o.DSNs,
+		"commentsSql": pulumi.String(o.CommentsSQL),
+		"ready":       o.Ready,
+	}
+}
+
+func toStringMapOutput(values map[string]pulumi.StringOutput) pulumi.StringMapOutput {
+	m := pulumi.StringMap{}
+	for key, value := range values {
+		m[key] = value
+	}
+	return m.ToStringMapOutput()
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// DefaultPayloadBuilder is the name of the builder used unless another one is picked
+const DefaultPayloadBuilder = "default"
+
+// PayloadUser is a provisioned user whose creds are exported.
+type PayloadUser struct {
+	Username pulumi.StringOutput
+	// Secret password of the user, unset for IamAuth users
+	Password pulumi.StringOutput
+	IamAuth  bool
+	// Password is only a verifier, which isn't exported
+	PasswordIsHashed bool
+	AllowedCidrs     []string
+	// Ready-made connection string, unset if the user has no endpoint
+	DSN pulumi.StringOutput
+}
+
+// PayloadProvider is the server the user connects to.
+type PayloadProvider struct {
+	Host pulumi.StringInput
+	Port int
+}
+
+// PayloadBuilder builds the creds payload of a user, i.e. the keys of the
+// exported secrets, outputs & helm values.
+type PayloadBuilder func(user PayloadUser, database string, provider PayloadProvider) pulumi.StringMapInput
+
+var (
+	payloadBuildersMu sync.RWMutex
+	payloadBuilders   = map[string]PayloadBuilder{
+		DefaultPayloadBuilder: defaultPayload,
+	}
+)
+
+// RegisterPayloadBuilder makes a custom payload builder selectable by name,
+// so orgs can add keys, rename fields or derive DSNs without forking the programs.
+func RegisterPayloadBuilder(name string, builder PayloadBuilder) error {
+	if name == "" {
+		return fmt.Errorf("payload builder name must not be empty")
+	}
+	if builder == nil {
+		return fmt.Errorf("payload builder '%s' must not be nil", name)
+	}
+	payloadBuildersMu.Lock()
+	defer payloadBuildersMu.Unlock()
+	if _, ok := payloadBuilders[name]; ok {
+		return fmt.Errorf("payload builder '%s' is already registered", name)
+	}
+	payloadBuilders[name] = builder
+	return nil
+}
+
+// MustRegisterPayloadBuilder is like RegisterPayloadBuilder but panics on error, for use in init().
+func MustRegisterPayloadBuilder(name string, builder PayloadBuilder) {
+	if err := RegisterPayloadBuilder(name, builder); err != nil {
+		panic(err)
+	}
+}
+
+func LookupPayloadBuilder(name string) (PayloadBuilder, bool) {
+	if name == "" {
+		name = DefaultPayloadBuilder
+	}
+	payloadBuildersMu.RLock()
+	defer payloadBuildersMu.RUnlock()
+	builder, ok := payloadBuilders[name]
+	return builder, ok
+}
+
+// DefaultPayload builds the payload of the db secret type, which custom
+// builders can extend instead of starting from scratch.
+func DefaultPayload(user PayloadUser, database string, provider PayloadProvider) pulumi.StringMap {
+	creds := pulumi.StringMap{
+		"username": user.Username,
+		"database": pulumi.String(database),
+		"host":     provider.Host,
+		"port":     pulumi.Sprintf("%d", provider.Port),
+	}
+	if user.IamAuth {
+		// the clients generate a token with `aws rds generate-db-auth-token`
+		creds["authentication"] = pulumi.String("iam")
+	} else if !user.PasswordIsHashed {
+		creds["password"] = pulumi.ToSecret(user.Password).(pulumi.StringOutput)
+	}
+	if len(user.AllowedCidrs) > 0 {
+		creds["allowedCidrs"] = pulumi.String(strings.Join(user.AllowedCidrs, ","))
+	}
+	if user.DSN != (pulumi.StringOutput{}) {
+		creds["dsn"] = user.DSN
+	}
+	return creds
+}
+
+func defaultPayload(user PayloadUser, database string, provider PayloadProvider) pulumi.StringMapInput {
+	return DefaultPayload(user, database, provider)
+}
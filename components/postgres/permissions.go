@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"fmt"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// PostgresPrivilegesProps is the privilege set of a custom role, applied on
+// the database and every schema of it.
+type PostgresPrivilegesProps struct {
+	// e.g. CONNECT, TEMPORARY
+	Database []string `json:"database"`
+	// e.g. USAGE
+	Schema []string `json:"schema"`
+	// e.g. SELECT, INSERT
+	Tables []string `json:"tables"`
+	// e.g. USAGE, SELECT
+	Sequences []string `json:"sequences"`
+}
+
+// roleSuffix is appended to the DB name to derive the role name
+func (props *PostgresDbRoleProps) roleSuffix() string {
+	if props.Permission == Custom {
+		return props.Name
+	}
+	return string(props.Permission)
+}
+
+// RoleName is the role granted the permission in the database
+func (props *PostgresDbRoleProps) RoleName(namePrefix string) string {
+	if props.ExistingRole != "" {
+		return props.ExistingRole
+	}
+	return fmt.Sprintf("%s-%s", namePrefix, props.roleSuffix())
+}
+
+// validateExistingRole rejects the attributes of an existing role, the stack
+// doesn't manage it
+func (props *PostgresDbRoleProps) validateExistingRole() error {
+	if props.ExistingRole == "" {
+		return nil
+	}
+	if props.ConnectionLimit != 0 || props.CreateDatabase || props.CreateRole || props.Superuser || props.Inherit != nil || len(props.Settings) > 0 {
+		return fmt.Errorf("existing role %s is managed outside of the stack, its attributes can't be set", props.ExistingRole)
+	}
+	return nil
+}
+
+// ownerPermission is the permission of the role owning the database: the
+// owner role if declared, else the read-write role
+func (props *PostgresDbProps) ownerPermission() PostgresUserPermission {
+	for _, role := range props.DbRoles {
+		if role.Permission == Owner {
+			return Owner
+		}
+	}
+	return ReadWrite
+}
+
+func (props *PostgresDbProps) validateRoles() error {
+	seen := map[string]bool{}
+	for _, role := range props.DbRoles {
+		suffix := role.roleSuffix()
+		if seen[suffix] {
+			return fmt.Errorf("role '%s' is declared more than once", suffix)
+		}
+		seen[suffix] = true
+		if err := role.validateExistingRole(); err != nil {
+			return err
+		}
+	}
+	ownerPermission := props.ownerPermission()
+	for _, role := range props.DbRoles {
+		if role.Permission == DDL && !seen[string(ownerPermission)] {
+			return fmt.Errorf("ddl role needs the %s role, which owns the data", ownerPermission)
+		}
+	}
+	return nil
+}
+
+// grantReadWriteAccess lets the read-write role read & write the data of the
+// owner role, without owning it: the objects created later by the migrations
+// are granted by default, but the app can't ALTER or DROP them.
+func (r *PostgresDBResource) grantReadWriteAccess(ctx *pulumi.Context, namePrefix string, roleName pulumi.StringOutput, owner pulumi.StringInput, userProps PostgresDbRoleProps, props *PostgresDbProps) error {
+	database := r.DB.Name
+	// GRANT CONNECT, TEMPORARY ON DATABASE $DB TO rwuser;
+	if err := r.newGrant(ctx, fmt.Sprintf("%s-readWriteDatabase", namePrefix), &postgresql.GrantArgs{
+		Database:   database,
+		ObjectType: pulumi.String("database"),
+		Privileges: pulumi.ToStringArray([]string{"CONNECT", "TEMPORARY"}),
+		Role:       roleName,
+	}); err != nil {
+		return err
+	}
+	for _, schema := range r.roleSchemaRefs(props, userProps) {
+		prefix := grantPrefix(namePrefix, schema.name)
+		// GRANT USAGE ON SCHEMA $SCHEMA TO rwuser;
+		if err := r.newGrant(ctx, fmt.Sprintf("%s-readWriteUsageSchema", prefix), &postgresql.GrantArgs{
+			Database:   database,
+			ObjectType: pulumi.String("schema"),
+			Privileges: pulumi.StringArray{pulumi.String("USAGE")},
+			Role:       roleName,
+			Schema:     schema.input,
+		}); err != nil {
+			return err
+		}
+		objects := []struct {
+			objectType string
+			grant      string
+			privileges []string
+		}{
+			{"table", "Tables", []string{"SELECT", "INSERT", "UPDATE", "DELETE"}},
+			{"sequence", "Sequences", []string{"USAGE", "SELECT", "UPDATE"}},
+		}
+		for _, object := range objects {
+			// GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA $SCHEMA TO rwuser;
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-readWrite%s", prefix, object.grant), &postgresql.GrantArgs{
+				Database:   database,
+				ObjectType: pulumi.String(object.objectType),
+				Objects:    pulumi.StringArray{},
+				Privileges: pulumi.ToStringArray(object.privileges),
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+			// ALTER DEFAULT PRIVILEGES FOR ROLE $OWNER IN SCHEMA $SCHEMA GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO rwuser;
+			if err := r.newDefaultPrivileges(ctx, fmt.Sprintf("%s-readWriteDefault%s", prefix, object.grant), &postgresql.DefaultPrivilegesArgs{
+				Database:   database,
+				Owner:      owner,
+				ObjectType: pulumi.String(object.objectType),
+				Privileges: pulumi.ToStringArray(object.privileges),
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// grantDDLAccess makes the ddl role a member of the owner role, so it can
+// ALTER and DROP the objects of the app. The migrations need to `SET ROLE`
+// to the owner before creating any object, so the app role keeps owning them.
+func (r *PostgresDBResource) grantDDLAccess(ctx *pulumi.Context, namePrefix string, roleName pulumi.StringOutput, owner pulumi.StringInput, userProps PostgresDbRoleProps, props *PostgresDbProps) error {
+	// GRANT rwuser TO ddluser;
+	roleGrantName := fmt.Sprintf("%s-ddlOwnerMembership", namePrefix)
+	roleGrant, err := postgresql.NewGrantRole(ctx, roleGrantName, &postgresql.GrantRoleArgs{
+		GrantRole: owner,
+		Role:      roleName,
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.GrantNames = append(r.GrantNames, roleGrantName)
+	r.RoleGrants = append(r.RoleGrants, roleGrant)
+	// GRANT CONNECT, CREATE, TEMPORARY ON DATABASE $DB TO ddluser;
+	if err := r.newGrant(ctx, fmt.Sprintf("%s-ddlDatabase", namePrefix), &postgresql.GrantArgs{
+		Database:   r.DB.Name,
+		ObjectType: pulumi.String("database"),
+		Privileges: pulumi.ToStringArray([]string{"CONNECT", "CREATE", "TEMPORARY"}),
+		Role:       roleName,
+	}); err != nil {
+		return err
+	}
+	for _, schema := range r.roleSchemaRefs(props, userProps) {
+		// GRANT USAGE, CREATE ON SCHEMA $SCHEMA TO ddluser;
+		if err := r.newGrant(ctx, fmt.Sprintf("%s-ddlSchema", grantPrefix(namePrefix, schema.name)), &postgresql.GrantArgs{
+			Database:   r.DB.Name,
+			ObjectType: pulumi.String("schema"),
+			Privileges: pulumi.ToStringArray([]string{"USAGE", "CREATE"}),
+			Role:       roleName,
+			Schema:     schema.input,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresDBResource) grantCustomAccess(ctx *pulumi.Context, namePrefix string, roleName pulumi.StringOutput, owner pulumi.StringInput, userProps PostgresDbRoleProps, props *PostgresDbProps) error {
+	privileges := userProps.Privileges
+	rolePrefix := fmt.Sprintf("%s-%s", namePrefix, userProps.Name)
+	if len(privileges.Database) > 0 {
+		if err := r.newGrant(ctx, fmt.Sprintf("%s-database", rolePrefix), &postgresql.GrantArgs{
+			Database:   r.DB.Name,
+			ObjectType: pulumi.String("database"),
+			Privileges: pulumi.ToStringArray(privileges.Database),
+			Role:       roleName,
+		}); err != nil {
+			return err
+		}
+	}
+	for _, schema := range r.roleSchemaRefs(props, userProps) {
+		prefix := grantPrefix(rolePrefix, schema.name)
+		if len(privileges.Schema) > 0 {
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-schema", prefix), &postgresql.GrantArgs{
+				Database:   r.DB.Name,
+				ObjectType: pulumi.String("schema"),
+				Privileges: pulumi.ToStringArray(privileges.Schema),
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+		}
+		objects := []struct {
+			objectType string
+			privileges []string
+		}{{"table", privileges.Tables}, {"sequence", privileges.Sequences}}
+		for _, object := range objects {
+			objectType, objectPrivileges := object.objectType, object.privileges
+			if len(objectPrivileges) == 0 {
+				continue
+			}
+			if err := r.newGrant(ctx, fmt.Sprintf("%s-%ss", prefix, objectType), &postgresql.GrantArgs{
+				Database:   r.DB.Name,
+				ObjectType: pulumi.String(objectType),
+				Objects:    pulumi.StringArray{},
+				Privileges: pulumi.ToStringArray(objectPrivileges),
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+			if userProps.SkipDefaultPrivileges {
+				continue
+			}
+			if err := r.newDefaultPrivileges(ctx, fmt.Sprintf("%s-default%ss", prefix, objectType), &postgresql.DefaultPrivilegesArgs{
+				Database:   r.DB.Name,
+				Owner:      owner,
+				ObjectType: pulumi.String(objectType),
+				Privileges: pulumi.ToStringArray(objectPrivileges),
+				Role:       roleName,
+				Schema:     schema.input,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"fmt"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	pgBouncerSchema      = "pgbouncer"
+	pgBouncerAuthFunc    = "get_auth"
+	defaultPgBouncerUser = "pgbouncer"
+)
+
+// PgBouncerAuthProps creates the lookup function of pgbouncer auth_query:
+//
+//	auth_user = pgbouncer
+//	auth_query = SELECT usename, passwd FROM pgbouncer.get_auth($1)
+//
+// The function reads pg_shadow, so the provider needs to connect as a real
+// superuser, which owns it.
+type PgBouncerAuthProps struct {
+	// Lookup role of the pooler, pgbouncer if not set
+	Username string `json:"username"`
+	// Set if the role is created already, e.g. by the stack of another
+	// database of the same server. Only the function & grants are created.
+	SkipRole bool `json:"skipRole"`
+}
+
+func (r *PostgresDBResource) provisionPgBouncerAuth(ctx *pulumi.Context, namePrefix string, props *PgBouncerAuthProps) error {
+	if props.Username == "" {
+		props.Username = defaultPgBouncerUser
+	}
+	var username pulumi.StringInput = pulumi.String(props.Username)
+	if !props.SkipRole {
+		password, err := utils.NewRandomPassword(ctx, fmt.Sprintf("%s-pgbouncer-password", namePrefix), 16, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		// CREATE ROLE pgbouncer LOGIN PASSWORD '$PASSWORD';
+		role, err := postgresql.NewRole(ctx, fmt.Sprintf("%s-pgbouncer", namePrefix), &postgresql.RoleArgs{
+			Name:     pulumi.String(props.Username),
+			Password: password,
+			Login:    pulumi.BoolPtr(true),
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.RoleNames = append(r.RoleNames, props.Username)
+		r.PgBouncerRole = role
+		username = role.Name
+	}
+
+	// CREATE SCHEMA pgbouncer;
+	schema, err := postgresql.NewSchema(ctx, fmt.Sprintf("%s-schema-pgbouncer", namePrefix), &postgresql.SchemaArgs{
+		Database: r.DB.Name,
+		Name:     pulumi.String(pgBouncerSchema),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	// CREATE FUNCTION pgbouncer.get_auth(uname TEXT) RETURNS TABLE(usename name, passwd text)
+	// LANGUAGE sql SECURITY DEFINER AS $$ SELECT usename, passwd FROM pg_catalog.pg_shadow WHERE usename = uname; $$;
+	function, err := postgresql.NewFunction(ctx, fmt.Sprintf("%s-pgbouncer-get_auth", namePrefix), &postgresql.FunctionArgs{
+		Database: r.DB.Name,
+		Schema:   schema.Name,
+		Name:     pulumi.String(pgBouncerAuthFunc),
+		Args: postgresql.FunctionArgArray{
+			postgresql.FunctionArgArgs{Name: pulumi.String("uname"), Type: pulumi.String("text")},
+			postgresql.FunctionArgArgs{Name: pulumi.String("usename"), Type: pulumi.String("name"), Mode: pulumi.String("OUT")},
+			postgresql.FunctionArgArgs{Name: pulumi.String("passwd"), Type: pulumi.String("text"), Mode: pulumi.String("OUT")},
+		},
+		Returns:         pulumi.String("record"),
+		Language:        pulumi.String("sql"),
+		SecurityDefiner: pulumi.BoolPtr(true),
+		Body:            pulumi.String("SELECT usename, passwd FROM pg_catalog.pg_shadow WHERE usename = uname;"),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.PgBouncerAuthFunction = function
+
+	functionOpts := pulumi.DependsOn([]pulumi.Resource{function})
+	// REVOKE ALL ON FUNCTION pgbouncer.get_auth FROM PUBLIC;
+	if err := r.newGrant(ctx, fmt.Sprintf("%s-pgbouncer-revokePublic", namePrefix), &postgresql.GrantArgs{
+		Database:   r.DB.Name,
+		ObjectType: pulumi.String("function"),
+		Objects:    pulumi.StringArray{pulumi.String(pgBouncerAuthFunc)},
+		Privileges: pulumi.StringArray{},
+		Role:       pulumi.String("public"),
+		Schema:     schema.Name,
+	}, functionOpts); err != nil {
+		return err
+	}
+	// GRANT EXECUTE ON FUNCTION pgbouncer.get_auth TO pgbouncer;
+	if err := r.newGrant(ctx, fmt.Sprintf("%s-pgbouncer-execute", namePrefix), &postgresql.GrantArgs{
+		Database:   r.DB.Name,
+		ObjectType: pulumi.String("function"),
+		Objects:    pulumi.StringArray{pulumi.String(pgBouncerAuthFunc)},
+		Privileges: pulumi.StringArray{pulumi.String("EXECUTE")},
+		Role:       username,
+		Schema:     schema.Name,
+	}, functionOpts); err != nil {
+		return err
+	}
+	// GRANT USAGE ON SCHEMA pgbouncer TO pgbouncer;
+	if err := r.newGrant(ctx, fmt.Sprintf("%s-pgbouncer-usageSchema", namePrefix), &postgresql.GrantArgs{
+		Database:   r.DB.Name,
+		ObjectType: pulumi.String("schema"),
+		Privileges: pulumi.StringArray{pulumi.String("USAGE")},
+		Role:       username,
+		Schema:     schema.Name,
+	}); err != nil {
+		return err
+	}
+	// GRANT CONNECT ON DATABASE $DB TO pgbouncer;
+	return r.newGrant(ctx, fmt.Sprintf("%s-pgbouncer-connectDatabase", namePrefix), &postgresql.GrantArgs{
+		Database:   r.DB.Name,
+		ObjectType: pulumi.String("database"),
+		Privileges: pulumi.StringArray{pulumi.String("CONNECT")},
+		Role:       username,
+	})
+}
+
+// ExportPgBouncerAuth exposes the creds of the lookup role of the database,
+// for the auth_user of the pooler, if the component created it
+func (e *CredsExporter) ExportPgBouncerAuth(ctx *pulumi.Context, dbRes *PostgresDBResource, username string) error {
+	role := dbRes.PgBouncerRole
+	if role == nil {
+		return nil
+	}
+	creds := e.roleCreds(role.Name, role.Password.Elem().ToStringOutput(), e.props.Database)
+	if dsn, ok := dbRes.DSNs[username]; ok {
+		creds["dsn"] = dsn
+	}
+	if !e.props.ExportAsSecret {
+		return utils.Export(ctx, utils.OutputCreds, "pgbouncerAuth", creds)
+	}
+	secretRes, err := e.newSecret(ctx, secret.AWSSecretProps{
+		Name:         fmt.Sprintf("pg-%s-pgbouncer", e.props.Database),
+		Type:         secret.DBCreds,
+		InitialValue: afterReady(pulumi.All(dbRes.Ready), creds),
+	}, username)
+	if err != nil {
+		return fmt.Errorf("failed to create secret for the pgbouncer auth user: %w", err)
+	}
+	return utils.Export(ctx, utils.OutputReference, "pgbouncerAuth", pulumi.StringMap{
+		"secretId": secretRes.Secret.ID(),
+	})
+}
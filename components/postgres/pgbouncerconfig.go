@@ -0,0 +1,254 @@
+package postgres
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-random/sdk/v4/go/random"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	PgBouncerAuthMd5   = "md5"
+	PgBouncerAuthScram = "scram-sha-256"
+	// the passwords are written as is, the pooler can then log into the
+	// server whatever its password_encryption
+	PgBouncerAuthPlain = "plain"
+
+	// same as the server, see scram_iterations
+	scramIterations = 4096
+)
+
+type PgBouncerUser struct {
+	Username string
+	Password pulumi.StringInput
+	// Password is already a verifier, written as is if it matches the auth type
+	PasswordIsHashed bool
+}
+
+// validateVerifier checks the password is a SCRAM-SHA-256 or md5 verifier,
+// the formats postgres stores as is (see pg_authid.rolpassword)
+func validateVerifier(verifier string) error {
+	if strings.HasPrefix(verifier, "md5") {
+		if _, err := hex.DecodeString(verifier[3:]); err != nil || len(verifier) != 35 {
+			return fmt.Errorf("invalid md5 verifier, expected md5 followed by 32 hex digits")
+		}
+		return nil
+	}
+	// SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey>
+	if rest, ok := strings.CutPrefix(verifier, "SCRAM-SHA-256$"); ok {
+		if parts := strings.Split(rest, "$"); len(parts) == 2 && strings.Count(parts[0], ":") == 1 && strings.Count(parts[1], ":") == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid verifier, expected SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey> or md5<hash>")
+}
+
+// PgBouncerConfigProps renders the userlist.txt and the [databases] entry of
+// a pooler in front of the database:
+//
+//	[databases]
+//	billing = host=db.internal port=5432 dbname=billing pool_mode=transaction
+//
+//	[pgbouncer]
+//	auth_type = scram-sha-256
+//	auth_file = /etc/pgbouncer/userlist.txt
+type PgBouncerConfigProps struct {
+	// md5, scram-sha-256 or plain, scram-sha-256 if not set
+	AuthType string `json:"authType"`
+	// pool_mode of the database, e.g. transaction. The one of the pooler if not set
+	PoolMode string `json:"poolMode"`
+	// pool_size of the database, the default_pool_size of the pooler if not set
+	PoolSize int `json:"poolSize"`
+	// Database the pool connects to, also the name of the pool
+	Database string             `json:"-"`
+	Host     pulumi.StringInput `json:"-"`
+	Port     int                `json:"-"`
+	// Users of the userlist, the ones without password (e.g. IAM auth) are left out
+	Users []PgBouncerUser `json:"-"`
+}
+
+type PgBouncerConfigResource struct {
+	pulumi.ResourceState
+
+	// Content of userlist.txt, a secret
+	Userlist pulumi.StringOutput
+	// Entry of the database in the [databases] section
+	Databases pulumi.StringOutput
+}
+
+func (props *PgBouncerConfigProps) validate() error {
+	switch props.AuthType {
+	case "":
+		props.AuthType = PgBouncerAuthScram
+	case PgBouncerAuthMd5, PgBouncerAuthScram, PgBouncerAuthPlain:
+	default:
+		return fmt.Errorf("invalid pgbouncer auth type '%s', expected %s, %s or %s", props.AuthType, PgBouncerAuthMd5, PgBouncerAuthScram, PgBouncerAuthPlain)
+	}
+	switch props.PoolMode {
+	case "", "session", "transaction", "statement":
+	default:
+		return fmt.Errorf("invalid pgbouncer pool mode '%s', expected session, transaction or statement", props.PoolMode)
+	}
+	if props.PoolSize < 0 {
+		return fmt.Errorf("pgbouncer pool size can't be negative")
+	}
+	return nil
+}
+
+// md5Secret is the md5 password hash of postgres, i.e. of the password salted with the username
+func md5Secret(username string, password string) string {
+	sum := md5.Sum([]byte(password + username))
+	return "md5" + hex.EncodeToString(sum[:])
+}
+
+func hmacSha256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// scramSecret is the SCRAM-SHA-256 verifier of postgres (RFC 5803 format):
+// SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey>
+func scramSecret(password string, salt []byte) string {
+	salted := pbkdf2.Key([]byte(password), salt, scramIterations, sha256.Size, sha256.New)
+	storedKey := sha256.Sum256(hmacSha256(salted, "Client Key"))
+	serverKey := hmacSha256(salted, "Server Key")
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", scramIterations,
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(storedKey[:]),
+		base64.StdEncoding.EncodeToString(serverKey))
+}
+
+// quoteUserlist quotes a field of userlist.txt, double quotes are doubled
+func quoteUserlist(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+// userlistEntry renders the line of a user, the salt is only used by scram-sha-256
+func (props *PgBouncerConfigProps) userlistEntry(username string, password string, hashed bool, salt string) (string, error) {
+	if hashed {
+		// the plaintext is unknown, only a verifier of the auth type will do
+		if props.AuthType == PgBouncerAuthPlain ||
+			(props.AuthType == PgBouncerAuthMd5) != strings.HasPrefix(password, "md5") {
+			return "", fmt.Errorf("hashed password of %s can't be used with pgbouncer auth type %s", username, props.AuthType)
+		}
+		return fmt.Sprintf("%s %s", quoteUserlist(username), quoteUserlist(password)), nil
+	}
+	secret := password
+	switch props.AuthType {
+	case PgBouncerAuthMd5:
+		secret = md5Secret(username, password)
+	case PgBouncerAuthScram:
+		saltBytes, err := base64.StdEncoding.DecodeString(salt)
+		if err != nil {
+			return "", fmt.Errorf("invalid scram salt of %s: %w", username, err)
+		}
+		secret = scramSecret(password, saltBytes)
+	}
+	return fmt.Sprintf("%s %s", quoteUserlist(username), quoteUserlist(secret)), nil
+}
+
+func (r *PgBouncerConfigResource) provision(ctx *pulumi.Context, name string, props *PgBouncerConfigProps) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	entries := pulumi.StringArray{}
+	for _, user := range props.Users {
+		if user.Password == nil {
+			continue
+		}
+		var salt pulumi.StringInput = pulumi.String("")
+		if props.AuthType == PgBouncerAuthScram && !user.PasswordIsHashed {
+			// kept in the state, so the verifier doesn't change on every deploy
+			saltRes, err := random.NewRandomBytes(ctx, fmt.Sprintf("%s-%s-scram-salt", name, user.Username), &random.RandomBytesArgs{
+				Length: pulumi.Int(16),
+			}, pulumi.Parent(r))
+			if err != nil {
+				return err
+			}
+			salt = saltRes.Base64
+		}
+		username, hashed := user.Username, user.PasswordIsHashed
+		entry := pulumi.All(user.Password, salt).ApplyT(func(args []interface{}) (string, error) {
+			return props.userlistEntry(username, args[0].(string), hashed, args[1].(string))
+		}).(pulumi.StringOutput)
+		entries = append(entries, entry)
+	}
+	r.Userlist = pulumi.ToSecret(entries.ToStringArrayOutput().ApplyT(func(lines []string) string {
+		if len(lines) == 0 {
+			return ""
+		}
+		return strings.Join(lines, "\n") + "\n"
+	})).(pulumi.StringOutput)
+
+	options := ""
+	if props.PoolMode != "" {
+		options += fmt.Sprintf(" pool_mode=%s", props.PoolMode)
+	}
+	if props.PoolSize > 0 {
+		options += fmt.Sprintf(" pool_size=%d", props.PoolSize)
+	}
+	r.Databases = pulumi.Sprintf("%s = host=%s port=%d dbname=%s%s", props.Database, props.Host, props.Port, props.Database, options)
+	return nil
+}
+
+// NewPgBouncerConfig renders the pooler config of the users provisioned in the
+// stack. The pooler authenticates the clients with the userlist, and logs
+// into the server with the same password.
+func NewPgBouncerConfig(ctx *pulumi.Context, name string, props PgBouncerConfigProps, opts ...pulumi.ResourceOption) (*PgBouncerConfigResource, error) {
+	resource := &PgBouncerConfigResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:pgbouncerconfig", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"userlist":  resource.Userlist,
+		"databases": resource.Databases,
+	})
+	return resource, nil
+}
+
+// ExportPgBouncerConfig renders the pooler config of the users, for the
+// auth_file of the pooler, and exports it as pgbouncerConfig, in a secret
+// once ready resolves with ExportAsSecret.
+func (e *CredsExporter) ExportPgBouncerConfig(ctx *pulumi.Context, props PgBouncerConfigProps, ready pulumi.ArrayOutput) error {
+	props.Database = e.props.Database
+	props.Host = e.props.Provider.Host
+	props.Port = e.props.Provider.Port
+	res, err := NewPgBouncerConfig(ctx, e.props.Database, props)
+	if err != nil {
+		return err
+	}
+	if !e.props.ExportAsSecret {
+		return utils.Export(ctx, utils.OutputCreds, "pgbouncerConfig", pulumi.StringMap{
+			"userlist":  res.Userlist,
+			"databases": res.Databases,
+		})
+	}
+	secretRes, err := e.newSecret(ctx, secret.AWSSecretProps{
+		Name: fmt.Sprintf("pg-%s-pgbouncer-config", e.props.Database),
+		Type: secret.PgBouncerConfig,
+		InitialValue: afterReady(ready, pulumi.StringMap{
+			"userlist.txt": res.Userlist,
+			"databases":    res.Databases,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create secret for the pgbouncer config: %w", err)
+	}
+	return utils.Export(ctx, utils.OutputReference, "pgbouncerConfig", pulumi.StringMap{
+		"secretId": secretRes.Secret.ID(),
+	})
+}
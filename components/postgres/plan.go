@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/outputs"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+// Plan lists the computed names of the access to the database, known in
+// preview already. The users are its login roles, the secrets the ones the
+// exporter created.
+func (e *CredsExporter) Plan(dbRes *PostgresDBResource, users []string) outputs.PlanOutput {
+	return outputs.PlanOutput{
+		Database:    e.props.Database,
+		Roles:       dbRes.RoleNames,
+		Users:       users,
+		Grants:      dbRes.GrantNames,
+		Secrets:     e.SecretNames,
+		RoleSecrets: e.RoleSecrets,
+	}
+}
+
+// ExportPlan exports the plan as plan, so the reviewers can check the names
+// against the naming conventions, and db-decommission reads it back.
+func ExportPlan(ctx *pulumi.Context, plan outputs.PlanOutput) error {
+	return utils.Export(ctx, utils.OutputReference, "plan", pulumi.Map{
+		"database":    pulumi.String(plan.Database),
+		"roles":       pulumi.ToStringArray(plan.Roles),
+		"users":       pulumi.ToStringArray(plan.Users),
+		"grants":      pulumi.ToStringArray(plan.Grants),
+		"secrets":     pulumi.ToStringArray(plan.Secrets),
+		"roleSecrets": pulumi.ToStringMap(plan.RoleSecrets),
+	})
+}
+
+// ExportAccessChangelog diffs the plan with the one of the last deployment,
+// read from the stack itself, for the security team to be notified of.
+func ExportAccessChangelog(ctx *pulumi.Context, current outputs.PlanOutput) error {
+	stack := fmt.Sprintf("%s/%s/%s", ctx.Organization(), ctx.Project(), ctx.Stack())
+	ref, err := pulumi.NewStackReference(ctx, "previous-deployment", &pulumi.StackReferenceArgs{
+		Name: pulumi.String(stack),
+	})
+	if err != nil {
+		return err
+	}
+	changelog := ref.GetOutput(pulumi.String("plan")).ApplyT(func(value interface{}) (map[string]interface{}, error) {
+		previous, err := outputs.ParsePlan(value)
+		if err != nil {
+			return nil, err
+		}
+		changes := outputs.DiffPlans(previous, current)
+		return map[string]interface{}{
+			"added":   changes.Added.ToMap(),
+			"removed": changes.Removed.ToMap(),
+			"changed": changes.Changed,
+		}, nil
+	}).(pulumi.MapOutput)
+	return utils.Export(ctx, utils.OutputReference, "accessChangelog", changelog)
+}
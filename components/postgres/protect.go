@@ -0,0 +1,16 @@
+package postgres
+
+import "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+// withProtection guards the object against a stack destroy. Protected ones
+// fail the destroy until the flag is turned off, the retained ones are only
+// removed from the state and kept in the server.
+func withProtection(protect bool, retainOnDelete bool, opts ...pulumi.ResourceOption) []pulumi.ResourceOption {
+	if protect {
+		opts = append(opts, pulumi.Protect(true))
+	}
+	if retainOnDelete {
+		opts = append(opts, pulumi.RetainOnDelete(true))
+	}
+	return opts
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+)
+
+// ProviderConfig is the provider namespace of the stacks managing a server
+// as its superuser, read with utils.ExtractConfig(ctx, "provider", ...).
+type ProviderConfig struct {
+	// Required, unless read from superuserSecretArn
+	Host              pulumi.StringInput `json:"host"`
+	SuperuserName     pulumi.StringInput `json:"superuserName"`
+	SuperuserPassword pulumi.StringInput `secret:"superuserPassword"`
+	// Secret (e.g. created by db-superuser-secret) with the superuser creds,
+	// instead of superuserName & superuserPassword
+	SuperuserSecretId string `json:"superuserSecretId"`
+	// Secret (e.g. managed by RDS) with the host & port too, read before
+	// the provider is configured. The host & port of the config win if set.
+	SuperuserSecretArn string `json:"superuserSecretArn"`
+	// Required, unless read from superuserSecretArn
+	Port       int  `json:"port"`
+	DisableSSL bool `json:"disableSSL"`
+}
+
+// LoadSuperuser resolves the superuser creds, either from the stack config or
+// from the restricted secret.
+func (cfg *ProviderConfig) LoadSuperuser(ctx *pulumi.Context) error {
+	if cfg.SuperuserSecretArn != "" {
+		if cfg.SuperuserSecretId != "" {
+			return fmt.Errorf("only one of provider:superuserSecretId and provider:superuserSecretArn can be set")
+		}
+		return cfg.readSuperuserSecret(ctx)
+	}
+	if cfg.Host == nil || cfg.Port == 0 {
+		return fmt.Errorf("provider:host and provider:port are required, unless provider:superuserSecretArn is set")
+	}
+	if cfg.SuperuserSecretId == "" {
+		for _, key := range []string{"provider:superuserName", "provider:superuserPassword"} {
+			if _, ok := ctx.GetConfig(key); !ok {
+				return fmt.Errorf("%s is required, unless provider:superuserSecretId is set", key)
+			}
+		}
+		return nil
+	}
+	creds := secret.LookupDBCreds(ctx, cfg.SuperuserSecretId)
+	cfg.SuperuserName = creds.MapIndex(pulumi.String("username"))
+	cfg.SuperuserPassword = creds.MapIndex(pulumi.String("password"))
+	return nil
+}
+
+// readSuperuserSecret resolves the whole endpoint from the secret, so none of
+// it lives in the stack config
+func (cfg *ProviderConfig) readSuperuserSecret(ctx *pulumi.Context) error {
+	creds, err := secret.ReadDBCreds(ctx, cfg.SuperuserSecretArn)
+	if err != nil {
+		return err
+	}
+	if creds["password"] == "" {
+		return fmt.Errorf("secret %s has no superuser password", cfg.SuperuserSecretArn)
+	}
+	cfg.SuperuserName = pulumi.String(creds["username"])
+	cfg.SuperuserPassword = pulumi.ToSecret(pulumi.String(creds["password"])).(pulumi.StringOutput)
+	if cfg.Host == nil {
+		if creds["host"] == "" {
+			return fmt.Errorf("secret %s has no host, set provider:host", cfg.SuperuserSecretArn)
+		}
+		cfg.Host = pulumi.String(creds["host"])
+	}
+	if cfg.Port == 0 {
+		if cfg.Port, err = strconv.Atoi(creds["port"]); err != nil || cfg.Port <= 0 {
+			return fmt.Errorf("secret %s has no valid port, set provider:port", cfg.SuperuserSecretArn)
+		}
+	}
+	return nil
+}
+
+func (cfg *ProviderConfig) ProviderArgs() *postgresql.ProviderArgs {
+	providerArgs := &postgresql.ProviderArgs{
+		Host:     cfg.Host,
+		Username: cfg.SuperuserName,
+		Password: cfg.SuperuserPassword,
+		Port:     pulumi.IntPtr(cfg.Port),
+	}
+	if cfg.DisableSSL {
+		providerArgs.Sslmode = pulumi.String("disable")
+	}
+	return providerArgs
+}
+
+// SQLConnection is how psql connects as the superuser of the provider, to run
+// what the provider doesn't model
+func (cfg *ProviderConfig) SQLConnection() *SQLConnection {
+	conn := &SQLConnection{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Username: cfg.SuperuserName,
+		Password: cfg.SuperuserPassword,
+	}
+	if cfg.DisableSSL {
+		conn.SslMode = "disable"
+	}
+	return conn
+}
+
+// Endpoint is where the users of the database connect to, for their DSNs
+func (cfg *ProviderConfig) Endpoint(database string) *ConnectionEndpoint {
+	endpoint := &ConnectionEndpoint{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Database: database,
+	}
+	if cfg.DisableSSL {
+		endpoint.SslMode = "disable"
+	}
+	return endpoint
+}
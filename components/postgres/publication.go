@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const defaultReplicationPlugin = "pgoutput"
+
+var replicationSlotPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+type PostgresPublicationProps struct {
+	Database string `json:"database"`
+	// Published tables, as "table" in public or "schema.table"
+	Tables []string `json:"tables"`
+	// Replication role the CDC pipeline logs in with
+	Username string `json:"username"`
+	// Generated if not set
+	Password pulumi.StringInput `json:"password"`
+	// Logical decoding plugin of the slot, defaults to pgoutput
+	Plugin string `json:"plugin"`
+	// Name of the publication and the slot, defaults to the component name
+	// with dashes replaced by underscores
+	SlotName string `json:"slotName"`
+	// RDS doesn't allow the REPLICATION attribute, the role is granted
+	// rds_replication instead
+	RDS bool `json:"rds"`
+}
+
+type PostgresPublicationResource struct {
+	pulumi.ResourceState
+
+	Role        *postgresql.Role
+	Publication *postgresql.Publication
+	Slot        *postgresql.ReplicationSlot
+	Grants      []*postgresql.Grant
+}
+
+func (props *PostgresPublicationProps) fillRuntimeInputs(ctx *pulumi.Context, name string, res *PostgresPublicationResource) (err error) {
+	if props.Database == "" || props.Username == "" {
+		return fmt.Errorf("database and username are required for the publication")
+	}
+	if len(props.Tables) == 0 {
+		return fmt.Errorf("at least one table is required for the publication")
+	}
+	tables := make([]string, len(props.Tables))
+	for i, table := range props.Tables {
+		if !strings.Contains(table, ".") {
+			table = fmt.Sprintf("%s.%s", publicSchema, table)
+		}
+		tables[i] = table
+	}
+	// the provider expects them in alphabetical order
+	sort.Strings(tables)
+	props.Tables = tables
+	if props.Plugin == "" {
+		props.Plugin = defaultReplicationPlugin
+	}
+	if props.SlotName == "" {
+		props.SlotName = strings.ReplaceAll(name, "-", "_")
+	}
+	if !replicationSlotPattern.MatchString(props.SlotName) {
+		return fmt.Errorf("invalid replication slot '%s', only lowercase letters, numbers and underscores are allowed", props.SlotName)
+	}
+	if props.Password == nil {
+		props.Password, err = utils.NewRandomPassword(
+			ctx, fmt.Sprintf("%s-%s-password", name, props.Username), 16,
+			pulumi.Parent(res), renamedFrom(fmt.Sprintf("%s-password", props.Username)))
+	}
+	return
+}
+
+func (r *PostgresPublicationResource) provision(ctx *pulumi.Context, name string, props *PostgresPublicationProps) error {
+	if err := props.fillRuntimeInputs(ctx, name, r); err != nil {
+		return err
+	}
+	// CREATE ROLE $USER LOGIN REPLICATION PASSWORD '$PASSWORD';
+	roleArgs := &postgresql.RoleArgs{
+		Name:     pulumi.String(props.Username),
+		Password: props.Password,
+		Login:    pulumi.BoolPtr(true),
+	}
+	if props.RDS {
+		// GRANT rds_replication TO $USER;
+		roleArgs.Roles = pulumi.StringArray{pulumi.String("rds_replication")}
+	} else {
+		roleArgs.Replication = pulumi.BoolPtr(true)
+	}
+	role, err := postgresql.NewRole(ctx, fmt.Sprintf("%s-%s", name, props.Username), roleArgs, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Role = role
+
+	// the initial snapshot of the CDC pipeline reads the published tables
+	for schema, tables := range tablesBySchema(props.Tables) {
+		// GRANT SELECT ON $TABLES TO $USER;
+		grant, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-%s-select", name, schema), &postgresql.GrantArgs{
+			Database:   pulumi.String(props.Database),
+			ObjectType: pulumi.String("table"),
+			Objects:    pulumi.ToStringArray(tables),
+			Privileges: pulumi.StringArray{pulumi.String("SELECT")},
+			Role:       role.Name,
+			Schema:     pulumi.String(schema),
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.Grants = append(r.Grants, grant)
+	}
+
+	// CREATE PUBLICATION $NAME FOR TABLE $TABLES;
+	publication, err := postgresql.NewPublication(ctx, fmt.Sprintf("%s-publication", name), &postgresql.PublicationArgs{
+		Database: pulumi.String(props.Database),
+		Name:     pulumi.String(props.SlotName),
+		Tables:   pulumi.ToStringArray(props.Tables),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Publication = publication
+
+	// SELECT pg_create_logical_replication_slot('$SLOT', '$PLUGIN');
+	slot, err := postgresql.NewReplicationSlot(ctx, fmt.Sprintf("%s-slot", name), &postgresql.ReplicationSlotArgs{
+		Database: pulumi.String(props.Database),
+		Name:     pulumi.String(props.SlotName),
+		Plugin:   pulumi.String(props.Plugin),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Slot = slot
+	return nil
+}
+
+// NewPostgresPublication provisions the source side of logical replication
+// (role, publication & slot), for CDC pipelines like Debezium or DMS. The
+// server needs wal_level=logical (rds.logical_replication=1 on RDS).
+func NewPostgresPublication(ctx *pulumi.Context, name string, props PostgresPublicationProps, opts ...pulumi.ResourceOption) (*PostgresPublicationResource, error) {
+	resource := &PostgresPublicationResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:publication", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"publication": resource.Publication.Name,
+		"slot":        resource.Slot.Name,
+		"username":    resource.Role.Name,
+		"password":    resource.Role.Password,
+	})
+	return resource, nil
+}
+
+// ExportPublication exposes the publication, its slot and the creds of its
+// replication role, which the subscribers build their connection string from
+func (e *CredsExporter) ExportPublication(ctx *pulumi.Context, name string, res *PostgresPublicationResource, username string) error {
+	outputName := fmt.Sprintf("publication-%s", name)
+	if !e.props.ExportAsSecret {
+		return utils.Export(ctx, utils.OutputCreds, outputName, pulumi.Map{
+			"publication": res.Publication.Name,
+			"slot":        res.Slot.Name,
+			"username":    res.Role.Name,
+			"password":    pulumi.ToSecret(res.Role.Password),
+			"host":        e.props.Provider.Host,
+			"port":        pulumi.Int(e.props.Provider.Port),
+		})
+	}
+	secretRes, err := e.newSecret(ctx, secret.AWSSecretProps{
+		Name:         fmt.Sprintf("pg-%s-publication-%s", e.props.Database, name),
+		Type:         secret.DBCreds,
+		InitialValue: e.roleCreds(res.Role.Name, res.Role.Password.Elem().ToStringOutput(), e.props.Database),
+	}, username)
+	if err != nil {
+		return fmt.Errorf("failed to create secret for publication %s: %w", name, err)
+	}
+	return utils.Export(ctx, utils.OutputReference, outputName, pulumi.Map{
+		"publication": res.Publication.Name,
+		"slot":        res.Slot.Name,
+		"secretId":    secretRes.Secret.ID(),
+	})
+}
@@ -0,0 +1,17 @@
+package postgres
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// readyAfter resolves to true only after all the resources are created, so
+// the consumers can be sequenced after the access is fully provisioned.
+func readyAfter(resources ...pulumi.CustomResource) pulumi.BoolOutput {
+	ids := make([]interface{}, len(resources))
+	for i, res := range resources {
+		ids[i] = res.ID()
+	}
+	return pulumi.All(ids...).ApplyT(func(_ []interface{}) bool {
+		return true
+	}).(pulumi.BoolOutput)
+}
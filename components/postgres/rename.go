@@ -0,0 +1,9 @@
+package postgres
+
+import "github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+// renamedFrom keeps the state of a child whose logical name changed, instead
+// of replacing it, e.g. regenerating a password.
+func renamedFrom(name string) pulumi.ResourceOption {
+	return pulumi.Aliases([]pulumi.Alias{{Name: pulumi.String(name)}})
+}
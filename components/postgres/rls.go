@@ -0,0 +1,217 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// roles which are keywords in the TO clause of a policy, instead of role names
+var policySpecialRoles = map[string]bool{
+	"public":       true,
+	"current_role": true,
+	"current_user": true,
+	"session_user": true,
+}
+
+type RowSecurityPolicyProps struct {
+	Name string `json:"name"`
+	// Statements the policy applies to, ALL if not set
+	Command string `json:"command" enum:"ALL,SELECT,INSERT,UPDATE,DELETE"`
+	// Roles the policy applies to, PUBLIC if not set
+	Roles []string `json:"roles"`
+	// SQL expressions, e.g. tenant_id = current_setting('app.tenant_id')::uuid
+	Using     string `json:"using"`
+	WithCheck string `json:"withCheck"`
+	// Combined with AND with the other policies, instead of OR
+	Restrictive bool `json:"restrictive"`
+}
+
+type RowSecurityTableProps struct {
+	// e.g. public.orders
+	Table string `json:"table"`
+	// Applies the policies to the owner of the table too
+	Force    bool                     `json:"force"`
+	Policies []RowSecurityPolicyProps `json:"policies"`
+}
+
+type PostgresRowSecurityProps struct {
+	Database string
+	// The tables and the roles of the policies need to exist already, e.g.
+	// created by the bootstrap SQL of the database
+	Tables []RowSecurityTableProps
+	// Where psql creates the policies
+	Connection *SQLConnection
+}
+
+type PostgresRowSecurityResource struct {
+	pulumi.ResourceState
+
+	// SQL enabling RLS and (re)creating the policies, and the command running it
+	PoliciesSQL string
+	// SQL dropping the policies and disabling RLS, when the command is deleted
+	// or the policies change
+	DropPoliciesSQL string
+	Policies        *SQLCommand
+}
+
+func quoteIdentifier(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+func (props *RowSecurityPolicyProps) validate(table string) error {
+	if err := validateIdentifier("policy", props.Name); err != nil {
+		return fmt.Errorf("table %s: %w", table, err)
+	}
+	switch props.Command {
+	case "":
+		props.Command = "ALL"
+	case "ALL", "SELECT", "INSERT", "UPDATE", "DELETE":
+	default:
+		return fmt.Errorf("invalid command '%s' of policy %s on %s", props.Command, props.Name, table)
+	}
+	if props.Using == "" && props.WithCheck == "" {
+		return fmt.Errorf("either using or withCheck is required for policy %s on %s", props.Name, table)
+	}
+	// postgres rejects these combinations on CREATE POLICY
+	if props.Command == "INSERT" && props.Using != "" {
+		return fmt.Errorf("policy %s on %s: INSERT policies only take withCheck", props.Name, table)
+	}
+	if (props.Command == "SELECT" || props.Command == "DELETE") && props.WithCheck != "" {
+		return fmt.Errorf("policy %s on %s: %s policies only take using", props.Name, table, props.Command)
+	}
+	for _, role := range props.Roles {
+		if policySpecialRoles[strings.ToLower(role)] {
+			continue
+		}
+		if err := validateRoleName(role); err != nil {
+			return fmt.Errorf("policy %s on %s: %w", props.Name, table, err)
+		}
+	}
+	return nil
+}
+
+func (props *RowSecurityPolicyProps) sql(table string) string {
+	roles := make([]string, len(props.Roles))
+	for i, role := range props.Roles {
+		if policySpecialRoles[strings.ToLower(role)] {
+			roles[i] = strings.ToUpper(role)
+		} else {
+			roles[i] = quoteIdentifier(role)
+		}
+	}
+	if len(roles) == 0 {
+		roles = []string{"PUBLIC"}
+	}
+	kind := "PERMISSIVE"
+	if props.Restrictive {
+		kind = "RESTRICTIVE"
+	}
+	create := fmt.Sprintf("CREATE POLICY %s ON %s AS %s FOR %s TO %s", quoteIdentifier(props.Name), table, kind, props.Command, strings.Join(roles, ", "))
+	if props.Using != "" {
+		create += fmt.Sprintf(" USING (%s)", props.Using)
+	}
+	if props.WithCheck != "" {
+		create += fmt.Sprintf(" WITH CHECK (%s)", props.WithCheck)
+	}
+	// CREATE POLICY has no IF NOT EXISTS, it's recreated to apply the changes
+	return strings.Join([]string{
+		fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s;", quoteIdentifier(props.Name), table),
+		create + ";",
+	}, "\n")
+}
+
+func (props *RowSecurityTableProps) validate() error {
+	if !tableNameRegex.MatchString(props.Table) {
+		return fmt.Errorf("invalid table name '%s' for row level security", props.Table)
+	}
+	if len(props.Policies) == 0 {
+		// RLS without policies denies every row to the non-owners
+		return fmt.Errorf("at least one policy is required for table %s", props.Table)
+	}
+	names := map[string]bool{}
+	for i := range props.Policies {
+		if err := props.Policies[i].validate(props.Table); err != nil {
+			return err
+		}
+		if names[props.Policies[i].Name] {
+			return fmt.Errorf("duplicate policy %s on %s", props.Policies[i].Name, props.Table)
+		}
+		names[props.Policies[i].Name] = true
+	}
+	return nil
+}
+
+func (props *RowSecurityTableProps) sql() string {
+	statements := []string{
+		fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;", props.Table),
+	}
+	if props.Force {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY;", props.Table))
+	}
+	for _, policy := range props.Policies {
+		statements = append(statements, policy.sql(props.Table))
+	}
+	return strings.Join(statements, "\n")
+}
+
+// dropSQL undoes sql, the table keeps no policy nor RLS
+func (props *RowSecurityTableProps) dropSQL() string {
+	statements := []string{}
+	for _, policy := range props.Policies {
+		statements = append(statements, fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s;", quoteIdentifier(policy.Name), props.Table))
+	}
+	if props.Force {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s NO FORCE ROW LEVEL SECURITY;", props.Table))
+	}
+	statements = append(statements, fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY;", props.Table))
+	return strings.Join(statements, "\n")
+}
+
+func (r *PostgresRowSecurityResource) provision(ctx *pulumi.Context, name string, props *PostgresRowSecurityProps) error {
+	if props.Database == "" {
+		return fmt.Errorf("database is required for row level security")
+	}
+	if props.Connection == nil {
+		return fmt.Errorf("connection is required to create the policies of database %s", props.Database)
+	}
+	statements := make([]string, len(props.Tables))
+	drops := make([]string, len(props.Tables))
+	for i := range props.Tables {
+		if err := props.Tables[i].validate(); err != nil {
+			return err
+		}
+		statements[i] = props.Tables[i].sql()
+		drops[i] = props.Tables[i].dropSQL()
+	}
+	r.PoliciesSQL = strings.Join(statements, "\n")
+	r.DropPoliciesSQL = strings.Join(drops, "\n")
+	// the policies are recreated, so it's rerun as a whole. A removed policy
+	// or table is dropped by the drop SQL of the previous deploy, run first.
+	policies, err := NewSQLCommand(ctx, fmt.Sprintf("%s-policies", name), props.Connection, pulumi.String(props.Database), pulumi.String(r.PoliciesSQL), r.DropPoliciesSQL, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Policies = policies
+	return nil
+}
+
+// NewPostgresRowSecurity enables row level security on the tables with their
+// per role policies, for multi-tenant isolation. The provider can't manage
+// policies, so psql creates them.
+func NewPostgresRowSecurity(ctx *pulumi.Context, name string, props PostgresRowSecurityProps, opts ...pulumi.ResourceOption) (*PostgresRowSecurityResource, error) {
+	resource := &PostgresRowSecurityResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:rowsecurity", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"policiesSql":     pulumi.String(resource.PoliciesSQL),
+		"dropPoliciesSql": pulumi.String(resource.DropPoliciesSQL),
+	})
+	return resource, nil
+}
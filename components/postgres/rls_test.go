@@ -0,0 +1,64 @@
+package postgres
+
+import "testing"
+
+func TestRowSecurityDropSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		table RowSecurityTableProps
+		want  string
+	}{
+		{
+			name: "policies",
+			table: RowSecurityTableProps{Table: "public.orders", Policies: []RowSecurityPolicyProps{
+				{Name: "tenant_isolation"},
+				{Name: "admins"},
+			}},
+			want: "DROP POLICY IF EXISTS \"tenant_isolation\" ON public.orders;\n" +
+				"DROP POLICY IF EXISTS \"admins\" ON public.orders;\n" +
+				"ALTER TABLE public.orders DISABLE ROW LEVEL SECURITY;",
+		},
+		{
+			name: "forced",
+			table: RowSecurityTableProps{Table: "public.orders", Force: true, Policies: []RowSecurityPolicyProps{
+				{Name: "tenant_isolation"},
+			}},
+			want: "DROP POLICY IF EXISTS \"tenant_isolation\" ON public.orders;\n" +
+				"ALTER TABLE public.orders NO FORCE ROW LEVEL SECURITY;\n" +
+				"ALTER TABLE public.orders DISABLE ROW LEVEL SECURITY;",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.table.dropSQL(); got != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUncommentSQL(t *testing.T) {
+	tests := []struct {
+		objectType string
+		name       string
+		want       string
+	}{
+		{
+			objectType: "ROLE",
+			name:       "billing-api",
+			want:       `DO $$ BEGIN IF EXISTS (SELECT FROM pg_roles WHERE rolname = 'billing-api') THEN COMMENT ON ROLE "billing-api" IS NULL; END IF; END $$;`,
+		},
+		{
+			objectType: "DATABASE",
+			name:       "o'brien",
+			want:       `DO $$ BEGIN IF EXISTS (SELECT FROM pg_database WHERE datname = 'o''brien') THEN COMMENT ON DATABASE "o'brien" IS NULL; END IF; END $$;`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.objectType, func(t *testing.T) {
+			if got := uncommentSQL(tt.objectType, tt.name); got != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	settingSearchPath          = "search_path"
+	settingStatementTimeout    = "statement_timeout"
+	settingIdleInTxSessTimeout = "idle_in_transaction_session_timeout"
+)
+
+// roleAttributes are the attributes shared by DB roles and users
+type roleAttributes struct {
+	connectionLimit int
+	createDatabase  bool
+	createRole      bool
+	superuser       bool
+	inherit         *bool
+	// role-level GUCs, only the ones the provider can set
+	settings map[string]string
+}
+
+// parseTimeoutMs parses the timeouts like postgres does, plain numbers
+// being milliseconds, e.g. 30000, 30s, 5min or 1h.
+func parseTimeoutMs(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+		return ms, nil
+	}
+	// min & d aren't units of time.ParseDuration
+	if strings.HasSuffix(value, "min") {
+		value = strings.TrimSuffix(value, "in")
+	} else if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timeout '%sd'", days)
+		}
+		value = fmt.Sprintf("%dh", n*24)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid timeout '%s'", value)
+	}
+	return int(duration.Milliseconds()), nil
+}
+
+func (attrs roleAttributes) apply(args *postgresql.RoleArgs) error {
+	if attrs.connectionLimit > 0 {
+		args.ConnectionLimit = pulumi.IntPtr(attrs.connectionLimit)
+	}
+	// ALTER ROLE $ROLE CREATEDB CREATEROLE SUPERUSER NOINHERIT;
+	if attrs.createDatabase {
+		args.CreateDatabase = pulumi.BoolPtr(true)
+	}
+	if attrs.createRole {
+		args.CreateRole = pulumi.BoolPtr(true)
+	}
+	if attrs.superuser {
+		args.Superuser = pulumi.BoolPtr(true)
+	}
+	if attrs.inherit != nil {
+		args.Inherit = pulumi.BoolPtr(*attrs.inherit)
+	}
+	// ALTER ROLE $ROLE SET search_path = ...; ALTER ROLE $ROLE SET statement_timeout = ...;
+	keys := make([]string, 0, len(attrs.settings))
+	for key := range attrs.settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := attrs.settings[key]
+		switch key {
+		case settingSearchPath:
+			paths := []string{}
+			for _, path := range strings.Split(value, ",") {
+				if path = strings.TrimSpace(path); path != "" {
+					paths = append(paths, path)
+				}
+			}
+			args.SearchPaths = pulumi.ToStringArray(paths)
+		case settingStatementTimeout, settingIdleInTxSessTimeout:
+			ms, err := parseTimeoutMs(value)
+			if err != nil {
+				return fmt.Errorf("setting %s: %w", key, err)
+			}
+			if key == settingStatementTimeout {
+				args.StatementTimeout = pulumi.IntPtr(ms)
+			} else {
+				args.IdleInTransactionSessionTimeout = pulumi.IntPtr(ms)
+			}
+		default:
+			return fmt.Errorf("setting %s isn't supported, expected one of [%s, %s, %s]", key, settingSearchPath, settingStatementTimeout, settingIdleInTxSessTimeout)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTimeoutMs(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr string
+	}{
+		{value: "30000", want: 30000},
+		{value: "0", want: 0},
+		{value: " 30s ", want: 30000},
+		{value: "500ms", want: 500},
+		{value: "5min", want: 300000},
+		{value: "1h", want: 3600000},
+		{value: "2d", want: 172800000},
+		{value: "1h30m", want: 5400000},
+		{value: "-1", wantErr: "invalid timeout '-1'"},
+		{value: "-5s", wantErr: "invalid timeout '-5s'"},
+		{value: "xd", wantErr: "invalid timeout 'xd'"},
+		{value: "soon", wantErr: "invalid timeout 'soon'"},
+		{value: "", wantErr: "invalid timeout ''"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTimeoutMs(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %d, %v", tt.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %d ms, want %d", got, tt.want)
+			}
+		})
+	}
+}
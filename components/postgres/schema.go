@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"fmt"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const publicSchema = "public"
+
+type PostgresSchemaProps struct {
+	Name string `json:"name"`
+	// Role owning the schema (CREATE SCHEMA ... AUTHORIZATION), e.g. a service
+	// role or one of the DB roles. Defaults to the role owning the data, i.e.
+	// the rw or owner role, even when the database has another owner.
+	Owner string `json:"owner"`
+}
+
+func (props *PostgresDbProps) validateSchemas() error {
+	seen := map[string]bool{publicSchema: true}
+	for _, schema := range props.Schemas {
+		if schema.Name == "" {
+			return fmt.Errorf("schema name is required")
+		}
+		if seen[schema.Name] {
+			return fmt.Errorf("schema '%s' is declared more than once or exists already", schema.Name)
+		}
+		seen[schema.Name] = true
+	}
+	return nil
+}
+
+type schemaRef struct {
+	name string
+	// resolves after the schema is created
+	input pulumi.StringInput
+}
+
+// schemaRefs returns all the schemas the DB roles are granted access on
+func (r *PostgresDBResource) schemaRefs(props *PostgresDbProps) []schemaRef {
+	refs := []schemaRef{{name: publicSchema, input: pulumi.String(publicSchema)}}
+	for i, schema := range props.Schemas {
+		refs = append(refs, schemaRef{name: schema.Name, input: r.Schemas[i].Name})
+	}
+	return refs
+}
+
+// hardenPublicSchema revokes the default privileges of PUBLIC, i.e. of any
+// role of the cluster: CREATE on schema public (the default before PG 15)
+// and CONNECT & TEMPORARY on the database.
+func (r *PostgresDBResource) hardenPublicSchema(ctx *pulumi.Context, namePrefix string) error {
+	database := r.DB.Name
+	// REVOKE ALL ON DATABASE $DB FROM PUBLIC;
+	if err := r.newGrant(ctx, fmt.Sprintf("%s-revokePublicDatabase", namePrefix), &postgresql.GrantArgs{
+		Database:   database,
+		ObjectType: pulumi.String("database"),
+		Privileges: pulumi.StringArray{},
+		Role:       pulumi.String("public"),
+	}); err != nil {
+		return err
+	}
+	// REVOKE CREATE ON SCHEMA public FROM PUBLIC;
+	if err := r.newGrant(ctx, fmt.Sprintf("%s-revokePublicSchemaCreate", namePrefix), &postgresql.GrantArgs{
+		Database:   database,
+		ObjectType: pulumi.String("schema"),
+		Privileges: pulumi.StringArray{pulumi.String("USAGE")},
+		Role:       pulumi.String("public"),
+		Schema:     pulumi.String(publicSchema),
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// grantDataOwner grants the role owning the schemas & objects what it gets
+// implicitly from the database ownership or PUBLIC otherwise, i.e. when
+// the database is owned by another role or PUBLIC is revoked.
+func (r *PostgresDBResource) grantDataOwner(ctx *pulumi.Context, namePrefix string, ownerRole *postgresql.Role, props *PostgresDbProps) error {
+	database := r.DB.Name
+	if props.DatabaseOwner != "" {
+		// GRANT CONNECT, TEMPORARY ON DATABASE $DB TO $OWNER;
+		if err := r.newGrant(ctx, fmt.Sprintf("%s-ownerDatabase", namePrefix), &postgresql.GrantArgs{
+			Database:   database,
+			ObjectType: pulumi.String("database"),
+			Privileges: pulumi.ToStringArray([]string{"CONNECT", "TEMPORARY"}),
+			Role:       ownerRole.Name,
+		}); err != nil {
+			return err
+		}
+	}
+	// schema public is owned by postgres before PG 15 (the owner role created
+	// its tables through PUBLIC), and by the database owner since
+	// GRANT USAGE, CREATE ON SCHEMA public TO $OWNER;
+	return r.newGrant(ctx, fmt.Sprintf("%s-usageCreatePublicSchema", namePrefix), &postgresql.GrantArgs{
+		Database:   database,
+		ObjectType: pulumi.String("schema"),
+		Privileges: pulumi.StringArray{pulumi.String("USAGE"), pulumi.String("CREATE")},
+		Role:       ownerRole.Name,
+		Schema:     pulumi.String(publicSchema),
+	})
+}
+
+// validateRoleSchemas checks the schemas the roles are narrowed to are declared
+func (props *PostgresDbProps) validateRoleSchemas() error {
+	schemas := map[string]bool{publicSchema: true}
+	for _, schema := range props.Schemas {
+		schemas[schema.Name] = true
+	}
+	for _, role := range props.DbRoles {
+		if len(role.Schemas) == 0 {
+			continue
+		}
+		if role.Permission == props.ownerPermission() {
+			return fmt.Errorf("schemas can't be set for the %s role, it owns them", role.Permission)
+		}
+		for _, schema := range role.Schemas {
+			if !schemas[schema] {
+				return fmt.Errorf("schema '%s' of role %s is not declared in the database", schema, role.roleSuffix())
+			}
+		}
+		for schema := range tablesBySchema(role.Tables) {
+			if !role.grantsSchema(schema) {
+				return fmt.Errorf("tables of schema '%s' are scoped for role %s, but not the schema", schema, role.roleSuffix())
+			}
+		}
+	}
+	return nil
+}
+
+// grantsSchema tells if the role is granted access on the schema, i.e. it's
+// one of its schemas or they're not set
+func (props *PostgresDbRoleProps) grantsSchema(schema string) bool {
+	if len(props.Schemas) == 0 {
+		return true
+	}
+	for _, s := range props.Schemas {
+		if s == schema {
+			return true
+		}
+	}
+	return false
+}
+
+// roleSchemaRefs narrows schemaRefs to the schemas of the role
+func (r *PostgresDBResource) roleSchemaRefs(props *PostgresDbProps, role PostgresDbRoleProps) []schemaRef {
+	refs := []schemaRef{}
+	for _, schema := range r.schemaRefs(props) {
+		if role.grantsSchema(schema.name) {
+			refs = append(refs, schema)
+		}
+	}
+	return refs
+}
+
+// grantPrefix keeps the names of the public schema grants as they were
+// before the schemas could be declared.
+func grantPrefix(namePrefix string, schema string) string {
+	if schema == publicSchema {
+		return namePrefix
+	}
+	return fmt.Sprintf("%s-%s", namePrefix, schema)
+}
+
+func (r *PostgresDBResource) provisionSchemas(ctx *pulumi.Context, namePrefix string, owner pulumi.StringInput, props *PostgresDbProps) error {
+	// the DB roles of the stack are created before the schemas they own
+	dbRoles := map[string]pulumi.StringInput{}
+	for i, role := range props.DbRoles {
+		dbRoles[role.RoleName(namePrefix)] = r.Roles[i].Name
+	}
+	for _, schemaProps := range props.Schemas {
+		schemaOwner := owner
+		if role, ok := dbRoles[schemaProps.Owner]; ok {
+			schemaOwner = role
+		} else if schemaProps.Owner != "" {
+			schemaOwner = pulumi.String(schemaProps.Owner)
+		}
+		// CREATE SCHEMA $SCHEMA AUTHORIZATION $OWNER;
+		schema, err := postgresql.NewSchema(ctx, fmt.Sprintf("%s-schema-%s", namePrefix, schemaProps.Name), &postgresql.SchemaArgs{
+			Database: r.DB.Name,
+			Name:     pulumi.String(schemaProps.Name),
+			Owner:    schemaOwner,
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.Schemas = append(r.Schemas, schema)
+	}
+	return nil
+}
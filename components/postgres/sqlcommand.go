@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+// The command of the command provider is registered by type token, the
+// engine loads the plugin (`pulumi plugin install resource command`) as for
+// any provider.
+const commandType = "command:local:Command"
+
+// PsqlTool is the CLI the SQL commands run, to pass to
+// utils.CheckPluginVersions by the programs using them
+const PsqlTool = "psql"
+
+// all or nothing, so a failed run is retried as is on the next deploy
+const psqlCommand = "psql --no-psqlrc --single-transaction --set ON_ERROR_STOP=1 --file -"
+
+// one transaction per statement, for the ones which can't run in a
+// transaction block, e.g. CREATE TABLESPACE
+const psqlAutocommitCommand = "psql --no-psqlrc --set ON_ERROR_STOP=1 --file -"
+
+// the delete statements are passed in the environment, stdin is only given to
+// the create and the update
+const psqlDeleteInput = `printf '%s\n' "$SQL_DELETE" | `
+
+// SQLConnection is how psql reaches the server of the postgresql provider, to
+// run the statements the provider doesn't model. It runs where `pulumi up`
+// does, so psql needs to be installed there.
+type SQLConnection struct {
+	Host     pulumi.StringInput
+	Port     int
+	Username pulumi.StringInput
+	Password pulumi.StringInput
+	// sslmode of psql, require (as the postgresql provider) if not set
+	SslMode string
+}
+
+// SQLCommand runs SQL in a database with psql, again whenever the SQL changes
+type SQLCommand struct {
+	pulumi.CustomResourceState
+
+	Stdout pulumi.StringOutput `pulumi:"stdout"`
+}
+
+// NewSQLCommand runs the SQL in the database through psql once created, and
+// again as the update when the SQL changes. The statements are run as is, so
+// they need to be idempotent to be changed later.
+//
+// deleteSQL undoes them when the command is deleted, e.g. DROP POLICY. It's
+// also run when it changes, before the new SQL, as a changed object list
+// (a removed policy) can't be undone by the new SQL. Without it the command
+// is create-only: deleting it leaves the database as is.
+func NewSQLCommand(ctx *pulumi.Context, name string, conn *SQLConnection, database pulumi.StringInput, sql pulumi.StringInput, deleteSQL string, opts ...pulumi.ResourceOption) (*SQLCommand, error) {
+	return newSQLCommand(ctx, name, psqlCommand, conn, database, sql, deleteSQL, opts...)
+}
+
+func newSQLCommand(ctx *pulumi.Context, name string, psql string, conn *SQLConnection, database pulumi.StringInput, sql pulumi.StringInput, deleteSQL string, opts ...pulumi.ResourceOption) (*SQLCommand, error) {
+	opts, err := utils.PinPlugin(ctx, "command", opts)
+	if err != nil {
+		return nil, err
+	}
+	sslMode := conn.SslMode
+	if sslMode == "" {
+		sslMode = defaultSslMode
+	}
+	env := pulumi.StringMap{
+		"PGHOST":     conn.Host,
+		"PGPORT":     pulumi.Sprintf("%d", conn.Port),
+		"PGUSER":     conn.Username,
+		"PGPASSWORD": pulumi.ToSecret(conn.Password).(pulumi.StringOutput),
+		"PGDATABASE": database,
+		"PGSSLMODE":  pulumi.String(sslMode),
+	}
+	args := pulumi.Map{
+		"create":      pulumi.String(psql),
+		"update":      pulumi.String(psql),
+		"stdin":       sql,
+		"environment": env,
+	}
+	if deleteSQL != "" {
+		args["delete"] = pulumi.String(psqlDeleteInput + psql)
+		env["SQL_DELETE"] = pulumi.String(deleteSQL)
+		opts = append(opts,
+			pulumi.ReplaceOnChanges([]string{"environment.SQL_DELETE"}),
+			pulumi.DeleteBeforeReplace(true),
+		)
+	}
+	command := &SQLCommand{}
+	if err := ctx.RegisterResource(commandType, name, args, command, opts...); err != nil {
+		return nil, err
+	}
+	return command, nil
+}
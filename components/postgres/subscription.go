@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+)
+
+type PostgresSubscriptionProps struct {
+	// Database on the target server the subscription is created in
+	Database string `json:"database"`
+	// Publications on the source server, see NewPostgresPublication
+	Publications []string `json:"publications"`
+	// Secret Manager secret (of DB creds type) of the replication role on the
+	// source server, the connection string is built from it
+	SourceSecretId string `json:"sourceSecretId"`
+	// Existing slot on the source, e.g. created by NewPostgresPublication.
+	// If not set, the subscription creates its own slot.
+	SlotName string `json:"slotName"`
+}
+
+type PostgresSubscriptionResource struct {
+	pulumi.ResourceState
+
+	Subscription *postgresql.Subscription
+}
+
+// quoteConninfo quotes a value of the libpq keyword/value connection string
+func quoteConninfo(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return fmt.Sprintf("'%s'", value)
+}
+
+func buildConninfo(creds map[string]string) string {
+	parts := []string{}
+	for _, keys := range [][2]string{{"host", "host"}, {"port", "port"}, {"dbname", "database"}, {"user", "username"}, {"password", "password"}} {
+		if value, ok := creds[keys[1]]; ok && value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", keys[0], quoteConninfo(value)))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (r *PostgresSubscriptionResource) provision(ctx *pulumi.Context, name string, props *PostgresSubscriptionProps) error {
+	if props.Database == "" || props.SourceSecretId == "" {
+		return fmt.Errorf("database and source secret are required for the subscription")
+	}
+	if len(props.Publications) == 0 {
+		return fmt.Errorf("at least one publication is required for the subscription")
+	}
+	creds := secret.LookupDBCreds(ctx, props.SourceSecretId, pulumi.Parent(r))
+	conninfo := pulumi.ToSecret(creds.ApplyT(buildConninfo)).(pulumi.StringOutput)
+
+	// CREATE SUBSCRIPTION $NAME CONNECTION '$CONNINFO' PUBLICATION $PUBLICATIONS;
+	args := &postgresql.SubscriptionArgs{
+		Database:     pulumi.String(props.Database),
+		Name:         pulumi.String(strings.ReplaceAll(name, "-", "_")),
+		Conninfo:     conninfo,
+		Publications: pulumi.ToStringArray(props.Publications),
+	}
+	if props.SlotName != "" {
+		args.SlotName = pulumi.String(props.SlotName)
+		args.CreateSlot = pulumi.BoolPtr(false)
+	}
+	subscription, err := postgresql.NewSubscription(ctx, fmt.Sprintf("%s-subscription", name), args, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Subscription = subscription
+	return nil
+}
+
+// NewPostgresSubscription subscribes the target database to publications of
+// another server, completing the replication set up by NewPostgresPublication.
+// The tables need to exist on the target already, the schema isn't replicated.
+func NewPostgresSubscription(ctx *pulumi.Context, name string, props PostgresSubscriptionProps, opts ...pulumi.ResourceOption) (*PostgresSubscriptionResource, error) {
+	resource := &PostgresSubscriptionResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:subscription", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"subscription": resource.Subscription.Name,
+	})
+	return resource, nil
+}
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tablesBySchema groups the tables of a role by their schema, tables
+// without the schema qualifier are in public.
+func tablesBySchema(tables []string) map[string][]string {
+	res := map[string][]string{}
+	for _, table := range tables {
+		schema, name, ok := strings.Cut(table, ".")
+		if !ok {
+			schema, name = publicSchema, table
+		}
+		res[schema] = append(res[schema], name)
+	}
+	return res
+}
+
+func (props *PostgresDbProps) validateRoleTables() error {
+	schemas := map[string]bool{publicSchema: true}
+	for _, schema := range props.Schemas {
+		schemas[schema.Name] = true
+	}
+	for _, role := range props.DbRoles {
+		if len(role.Tables) == 0 {
+			continue
+		}
+		if role.Permission != ReadOnly {
+			return fmt.Errorf("tables can only be scoped for the read-only role, the read-write role owns all of them")
+		}
+		for _, table := range role.Tables {
+			schema, name, ok := strings.Cut(table, ".")
+			if !ok {
+				schema, name = publicSchema, table
+			}
+			if name == "" || strings.Contains(name, ".") {
+				return fmt.Errorf("invalid table '%s', expected 'table' or 'schema.table'", table)
+			}
+			if !schemas[schema] {
+				return fmt.Errorf("schema of table '%s' is not declared in the database", table)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// PostgresTablespaceProps is a tablespace created by psql, the postgresql
+// provider has no resource for them, e.g. to put the cold tables of a
+// self-hosted cluster on cheaper storage.
+type PostgresTablespaceProps struct {
+	Name string `json:"name"`
+	// Directory of the tablespace on the server, which has to exist, be
+	// empty and owned by the postgres OS user. Required unless RDS.
+	Location string `json:"location"`
+	// RDS maps the location into the storage of the instance, so the
+	// tablespaces don't separate any storage there. /<name> if no Location.
+	RDS bool `json:"rds"`
+	// Role owning the tablespace, the user of the connection if not set
+	Owner string `json:"owner"`
+	// Roles granted CREATE on the tablespace, which becomes their
+	// default_tablespace. They need to exist when the database is created,
+	// e.g. its DB roles.
+	Roles []string `json:"roles"`
+}
+
+func (props *PostgresTablespaceProps) validate() error {
+	if err := validateIdentifier("tablespace", props.Name); err != nil {
+		return err
+	}
+	// reserved for pg_default & pg_global
+	if strings.HasPrefix(strings.ToLower(props.Name), "pg_") {
+		return fmt.Errorf("tablespace name '%s' is reserved", props.Name)
+	}
+	if props.Location == "" && !props.RDS {
+		return fmt.Errorf("location of tablespace %s is required, unless on RDS", props.Name)
+	}
+	if props.Location != "" && !path.IsAbs(props.Location) {
+		return fmt.Errorf("location of tablespace %s must be an absolute path, got '%s'", props.Name, props.Location)
+	}
+	if props.Owner != "" {
+		if err := validateRoleName(props.Owner); err != nil {
+			return err
+		}
+	}
+	for _, role := range props.Roles {
+		if err := validateRoleName(role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (props *PostgresTablespaceProps) location() string {
+	if props.Location == "" {
+		return "/" + props.Name
+	}
+	return props.Location
+}
+
+// sql creates the tablespace if missing, CREATE TABLESPACE has no IF NOT
+// EXISTS so psql runs it through \gexec, then assigns it to the roles
+func (props *PostgresTablespaceProps) sql() string {
+	name := quoteIdentifier(props.Name)
+	create := fmt.Sprintf("CREATE TABLESPACE %s", name)
+	if props.Owner != "" {
+		create += fmt.Sprintf(" OWNER %s", quoteIdentifier(props.Owner))
+	}
+	create += fmt.Sprintf(" LOCATION %s", quoteLiteral(props.location()))
+	statements := []string{
+		fmt.Sprintf("SELECT %s WHERE NOT EXISTS (SELECT 1 FROM pg_tablespace WHERE spcname = %s)\\gexec", quoteLiteral(create), quoteLiteral(props.Name)),
+	}
+	if props.Owner != "" {
+		statements = append(statements, fmt.Sprintf("ALTER TABLESPACE %s OWNER TO %s;", name, quoteIdentifier(props.Owner)))
+	}
+	for _, role := range props.Roles {
+		statements = append(statements,
+			fmt.Sprintf("GRANT CREATE ON TABLESPACE %s TO %s;", name, quoteIdentifier(role)),
+			fmt.Sprintf("ALTER ROLE %s SET default_tablespace = %s;", quoteIdentifier(role), name))
+	}
+	return strings.Join(statements, "\n")
+}
+
+func (props *PostgresDbProps) validateTablespaces() error {
+	if len(props.Tablespaces) == 0 {
+		return nil
+	}
+	if props.Connection == nil {
+		return fmt.Errorf("connection is required to create the tablespaces of database %s", props.Database)
+	}
+	seen := map[string]bool{}
+	for i := range props.Tablespaces {
+		tablespace := &props.Tablespaces[i]
+		if err := tablespace.validate(); err != nil {
+			return err
+		}
+		if seen[tablespace.Name] {
+			return fmt.Errorf("duplicate tablespace %s", tablespace.Name)
+		}
+		seen[tablespace.Name] = true
+	}
+	return nil
+}
+
+// provisionTablespaces creates the tablespaces once the DB roles exist, so
+// they can own them, and before the database, which may be in one of them.
+// Each statement is its own transaction, CREATE TABLESPACE can't run in a
+// transaction block.
+func (r *PostgresDBResource) provisionTablespaces(ctx *pulumi.Context, namePrefix string, props *PostgresDbProps) error {
+	if len(props.Tablespaces) == 0 {
+		return nil
+	}
+	statements := make([]string, len(props.Tablespaces))
+	for i := range props.Tablespaces {
+		statements[i] = props.Tablespaces[i].sql()
+	}
+	r.TablespacesSQL = strings.Join(statements, "\n")
+	dependsOn := []pulumi.Resource{}
+	for _, role := range r.Roles {
+		dependsOn = append(dependsOn, role)
+	}
+	// the tablespaces belong to the server, any database does. It's
+	// create-only, a tablespace can't be dropped while it holds tables.
+	command, err := newSQLCommand(ctx, fmt.Sprintf("%s-tablespaces", namePrefix), psqlAutocommitCommand, props.Connection, pulumi.String("postgres"), pulumi.String(r.TablespacesSQL), "",
+		pulumi.Parent(r), pulumi.DependsOn(dependsOn))
+	if err != nil {
+		return err
+	}
+	r.Tablespaces = command
+	return nil
+}
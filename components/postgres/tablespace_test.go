@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTablespaceSQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		props   PostgresTablespaceProps
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "location",
+			props: PostgresTablespaceProps{Name: "hot", Location: "/mnt/nvme/pg-hot"},
+			want:  `SELECT 'CREATE TABLESPACE "hot" LOCATION ''/mnt/nvme/pg-hot''' WHERE NOT EXISTS (SELECT 1 FROM pg_tablespace WHERE spcname = 'hot')\gexec`,
+		},
+		{
+			name:  "owner and roles",
+			props: PostgresTablespaceProps{Name: "cold", Location: "/mnt/hdd/pg-cold", Owner: "billing-rw", Roles: []string{"billing-rw"}},
+			want: strings.Join([]string{
+				`SELECT 'CREATE TABLESPACE "cold" OWNER "billing-rw" LOCATION ''/mnt/hdd/pg-cold''' WHERE NOT EXISTS (SELECT 1 FROM pg_tablespace WHERE spcname = 'cold')\gexec`,
+				`ALTER TABLESPACE "cold" OWNER TO "billing-rw";`,
+				`GRANT CREATE ON TABLESPACE "cold" TO "billing-rw";`,
+				`ALTER ROLE "billing-rw" SET default_tablespace = "cold";`,
+			}, "\n"),
+		},
+		{
+			name:  "rds defaults the location",
+			props: PostgresTablespaceProps{Name: "archive", RDS: true},
+			want:  `SELECT 'CREATE TABLESPACE "archive" LOCATION ''/archive''' WHERE NOT EXISTS (SELECT 1 FROM pg_tablespace WHERE spcname = 'archive')\gexec`,
+		},
+		{
+			name:    "location required off RDS",
+			props:   PostgresTablespaceProps{Name: "hot"},
+			wantErr: "location of tablespace hot is required",
+		},
+		{
+			name:    "relative location",
+			props:   PostgresTablespaceProps{Name: "hot", Location: "pg-hot"},
+			wantErr: "must be an absolute path",
+		},
+		{
+			name:    "reserved name",
+			props:   PostgresTablespaceProps{Name: "pg_hot", Location: "/mnt/hot"},
+			wantErr: "is reserved",
+		},
+		{
+			name:    "invalid role",
+			props:   PostgresTablespaceProps{Name: "hot", Location: "/mnt/hot", Roles: []string{"public"}},
+			wantErr: "role name 'public' is reserved",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.props.validate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := tt.props.sql(); got != tt.want {
+				t.Fatalf("got SQL\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
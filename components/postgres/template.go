@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"fmt"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type PostgresDbFromTemplateProps struct {
+	Database string `json:"database"`
+	// Database marked as template, see PostgresDbProps.TemplateDatabase
+	Template string `json:"template"`
+	// Defaults to the user of the provider
+	Owner string `json:"owner"`
+}
+
+type PostgresDbFromTemplateResource struct {
+	pulumi.ResourceState
+
+	DB *postgresql.Database
+}
+
+func (r *PostgresDbFromTemplateResource) provision(ctx *pulumi.Context, name string, props *PostgresDbFromTemplateProps) error {
+	if props.Database == "" || props.Template == "" {
+		return fmt.Errorf("both database and template are required")
+	}
+	if err := validateIdentifier("database", props.Database); err != nil {
+		return err
+	}
+	// CREATE DATABASE $DB TEMPLATE $TEMPLATE;
+	args := &postgresql.DatabaseArgs{
+		Name:     pulumi.String(props.Database),
+		Template: pulumi.String(props.Template),
+	}
+	if props.Owner != "" {
+		args.Owner = pulumi.String(props.Owner)
+	}
+	// the clone is independent of the template once created, so updating
+	// the template later must not replace the existing clones
+	db, err := postgresql.NewDatabase(ctx, fmt.Sprintf("%s-db", name), args, pulumi.Parent(r), pulumi.IgnoreChanges([]string{"template"}))
+	if err != nil {
+		return err
+	}
+	r.DB = db
+	return nil
+}
+
+// NewDatabaseFromTemplate clones the template database server-side, e.g. for
+// per-tenant or per-preview databases. Postgres refuses to clone while the
+// template has any active connection.
+func NewDatabaseFromTemplate(ctx *pulumi.Context, name string, props PostgresDbFromTemplateProps, opts ...pulumi.ResourceOption) (*PostgresDbFromTemplateResource, error) {
+	resource := &PostgresDbFromTemplateResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:databaseFromTemplate", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"database": resource.DB.Name,
+		"template": pulumi.String(props.Template),
+	})
+	return resource, nil
+}
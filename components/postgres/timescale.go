@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// table or schema.table, it's used as identifier in the SQL
+var tableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+type TimescaleHypertableProps struct {
+	// e.g. public.metrics
+	Table      string `json:"table"`
+	TimeColumn string `json:"timeColumn"`
+	// Postgres intervals, e.g. "1 day". The timescale defaults if not set.
+	ChunkInterval string `json:"chunkInterval"`
+	// Chunks older than it are dropped, kept forever if not set
+	RetentionPeriod string `json:"retentionPeriod"`
+	// Chunks older than it are compressed, not compressed if not set
+	CompressAfter string `json:"compressAfter"`
+	// Columns the compressed rows are grouped by, e.g. device_id
+	CompressSegmentBy []string `json:"compressSegmentBy"`
+}
+
+type PostgresTimescaleProps struct {
+	Database string
+	// Pins the timescaledb version, defaults to the one installed
+	Version string
+	// Created with psql once the extension is, the tables need to exist
+	// already, e.g. created by the bootstrap SQL of the database
+	Hypertables []TimescaleHypertableProps
+	// Where psql creates the hypertables, required with them
+	Connection *SQLConnection
+}
+
+type PostgresTimescaleResource struct {
+	pulumi.ResourceState
+
+	Extension *postgresql.Extension
+	// SQL creating the hypertables and their policies, and the command
+	// running it if there are hypertables
+	HypertablesSQL string
+	Hypertables    *SQLCommand
+}
+
+func (props *TimescaleHypertableProps) validate() error {
+	if !tableNameRegex.MatchString(props.Table) {
+		return fmt.Errorf("invalid hypertable name '%s'", props.Table)
+	}
+	if props.TimeColumn == "" {
+		return fmt.Errorf("time column is required for hypertable %s", props.Table)
+	}
+	if len(props.CompressSegmentBy) > 0 && props.CompressAfter == "" {
+		return fmt.Errorf("compressAfter is required to compress hypertable %s", props.Table)
+	}
+	return nil
+}
+
+func (props *TimescaleHypertableProps) sql() string {
+	table := quoteLiteral(props.Table)
+	createArgs := []string{table, quoteLiteral(props.TimeColumn)}
+	if props.ChunkInterval != "" {
+		createArgs = append(createArgs, fmt.Sprintf("chunk_time_interval => INTERVAL %s", quoteLiteral(props.ChunkInterval)))
+	}
+	createArgs = append(createArgs, "if_not_exists => TRUE")
+	statements := []string{
+		fmt.Sprintf("SELECT create_hypertable(%s);", strings.Join(createArgs, ", ")),
+	}
+	if props.CompressAfter != "" {
+		settings := "timescaledb.compress"
+		if len(props.CompressSegmentBy) > 0 {
+			settings += fmt.Sprintf(", timescaledb.compress_segmentby = %s", quoteLiteral(strings.Join(props.CompressSegmentBy, ", ")))
+		}
+		statements = append(statements,
+			fmt.Sprintf("ALTER TABLE %s SET (%s);", props.Table, settings),
+			fmt.Sprintf("SELECT add_compression_policy(%s, INTERVAL %s, if_not_exists => TRUE);", table, quoteLiteral(props.CompressAfter)),
+		)
+	}
+	if props.RetentionPeriod != "" {
+		statements = append(statements,
+			fmt.Sprintf("SELECT add_retention_policy(%s, INTERVAL %s, if_not_exists => TRUE);", table, quoteLiteral(props.RetentionPeriod)))
+	}
+	return strings.Join(statements, "\n")
+}
+
+func (r *PostgresTimescaleResource) provision(ctx *pulumi.Context, name string, props *PostgresTimescaleProps) error {
+	if props.Database == "" {
+		return fmt.Errorf("database is required to install timescaledb")
+	}
+	statements := make([]string, len(props.Hypertables))
+	for i, hypertable := range props.Hypertables {
+		if err := hypertable.validate(); err != nil {
+			return err
+		}
+		statements[i] = hypertable.sql()
+	}
+	if len(statements) > 0 && props.Connection == nil {
+		return fmt.Errorf("connection is required to create the hypertables of database %s", props.Database)
+	}
+
+	// CREATE EXTENSION timescaledb VERSION $VERSION;
+	args := &postgresql.ExtensionArgs{
+		Database: pulumi.String(props.Database),
+		Name:     pulumi.String("timescaledb"),
+	}
+	if props.Version != "" {
+		args.Version = pulumi.String(props.Version)
+	}
+	ext, err := postgresql.NewExtension(ctx, fmt.Sprintf("%s-timescaledb", name), args, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	r.Extension = ext
+	r.HypertablesSQL = strings.Join(statements, "\n")
+	if r.HypertablesSQL == "" {
+		return nil
+	}
+	// the calls are idempotent (if_not_exists), so it's rerun as a whole. It's
+	// create-only, a hypertable can't be turned back into a plain table.
+	r.Hypertables, err = NewSQLCommand(ctx, fmt.Sprintf("%s-hypertables", name), props.Connection, pulumi.String(props.Database), pulumi.String(r.HypertablesSQL), "",
+		pulumi.Parent(r), pulumi.DependsOn([]pulumi.Resource{ext}))
+	return err
+}
+
+// NewPostgresTimescale installs timescaledb in the database, and turns the
+// configured tables into hypertables with their chunk interval, compression
+// and retention policies.
+func NewPostgresTimescale(ctx *pulumi.Context, name string, props PostgresTimescaleProps, opts ...pulumi.ResourceOption) (*PostgresTimescaleResource, error) {
+	resource := &PostgresTimescaleResource{}
+	if err := ctx.RegisterComponentResource("ss9:postgres:timescale", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"extension":      resource.Extension.Name,
+		"hypertablesSql": pulumi.String(resource.HypertablesSQL),
+	})
+	return resource, nil
+}
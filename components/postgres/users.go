@@ -1,18 +1,34 @@
 package postgres
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	postgresql "github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
 	"github.com/shivanshs9/iac-pulumi/components/utils"
 )
 
 type PostgresUsersResource struct {
 	pulumi.ResourceState
 
-	Users      []*postgresql.Role
-	FailedUser string
+	// Indexed as the props, nil for the users which failed
+	Users []*postgresql.Role
+	// Errors of the failed users, keyed by username
+	FailedUsers map[string]error
+	// Connection strings keyed by username, of the users with an Endpoint
+	DSNs map[string]pulumi.StringOutput
+	// COMMENT ON ROLE of the users with a Comment, run by their comment command
+	CommentsSQL string
+	// Resolves once all the users are provisioned
+	Ready pulumi.BoolOutput
+	// Set once provisioned, also registered as the outputs of the component
+	Outputs *PostgresUsersOutputs
+	// GRANT role TO user of the users with ExplicitSetRole
+	memberships []pulumi.CustomResource
 }
 
 type PostgresUserProps struct {
@@ -20,57 +36,295 @@ type PostgresUserProps struct {
 	Password   pulumi.StringInput `json:"password"`
 	AssumeRole pulumi.StringInput `json:"assumeRole"`
 	Login      bool               `json:"login"`
+	// Granted besides AssumeRole, e.g. the ro role of another database
+	Roles []pulumi.StringInput `json:"roles"`
+	// Max concurrent connections of the user, unlimited if not set
+	ConnectionLimit int  `json:"connectionLimit"`
+	CreateDatabase  bool `json:"createDatabase"`
+	CreateRole      bool `json:"createRole"`
+	Superuser       bool `json:"superuser"`
+	// Whether the privileges of the assumed role are inherited, true if not set
+	Inherit *bool `json:"inherit"`
+	// Creates the user NOINHERIT without switching to AssumeRole at login,
+	// so it has to SET ROLE before using the privileges of its roles. The
+	// memberships are granted explicitly, one GRANT role TO user each.
+	ExplicitSetRole bool `json:"explicitSetRole"`
+	// Timestamp after which the password isn't valid anymore, e.g. 2025-01-31T00:00:00Z
+	ValidUntil string `json:"validUntil"`
+	// Duration (e.g. 72h) after the first deploy when the password expires.
+	// It's resolved only on creation, so later deploys don't extend it.
+	TTL string `json:"ttl"`
+	// Bump it to rotate the generated password, the role and the exported
+	// secrets get the new one in the same deploy
+	RotationTrigger string `json:"rotationTrigger"`
+	// Secret Manager secret (ARN or name) holding the password, e.g. owned by
+	// another team, instead of generating one. It's read on every deploy, so
+	// the role follows the rotations of the secret.
+	PasswordSecretArn string `json:"passwordSecretArn"`
+	// Arbitrary values which also rotate the generated password on change
+	Keepers map[string]string `json:"keepers"`
+	// The password is rotated by Secret Manager (e.g. the RotatePostgres of
+	// its secret), the role only sets it on creation and ignores its changes
+	// afterwards, or each deploy would reset it. The user gets no DSN, it
+	// would keep the initial password.
+	RotatedBySecret bool `json:"rotatedBySecret"`
+	// Password (or the one of PasswordSecretArn) is a SCRAM-SHA-256 or md5
+	// verifier, e.g. mirrored from another server, which postgres stores as
+	// is. The plaintext is never known, so the user gets no DSN.
+	PasswordIsHashed bool `json:"passwordIsHashed"`
+	// Adopts the role created by hand, instead of creating it
+	ImportExisting bool `json:"importExisting"`
+	// Authenticates with RDS IAM tokens instead of a password, the role is
+	// granted rds_iam and no password is generated
+	IamAuth bool `json:"iamAuth"`
+	// Fails any delete of the role, e.g. in a destroy
+	Protect bool `json:"protect"`
+	// Keeps the role in the server when it's deleted from the stack
+	RetainOnDelete bool `json:"retainOnDelete"`
+	// Role-level parameters: search_path, statement_timeout & idle_in_transaction_session_timeout
+	Settings map[string]string `json:"settings"`
+	// Where the user connects to, its DSN is exposed in DSNs if set
+	Endpoint *ConnectionEndpoint `json:"-"`
+	// Ownership or team metadata, shown by \du+. The provider can't set it,
+	// psql does through Connection.
+	Comment string `json:"comment"`
+	// Where psql sets Comment, required with it
+	Connection *SQLConnection `json:"-"`
 }
 
-func (props *PostgresUserProps) fillRuntimeInputs(ctx *pulumi.Context, res *PostgresUsersResource) (err error) {
+func (props *PostgresUserProps) attributes() roleAttributes {
+	return roleAttributes{
+		connectionLimit: props.ConnectionLimit,
+		createDatabase:  props.CreateDatabase,
+		createRole:      props.CreateRole,
+		superuser:       props.Superuser,
+		inherit:         props.Inherit,
+		settings:        props.Settings,
+	}
+}
+
+func (props *PostgresUserProps) fillRuntimeInputs(ctx *pulumi.Context, name string, res *PostgresUsersResource) (err error) {
+	if props.ValidUntil != "" && props.TTL != "" {
+		return fmt.Errorf("only one of validUntil and ttl can be set for user %s", props.Username)
+	}
+	if props.ValidUntil != "" {
+		if _, err := time.Parse(time.RFC3339, props.ValidUntil); err != nil && props.ValidUntil != "infinity" {
+			return fmt.Errorf("invalid validUntil of user %s: %w", props.Username, err)
+		}
+	}
+	if props.TTL != "" {
+		ttl, err := time.ParseDuration(props.TTL)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid ttl '%s' of user %s", props.TTL, props.Username)
+		}
+		props.ValidUntil = time.Now().UTC().Add(ttl).Format(time.RFC3339)
+	}
+	if props.PasswordIsHashed && props.Password == nil && props.PasswordSecretArn == "" {
+		return fmt.Errorf("password of user %s is hashed, the verifier needs to be set", props.Username)
+	}
+	if props.Comment != "" && props.Connection == nil {
+		return fmt.Errorf("connection is required to comment on user %s", props.Username)
+	}
+	if props.RotatedBySecret && (props.IamAuth || props.PasswordIsHashed || props.PasswordSecretArn != "" || props.RotationTrigger != "" || len(props.Keepers) > 0) {
+		return fmt.Errorf("password of user %s is rotated by its secret, it can't be hashed, read from a secret nor rotated by the stack", props.Username)
+	}
+	if props.IamAuth {
+		if props.Password != nil || props.RotationTrigger != "" || props.PasswordSecretArn != "" {
+			return fmt.Errorf("user %s authenticates with IAM, it can't have a password", props.Username)
+		}
+		return nil
+	}
+	if props.PasswordSecretArn != "" {
+		if props.Password != nil || props.RotationTrigger != "" || len(props.Keepers) > 0 {
+			return fmt.Errorf("password of user %s is read from secret %s, it can't be set nor rotated by the stack", props.Username, props.PasswordSecretArn)
+		}
+		props.Password = secret.LookupPassword(ctx, props.PasswordSecretArn)
+	}
+	if props.PasswordIsHashed {
+		if props.RotationTrigger != "" || len(props.Keepers) > 0 {
+			return fmt.Errorf("password of user %s is hashed, it can't be rotated by the stack", props.Username)
+		}
+		username := props.Username
+		props.Password = props.Password.ToStringOutput().ApplyT(func(verifier string) (string, error) {
+			if err := validateVerifier(verifier); err != nil {
+				return "", fmt.Errorf("hashed password of user %s: %w", username, err)
+			}
+			return verifier, nil
+		}).(pulumi.StringOutput)
+		return nil
+	}
+	if props.PasswordSecretArn != "" {
+		return nil
+	}
 	if props.Password == nil {
-		props.Password, err = utils.NewRandomPassword(
-			ctx, fmt.Sprintf("%s-%s", props.Username, "password"), 16, pulumi.Parent(res))
+		keepers := map[string]string{}
+		for k, v := range props.Keepers {
+			keepers[k] = v
+		}
+		if props.RotationTrigger != "" {
+			keepers["rotationTrigger"] = props.RotationTrigger
+		}
+		// prefixed like the role, the users of two components can share a name
+		props.Password, err = utils.NewRotatingPassword(
+			ctx, fmt.Sprintf("%s-%s-password", name, props.Username), 16, keepers,
+			pulumi.Parent(res), renamedFrom(fmt.Sprintf("%s-password", props.Username)))
 	}
 	return
 }
 
-func (r *PostgresUsersResource) provision(ctx *pulumi.Context, name string, props *PostgresUserProps) error {
-	if err := props.fillRuntimeInputs(ctx, r); err != nil {
-		return err
+func (props *PostgresUserProps) validateSetRole() error {
+	if !props.ExplicitSetRole {
+		return nil
+	}
+	if props.Inherit != nil && *props.Inherit {
+		return fmt.Errorf("user %s needs SET ROLE, it can't inherit the privileges of its roles", props.Username)
+	}
+	if props.AssumeRole == nil && len(props.Roles) == 0 {
+		return fmt.Errorf("user %s needs SET ROLE, but isn't granted any role", props.Username)
+	}
+	noInherit := false
+	props.Inherit = &noInherit
+	return nil
+}
+
+// grantMemberships grants the roles of the user one by one, instead of
+// through the roles of postgresql.Role
+func (r *PostgresUsersResource) grantMemberships(ctx *pulumi.Context, resName string, user *postgresql.Role, roles pulumi.StringArray) error {
+	for i, role := range roles {
+		// GRANT $ROLE TO $USER;
+		grant, err := postgresql.NewGrantRole(ctx, fmt.Sprintf("%s-grantRole-%d", resName, i), &postgresql.GrantRoleArgs{
+			GrantRole: role,
+			Role:      user.Name,
+		}, pulumi.Parent(r))
+		if err != nil {
+			return err
+		}
+		r.memberships = append(r.memberships, grant)
+	}
+	return nil
+}
+
+func (r *PostgresUsersResource) provision(ctx *pulumi.Context, name string, props *PostgresUserProps) (*postgresql.Role, error) {
+	if err := props.validateSetRole(); err != nil {
+		return nil, err
+	}
+	if err := props.fillRuntimeInputs(ctx, name, r); err != nil {
+		return nil, err
 	}
 
-	role, err := postgresql.NewRole(ctx, fmt.Sprintf("%s-%s", name, props.Username), &postgresql.RoleArgs{
+	roles := pulumi.StringArray{}
+	if props.AssumeRole != nil {
+		roles = append(roles, props.AssumeRole)
+	}
+	roles = append(roles, props.Roles...)
+	if props.IamAuth {
+		// GRANT rds_iam TO $USER;
+		roles = append(roles, pulumi.String("rds_iam"))
+	}
+	args := &postgresql.RoleArgs{
 		Name:       pulumi.String(props.Username),
 		Password:   props.Password,
 		Login:      pulumi.BoolPtr(props.Login),
 		AssumeRole: props.AssumeRole,
-		Roles:      pulumi.StringArray{props.AssumeRole},
-	}, pulumi.Parent(r))
-	if err != nil {
-		return err
+		Roles:      roles,
 	}
-	r.Users = append(r.Users, role)
-	return nil
+	if err := props.attributes().apply(args); err != nil {
+		return nil, fmt.Errorf("user %s: %w", props.Username, err)
+	}
+	opts := withProtection(props.Protect, props.RetainOnDelete, withImport(props.ImportExisting, props.Username, pulumi.Parent(r))...)
+	if props.ValidUntil != "" {
+		args.ValidUntil = pulumi.String(props.ValidUntil)
+	}
+	ignoreChanges := []string{}
+	if props.TTL != "" {
+		// the expiry is relative to the creation, not to every deploy
+		ignoreChanges = append(ignoreChanges, "validUntil")
+	}
+	if props.ExplicitSetRole {
+		// no ALTER ROLE $USER SET ROLE $ROLE, and the memberships belong to
+		// the grants, the role would revoke them otherwise
+		args.AssumeRole = nil
+		args.Roles = nil
+		ignoreChanges = append(ignoreChanges, "roles")
+	}
+	if props.RotatedBySecret {
+		// the rotations own the password once the role exists
+		ignoreChanges = append(ignoreChanges, "password")
+	}
+	if len(ignoreChanges) > 0 {
+		opts = append(opts, pulumi.IgnoreChanges(ignoreChanges))
+	}
+	resName := fmt.Sprintf("%s-%s", name, props.Username)
+	user, err := postgresql.NewRole(ctx, resName, args, opts...)
+	if err != nil || !props.ExplicitSetRole {
+		return user, err
+	}
+	return user, r.grantMemberships(ctx, resName, user, roles)
 }
 
 func NewPostgresUsers(ctx *pulumi.Context, name string, props []PostgresUserProps, opts ...pulumi.ResourceOption) (*PostgresUsersResource, error) {
-	resource := &PostgresUsersResource{}
+	for _, prop := range props {
+		if err := validateRoleName(prop.Username); err != nil {
+			return nil, err
+		}
+	}
+	resource := &PostgresUsersResource{
+		Users:       make([]*postgresql.Role, len(props)),
+		FailedUsers: map[string]error{},
+		DSNs:        map[string]pulumi.StringOutput{},
+	}
 	if err := ctx.RegisterComponentResource("ss9:postgres:users", name, resource, opts...); err != nil {
 		return nil, err
 	}
-	for _, prop := range props {
-		err := resource.provision(ctx, name, &prop)
+	// a failed user doesn't hold back the others
+	errs := []error{}
+	comments := []string{}
+	for i, prop := range props {
+		role, err := resource.provision(ctx, name, &prop)
 		if err != nil {
-			resource.FailedUser = prop.Username
-			return resource, err
+			resource.FailedUsers[prop.Username] = err
+			errs = append(errs, fmt.Errorf("user %s: %w", prop.Username, err))
+			continue
+		}
+		resource.Users[i] = role
+		if prop.Endpoint != nil && !prop.PasswordIsHashed && !prop.RotatedBySecret {
+			endpoint := *prop.Endpoint
+			if endpoint.Database == "" {
+				endpoint.Database = name
+			}
+			resource.DSNs[prop.Username] = endpoint.DSN(role.Name, prop.Password)
+		}
+		if prop.Comment != "" {
+			comments = append(comments, commentSQL("ROLE", prop.Username, prop.Comment))
+			if _, err := NewRoleComment(ctx, fmt.Sprintf("%s-%s-comment", name, prop.Username), prop.Connection, prop.Username, pulumi.String(prop.Comment),
+				pulumi.Parent(resource), pulumi.DependsOn([]pulumi.Resource{role})); err != nil {
+				errs = append(errs, fmt.Errorf("user %s: %w", prop.Username, err))
+			}
 		}
 	}
+	resource.CommentsSQL = strings.Join(comments, "\n")
 
-	outputRoles := make([]pulumi.MapInput, len(resource.Users))
-	for _, role := range resource.Users {
-		outputRoles = append(outputRoles, pulumi.Map{
-			"username": role.Name,
-			"password": role.Password,
-		})
-	}
-	ctx.RegisterResourceOutputs(resource, pulumi.Map{
-		"users": pulumi.MapArray(outputRoles),
-	})
-	return resource, nil
+	roles := make([]pulumi.CustomResource, 0, len(resource.Users))
+	usernames := make(pulumi.StringArray, 0, len(resource.Users))
+	passwords := pulumi.StringMap{}
+	for i, role := range resource.Users {
+		if role == nil {
+			continue
+		}
+		roles = append(roles, role)
+		usernames = append(usernames, role.Name)
+		if !props[i].IamAuth && !props[i].PasswordIsHashed {
+			passwords[props[i].Username] = role.Password.Elem()
+		}
+	}
+	resource.Ready = readyAfter(append(roles, resource.memberships...)...)
+	resource.Outputs = &PostgresUsersOutputs{
+		Usernames:   usernames.ToStringArrayOutput(),
+		Passwords:   pulumi.ToSecret(passwords).(pulumi.StringMapOutput),
+		DSNs:        toStringMapOutput(resource.DSNs),
+		CommentsSQL: resource.CommentsSQL,
+		Ready:       resource.Ready,
+	}
+	ctx.RegisterResourceOutputs(resource, resource.Outputs.resourceOutputs())
+	return resource, errors.Join(errs...)
 }
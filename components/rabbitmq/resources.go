@@ -0,0 +1,30 @@
+package rabbitmq
+
+import (
+	rabbitmqsdk "github.com/pulumi/pulumi-rabbitmq/sdk/v3/go/rabbitmq"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ProviderArgs struct {
+	// URL of the management API, e.g. https://broker:15671
+	Endpoint pulumi.StringInput
+	Username pulumi.StringInput
+	Password pulumi.StringInput
+	// Skips the verification of the certificate of the management API
+	Insecure bool
+}
+
+// NewProvider configures the broker the components provision, through its
+// management API. The plugin runs the version of the pulumi-rabbitmq SDK,
+// checked against plugins:rabbitmq by utils.CheckPluginVersions.
+func NewProvider(ctx *pulumi.Context, name string, args ProviderArgs, opts ...pulumi.ResourceOption) (*rabbitmqsdk.Provider, error) {
+	providerArgs := &rabbitmqsdk.ProviderArgs{
+		Endpoint: args.Endpoint.ToStringOutput(),
+		Username: args.Username.ToStringOutput(),
+		Password: pulumi.ToSecret(args.Password).(pulumi.StringOutput),
+	}
+	if args.Insecure {
+		providerArgs.Insecure = pulumi.Bool(true)
+	}
+	return rabbitmqsdk.NewProvider(ctx, name, providerArgs, opts...)
+}
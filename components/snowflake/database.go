@@ -0,0 +1,322 @@
+package snowflake
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	snowflakesdk "github.com/pulumi/pulumi-snowflake/sdk/go/snowflake"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	defaultWarehouseSize = "XSMALL"
+	// seconds of inactivity before the warehouse is suspended, and stops billing
+	defaultAutoSuspend = 60
+)
+
+type rolePrivileges struct {
+	schema []string
+	tables []string
+	views  []string
+}
+
+var (
+	nameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$-]*$`)
+	// privileges of the roles in the database, as the rw/ro roles of the
+	// postgres components
+	permissionPrivileges = map[postgres.PostgresUserPermission]rolePrivileges{
+		postgres.ReadWrite: {
+			schema: []string{"USAGE", "CREATE TABLE", "CREATE VIEW"},
+			tables: []string{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE"},
+			views:  []string{"SELECT"},
+		},
+		postgres.ReadOnly: {
+			schema: []string{"USAGE"},
+			tables: []string{"SELECT"},
+			views:  []string{"SELECT"},
+		},
+	}
+)
+
+type SnowflakeWarehouseProps struct {
+	// XSMALL if not set
+	Size string `json:"size"`
+	// 60 if not set
+	AutoSuspendSeconds int `json:"autoSuspendSeconds"`
+}
+
+// SnowflakeDbProps is the database, its warehouse & roles, with the permission
+// model of the postgres component, so the programs can be backend-agnostic.
+type SnowflakeDbProps struct {
+	Database string `json:"database"`
+	Comment  string `json:"comment"`
+	// Only rw & ro are supported (with schemas and existingRole), rw and ro
+	// if not set
+	DbRoles   []postgres.PostgresDbRoleProps `json:"dbRoles"`
+	Warehouse SnowflakeWarehouseProps        `json:"warehouse"`
+	// Days of time travel of the tables, the account's default if not set
+	DataRetentionDays int `json:"dataRetentionDays"`
+	// Fails any delete of the database, e.g. in a destroy
+	Protect bool `json:"protect"`
+}
+
+type SnowflakeDBResource struct {
+	pulumi.ResourceState
+
+	DB        *snowflakesdk.Database
+	Warehouse *snowflakesdk.Warehouse
+	// Indexed as DbRoles, nil for the existing roles
+	Roles []*snowflakesdk.AccountRole
+	// Names of the roles keyed by permission, known before they're created
+	RoleNames map[postgres.PostgresUserPermission]string
+	// Name of the database in snowflake, i.e. in uppercase
+	Name string
+	// Name of the warehouse, known before it's created
+	WarehouseName string
+}
+
+// identifier is the unquoted name of the object in snowflake, i.e. in
+// uppercase with - replaced
+func identifier(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func validateName(kind string, name string) error {
+	if !nameRegex.MatchString(name) {
+		return fmt.Errorf("invalid %s name '%s', only letters, digits and _ $ - are allowed", kind, name)
+	}
+	return nil
+}
+
+// validateDbRole rejects the props of the postgres roles snowflake has no equivalent of
+func validateDbRole(role *postgres.PostgresDbRoleProps) error {
+	if _, ok := permissionPrivileges[role.Permission]; !ok {
+		return fmt.Errorf("permission '%s' isn't supported by snowflake, expected %s or %s", role.Permission, postgres.ReadWrite, postgres.ReadOnly)
+	}
+	if len(role.Tables) > 0 || len(role.FunctionSchemas) > 0 || len(role.Settings) > 0 {
+		return fmt.Errorf("role %s: tables, functionSchemas and settings aren't supported by snowflake", role.Permission)
+	}
+	if role.ConnectionLimit != 0 || role.CreateDatabase || role.CreateRole || role.Superuser || role.Inherit != nil {
+		return fmt.Errorf("role %s: role attributes aren't supported by snowflake", role.Permission)
+	}
+	for _, schema := range role.Schemas {
+		if err := validateName("schema", schema); err != nil {
+			return fmt.Errorf("role %s: %w", role.Permission, err)
+		}
+	}
+	return nil
+}
+
+func (props *SnowflakeDbProps) validate() error {
+	if err := validateName("database", props.Database); err != nil {
+		return err
+	}
+	if len(props.DbRoles) == 0 {
+		props.DbRoles = []postgres.PostgresDbRoleProps{{Permission: postgres.ReadWrite}, {Permission: postgres.ReadOnly}}
+	}
+	seen := map[postgres.PostgresUserPermission]bool{}
+	for i := range props.DbRoles {
+		role := &props.DbRoles[i]
+		if err := validateDbRole(role); err != nil {
+			return err
+		}
+		if seen[role.Permission] {
+			return fmt.Errorf("duplicate %s role of database %s", role.Permission, props.Database)
+		}
+		seen[role.Permission] = true
+	}
+	if props.Warehouse.Size == "" {
+		props.Warehouse.Size = defaultWarehouseSize
+	}
+	if props.Warehouse.AutoSuspendSeconds == 0 {
+		props.Warehouse.AutoSuspendSeconds = defaultAutoSuspend
+	}
+	return nil
+}
+
+// grantScope is the database or the schema of the objects of a grant, keyed
+// in the name of the resource
+type grantScope struct {
+	key        string
+	inDatabase pulumi.StringPtrInput
+	inSchema   pulumi.StringPtrInput
+}
+
+// schemaGrant is the schemas of a grant, keyed in the name of the resource
+type schemaGrant struct {
+	key string
+	on  *snowflakesdk.GrantPrivilegesToAccountRoleOnSchemaArgs
+}
+
+// grant grants the privileges to the role, on the object set in args
+func (r *SnowflakeDBResource) grant(ctx *pulumi.Context, name string, role pulumi.StringInput, privileges []string, args *snowflakesdk.GrantPrivilegesToAccountRoleArgs) error {
+	args.AccountRoleName = role
+	args.Privileges = pulumi.ToStringArray(privileges)
+	_, err := snowflakesdk.NewGrantPrivilegesToAccountRole(ctx, name, args, pulumi.Parent(r))
+	return err
+}
+
+// grantSchemas grants the privileges on the schemas, and on their tables &
+// views, existing and future ones
+func (r *SnowflakeDBResource) grantSchemas(ctx *pulumi.Context, prefix string, role pulumi.StringInput, dbRole *postgres.PostgresDbRoleProps) error {
+	privileges := permissionPrivileges[dbRole.Permission]
+	database := r.DB.Name
+	// the whole database, unless the role is restricted to some schemas
+	scopes := []grantScope{{key: "db", inDatabase: database}}
+	schemaGrants := []schemaGrant{
+		{"db", &snowflakesdk.GrantPrivilegesToAccountRoleOnSchemaArgs{AllSchemasInDatabase: database}},
+		{"db-future", &snowflakesdk.GrantPrivilegesToAccountRoleOnSchemaArgs{FutureSchemasInDatabase: database}},
+	}
+	if len(dbRole.Schemas) > 0 {
+		scopes, schemaGrants = nil, nil
+		for _, schema := range dbRole.Schemas {
+			qualified := pulumi.Sprintf(`"%s"."%s"`, database, identifier(schema))
+			scopes = append(scopes, grantScope{key: schema, inSchema: qualified})
+			schemaGrants = append(schemaGrants, schemaGrant{schema, &snowflakesdk.GrantPrivilegesToAccountRoleOnSchemaArgs{SchemaName: qualified}})
+		}
+	}
+	for _, schemaGrant := range schemaGrants {
+		// GRANT USAGE ON ALL SCHEMAS IN DATABASE $DB TO ROLE $ROLE;
+		if err := r.grant(ctx, fmt.Sprintf("%s-schemas-%s", prefix, schemaGrant.key), role, privileges.schema, &snowflakesdk.GrantPrivilegesToAccountRoleArgs{
+			OnSchema: schemaGrant.on,
+		}); err != nil {
+			return err
+		}
+	}
+	for _, scope := range scopes {
+		for _, objects := range []struct {
+			plural     string
+			privileges []string
+		}{{"TABLES", privileges.tables}, {"VIEWS", privileges.views}} {
+			kind := strings.ToLower(objects.plural)
+			// GRANT SELECT ON ALL TABLES IN DATABASE $DB TO ROLE $ROLE;
+			if err := r.grant(ctx, fmt.Sprintf("%s-%s-%s", prefix, kind, scope.key), role, objects.privileges, &snowflakesdk.GrantPrivilegesToAccountRoleArgs{
+				OnSchemaObject: &snowflakesdk.GrantPrivilegesToAccountRoleOnSchemaObjectArgs{
+					All: &snowflakesdk.GrantPrivilegesToAccountRoleOnSchemaObjectAllArgs{
+						ObjectTypePlural: pulumi.String(objects.plural),
+						InDatabase:       scope.inDatabase,
+						InSchema:         scope.inSchema,
+					},
+				},
+			}); err != nil {
+				return err
+			}
+			// GRANT SELECT ON FUTURE TABLES IN DATABASE $DB TO ROLE $ROLE;
+			if err := r.grant(ctx, fmt.Sprintf("%s-%s-%s-future", prefix, kind, scope.key), role, objects.privileges, &snowflakesdk.GrantPrivilegesToAccountRoleArgs{
+				OnSchemaObject: &snowflakesdk.GrantPrivilegesToAccountRoleOnSchemaObjectArgs{
+					Future: &snowflakesdk.GrantPrivilegesToAccountRoleOnSchemaObjectFutureArgs{
+						ObjectTypePlural: pulumi.String(objects.plural),
+						InDatabase:       scope.inDatabase,
+						InSchema:         scope.inSchema,
+					},
+				},
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *SnowflakeDBResource) provision(ctx *pulumi.Context, namePrefix string, props *SnowflakeDbProps) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	destroyProtected, err := utils.DestroyProtected(ctx)
+	if err != nil {
+		return err
+	}
+	r.Name = identifier(props.Database)
+	// CREATE DATABASE $DB DATA_RETENTION_TIME_IN_DAYS = $DAYS;
+	dbArgs := &snowflakesdk.DatabaseArgs{
+		Name: pulumi.String(r.Name),
+	}
+	if props.Comment != "" {
+		dbArgs.Comment = pulumi.String(props.Comment)
+	}
+	if props.DataRetentionDays > 0 {
+		dbArgs.DataRetentionTimeInDays = pulumi.Int(props.DataRetentionDays)
+	}
+	r.DB, err = snowflakesdk.NewDatabase(ctx, fmt.Sprintf("%s-db", namePrefix), dbArgs, pulumi.Parent(r), pulumi.Protect(props.Protect || destroyProtected))
+	if err != nil {
+		return err
+	}
+	// CREATE WAREHOUSE $DB_WH WAREHOUSE_SIZE = XSMALL AUTO_SUSPEND = 60 INITIALLY_SUSPENDED = TRUE;
+	r.WarehouseName = identifier(fmt.Sprintf("%s-wh", props.Database))
+	r.Warehouse, err = snowflakesdk.NewWarehouse(ctx, fmt.Sprintf("%s-wh", namePrefix), &snowflakesdk.WarehouseArgs{
+		Name:               pulumi.String(r.WarehouseName),
+		WarehouseSize:      pulumi.String(props.Warehouse.Size),
+		AutoSuspend:        pulumi.Int(props.Warehouse.AutoSuspendSeconds),
+		AutoResume:         pulumi.String("true"),
+		InitiallySuspended: pulumi.Bool(true),
+	}, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+
+	r.Roles = make([]*snowflakesdk.AccountRole, len(props.DbRoles))
+	r.RoleNames = map[postgres.PostgresUserPermission]string{}
+	for i := range props.DbRoles {
+		dbRole := &props.DbRoles[i]
+		prefix := fmt.Sprintf("%s-%s", namePrefix, dbRole.Permission)
+		roleName := dbRole.ExistingRole
+		var grantee pulumi.StringInput = pulumi.String(roleName)
+		if roleName == "" {
+			roleName = identifier(dbRole.RoleName(props.Database))
+			// CREATE ROLE $DB_RW;
+			role, err := snowflakesdk.NewAccountRole(ctx, prefix, &snowflakesdk.AccountRoleArgs{
+				Name: pulumi.String(roleName),
+			}, pulumi.Parent(r))
+			if err != nil {
+				return err
+			}
+			r.Roles[i] = role
+			grantee = role.Name
+		}
+		r.RoleNames[dbRole.Permission] = roleName
+		// GRANT USAGE ON DATABASE $DB TO ROLE $ROLE;
+		if err := r.grant(ctx, fmt.Sprintf("%s-database", prefix), grantee, []string{"USAGE"}, &snowflakesdk.GrantPrivilegesToAccountRoleArgs{
+			OnAccountObject: &snowflakesdk.GrantPrivilegesToAccountRoleOnAccountObjectArgs{ObjectType: pulumi.String("DATABASE"), ObjectName: r.DB.Name},
+		}); err != nil {
+			return err
+		}
+		// GRANT USAGE ON WAREHOUSE $DB_WH TO ROLE $ROLE;
+		if err := r.grant(ctx, fmt.Sprintf("%s-warehouse", prefix), grantee, []string{"USAGE"}, &snowflakesdk.GrantPrivilegesToAccountRoleArgs{
+			OnAccountObject: &snowflakesdk.GrantPrivilegesToAccountRoleOnAccountObjectArgs{ObjectType: pulumi.String("WAREHOUSE"), ObjectName: r.Warehouse.Name},
+		}); err != nil {
+			return err
+		}
+		if err := r.grantSchemas(ctx, prefix, grantee, dbRole); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewSnowflakeDatabase creates the database, its warehouse and its rw & ro
+// roles granted on its schemas, tables & views, existing and future ones.
+func NewSnowflakeDatabase(ctx *pulumi.Context, name string, props SnowflakeDbProps, opts ...pulumi.ResourceOption) (*SnowflakeDBResource, error) {
+	resource := &SnowflakeDBResource{}
+	if err := ctx.RegisterComponentResource("ss9:snowflake:database", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	if err := resource.provision(ctx, name, &props); err != nil {
+		return resource, err
+	}
+
+	roles := []string{}
+	for _, role := range resource.RoleNames {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"database":  resource.DB.Name,
+		"warehouse": resource.Warehouse.Name,
+		"roles":     pulumi.ToStringArray(roles),
+	})
+	return resource, nil
+}
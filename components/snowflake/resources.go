@@ -0,0 +1,33 @@
+package snowflake
+
+import (
+	snowflakesdk "github.com/pulumi/pulumi-snowflake/sdk/go/snowflake"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ProviderArgs struct {
+	// Account identifier, e.g. myorg-account1
+	Account  string
+	User     pulumi.StringInput
+	Password pulumi.StringInput
+	// Role the resources are managed with, SYSADMIN can't manage the users
+	// and grants, SECURITYADMIN if not set
+	Role string
+}
+
+// NewProvider configures the account the components provision, as
+// postgresql.NewProvider does for postgres. The plugin runs the version of
+// the pulumi-snowflake SDK, checked against plugins:snowflake by
+// utils.CheckPluginVersions.
+func NewProvider(ctx *pulumi.Context, name string, args ProviderArgs, opts ...pulumi.ResourceOption) (*snowflakesdk.Provider, error) {
+	role := args.Role
+	if role == "" {
+		role = "SECURITYADMIN"
+	}
+	return snowflakesdk.NewProvider(ctx, name, &snowflakesdk.ProviderArgs{
+		Account:  pulumi.String(args.Account),
+		User:     args.User.ToStringOutput(),
+		Password: pulumi.ToSecret(args.Password).(pulumi.StringOutput),
+		Role:     pulumi.String(role),
+	}, opts...)
+}
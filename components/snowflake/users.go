@@ -0,0 +1,112 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+
+	snowflakesdk "github.com/pulumi/pulumi-snowflake/sdk/go/snowflake"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+type SnowflakeUserProps struct {
+	Username string `json:"username"`
+	// Role of the database the user is granted, rw if not set
+	Permission postgres.PostgresUserPermission `json:"permission"`
+	// Bump it to rotate the generated password
+	RotationTrigger string `json:"rotationTrigger"`
+	// Fails any delete of the user, e.g. in a destroy
+	Protect bool `json:"protect"`
+}
+
+type SnowflakeUsersResource struct {
+	pulumi.ResourceState
+
+	// Keyed by username
+	Users     map[string]*snowflakesdk.User
+	Passwords map[string]pulumi.StringOutput
+	// Errors of the failed users, keyed by username
+	FailedUsers map[string]error
+}
+
+func (r *SnowflakeUsersResource) provision(ctx *pulumi.Context, name string, db *SnowflakeDBResource, props *SnowflakeUserProps) error {
+	if err := validateName("user", props.Username); err != nil {
+		return err
+	}
+	if props.Permission == "" {
+		props.Permission = postgres.ReadWrite
+	}
+	roleName, ok := db.RoleNames[props.Permission]
+	if !ok {
+		return fmt.Errorf("database %s has no %s role", db.Name, props.Permission)
+	}
+	resName := fmt.Sprintf("%s-%s", name, props.Username)
+	keepers := map[string]string{}
+	if props.RotationTrigger != "" {
+		keepers["rotationTrigger"] = props.RotationTrigger
+	}
+	// the default password policy requires upper & lowercase letters and digits
+	password, err := utils.NewComplexPassword(ctx, fmt.Sprintf("%s-password", resName), 16, keepers, pulumi.Parent(r))
+	if err != nil {
+		return err
+	}
+	username := identifier(props.Username)
+	// CREATE USER $USER PASSWORD = '$PASSWORD' DEFAULT_ROLE = $ROLE DEFAULT_WAREHOUSE = $DB_WH;
+	user, err := snowflakesdk.NewUser(ctx, resName, &snowflakesdk.UserArgs{
+		Name:               pulumi.String(username),
+		LoginName:          pulumi.String(username),
+		Password:           pulumi.ToSecret(password).(pulumi.StringOutput),
+		DefaultRole:        pulumi.String(roleName),
+		DefaultWarehouse:   pulumi.String(db.WarehouseName),
+		DefaultNamespace:   pulumi.String(db.Name),
+		MustChangePassword: pulumi.Bool(false),
+	}, pulumi.Parent(r), pulumi.Protect(props.Protect))
+	if err != nil {
+		return err
+	}
+	// GRANT ROLE $ROLE TO USER $USER;
+	if _, err := snowflakesdk.NewGrantAccountRole(ctx, fmt.Sprintf("%s-role", resName), &snowflakesdk.GrantAccountRoleArgs{
+		RoleName: pulumi.String(roleName),
+		UserName: user.Name,
+	}, pulumi.Parent(r)); err != nil {
+		return err
+	}
+	r.Users[props.Username] = user
+	r.Passwords[props.Username] = pulumi.ToSecret(password).(pulumi.StringOutput)
+	return nil
+}
+
+// NewSnowflakeUsers creates the login users of the database, named as the
+// component, with random passwords and the role of their permission as
+// default role.
+func NewSnowflakeUsers(ctx *pulumi.Context, name string, db *SnowflakeDBResource, props []SnowflakeUserProps, opts ...pulumi.ResourceOption) (*SnowflakeUsersResource, error) {
+	if db == nil || db.DB == nil {
+		return nil, fmt.Errorf("database of the users is required")
+	}
+	resource := &SnowflakeUsersResource{
+		Users:       map[string]*snowflakesdk.User{},
+		Passwords:   map[string]pulumi.StringOutput{},
+		FailedUsers: map[string]error{},
+	}
+	opts = append(opts, pulumi.DependsOn([]pulumi.Resource{db}))
+	if err := ctx.RegisterComponentResource("ss9:snowflake:users", name, resource, opts...); err != nil {
+		return nil, err
+	}
+	// a failed user doesn't hold back the others
+	errs := []error{}
+	usernames := pulumi.StringArray{}
+	for _, prop := range props {
+		if err := resource.provision(ctx, name, db, &prop); err != nil {
+			resource.FailedUsers[prop.Username] = err
+			errs = append(errs, fmt.Errorf("user %s: %w", prop.Username, err))
+			continue
+		}
+		usernames = append(usernames, pulumi.String(identifier(prop.Username)))
+	}
+
+	ctx.RegisterResourceOutputs(resource, pulumi.Map{
+		"usernames": usernames,
+	})
+	return resource, errors.Join(errs...)
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
@@ -97,6 +99,8 @@ func loadJsonConfig(cfg *config.Config, fieldName string, isRequired bool, curr
 // json/config - the name of the config key
 // secret - the name of the secret config key
 // required - whether the config is required
+// union - the tagged union an interface field is decoded into, see RegisterUnionType
+// enum - the comma separated values a string field accepts, e.g. enum:"perUser,consolidated"
 // The tags are used to map the config to the struct fields.
 func ExtractConfig(ctx *pulumi.Context, namespace string, obj interface{}) error {
 	cfg := config.New(ctx, namespace)
@@ -222,6 +226,23 @@ func ExtractConfig(ctx *pulumi.Context, namespace string, obj interface{}) error
 				fv.Set(val.Elem())
 			}
 		case reflect.Interface:
+			if union := ff.Tag.Get("union"); union != "" {
+				data, err := loadJsonConfig(cfg, fieldName, isRequired, nil)
+				if err != nil {
+					if errors.Is(err, ErrJsonEmpty) {
+						continue
+					}
+					return fmt.Errorf("failed to load json config for field '%s': %w", fieldName, err)
+				}
+				dict := map[string]interface{}{}
+				if err := json.Unmarshal(data, &dict); err != nil {
+					return fmt.Errorf("failed to unmarshal json config for field '%s': %w", fieldName, err)
+				}
+				if err := unmarshalUnion(fv, union, dict, fieldName); err != nil {
+					return err
+				}
+				continue
+			}
 			switch ff.Type {
 			case reflect.TypeOf((*pulumi.StringInput)(nil)).Elem():
 				curr, ok := fv.Interface().(pulumi.String)
@@ -276,6 +297,63 @@ func ExtractConfig(ctx *pulumi.Context, namespace string, obj interface{}) error
 		}
 	}
 
+	return validateEnums(v.Elem(), namespace+":")
+}
+
+// validateEnums checks the set string fields tagged with `enum:"a,b"` against
+// their values, in the nested structs too. Unset fields are left to the
+// defaults of the components.
+func validateEnums(v reflect.Value, prefix string) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateEnums(v.Elem(), prefix)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateEnums(v.Index(i), fmt.Sprintf("%s[%d]", prefix, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := validateEnums(iter.Value(), fmt.Sprintf("%s[%v]", prefix, iter.Key())); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			ff := t.Field(i)
+			if !ff.IsExported() {
+				continue
+			}
+			name := ff.Tag.Get("config")
+			if name == "" {
+				name = ff.Tag.Get("json")
+			}
+			if name == "" {
+				continue
+			}
+			key := prefix + name
+			if !strings.HasSuffix(prefix, ":") {
+				key = prefix + "." + name
+			}
+			fv := v.Field(i)
+			if enum := ff.Tag.Get("enum"); enum != "" && fv.Kind() == reflect.String && fv.String() != "" {
+				values := strings.Split(enum, ",")
+				if !slices.Contains(values, fv.String()) {
+					return fmt.Errorf("config %s: '%s' is not one of [%s]", key, fv.String(), strings.Join(values, ", "))
+				}
+				continue
+			}
+			if err := validateEnums(fv, key); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -338,6 +416,14 @@ func unmarshallJSONMap(dict map[string]interface{}, obj interface{}) error {
 
 		switch fv.Kind() {
 		case reflect.Interface:
+			if union := ff.Tag.Get("union"); union != "" {
+				if childDict, ok := dict[fieldName].(map[string]interface{}); ok {
+					if err := unmarshalUnion(fv, union, childDict, fieldName); err != nil {
+						return err
+					}
+				}
+				continue
+			}
 			switch ff.Type {
 			case reflect.TypeOf((*pulumi.StringInput)(nil)).Elem():
 				_, ok := fv.Interface().(pulumi.String)
@@ -370,6 +456,15 @@ func unmarshallJSONMap(dict map[string]interface{}, obj interface{}) error {
 				if err := unmarshallJSONMap(dict[fieldName].(map[string]interface{}), fv.Addr().Interface()); err != nil {
 					return fmt.Errorf("failed to unmarshal json map for field '%s': %w", fieldName, err)
 				}
+			} else if fv.Kind() == reflect.Map {
+				// as the maps of the top level fields
+				bytes, err := json.Marshal(dict[fieldName])
+				if err != nil {
+					return fmt.Errorf("failed to marshal json map for field '%s': %w", fieldName, err)
+				}
+				if err := json.Unmarshal(bytes, fv.Addr().Interface()); err != nil {
+					return fmt.Errorf("failed to unmarshal json map for field '%s': %w", fieldName, err)
+				}
 			} else if fv.Kind() == reflect.Ptr {
 				if fv.IsNil() {
 					fv.Set(reflect.New(fv.Type().Elem()))
@@ -385,7 +480,9 @@ func unmarshallJSONMap(dict map[string]interface{}, obj interface{}) error {
 				} else {
 					// pointer to simple fields/interface
 					if val, ok := dict[fieldName]; ok {
-						return setFieldValue(fv.Elem(), val, fieldName)
+						if err := setFieldValue(fv.Elem(), val, fieldName); err != nil {
+							return err
+						}
 					}
 				}
 			} else if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
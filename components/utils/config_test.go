@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type configTestMocks struct{}
+
+func (configTestMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	return args.Name, args.Inputs, nil
+}
+
+func (configTestMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+// extractTestConfig runs ExtractConfig on the stack config, keyed as in
+// Pulumi.<stack>.yaml (`test:exporter`), the objects given as JSON.
+func extractTestConfig(t *testing.T, namespace string, config map[string]string, obj interface{}) error {
+	t.Helper()
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(pulumi.EnvConfig, string(data))
+	var extractErr error
+	if err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		extractErr = ExtractConfig(ctx, namespace, obj)
+		return nil
+	}, pulumi.WithMocks("project", "stack", configTestMocks{})); err != nil {
+		t.Fatal(err)
+	}
+	return extractErr
+}
+
+func TestExtractConfigNestedMap(t *testing.T) {
+	type profile struct {
+		Name     string            `json:"name"`
+		Settings map[string]string `json:"settings"`
+	}
+	type profilesConfig struct {
+		Profiles []profile `json:"profiles"`
+	}
+	got := profilesConfig{}
+	if err := extractTestConfig(t, "test", map[string]string{
+		"test:profiles": `[{"name": "analysts", "settings": {"max_memory_usage": "10000000000"}}]`,
+	}, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := profilesConfig{Profiles: []profile{{Name: "analysts", Settings: map[string]string{"max_memory_usage": "10000000000"}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestExtractConfigEnum(t *testing.T) {
+	type policy struct {
+		Name    string `json:"name"`
+		Command string `json:"command" enum:"ALL,SELECT,INSERT,UPDATE,DELETE"`
+	}
+	type table struct {
+		Table    string   `json:"table"`
+		Policies []policy `json:"policies"`
+	}
+	type enumConfig struct {
+		ExportMode string  `json:"exportMode" enum:"perUser,consolidated"`
+		Tables     []table `json:"tables"`
+	}
+
+	tests := []struct {
+		name    string
+		config  map[string]string
+		wantErr string
+	}{
+		{name: "valid", config: map[string]string{"test:exportMode": "consolidated"}},
+		{name: "unset", config: map[string]string{}},
+		{
+			name:    "invalid",
+			config:  map[string]string{"test:exportMode": "perService"},
+			wantErr: "config test:exportMode: 'perService' is not one of [perUser, consolidated]",
+		},
+		{
+			name:   "valid in an array",
+			config: map[string]string{"test:tables": `[{"table": "public.orders", "policies": [{"name": "tenant", "command": "SELECT"}, {"name": "all"}]}]`},
+		},
+		{
+			name:    "invalid in an array",
+			config:  map[string]string{"test:tables": `[{"table": "public.orders", "policies": [{"name": "tenant", "command": "select"}]}]`},
+			wantErr: "config test:tables[0].policies[0].command: 'select' is not one of [ALL, SELECT, INSERT, UPDATE, DELETE]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := extractTestConfig(t, "test", tt.config, &enumConfig{})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// AllowDestroyEnv needs to be set to the stack name, besides destroy:allow
+// in the stack config, to lift the destroy protection.
+const AllowDestroyEnv = "PULUMI_ALLOW_DESTROY"
+
+// DestroyProtection protects the production data access (databases and DB
+// creds secrets) against an accidental `pulumi destroy`. It's read from the
+// `destroy` config namespace. Lifting it takes two factors: the config flag,
+// committed and reviewed, and the env var, set by whoever runs the destroy.
+type DestroyProtection struct {
+	Protection bool `json:"protection"`
+	Allow      bool `json:"allow"`
+
+	protected bool
+}
+
+var destroyProtections sync.Map
+
+func LoadDestroyProtection(ctx *pulumi.Context) (*DestroyProtection, error) {
+	if protection, ok := destroyProtections.Load(ctx); ok {
+		return protection.(*DestroyProtection), nil
+	}
+	protection := &DestroyProtection{}
+	if err := ExtractConfig(ctx, "destroy", protection); err != nil {
+		return nil, err
+	}
+	protection.protected = protection.resolve(ctx)
+	destroyProtections.Store(ctx, protection)
+	return protection, nil
+}
+
+// resolve tells whether the guarded resources are to be protected. Once
+// both factors are set, a `pulumi up` unprotects them, so the following
+// `pulumi destroy` can delete them.
+func (p *DestroyProtection) resolve(ctx *pulumi.Context) bool {
+	if !p.Protection {
+		return false
+	}
+	envAllowed := os.Getenv(AllowDestroyEnv) == ctx.Stack()
+	if p.Allow && envAllowed {
+		ctx.Log.Warn("destroy protection is lifted, the databases and the DB creds secrets can be destroyed", nil)
+		return false
+	}
+	if p.Allow || envAllowed {
+		ctx.Log.Warn(fmt.Sprintf("destroy protection needs both destroy:allow and %s=%s to be lifted", AllowDestroyEnv, ctx.Stack()), nil)
+	}
+	return true
+}
+
+// DestroyProtected loads the destroy protection from config and tells whether
+// the guarded resources are to be protected.
+func DestroyProtected(ctx *pulumi.Context) (bool, error) {
+	protection, err := LoadDestroyProtection(ctx)
+	if err != nil {
+		return false, err
+	}
+	return protection.protected, nil
+}
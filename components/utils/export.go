@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// OutputClass tells what a stack output holds, the redaction profiles allow
+// or deny the outputs by their class.
+type OutputClass string
+
+const (
+	// Creds in the output itself, even though they're marked secret
+	OutputCreds OutputClass = "creds"
+	// Names, IDs & ARNs of the provisioned resources
+	OutputReference OutputClass = "reference"
+	// Manifests to be handed off to other tools, e.g. GitOps
+	OutputManifest OutputClass = "manifest"
+)
+
+// classes denied by each redaction profile
+var redactionProfiles = map[string][]OutputClass{
+	"default": {},
+	// creds are only exposed via secrets stores in prod
+	"prod": {OutputCreds},
+}
+
+// ExportPolicy decides which outputs a program may export. It's read from
+// the `export` config namespace, so each environment can set its profile.
+type ExportPolicy struct {
+	Profile string `json:"profile" enum:"default,prod"`
+	// Glob patterns (path.Match) of the output keys which are never exported
+	DenyKeys []string `json:"denyKeys"`
+	// Fail the deployment on a redacted output, instead of warning
+	FailOnRedaction bool `json:"failOnRedaction"`
+}
+
+var exportPolicies sync.Map
+
+func LoadExportPolicy(ctx *pulumi.Context) (*ExportPolicy, error) {
+	if policy, ok := exportPolicies.Load(ctx); ok {
+		return policy.(*ExportPolicy), nil
+	}
+	policy := &ExportPolicy{}
+	if err := ExtractConfig(ctx, "export", policy); err != nil {
+		return nil, err
+	}
+	if policy.Profile == "" {
+		policy.Profile = "default"
+	}
+	if _, ok := redactionProfiles[policy.Profile]; !ok {
+		return nil, fmt.Errorf("unknown export profile '%s'", policy.Profile)
+	}
+	for _, pattern := range policy.DenyKeys {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid deny pattern '%s' of export policy: %w", pattern, err)
+		}
+	}
+	exportPolicies.Store(ctx, policy)
+	return policy, nil
+}
+
+// redactionReason returns why the output is denied, empty if it's allowed
+func (p *ExportPolicy) redactionReason(class OutputClass, key string) string {
+	for _, denied := range redactionProfiles[p.Profile] {
+		if denied == class {
+			return fmt.Sprintf("%s outputs are denied by export profile '%s'", class, p.Profile)
+		}
+	}
+	for _, pattern := range p.DenyKeys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return fmt.Sprintf("key matches denied pattern '%s'", pattern)
+		}
+	}
+	return ""
+}
+
+// Export is the single way programs export their outputs, so the export
+// policy of the stack is enforced on all of them.
+func Export(ctx *pulumi.Context, class OutputClass, key string, value pulumi.Input) error {
+	policy, err := LoadExportPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if reason := policy.redactionReason(class, key); reason != "" {
+		if policy.FailOnRedaction {
+			return fmt.Errorf("output '%s' can't be exported: %s", key, reason)
+		}
+		ctx.Log.Warn(fmt.Sprintf("output '%s' is redacted: %s", key, reason), nil)
+		return nil
+	}
+	ctx.Export(key, value)
+	return nil
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// ImportAliases adopts the resources listed in the logical name → ID map,
+// e.g. the roles & secrets of a database created by hand, instead of
+// creating them. Values which are URNs (urn:pulumi:...) alias the resource
+// to the one already in the state under that URN instead.
+func ImportAliases(ids map[string]string) pulumi.ResourceTransformation {
+	return func(args *pulumi.ResourceTransformationArgs) *pulumi.ResourceTransformationResult {
+		id, ok := ids[args.Name]
+		if !ok {
+			return nil
+		}
+		opts := args.Opts
+		if strings.HasPrefix(id, "urn:pulumi:") {
+			opts = append(opts, pulumi.Aliases([]pulumi.Alias{{URN: pulumi.URN(id)}}))
+		} else if _, custom := args.Resource.(pulumi.CustomResource); custom {
+			// components have no ID in the provider
+			opts = append(opts, pulumi.Import(pulumi.ID(id)))
+		}
+		return &pulumi.ResourceTransformationResult{Props: args.Props, Opts: opts}
+	}
+}
+
+// WithImportAliases applies ImportAliases to the component and all its children
+func WithImportAliases(ids map[string]string) pulumi.ResourceOption {
+	return pulumi.Transformations([]pulumi.ResourceTransformation{ImportAliases(ids)})
+}
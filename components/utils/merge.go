@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+var (
+	inputType  = reflect.TypeOf((*pulumi.Input)(nil)).Elem()
+	outputType = reflect.TypeOf((*pulumi.Output)(nil)).Elem()
+)
+
+// MergeProps deep-merges src into dst, both pointers to the same props
+// struct, e.g. base props overridden by the per-environment ones:
+//   - zero fields of src are skipped, so a layer only sets what it overrides
+//     (which also means a layer can't reset a field to its zero value)
+//   - structs and pointers to structs are merged field by field, into a copy
+//     of the pointed struct so an earlier layer isn't mutated
+//   - maps are merged key by key, src winning on conflicts
+//   - slices, interfaces and the pulumi Inputs & Outputs (e.g. a
+//     pulumi.StringOutput, which is a struct) are replaced as a whole
+//
+// Unexported fields are left untouched.
+func MergeProps(dst interface{}, src interface{}) error {
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() || dv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dst must be a pointer to a struct, got %T", dst)
+	}
+	if sv.Kind() != reflect.Pointer || sv.IsNil() {
+		return fmt.Errorf("src must be a pointer to a struct, got %T", src)
+	}
+	if dv.Type() != sv.Type() {
+		return fmt.Errorf("can't merge %T into %T", src, dst)
+	}
+	mergeValue(dv.Elem(), sv.Elem())
+	return nil
+}
+
+// isLeaf tells the types replaced as a whole, whatever their kind
+func isLeaf(t reflect.Type) bool {
+	for _, iface := range []reflect.Type{inputType, outputType} {
+		if t.Implements(iface) || reflect.PointerTo(t).Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeValue(dst reflect.Value, src reflect.Value) {
+	if isLeaf(src.Type()) {
+		if !src.IsZero() {
+			dst.Set(src)
+		}
+		return
+	}
+	switch src.Kind() {
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			if !dst.Field(i).CanSet() {
+				continue
+			}
+			mergeValue(dst.Field(i), src.Field(i))
+		}
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		if src.Elem().Kind() != reflect.Struct {
+			dst.Set(src)
+			return
+		}
+		merged := reflect.New(src.Elem().Type())
+		if !dst.IsNil() {
+			merged.Elem().Set(dst.Elem())
+		}
+		mergeValue(merged.Elem(), src.Elem())
+		dst.Set(merged)
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		// copied, so the map of an earlier layer isn't mutated
+		merged := reflect.MakeMapWithSize(src.Type(), dst.Len()+src.Len())
+		for _, m := range []reflect.Value{dst, src} {
+			iter := m.MapRange()
+			for iter.Next() {
+				merged.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+		dst.Set(merged)
+	default:
+		if !src.IsZero() {
+			dst.Set(src)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type mergeTestPool struct {
+	Size    int    `json:"size"`
+	Mode    string `json:"mode"`
+	Enabled bool   `json:"enabled"`
+}
+
+type mergeTestProps struct {
+	Name     string             `json:"name"`
+	Replicas int                `json:"replicas"`
+	Pool     mergeTestPool      `json:"pool"`
+	Backup   *mergeTestPool     `json:"backup"`
+	Tags     map[string]string  `json:"tags"`
+	Schemas  []string           `json:"schemas"`
+	Host     pulumi.StringInput `json:"host"`
+	Endpoint pulumi.StringOutput
+	Timeout  *int `json:"timeout"`
+
+	internal string
+}
+
+func TestMergeProps(t *testing.T) {
+	endpoint := pulumi.String("db.internal").ToStringOutput()
+	override := pulumi.String("db.replica").ToStringOutput()
+	timeout := 30
+
+	tests := []struct {
+		name string
+		dst  mergeTestProps
+		src  mergeTestProps
+		want mergeTestProps
+	}{
+		{
+			name: "set fields override, zero ones are skipped",
+			dst:  mergeTestProps{Name: "billing", Replicas: 2},
+			src:  mergeTestProps{Replicas: 3},
+			want: mergeTestProps{Name: "billing", Replicas: 3},
+		},
+		{
+			name: "structs are merged field by field",
+			dst:  mergeTestProps{Pool: mergeTestPool{Size: 10, Mode: "session"}},
+			src:  mergeTestProps{Pool: mergeTestPool{Mode: "transaction", Enabled: true}},
+			want: mergeTestProps{Pool: mergeTestPool{Size: 10, Mode: "transaction", Enabled: true}},
+		},
+		{
+			name: "pointers to structs are merged field by field",
+			dst:  mergeTestProps{Backup: &mergeTestPool{Size: 10, Mode: "session"}},
+			src:  mergeTestProps{Backup: &mergeTestPool{Size: 20}},
+			want: mergeTestProps{Backup: &mergeTestPool{Size: 20, Mode: "session"}},
+		},
+		{
+			name: "pointer set by the src only",
+			src:  mergeTestProps{Backup: &mergeTestPool{Size: 20}, Timeout: &timeout},
+			want: mergeTestProps{Backup: &mergeTestPool{Size: 20}, Timeout: &timeout},
+		},
+		{
+			name: "maps are merged key by key",
+			dst:  mergeTestProps{Tags: map[string]string{"team": "billing", "env": "dev"}},
+			src:  mergeTestProps{Tags: map[string]string{"env": "prod"}},
+			want: mergeTestProps{Tags: map[string]string{"team": "billing", "env": "prod"}},
+		},
+		{
+			name: "slices are replaced",
+			dst:  mergeTestProps{Schemas: []string{"public", "audit"}},
+			src:  mergeTestProps{Schemas: []string{"reporting"}},
+			want: mergeTestProps{Schemas: []string{"reporting"}},
+		},
+		{
+			name: "inputs are replaced",
+			dst:  mergeTestProps{Host: pulumi.String("db.internal")},
+			src:  mergeTestProps{Host: pulumi.String("db.replica")},
+			want: mergeTestProps{Host: pulumi.String("db.replica")},
+		},
+		{
+			name: "outputs are replaced as a whole",
+			dst:  mergeTestProps{Endpoint: endpoint},
+			src:  mergeTestProps{Endpoint: override},
+			want: mergeTestProps{Endpoint: override},
+		},
+		{
+			name: "zero outputs are skipped",
+			dst:  mergeTestProps{Endpoint: endpoint},
+			src:  mergeTestProps{Name: "billing"},
+			want: mergeTestProps{Name: "billing", Endpoint: endpoint},
+		},
+		{
+			name: "unexported fields are left untouched",
+			dst:  mergeTestProps{internal: "dst"},
+			src:  mergeTestProps{internal: "src"},
+			want: mergeTestProps{internal: "dst"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := MergeProps(&tt.dst, &tt.src); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.dst.Endpoint != tt.want.Endpoint {
+				t.Fatalf("got endpoint %v, want %v", tt.dst.Endpoint, tt.want.Endpoint)
+			}
+			tt.dst.Endpoint, tt.want.Endpoint = pulumi.StringOutput{}, pulumi.StringOutput{}
+			if !reflect.DeepEqual(tt.dst, tt.want) {
+				t.Fatalf("got %+v, want %+v", tt.dst, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePropsKeepsLayers(t *testing.T) {
+	base := mergeTestProps{
+		Backup: &mergeTestPool{Size: 10, Mode: "session"},
+		Tags:   map[string]string{"team": "billing"},
+	}
+	env := mergeTestProps{
+		Backup: &mergeTestPool{Size: 20},
+		Tags:   map[string]string{"env": "prod"},
+	}
+	// the props of a stack start from the base layer
+	merged := base
+	if err := MergeProps(&merged, &env); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if base.Backup.Size != 10 || len(base.Tags) != 1 {
+		t.Fatalf("the base layer was mutated: %+v %v", *base.Backup, base.Tags)
+	}
+	if env.Backup.Mode != "" || len(env.Tags) != 1 {
+		t.Fatalf("the env layer was mutated: %+v %v", *env.Backup, env.Tags)
+	}
+	if merged.Backup == base.Backup || merged.Backup == env.Backup {
+		t.Fatal("the merged props share the pointed struct of a layer")
+	}
+}
+
+func TestMergePropsErrors(t *testing.T) {
+	props := mergeTestProps{}
+	tests := []struct {
+		name    string
+		dst     interface{}
+		src     interface{}
+		wantErr string
+	}{
+		{name: "dst not a pointer", dst: props, src: &props, wantErr: "dst must be a pointer to a struct"},
+		{name: "nil dst", dst: (*mergeTestProps)(nil), src: &props, wantErr: "dst must be a pointer to a struct"},
+		{name: "src not a pointer", dst: &props, src: props, wantErr: "src must be a pointer to a struct"},
+		{name: "other types", dst: &props, src: &mergeTestPool{}, wantErr: "can't merge *utils.mergeTestPool into *utils.mergeTestProps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MergeProps(tt.dst, tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"runtime/debug"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// bump it on any breaking change in the exported envelope
+const stackMetadataVersion = "1"
+
+// modules whose versions are reported in the stack metadata
+var trackedModulePrefixes = []string{
+	"github.com/pulumi/pulumi/sdk/",
+	"github.com/pulumi/pulumi-",
+	"github.com/shivanshs9/iac-pulumi/",
+}
+
+// StackMetadata is exported by every program under the `metadata` output,
+// so the stacks can be queried uniformly (e.g. which stacks manage database X).
+// Service and Owner are read from the `stack` config namespace.
+type StackMetadata struct {
+	Service string `json:"service"`
+	Owner   string `json:"owner"`
+
+	Databases []string
+}
+
+func LoadStackMetadata(ctx *pulumi.Context) (*StackMetadata, error) {
+	metadata := &StackMetadata{}
+	if err := ExtractConfig(ctx, "stack", metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func componentVersions() pulumi.StringMap {
+	versions := pulumi.StringMap{}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return versions
+	}
+	for _, dep := range info.Deps {
+		for _, prefix := range trackedModulePrefixes {
+			if strings.HasPrefix(dep.Path, prefix) {
+				version := dep.Version
+				if dep.Replace != nil && dep.Replace.Version != "" {
+					version = dep.Replace.Version
+				}
+				versions[dep.Path] = pulumi.String(version)
+				break
+			}
+		}
+	}
+	return versions
+}
+
+// Export registers the metadata envelope as the `metadata` stack output, a
+// reference output under the export policy like the others.
+func (m *StackMetadata) Export(ctx *pulumi.Context) error {
+	return Export(ctx, OutputReference, "metadata", pulumi.Map{
+		"version":           pulumi.String(stackMetadataVersion),
+		"project":           pulumi.String(ctx.Project()),
+		"stack":             pulumi.String(ctx.Stack()),
+		"service":           pulumi.String(m.Service),
+		"owner":             pulumi.String(m.Owner),
+		"databases":         pulumi.ToStringArray(m.Databases),
+		"componentVersions": componentVersions(),
+	})
+}
@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// provider SDKs of the programs, keyed by module path prefix. The engine
+// runs the plugin of the same version as the SDK the program is built with.
+var providerSDKs = map[string]string{
+	"github.com/pulumi/pulumi-postgresql/sdk/":   "postgresql",
+	"github.com/pulumi/pulumi-aws/sdk/":          "aws",
+	"github.com/pulumi/pulumi-random/sdk/":       "random",
+	"github.com/pulumi/pulumi-mysql/sdk/":        "mysql",
+	"github.com/pulumi/pulumi-mongodbatlas/sdk/": "mongodbatlas",
+	"github.com/pulumi/pulumi-rabbitmq/sdk/":     "rabbitmq",
+	"github.com/pulumi/pulumi-kafka/sdk/":        "kafka",
+	"github.com/pulumi/pulumi-snowflake/sdk/":    "snowflake",
+	"github.com/pulumi/pulumi-vault/sdk/":        "vault",
+	"github.com/pulumi/pulumi-kubernetes/sdk/":   "kubernetes",
+}
+
+// providers whose resources the components register by type token, without
+// SDK. Their plugin runs the version of their pin, the latest installed one
+// if not pinned.
+var tokenProviders = []string{"command", "opensearch"}
+
+// PluginPins are the provider plugin versions a program is expected to run
+// with. It's read from the `plugins` config namespace, meant to be set in the
+// project config (Pulumi.yaml) of each program. Unset pins aren't checked.
+type PluginPins struct {
+	Postgresql   string `json:"postgresql"`
+	Aws          string `json:"aws"`
+	Random       string `json:"random"`
+	Mysql        string `json:"mysql"`
+	Mongodbatlas string `json:"mongodbatlas"`
+	Rabbitmq     string `json:"rabbitmq"`
+	Kafka        string `json:"kafka"`
+	Snowflake    string `json:"snowflake"`
+	// The secret stores of components/aws/secret
+	Vault      string `json:"vault"`
+	Kubernetes string `json:"kubernetes"`
+	// Providers whose resources are registered by type token, without SDK.
+	// Their NewProvider passes it as the version of the provider resource.
+	Opensearch string `json:"opensearch"`
+	Command    string `json:"command"`
+}
+
+func LoadPluginPins(ctx *pulumi.Context) (*PluginPins, error) {
+	pins := &PluginPins{}
+	if err := ExtractConfig(ctx, "plugins", pins); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+func (p *PluginPins) pin(name string) string {
+	switch name {
+	case "postgresql":
+		return p.Postgresql
+	case "aws":
+		return p.Aws
+	case "random":
+		return p.Random
+	case "mysql":
+		return p.Mysql
+	case "mongodbatlas":
+		return p.Mongodbatlas
+	case "rabbitmq":
+		return p.Rabbitmq
+	case "kafka":
+		return p.Kafka
+	case "snowflake":
+		return p.Snowflake
+	case "opensearch":
+		return p.Opensearch
+	case "command":
+		return p.Command
+	case "vault":
+		return p.Vault
+	case "kubernetes":
+		return p.Kubernetes
+	}
+	return ""
+}
+
+// PinPlugin pins the provider resource of a provider without SDK to its
+// plugins:<name> version, if set
+func PinPlugin(ctx *pulumi.Context, name string, opts []pulumi.ResourceOption) ([]pulumi.ResourceOption, error) {
+	pins, err := LoadPluginPins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pin := pins.pin(name)
+	if pin == "" {
+		return opts, nil
+	}
+	return append(opts, pulumi.Version(strings.TrimPrefix(pin, "v"))), nil
+}
+
+// sdkVersions reads the versions of the provider SDKs linked in the program
+func sdkVersions() map[string]string {
+	versions := map[string]string{}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return versions
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		for prefix, name := range providerSDKs {
+			if strings.HasPrefix(dep.Path, prefix) {
+				versions[name] = strings.TrimPrefix(dep.Version, "v")
+			}
+		}
+	}
+	return versions
+}
+
+// installedPlugins lists the versions of the plugin in the plugin cache of
+// the engine, nil if the cache can't be read.
+func installedPlugins(name string) []string {
+	home := os.Getenv("PULUMI_HOME")
+	if home == "" {
+		userHome, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		home = filepath.Join(userHome, ".pulumi")
+	}
+	entries, err := os.ReadDir(filepath.Join(home, "plugins"))
+	if err != nil {
+		return nil
+	}
+	prefix := fmt.Sprintf("resource-%s-v", name)
+	versions := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			versions = append(versions, strings.TrimPrefix(entry.Name(), prefix))
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// warnMissingPlugin warns about the version of the plugin missing from the
+// plugin cache, if the cache can be read
+func warnMissingPlugin(ctx *pulumi.Context, name, version string) {
+	installed := installedPlugins(name)
+	if len(installed) == 0 {
+		return
+	}
+	for _, v := range installed {
+		if v == version {
+			return
+		}
+	}
+	ctx.Log.Warn(fmt.Sprintf("%s plugin v%s isn't installed (found %s), install it with `pulumi plugin install resource %s %s` on offline runners",
+		name, version, strings.Join(installed, ", "), name, version), nil)
+}
+
+// CheckPluginVersions fails on a provider SDK not matching its pin, before
+// any resource is registered, instead of gRPC errors midway through the
+// deploy. Plugins missing from the cache are only warned about, since the
+// engine downloads them if it can, the pinned providers without SDK too.
+//
+// tools are the CLIs the command resources of the program run where pulumi
+// does, e.g. psql. A missing one fails the deploy, it's only warned about in
+// a preview, which doesn't run the commands.
+func CheckPluginVersions(ctx *pulumi.Context, tools ...string) error {
+	pins, err := LoadPluginPins(ctx)
+	if err != nil {
+		return err
+	}
+	versions := sdkVersions()
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		version := versions[name]
+		if pin := pins.pin(name); pin != "" && strings.TrimPrefix(pin, "v") != version {
+			return fmt.Errorf("%s SDK of the program is v%s, but plugins:%s pins v%s: bump the SDK in go.mod (go get github.com/pulumi/pulumi-%s/sdk/...@v%s) or the pin",
+				name, version, name, strings.TrimPrefix(pin, "v"), name, strings.TrimPrefix(pin, "v"))
+		}
+		warnMissingPlugin(ctx, name, version)
+	}
+	for _, name := range tokenProviders {
+		if pin := pins.pin(name); pin != "" {
+			warnMissingPlugin(ctx, name, strings.TrimPrefix(pin, "v"))
+		}
+	}
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err != nil {
+			msg := fmt.Sprintf("%s isn't installed where pulumi runs, the commands of the program run it", tool)
+			if ctx.DryRun() {
+				ctx.Log.Warn(msg, nil)
+				continue
+			}
+			return errors.New(msg)
+		}
+	}
+	return nil
+}
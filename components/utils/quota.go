@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// kinds of the resources counted against the quota
+const (
+	QuotaDatabases = "databases"
+	QuotaUsers     = "users"
+	QuotaSecrets   = "secrets"
+)
+
+// ResourceQuota caps the resources a stack can create, so a runaway config
+// can't create hundreds of roles. It's read from the `quota` config namespace,
+// which is meant to be set org-wide in the project config. 0 disables a limit.
+type ResourceQuota struct {
+	MaxDatabases int `json:"maxDatabases"`
+	MaxUsers     int `json:"maxUsers"`
+	MaxSecrets   int `json:"maxSecrets"`
+}
+
+func LoadResourceQuota(ctx *pulumi.Context) (*ResourceQuota, error) {
+	quota := &ResourceQuota{}
+	if err := ExtractConfig(ctx, "quota", quota); err != nil {
+		return nil, err
+	}
+	return quota, nil
+}
+
+type quotaLimit struct {
+	kind      string
+	configKey string
+	limit     int
+}
+
+func (q *ResourceQuota) limits() []quotaLimit {
+	return []quotaLimit{
+		{QuotaDatabases, "quota:maxDatabases", q.MaxDatabases},
+		{QuotaUsers, "quota:maxUsers", q.MaxUsers},
+		{QuotaSecrets, "quota:maxSecrets", q.MaxSecrets},
+	}
+}
+
+// Check validates the resources the program is about to create, per kind,
+// before any of them is registered.
+func (q *ResourceQuota) Check(planned map[string]int) error {
+	for _, l := range q.limits() {
+		if l.limit > 0 && planned[l.kind] > l.limit {
+			return fmt.Errorf("stack would create %d %s, more than the quota of %d (%s)", planned[l.kind], l.kind, l.limit, l.configKey)
+		}
+	}
+	return nil
+}
+
+// CheckResourceQuota loads the quota from config and checks the planned resources against it.
+func CheckResourceQuota(ctx *pulumi.Context, planned map[string]int) error {
+	quota, err := LoadResourceQuota(ctx)
+	if err != nil {
+		return err
+	}
+	return quota.Check(planned)
+}
@@ -6,10 +6,41 @@ import (
 )
 
 func NewRandomPassword(ctx *pulumi.Context, name string, len int, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
-	passwd, err := random.NewRandomPassword(ctx, name, &random.RandomPasswordArgs{
-		Length:          pulumi.Int(len),
+	return NewRotatingPassword(ctx, name, len, nil, opts...)
+}
+
+// NewRotatingPassword generates a new password whenever any of the keepers changes
+func NewRotatingPassword(ctx *pulumi.Context, name string, length int, keepers map[string]string, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
+	args := &random.RandomPasswordArgs{
+		Length:          pulumi.Int(length),
 		OverrideSpecial: pulumi.String("!#$%&*()-_=+[]{}<>:?"),
-	}, opts...)
+	}
+	if len(keepers) > 0 {
+		args.Keepers = pulumi.ToStringMap(keepers)
+	}
+	passwd, err := random.NewRandomPassword(ctx, name, args, opts...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	return passwd.Result, nil
+}
+
+// NewComplexPassword is a rotating password with at least one uppercase &
+// lowercase letter, digit and special character, for the servers whose
+// password policy requires them
+func NewComplexPassword(ctx *pulumi.Context, name string, length int, keepers map[string]string, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
+	args := &random.RandomPasswordArgs{
+		Length:          pulumi.Int(length),
+		OverrideSpecial: pulumi.String("!#$%&*()-_=+[]{}<>:?"),
+		MinUpper:        pulumi.Int(1),
+		MinLower:        pulumi.Int(1),
+		MinNumeric:      pulumi.Int(1),
+		MinSpecial:      pulumi.Int(1),
+	}
+	if len(keepers) > 0 {
+		args.Keepers = pulumi.ToStringMap(keepers)
+	}
+	passwd, err := random.NewRandomPassword(ctx, name, args, opts...)
 	if err != nil {
 		return pulumi.StringOutput{}, err
 	}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// unionDiscriminator is the key in the config object which selects the union member
+const unionDiscriminator = "type"
+
+// UnionFactory returns a pointer to a new zero value of the union member, the
+// rest of the config object is then decoded into it.
+type UnionFactory func() interface{}
+
+var (
+	unionsMu sync.RWMutex
+	unions   = map[string]map[string]UnionFactory{}
+)
+
+// RegisterUnionType registers a member of the tagged union, so an interface
+// field tagged with `union:"<union>"` gets decoded into it when its config
+// object has `type: <typeName>`, e.g.:
+//
+//	Exporter secret.SecretStore `json:"exporter" union:"secretStore"`
+//
+// The members can also be picked by name only, see NewUnionMember.
+func RegisterUnionType(union string, typeName string, factory UnionFactory) error {
+	if union == "" || typeName == "" {
+		return fmt.Errorf("union and type name must not be empty")
+	}
+	unionsMu.Lock()
+	defer unionsMu.Unlock()
+	if unions[union] == nil {
+		unions[union] = map[string]UnionFactory{}
+	}
+	if _, ok := unions[union][typeName]; ok {
+		return fmt.Errorf("type '%s' is already registered in union '%s'", typeName, union)
+	}
+	unions[union][typeName] = factory
+	return nil
+}
+
+// MustRegisterUnionType is like RegisterUnionType but panics on error, for use in init().
+func MustRegisterUnionType(union string, typeName string, factory UnionFactory) {
+	if err := RegisterUnionType(union, typeName, factory); err != nil {
+		panic(err)
+	}
+}
+
+// NewUnionMember returns a new zero member of the tagged union by its type
+// name, e.g. for the members picked by name only as `exportTargets: [aws]`.
+func NewUnionMember(union string, typeName string) (interface{}, error) {
+	unionsMu.RLock()
+	factory, ok := unions[union][typeName]
+	names := unionTypeNames(union)
+	unionsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown %s type '%s', expected one of [%s]", union, typeName, strings.Join(names, ", "))
+	}
+	return factory(), nil
+}
+
+func unionTypeNames(union string) []string {
+	names := []string{}
+	for name := range unions[union] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unmarshalUnion builds the union member selected by the discriminator of the
+// config object, decodes the other keys into it and sets it in the interface
+// field.
+func unmarshalUnion(fv reflect.Value, union string, dict map[string]interface{}, fieldName string) error {
+	typeName, ok := dict[unionDiscriminator].(string)
+	if !ok || typeName == "" {
+		return fmt.Errorf("'%s' is required in field '%s' to select the %s", unionDiscriminator, fieldName, union)
+	}
+	member, err := NewUnionMember(union, typeName)
+	if err != nil {
+		return fmt.Errorf("field '%s': %w", fieldName, err)
+	}
+	mv := reflect.ValueOf(member)
+	if mv.Kind() != reflect.Ptr || mv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("factory of %s type '%s' must return a pointer to a struct", union, typeName)
+	}
+	if !mv.Type().AssignableTo(fv.Type()) {
+		return fmt.Errorf("%s type '%s' (%v) doesn't implement %v of field '%s'", union, typeName, mv.Type(), fv.Type(), fieldName)
+	}
+	settings := make(map[string]interface{}, len(dict))
+	for key, value := range dict {
+		if key != unionDiscriminator {
+			settings[key] = value
+		}
+	}
+	if err := unmarshallJSONMap(settings, member); err != nil {
+		return fmt.Errorf("failed to unmarshal %s type '%s' for field '%s': %w", union, typeName, fieldName, err)
+	}
+	fv.Set(mv)
+	return nil
+}
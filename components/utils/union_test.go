@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type unionTestMember struct {
+	Name string `json:"name"`
+}
+
+type unionTestStore interface {
+	store() string
+}
+
+type unionTestVault struct {
+	Mount string            `json:"mount"`
+	Tags  map[string]string `json:"tags"`
+}
+
+func (s *unionTestVault) store() string { return "vault" }
+
+type unionTestAWS struct {
+	KmsKeyId string `json:"kmsKeyId"`
+}
+
+func (s *unionTestAWS) store() string { return "aws" }
+
+func TestRegisterUnionType(t *testing.T) {
+	factory := func() interface{} { return &unionTestMember{} }
+	MustRegisterUnionType("test-register", "a", factory)
+
+	tests := []struct {
+		name     string
+		union    string
+		typeName string
+		wantErr  string
+	}{
+		{name: "new type", union: "test-register", typeName: "b"},
+		{name: "same type in another union", union: "test-register-other", typeName: "a"},
+		{name: "duplicate type", union: "test-register", typeName: "a", wantErr: "already registered"},
+		{name: "empty union", union: "", typeName: "c", wantErr: "must not be empty"},
+		{name: "empty type", union: "test-register", typeName: "", wantErr: "must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterUnionType(tt.union, tt.typeName, factory)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewUnionMember(t *testing.T) {
+	MustRegisterUnionType("test-member", "a", func() interface{} { return &unionTestMember{Name: "a"} })
+	MustRegisterUnionType("test-member", "b", func() interface{} { return &unionTestMember{Name: "b"} })
+
+	tests := []struct {
+		name     string
+		union    string
+		typeName string
+		want     string
+		wantErr  string
+	}{
+		{name: "registered type", union: "test-member", typeName: "b", want: "b"},
+		{name: "unknown type lists the registered ones", union: "test-member", typeName: "c", wantErr: "unknown test-member type 'c', expected one of [a, b]"},
+		{name: "unknown union", union: "test-missing", typeName: "a", wantErr: "expected one of []"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			member, err := NewUnionMember(tt.union, tt.typeName)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := member.(*unionTestMember).Name; got != tt.want {
+				t.Fatalf("got member %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// every call returns a new member
+	first, _ := NewUnionMember("test-member", "a")
+	second, _ := NewUnionMember("test-member", "a")
+	if first == second {
+		t.Fatal("members of two calls are the same")
+	}
+}
+
+func TestExtractConfigUnion(t *testing.T) {
+	MustRegisterUnionType("test-store", "vault", func() interface{} { return &unionTestVault{} })
+	MustRegisterUnionType("test-store", "aws", func() interface{} { return &unionTestAWS{} })
+	// doesn't implement unionTestStore
+	MustRegisterUnionType("test-store", "member", func() interface{} { return &unionTestMember{} })
+
+	type target struct {
+		Name  string         `json:"name"`
+		Store unionTestStore `json:"store" union:"test-store"`
+	}
+	type exportConfig struct {
+		Exporter unionTestStore `json:"exporter" union:"test-store"`
+		Targets  []target       `json:"targets"`
+	}
+
+	tests := []struct {
+		name    string
+		config  map[string]string
+		want    exportConfig
+		wantErr string
+	}{
+		{
+			name:   "member with settings",
+			config: map[string]string{"test:exporter": `{"type": "vault", "mount": "kv", "tags": {"team": "billing"}}`},
+			want:   exportConfig{Exporter: &unionTestVault{Mount: "kv", Tags: map[string]string{"team": "billing"}}},
+		},
+		{
+			name:   "member without settings",
+			config: map[string]string{"test:exporter": `{"type": "aws"}`},
+			want:   exportConfig{Exporter: &unionTestAWS{}},
+		},
+		{
+			name:   "not set",
+			config: map[string]string{},
+			want:   exportConfig{},
+		},
+		{
+			name:   "members in an array of objects",
+			config: map[string]string{"test:targets": `[{"name": "primary", "store": {"type": "aws", "kmsKeyId": "alias/shared"}}, {"name": "dr", "store": {"type": "vault", "mount": "dr"}}]`},
+			want: exportConfig{Targets: []target{
+				{Name: "primary", Store: &unionTestAWS{KmsKeyId: "alias/shared"}},
+				{Name: "dr", Store: &unionTestVault{Mount: "dr"}},
+			}},
+		},
+		{
+			name:    "missing type",
+			config:  map[string]string{"test:exporter": `{"mount": "kv"}`},
+			wantErr: "'type' is required in field 'exporter' to select the test-store",
+		},
+		{
+			name:    "unknown type",
+			config:  map[string]string{"test:exporter": `{"type": "gcp"}`},
+			wantErr: "unknown test-store type 'gcp', expected one of [aws, member, vault]",
+		},
+		{
+			name:    "member not implementing the field",
+			config:  map[string]string{"test:exporter": `{"type": "member"}`},
+			wantErr: "doesn't implement",
+		},
+		{
+			name:    "invalid settings",
+			config:  map[string]string{"test:exporter": `{"type": "vault", "mount": 42}`},
+			wantErr: "failed to unmarshal test-store type 'vault' for field 'exporter'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exportConfig{}
+			err := extractTestConfig(t, "test", tt.config, &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/clickhouse"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	// native protocol over TLS, as ClickHouse Cloud
+	defaultProtocol = "nativesecure"
+	defaultPort     = 9440
+)
+
+type clickhouseProviderArg struct {
+	Host string `required:"" json:"host"`
+	Port int    `json:"port"`
+	// nativesecure (TLS) or native, the protocols of clickhouse-client
+	Protocol          string             `json:"protocol" enum:"nativesecure,native"`
+	SuperuserName     pulumi.StringInput `json:"superuserName"`
+	SuperuserPassword pulumi.StringInput `secret:"superuserPassword"`
+	// Secret with the admin creds, instead of superuserName & superuserPassword
+	SuperuserSecretId string `json:"superuserSecretId"`
+}
+
+type clickhouseConfig struct {
+	Database string                              `json:"database" required:""`
+	Comment  string                              `json:"comment"`
+	Profiles []clickhouse.ClickHouseProfileProps `json:"profiles"`
+	Users    []clickhouse.ClickHouseUserProps    `json:"users"`
+	Protect  bool                                `json:"protect"`
+	// Exports the creds as AWS secrets, stack outputs otherwise
+	ExportAsSecret bool `json:"exportAsSecret"`
+
+	provider clickhouseProviderArg
+}
+
+func (cfg *clickhouseConfig) loadSuperuser(ctx *pulumi.Context) error {
+	if cfg.provider.Protocol == "" {
+		cfg.provider.Protocol = defaultProtocol
+	}
+	if cfg.provider.Protocol != "nativesecure" && cfg.provider.Protocol != "native" {
+		return fmt.Errorf("provider:protocol '%s' isn't supported by clickhouse-client, expected nativesecure or native", cfg.provider.Protocol)
+	}
+	if cfg.provider.Port == 0 {
+		cfg.provider.Port = defaultPort
+	}
+	if cfg.provider.SuperuserSecretId == "" {
+		for _, key := range []string{"provider:superuserName", "provider:superuserPassword"} {
+			if _, ok := ctx.GetConfig(key); !ok {
+				return fmt.Errorf("%s is required, unless provider:superuserSecretId is set", key)
+			}
+		}
+		return nil
+	}
+	creds := secret.LookupDBCreds(ctx, cfg.provider.SuperuserSecretId)
+	cfg.provider.SuperuserName = creds.MapIndex(pulumi.String("username"))
+	cfg.provider.SuperuserPassword = creds.MapIndex(pulumi.String("password"))
+	return nil
+}
+
+func (cfg *clickhouseConfig) creds(dbRes *clickhouse.ClickHouseDatabaseResource, username string) pulumi.StringMap {
+	return pulumi.StringMap{
+		"username": pulumi.String(username),
+		"password": dbRes.Passwords[username],
+		"database": pulumi.String(cfg.Database),
+		"host":     pulumi.String(cfg.provider.Host),
+		"port":     pulumi.Sprintf("%d", cfg.provider.Port),
+		"protocol": pulumi.String(cfg.provider.Protocol),
+		"engine":   pulumi.String("clickhouse"),
+	}
+}
+
+// exportCreds exposes each user creds in its own secret, or all of them in
+// the users output
+func (cfg *clickhouseConfig) exportCreds(ctx *pulumi.Context, dbRes *clickhouse.ClickHouseDatabaseResource) error {
+	users := pulumi.Map{}
+	for _, user := range cfg.Users {
+		if _, ok := dbRes.Passwords[user.Username]; !ok {
+			continue
+		}
+		creds := cfg.creds(dbRes, user.Username)
+		if !cfg.ExportAsSecret {
+			users[user.Username] = creds
+			continue
+		}
+		res, err := secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+			Name:         fmt.Sprintf("clickhouse-%s-user-%s", cfg.Database, user.Username),
+			Type:         secret.DBCreds,
+			InitialValue: creds,
+			Tags: map[string]string{
+				"clickhouse:database": cfg.Database,
+				"clickhouse:user":     user.Username,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create secret for user %s: %w", user.Username, err)
+		}
+		if err := utils.Export(ctx, utils.OutputReference, fmt.Sprintf("secret-%s", user.Username), pulumi.StringMap{
+			"secretId": res.Secret.ID().ToStringOutput(),
+		}); err != nil {
+			return err
+		}
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	return utils.Export(ctx, utils.OutputCreds, "users", users)
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		if err := utils.CheckPluginVersions(ctx, clickhouse.ClientTool); err != nil {
+			return err
+		}
+		cfg := &clickhouseConfig{}
+		if err := utils.ExtractConfig(ctx, "clickhouse", cfg); err != nil {
+			return err
+		}
+		if err := utils.ExtractConfig(ctx, "provider", &cfg.provider); err != nil {
+			return err
+		}
+		if err := cfg.loadSuperuser(ctx); err != nil {
+			return err
+		}
+		secrets := 0
+		if cfg.ExportAsSecret {
+			secrets = len(cfg.Users)
+		}
+		if err := utils.CheckResourceQuota(ctx, map[string]int{
+			utils.QuotaDatabases: 1,
+			utils.QuotaUsers:     len(cfg.Users),
+			utils.QuotaSecrets:   secrets,
+		}); err != nil {
+			return err
+		}
+
+		dbRes, dbErr := clickhouse.NewClickHouseDatabase(ctx, cfg.Database, clickhouse.ClickHouseDatabaseProps{
+			Database: cfg.Database,
+			Comment:  cfg.Comment,
+			Profiles: cfg.Profiles,
+			Users:    cfg.Users,
+			Protect:  cfg.Protect,
+			Connection: &clickhouse.ClientConnection{
+				Host:     pulumi.String(cfg.provider.Host),
+				Port:     cfg.provider.Port,
+				Username: cfg.provider.SuperuserName,
+				Password: cfg.provider.SuperuserPassword,
+				Secure:   cfg.provider.Protocol == "nativesecure",
+			},
+		})
+		if dbRes == nil || dbRes.DB == nil {
+			return fmt.Errorf("failed to create database %s: %w", cfg.Database, dbErr)
+		}
+		// the failed users are reported, the others are still exported
+		for _, user := range cfg.Users {
+			if userErr, ok := dbRes.FailedUsers[user.Username]; ok {
+				wrappedErr := fmt.Errorf("failed to create user '%s': %w", user.Username, userErr)
+				ctx.Log.Error(wrappedErr.Error(), &pulumi.LogArgs{Resource: dbRes})
+			}
+		}
+		if err := cfg.exportCreds(ctx, dbRes); err != nil {
+			return err
+		}
+		if err := utils.Export(ctx, utils.OutputReference, "database", pulumi.String(cfg.Database)); err != nil {
+			return err
+		}
+		if err := secret.CheckCostGuard(ctx); err != nil {
+			return err
+		}
+
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		if err := metadata.Export(ctx); err != nil {
+			return err
+		}
+		return dbErr
+	})
+}
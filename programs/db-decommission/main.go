@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/s3"
+	"github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/outputs"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const defaultRecoveryWindow = 30
+
+type decommissionConfig struct {
+	// db-postgres-creds stack of the database, e.g. org/db-postgres-creds/billing
+	CredsStack string `json:"credsStack" required:""`
+	// Ticket or reason of the decommission, kept in the audit record
+	Reason string `json:"reason" required:""`
+	// RDS instance of the database, its final snapshot is taken if set
+	InstanceId string `json:"instanceId"`
+	// Schemas whose table & sequence grants are revoked, public if not set
+	Schemas []string `json:"schemas"`
+	// Days (7-30) during which the deleted secrets can be restored
+	RecoveryWindow int `json:"recoveryWindow"`
+	// Bucket the audit record is written to, only exported if not set
+	AuditBucket string `json:"auditBucket"`
+
+	provider postgres.ProviderConfig
+	// plan of the creds stack, i.e. its roles, users & secrets
+	plan outputs.PlanOutput
+}
+
+// loadPlan reads the roles, users & secrets of the database from the plan
+// output of its creds stack.
+func (cfg *decommissionConfig) loadPlan(ctx *pulumi.Context) error {
+	ref, err := pulumi.NewStackReference(ctx, "creds-stack", &pulumi.StackReferenceArgs{
+		Name: pulumi.String(cfg.CredsStack),
+	})
+	if err != nil {
+		return err
+	}
+	details, err := ref.GetOutputDetails("plan")
+	if err != nil {
+		return fmt.Errorf("failed to read the plan of stack %s: %w", cfg.CredsStack, err)
+	}
+	value := details.Value
+	if value == nil {
+		value = details.SecretValue
+	}
+	if value == nil {
+		return fmt.Errorf("stack %s has no plan output, is it a db-postgres-creds stack?", cfg.CredsStack)
+	}
+	if cfg.plan, err = outputs.ParsePlan(value); err != nil {
+		return err
+	}
+	if cfg.plan.Database == "" {
+		return fmt.Errorf("plan of stack %s has no database", cfg.CredsStack)
+	}
+	return nil
+}
+
+// grantees are the roles & users of the database, and PUBLIC which can
+// connect to any database by default
+func (cfg *decommissionConfig) grantees() []string {
+	grantees := append([]string{"public"}, cfg.plan.Roles...)
+	return append(grantees, cfg.plan.Users...)
+}
+
+// disableLogins revokes CONNECT on the database from all its roles. The
+// roles themselves are left as is, they may log into other databases.
+func (cfg *decommissionConfig) disableLogins(ctx *pulumi.Context, provider *postgresql.Provider) ([]pulumi.Resource, error) {
+	revokes := []pulumi.Resource{}
+	for _, role := range cfg.grantees() {
+		// REVOKE ALL ON DATABASE $DB FROM $ROLE;
+		grant, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-revoke-%s-database", cfg.plan.Database, role), &postgresql.GrantArgs{
+			Database:   pulumi.String(cfg.plan.Database),
+			ObjectType: pulumi.String("database"),
+			Privileges: pulumi.StringArray{},
+			Role:       pulumi.String(role),
+		}, pulumi.Provider(provider))
+		if err != nil {
+			return nil, fmt.Errorf("failed to revoke connect of %s: %w", role, err)
+		}
+		revokes = append(revokes, grant)
+	}
+	return revokes, nil
+}
+
+// provisionSnapshot takes the final snapshot of the instance, it's kept
+// when this stack is destroyed.
+func (cfg *decommissionConfig) provisionSnapshot(ctx *pulumi.Context, dependsOn []pulumi.Resource) (*rds.Snapshot, error) {
+	// snapshot identifiers only allow letters, digits & hyphens
+	snapshotId := strings.ReplaceAll(fmt.Sprintf("%s-%s-final", cfg.InstanceId, cfg.plan.Database), "_", "-")
+	return rds.NewSnapshot(ctx, fmt.Sprintf("%s-final-snapshot", cfg.plan.Database), &rds.SnapshotArgs{
+		DbInstanceIdentifier: pulumi.String(cfg.InstanceId),
+		DbSnapshotIdentifier: pulumi.String(snapshotId),
+		Tags: pulumi.StringMap{
+			"Pulumi":            pulumi.String("true"),
+			"postgres:database": pulumi.String(cfg.plan.Database),
+		},
+	}, pulumi.DependsOn(dependsOn), pulumi.RetainOnDelete(true))
+}
+
+// revokeGrants revokes the privileges of all the roles on the tables &
+// sequences, once the final snapshot is taken.
+func (cfg *decommissionConfig) revokeGrants(ctx *pulumi.Context, provider *postgresql.Provider, dependsOn []pulumi.Resource) error {
+	for _, role := range cfg.grantees() {
+		for _, schema := range cfg.Schemas {
+			for _, objectType := range []string{"table", "sequence"} {
+				// REVOKE ALL ON ALL TABLES IN SCHEMA $SCHEMA FROM $ROLE;
+				if _, err := postgresql.NewGrant(ctx, fmt.Sprintf("%s-revoke-%s-%s-%ss", cfg.plan.Database, role, schema, objectType), &postgresql.GrantArgs{
+					Database:   pulumi.String(cfg.plan.Database),
+					ObjectType: pulumi.String(objectType),
+					Objects:    pulumi.StringArray{},
+					Privileges: pulumi.StringArray{},
+					Role:       pulumi.String(role),
+					Schema:     pulumi.String(schema),
+				}, pulumi.Provider(provider), pulumi.DependsOn(dependsOn)); err != nil {
+					return fmt.Errorf("failed to revoke %s grants of %s in %s: %w", objectType, role, schema, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// exportAudit records what was off-boarded, in the bucket too if set
+func (cfg *decommissionConfig) exportAudit(ctx *pulumi.Context, snapshot *rds.Snapshot) error {
+	audit := pulumi.Map{
+		"database":       pulumi.String(cfg.plan.Database),
+		"credsStack":     pulumi.String(cfg.CredsStack),
+		"stack":          pulumi.Sprintf("%s/%s/%s", ctx.Organization(), ctx.Project(), ctx.Stack()),
+		"reason":         pulumi.String(cfg.Reason),
+		"roles":          pulumi.ToStringArray(cfg.plan.Roles),
+		"users":          pulumi.ToStringArray(cfg.plan.Users),
+		"secrets":        pulumi.ToStringArray(cfg.plan.Secrets),
+		"recoveryWindow": pulumi.Int(cfg.RecoveryWindow),
+	}
+	if snapshot != nil {
+		audit["snapshotArn"] = snapshot.DbSnapshotArn
+	}
+	if cfg.AuditBucket != "" {
+		if _, err := s3.NewBucketObjectv2(ctx, fmt.Sprintf("%s-decommission-audit", cfg.plan.Database), &s3.BucketObjectv2Args{
+			Bucket:               pulumi.String(cfg.AuditBucket),
+			Key:                  pulumi.Sprintf("decommission/%s/%s.json", ctx.Stack(), cfg.plan.Database),
+			Content:              pulumi.JSONMarshal(audit),
+			ContentType:          pulumi.String("application/json"),
+			ServerSideEncryption: pulumi.String("aws:kms"),
+			Tags: pulumi.StringMap{
+				"Pulumi": pulumi.String("true"),
+			},
+		}, pulumi.RetainOnDelete(true)); err != nil {
+			return fmt.Errorf("failed to write the audit record to s3://%s: %w", cfg.AuditBucket, err)
+		}
+	}
+	return utils.Export(ctx, utils.OutputReference, "audit", audit)
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		if err := utils.CheckPluginVersions(ctx); err != nil {
+			return err
+		}
+		cfg := &decommissionConfig{}
+		if err := utils.ExtractConfig(ctx, "decommission", cfg); err != nil {
+			return err
+		}
+		if len(cfg.Schemas) == 0 {
+			cfg.Schemas = []string{"public"}
+		}
+		if cfg.RecoveryWindow == 0 {
+			cfg.RecoveryWindow = defaultRecoveryWindow
+		}
+		if cfg.RecoveryWindow < 7 || cfg.RecoveryWindow > 30 {
+			return fmt.Errorf("recovery window must be within 7 and 30 days, got %d", cfg.RecoveryWindow)
+		}
+		if err := cfg.loadPlan(ctx); err != nil {
+			return err
+		}
+		if err := utils.ExtractConfig(ctx, "provider", &cfg.provider); err != nil {
+			return err
+		}
+		if err := cfg.provider.LoadSuperuser(ctx); err != nil {
+			return err
+		}
+		provider, err := postgresql.NewProvider(ctx, "postgresql", cfg.provider.ProviderArgs())
+		if err != nil {
+			return err
+		}
+
+		// logins are disabled before the snapshot, so it's the final state
+		revokes, err := cfg.disableLogins(ctx, provider)
+		if err != nil {
+			return err
+		}
+		var snapshot *rds.Snapshot
+		if cfg.InstanceId != "" {
+			if snapshot, err = cfg.provisionSnapshot(ctx, revokes); err != nil {
+				return fmt.Errorf("failed to take the final snapshot of %s: %w", cfg.InstanceId, err)
+			}
+			revokes = append(revokes, snapshot)
+		}
+		if err := cfg.revokeGrants(ctx, provider, revokes); err != nil {
+			return err
+		}
+		// the secrets belong to the creds stack, `iac decommission-secrets`
+		// deletes the ones of the audit record
+		if err := cfg.exportAudit(ctx, snapshot); err != nil {
+			return err
+		}
+
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		return metadata.Export(ctx)
+	})
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pulumi/pulumi-mongodbatlas/sdk/v3/go/mongodbatlas"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/mongo"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+type atlasProviderArg struct {
+	PublicKey  pulumi.StringInput `required:"" json:"publicKey"`
+	PrivateKey pulumi.StringInput `required:"" secret:"privateKey"`
+}
+
+type mongoServiceArg struct {
+	// Prefix of the secrets of the service
+	Name      string                 `json:"name"`
+	Databases []string               `json:"databases"`
+	Users     []mongo.MongoUserProps `json:"users"`
+}
+
+type mongoConfig struct {
+	ProjectId string `json:"projectId" required:""`
+	// Cluster the users are scoped to, all the clusters of the project if not set
+	Cluster string `json:"cluster"`
+	// SRV host of the cluster, e.g. cluster0.ab1cd.mongodb.net
+	Host     string            `json:"host" required:""`
+	Services []mongoServiceArg `json:"services" required:""`
+
+	provider atlasProviderArg
+}
+
+func (cfg *mongoConfig) validate() error {
+	seen := map[string]bool{}
+	for _, service := range cfg.Services {
+		if service.Name == "" {
+			return fmt.Errorf("name of the service is required")
+		}
+		if seen[service.Name] {
+			return fmt.Errorf("duplicate service %s", service.Name)
+		}
+		seen[service.Name] = true
+	}
+	return nil
+}
+
+// plannedResources counts the users of all the services, each one gets a secret
+func (cfg *mongoConfig) plannedResources() map[string]int {
+	users := 0
+	for _, service := range cfg.Services {
+		users += len(service.Users)
+	}
+	return map[string]int{
+		utils.QuotaUsers:   users,
+		utils.QuotaSecrets: users,
+	}
+}
+
+// provisionService creates the users of the service, and a secret holding
+// the creds & URI of each one
+func (cfg *mongoConfig) provisionService(ctx *pulumi.Context, provider *mongodbatlas.Provider, service mongoServiceArg) (pulumi.StringMap, error) {
+	res, usersErr := mongo.NewMongoDatabases(ctx, service.Name, mongo.MongoDatabasesProps{
+		ProjectId: cfg.ProjectId,
+		Cluster:   cfg.Cluster,
+		Databases: service.Databases,
+		Users:     service.Users,
+		Host:      pulumi.String(cfg.Host),
+	}, pulumi.Provider(provider))
+	if res == nil {
+		return nil, usersErr
+	}
+	refs := pulumi.StringMap{}
+	for i, user := range service.Users {
+		if res.Users[i] == nil {
+			continue
+		}
+		secretRes, err := secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+			Name:         fmt.Sprintf("mongo-%s-user-%s", service.Name, user.Username),
+			Type:         secret.MongoCreds,
+			InitialValue: res.Creds[user.Username],
+			Tags: map[string]string{
+				"mongo:service": service.Name,
+				"mongo:user":    user.Username,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create secret for user %s: %w", user.Username, err)
+		}
+		refs[user.Username] = secretRes.Secret.ID().ToStringOutput()
+	}
+	return refs, usersErr
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		if err := utils.CheckPluginVersions(ctx); err != nil {
+			return err
+		}
+		cfg := &mongoConfig{}
+		if err := utils.ExtractConfig(ctx, "mongo", cfg); err != nil {
+			return err
+		}
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		if err := utils.ExtractConfig(ctx, "provider", &cfg.provider); err != nil {
+			return err
+		}
+		if err := utils.CheckResourceQuota(ctx, cfg.plannedResources()); err != nil {
+			return err
+		}
+
+		provider, err := mongo.NewProvider(ctx, "mongodbatlas", mongo.ProviderArgs{
+			PublicKey:  cfg.provider.PublicKey,
+			PrivateKey: cfg.provider.PrivateKey,
+		})
+		if err != nil {
+			return err
+		}
+		// a failed service doesn't hold back the others
+		errs := []error{}
+		secrets := pulumi.Map{}
+		for _, service := range cfg.Services {
+			refs, err := cfg.provisionService(ctx, provider, service)
+			if err != nil {
+				ctx.Log.Error(fmt.Sprintf("service %s: %s", service.Name, err), nil)
+				errs = append(errs, fmt.Errorf("service %s: %w", service.Name, err))
+			}
+			if len(refs) > 0 {
+				secrets[service.Name] = refs
+			}
+		}
+		if err := utils.Export(ctx, utils.OutputReference, "secrets", secrets); err != nil {
+			return err
+		}
+		if err := secret.CheckCostGuard(ctx); err != nil {
+			return err
+		}
+
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		if err := metadata.Export(ctx); err != nil {
+			return err
+		}
+		return errors.Join(errs...)
+	})
+}
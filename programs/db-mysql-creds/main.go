@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/mysql"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const defaultPort = 3306
+
+type mysqlProviderArg struct {
+	Host              string             `required:"" json:"host"`
+	Port              int                `json:"port"`
+	SuperuserName     pulumi.StringInput `json:"superuserName"`
+	SuperuserPassword pulumi.StringInput `secret:"superuserPassword"`
+	// Secret with the admin creds, instead of superuserName & superuserPassword
+	SuperuserSecretId string `json:"superuserSecretId"`
+	// Requires TLS to the server
+	Tls bool `json:"tls"`
+}
+
+type mysqlUserArg struct {
+	Username string `json:"username"`
+	// rw or ro, rw if not set
+	Permission postgres.PostgresUserPermission `json:"permission"`
+	// '%' (any host) if not set, patterns & CIDRs are allowed
+	Hosts []string `json:"hosts"`
+	// Plugin the user authenticates with instead of a password, e.g.
+	// AWSAuthenticationPlugin for the RDS IAM authentication
+	AuthPlugin string `json:"authPlugin"`
+	// Key of the secret config (in mysql namespace) holding the existing
+	// password of the user, a random one is generated if not set
+	Password string `json:"password"`
+	// Bump it to rotate the generated password
+	RotationTrigger string `json:"rotationTrigger"`
+	Protect         bool   `json:"protect"`
+}
+
+type mysqlConfig struct {
+	Database string `json:"database" required:""`
+	// mysql or mariadb, mysql if not set
+	Flavor mysql.MySQLFlavor `json:"flavor"`
+	// rw and ro roles if not set
+	DbRoles      []postgres.PostgresDbRoleProps `json:"dbRoles"`
+	CharacterSet string                         `json:"characterSet"`
+	Collation    string                         `json:"collation"`
+	Protect      bool                           `json:"protect"`
+	Users        []mysqlUserArg                 `json:"users"`
+	// Exports the creds as AWS secrets, stack outputs otherwise
+	ExportAsSecret bool `json:"exportAsSecret"`
+
+	provider mysqlProviderArg
+}
+
+func (cfg *mysqlConfig) loadSuperuser(ctx *pulumi.Context) error {
+	if cfg.provider.Port == 0 {
+		cfg.provider.Port = defaultPort
+	}
+	if cfg.provider.SuperuserSecretId == "" {
+		for _, key := range []string{"provider:superuserName", "provider:superuserPassword"} {
+			if _, ok := ctx.GetConfig(key); !ok {
+				return fmt.Errorf("%s is required, unless provider:superuserSecretId is set", key)
+			}
+		}
+		return nil
+	}
+	creds := secret.LookupDBCreds(ctx, cfg.provider.SuperuserSecretId)
+	cfg.provider.SuperuserName = creds.MapIndex(pulumi.String("username"))
+	cfg.provider.SuperuserPassword = creds.MapIndex(pulumi.String("password"))
+	return nil
+}
+
+func (cfg *mysqlConfig) userProps(ctx *pulumi.Context) ([]mysql.MySQLUserProps, error) {
+	props := make([]mysql.MySQLUserProps, len(cfg.Users))
+	for i, user := range cfg.Users {
+		props[i] = mysql.MySQLUserProps{
+			Username:        user.Username,
+			Permission:      user.Permission,
+			Hosts:           user.Hosts,
+			Flavor:          cfg.Flavor,
+			AuthPlugin:      user.AuthPlugin,
+			RotationTrigger: user.RotationTrigger,
+			Protect:         user.Protect,
+		}
+		if user.Password == "" {
+			continue
+		}
+		key := fmt.Sprintf("mysql:%s", user.Password)
+		if !ctx.IsConfigSecret(key) {
+			return nil, fmt.Errorf("password config '%s' of user %s needs to be set with --secret", key, user.Username)
+		}
+		props[i].Password = config.RequireSecret(ctx, key)
+	}
+	return props, nil
+}
+
+// creds of the user, nil if it can't log in with them, e.g. mysql_no_login
+func (cfg *mysqlConfig) creds(usersRes *mysql.MySQLUsersResource, user *mysqlUserArg) pulumi.StringMap {
+	engine := cfg.Flavor
+	if engine == "" {
+		engine = mysql.MySQL
+	}
+	creds := pulumi.StringMap{
+		"username": pulumi.String(user.Username),
+		"database": pulumi.String(cfg.Database),
+		"host":     pulumi.String(cfg.provider.Host),
+		"port":     pulumi.Sprintf("%d", cfg.provider.Port),
+		"engine":   pulumi.String(engine),
+	}
+	if password, ok := usersRes.Passwords[user.Username]; ok {
+		creds["password"] = password
+		return creds
+	}
+	if user.AuthPlugin == "AWSAuthenticationPlugin" {
+		creds["authentication"] = pulumi.String("iam")
+		return creds
+	}
+	return nil
+}
+
+// exportCreds exposes each user creds in its own secret, or all of them in
+// the users output
+func (cfg *mysqlConfig) exportCreds(ctx *pulumi.Context, usersRes *mysql.MySQLUsersResource) error {
+	users := pulumi.Map{}
+	for i := range cfg.Users {
+		user := &cfg.Users[i]
+		if _, ok := usersRes.Accounts[user.Username]; !ok {
+			continue
+		}
+		creds := cfg.creds(usersRes, user)
+		if creds == nil {
+			continue
+		}
+		if !cfg.ExportAsSecret {
+			users[user.Username] = creds
+			continue
+		}
+		res, err := secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+			Name:         fmt.Sprintf("mysql-%s-user-%s", cfg.Database, user.Username),
+			Type:         secret.DBCreds,
+			InitialValue: creds,
+			Tags: map[string]string{
+				"mysql:database": cfg.Database,
+				"mysql:user":     user.Username,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create secret for user %s: %w", user.Username, err)
+		}
+		if err := utils.Export(ctx, utils.OutputReference, fmt.Sprintf("secret-%s", user.Username), pulumi.StringMap{
+			"secretId": res.Secret.ID().ToStringOutput(),
+		}); err != nil {
+			return err
+		}
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	return utils.Export(ctx, utils.OutputCreds, "users", users)
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		if err := utils.CheckPluginVersions(ctx); err != nil {
+			return err
+		}
+		cfg := &mysqlConfig{}
+		if err := utils.ExtractConfig(ctx, "mysql", cfg); err != nil {
+			return err
+		}
+		if err := utils.ExtractConfig(ctx, "provider", &cfg.provider); err != nil {
+			return err
+		}
+		if err := cfg.loadSuperuser(ctx); err != nil {
+			return err
+		}
+		secrets := 0
+		if cfg.ExportAsSecret {
+			secrets = len(cfg.Users)
+		}
+		if err := utils.CheckResourceQuota(ctx, map[string]int{
+			utils.QuotaDatabases: 1,
+			utils.QuotaUsers:     len(cfg.Users),
+			utils.QuotaSecrets:   secrets,
+		}); err != nil {
+			return err
+		}
+
+		provider, err := mysql.NewProvider(ctx, "mysql", mysql.ProviderArgs{
+			Endpoint: pulumi.String(fmt.Sprintf("%s:%d", cfg.provider.Host, cfg.provider.Port)),
+			Username: cfg.provider.SuperuserName,
+			Password: cfg.provider.SuperuserPassword,
+			Tls:      cfg.provider.Tls,
+		})
+		if err != nil {
+			return err
+		}
+		dbRes, err := mysql.NewMySQLDatabase(ctx, cfg.Database, mysql.MySQLDbProps{
+			Database:     cfg.Database,
+			Flavor:       cfg.Flavor,
+			DbRoles:      cfg.DbRoles,
+			CharacterSet: cfg.CharacterSet,
+			Collation:    cfg.Collation,
+			Protect:      cfg.Protect,
+		}, pulumi.Provider(provider))
+		if err != nil {
+			return fmt.Errorf("failed to create database %s: %w", cfg.Database, err)
+		}
+		userProps, err := cfg.userProps(ctx)
+		if err != nil {
+			return err
+		}
+		// the grants of the users need the database
+		usersRes, usersErr := mysql.NewMySQLUsers(ctx, cfg.Database, userProps, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{dbRes}))
+		if usersRes == nil {
+			return usersErr
+		}
+		// the failed users are reported, the others are still exported
+		for _, user := range cfg.Users {
+			if userErr, ok := usersRes.FailedUsers[user.Username]; ok {
+				wrappedErr := fmt.Errorf("failed to create user '%s': %w", user.Username, userErr)
+				ctx.Log.Error(wrappedErr.Error(), &pulumi.LogArgs{Resource: usersRes})
+			}
+		}
+		if err := cfg.exportCreds(ctx, usersRes); err != nil {
+			return err
+		}
+		if err := utils.Export(ctx, utils.OutputReference, "database", pulumi.String(cfg.Database)); err != nil {
+			return err
+		}
+		if err := utils.Export(ctx, utils.OutputReference, "roles", pulumi.ToStringArray(dbRes.RoleNames)); err != nil {
+			return err
+		}
+		if err := secret.CheckCostGuard(ctx); err != nil {
+			return err
+		}
+
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		if err := metadata.Export(ctx); err != nil {
+			return err
+		}
+		return usersErr
+	})
+}
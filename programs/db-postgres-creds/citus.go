@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+)
+
+type pgCitusWorkerArg struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	// Port of the coordinator if not set
+	Port int `json:"port"`
+}
+
+// citusConfig is the citus namespace: the workers of the Citus cluster, the
+// provider host being the coordinator. The database is distributed once
+// workers are set.
+type citusConfig struct {
+	Workers             []pgCitusWorkerArg `json:"workers"`
+	SkipWorkerDatabases bool               `json:"skipWorkerDatabases"`
+	DistributedTables   map[string]string  `json:"distributedTables"`
+	ReferenceTables     []string           `json:"referenceTables"`
+}
+
+func (p *program) provisionCitus(ctx *pulumi.Context, provider *postgresql.Provider, dbRes *postgres.PostgresDBResource) error {
+	cfg := &p.citus
+	if len(cfg.Workers) == 0 {
+		if len(cfg.DistributedTables) > 0 || len(cfg.ReferenceTables) > 0 {
+			return fmt.Errorf("citus:workers is required to distribute the tables")
+		}
+		return nil
+	}
+	workers := make([]postgres.CitusWorker, len(cfg.Workers))
+	for i, worker := range cfg.Workers {
+		providerArgs := p.provider.ProviderArgs()
+		providerArgs.Host = pulumi.String(worker.Host)
+		if worker.Port != 0 {
+			providerArgs.Port = pulumi.IntPtr(worker.Port)
+		}
+		workerProvider, err := postgresql.NewProvider(ctx, fmt.Sprintf("postgresql-worker-%s", worker.Name), providerArgs)
+		if err != nil {
+			return err
+		}
+		workers[i] = postgres.CitusWorker{Name: worker.Name, Provider: workerProvider}
+	}
+	if _, err := postgres.NewPostgresCitus(ctx, p.pg.Database, postgres.PostgresCitusProps{
+		Database:            p.pg.Database,
+		Workers:             workers,
+		SkipWorkerDatabases: cfg.SkipWorkerDatabases,
+		DistributedTables:   cfg.DistributedTables,
+		ReferenceTables:     cfg.ReferenceTables,
+		Connection:          p.provider.SQLConnection(),
+	}, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{dbRes})); err != nil {
+		return fmt.Errorf("failed to distribute database %s with citus: %w", p.pg.Database, err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/outputs"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+// pgConfig is the pg namespace: the database, its DB roles and the login users
+type pgConfig struct {
+	Database         string `json:"database" required:""`
+	Tablespace       string `json:"tablespace"`
+	TemplateDatabase bool   `json:"templateDatabase"`
+	Encoding         string `json:"encoding"`
+	LcCollate        string `json:"lcCollate"`
+	LcCtype          string `json:"lcCtype"`
+	Template         string `json:"template"`
+	// Tablespaces created before the database, e.g. hot & cold storage
+	Tablespaces []postgres.PostgresTablespaceProps `json:"tablespaces"`
+	// Max concurrent connections to the database, besides the per-user limits
+	ConnectionLimit int `json:"connectionLimit"`
+	// Adopts the database & DB roles created by hand
+	ImportExisting bool `json:"importExisting"`
+	// IDs (or URNs to alias) of any resource of the stack created by hand,
+	// keyed by logical name, e.g. the grants & secrets of an adopted database
+	ImportIds map[string]string `json:"importIds"`
+	// Guard the database & DB roles against a destroy
+	Protect        bool                           `json:"protect"`
+	RetainOnDelete bool                           `json:"retainOnDelete"`
+	Schemas        []postgres.PostgresSchemaProps `json:"schemas"`
+	// Existing role owning the database, e.g. an admin, instead of the rw role
+	DatabaseOwner string                            `json:"databaseOwner"`
+	Extensions    []postgres.PostgresExtensionProps `json:"extensions"`
+	Users         []pgUserArg                       `json:"users"`
+	// Ownership or team metadata of the database
+	Comment string `json:"comment"`
+	// DB roles besides the rw one, e.g. ro narrowed to some schemas
+	DbRoles []postgres.PostgresDbRoleProps `json:"dbRoles"`
+	// Default privileges between roles not managed here, e.g. of an existing database
+	DefaultPrivileges []postgres.PostgresDefaultPrivilegesProps `json:"defaultPrivileges"`
+	// Revokes CREATE on schema public and CONNECT on the database from PUBLIC
+	HardenPublicSchema bool `json:"hardenPublicSchema"`
+	// Idempotent statements run with psql once the database is provisioned
+	BootstrapSQL []string `json:"bootstrapSql"`
+	// File of statements run after bootstrapSql, relative to the program
+	BootstrapSQLFile string `json:"bootstrapSqlFile"`
+	// Exports the access changes since the last deployment
+	AccessChangelog bool `json:"accessChangelog"`
+}
+
+// bootstrapSQL appends the script of bootstrapSqlFile to the statements
+func (cfg *pgConfig) bootstrapSQL() ([]string, error) {
+	statements := cfg.BootstrapSQL
+	if cfg.BootstrapSQLFile != "" {
+		script, err := os.ReadFile(cfg.BootstrapSQLFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bootstrap SQL file: %w", err)
+		}
+		statements = append(statements, string(script))
+	}
+	return statements, nil
+}
+
+// dbRoles always has the rw role, and the ro one if a user is granted it
+func (cfg *pgConfig) dbRoles() []postgres.PostgresDbRoleProps {
+	dbRoles := cfg.DbRoles
+	if !cfg.hasDbRole(postgres.ReadWrite) {
+		dbRoles = append([]postgres.PostgresDbRoleProps{{Permission: postgres.ReadWrite}}, dbRoles...)
+	}
+	if !cfg.hasDbRole(postgres.ReadOnly) {
+		for _, user := range cfg.Users {
+			if user.Permission == postgres.ReadOnly {
+				return append(dbRoles, postgres.PostgresDbRoleProps{Permission: postgres.ReadOnly})
+			}
+		}
+	}
+	return dbRoles
+}
+
+func (cfg *pgConfig) hasDbRole(permission postgres.PostgresUserPermission) bool {
+	for _, role := range cfg.DbRoles {
+		if role.Permission == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// userRole is the role the user assumes, the rw one unless its permission is ro
+func (cfg *pgConfig) userRole(user pgUserArg) string {
+	if user.Permission != postgres.ReadOnly {
+		return cfg.rwRole()
+	}
+	for _, role := range cfg.dbRoles() {
+		if role.Permission == postgres.ReadOnly {
+			return role.RoleName(cfg.Database)
+		}
+	}
+	return fmt.Sprintf("%s-ro", cfg.Database)
+}
+
+// rwRole is the role the users assume, <db>-rw unless it's an existing role
+func (cfg *pgConfig) rwRole() string {
+	for _, role := range cfg.dbRoles() {
+		if role.Permission == postgres.ReadWrite {
+			return role.RoleName(cfg.Database)
+		}
+	}
+	return fmt.Sprintf("%s-rw", cfg.Database)
+}
+
+func (p *program) provisionDatabase(ctx *pulumi.Context, provider *postgresql.Provider) (*postgres.PostgresDBResource, error) {
+	cfg := &p.pg
+	bootstrapSQL, err := cfg.bootstrapSQL()
+	if err != nil {
+		return nil, err
+	}
+	dbProps := postgres.PostgresDbProps{
+		Database:           cfg.Database,
+		Tablespace:         cfg.Tablespace,
+		Tablespaces:        cfg.Tablespaces,
+		TemplateDatabase:   cfg.TemplateDatabase,
+		Encoding:           cfg.Encoding,
+		LcCollate:          cfg.LcCollate,
+		LcCtype:            cfg.LcCtype,
+		Template:           cfg.Template,
+		ConnectionLimit:    cfg.ConnectionLimit,
+		ImportExisting:     cfg.ImportExisting,
+		Protect:            cfg.Protect,
+		RetainOnDelete:     cfg.RetainOnDelete,
+		PgBouncerAuth:      p.pgbouncer.Auth,
+		Schemas:            cfg.Schemas,
+		DatabaseOwner:      cfg.DatabaseOwner,
+		Extensions:         cfg.Extensions,
+		Endpoint:           p.provider.Endpoint(cfg.Database),
+		Comment:            cfg.Comment,
+		HardenPublicSchema: cfg.HardenPublicSchema,
+		BootstrapSQL:       bootstrapSQL,
+		Connection:         p.provider.SQLConnection(),
+		DbRoles:            cfg.dbRoles(),
+	}
+	return postgres.NewPostgresDatabase(ctx, cfg.Database, dbProps, pulumi.Provider(provider))
+}
+
+// provisionDefaultPrivileges grants the default privileges of pg:defaultPrivileges,
+// in the database of the stack if not set.
+func (p *program) provisionDefaultPrivileges(ctx *pulumi.Context, provider *postgresql.Provider, dbRes *postgres.PostgresDBResource) error {
+	for _, props := range p.pg.DefaultPrivileges {
+		if props.Database == "" {
+			props.Database = p.pg.Database
+		}
+		res, err := postgres.NewPostgresDefaultPrivileges(ctx, fmt.Sprintf("%s-%s-to-%s", props.Database, props.Owner, props.Role), props, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{dbRes}))
+		if err != nil {
+			return fmt.Errorf("failed to grant default privileges of %s to %s in database %s: %w", props.Owner, props.Role, props.Database, err)
+		}
+		p.defaultPrivilegesRes = append(p.defaultPrivilegesRes, res)
+	}
+	return nil
+}
+
+func (p *program) plan(dbRes *postgres.PostgresDBResource) outputs.PlanOutput {
+	usernames := make([]string, len(p.pg.Users))
+	for i, user := range p.pg.Users {
+		usernames[i] = user.Username
+	}
+	// the secrets of the readers are in other accounts, so they're left out of the secrets
+	usernames = append(usernames, p.readerNames...)
+	return p.exporter.Plan(dbRes, usernames)
+}
+
+// exportAccessGraph exports the DOT graph of the role memberships and the
+// grants, rendered with `dot -Tsvg` for the access reviews.
+func (p *program) exportAccessGraph(ctx *pulumi.Context, dbRes *postgres.PostgresDBResource) error {
+	graph := &postgres.AccessGraph{}
+	graph.Merge(dbRes.AccessGraph)
+	if p.monitoringRes != nil {
+		graph.Merge(p.monitoringRes.AccessGraph)
+	}
+	for _, res := range p.readerRes {
+		graph.Merge(res.AccessGraph)
+	}
+	for _, res := range p.defaultPrivilegesRes {
+		graph.Merge(res.AccessGraph)
+	}
+	for _, user := range p.pg.Users {
+		graph.AddMembership(user.Username, p.pg.userRole(user))
+		for _, role := range user.Roles {
+			graph.AddMembership(user.Username, role)
+		}
+	}
+	for _, pub := range p.replication.Publications {
+		graph.AddPrivileges(pub.Username, fmt.Sprintf("publication %s", pub.Name), "REPLICATION")
+		for _, table := range pub.Tables {
+			graph.AddPrivileges(pub.Username, fmt.Sprintf("table %s.%s", p.pg.Database, table), "SELECT")
+		}
+	}
+	for _, server := range p.replication.ForeignServers {
+		for _, user := range server.Users {
+			graph.AddPrivileges(user, fmt.Sprintf("foreign server %s", server.Name), "USAGE")
+		}
+	}
+	return utils.Export(ctx, utils.OutputReference, "accessGraph", pulumi.String(graph.DOT()))
+}
@@ -10,91 +10,230 @@ import (
 	"github.com/shivanshs9/iac-pulumi/components/utils"
 )
 
-type pgProviderArg struct {
-	Host              pulumi.StringInput `required:"" json:"host"`
-	SuperuserName     pulumi.StringInput `required:"" json:"superuserName"`
-	SuperuserPassword pulumi.StringInput `required:"" secret:"superuserPassword"`
-	Port              int                `required:"" json:"port"`
-	DisableSSL        bool               `json:"disableSSL"`
-}
+// program holds the config namespaces of the stack, each one read into the
+// props of the components it provisions, and the resources shared between
+// them.
+type program struct {
+	// pg: the database, its DB roles and the login users
+	pg pgConfig
+	// creds: where and how the creds of the users are exported
+	creds credsConfig
+	// rds: the AWS resources around the RDS instance
+	rds rdsConfig
+	// replication: publications, subscriptions and foreign servers
+	replication replicationConfig
+	citus       citusConfig
+	timescale   timescaleConfig
+	// rls: row level security policies
+	rls rowSecurityConfig
+	// pgbouncer: auth_query and userlist of the pooler
+	pgbouncer  pgbouncerConfig
+	monitoring monitoringConfig
+	// readers: time-boxed read-only users of other teams
+	readers  readersConfig
+	provider postgres.ProviderConfig
 
-type pgUserArg struct {
-	Username string `json:"username"`
-	Login    bool   `json:"login"`
+	// builds the creds payload of the users
+	buildPayload postgres.PayloadBuilder
+	// exports the creds of the roles, and records their secrets
+	exporter *postgres.CredsExporter
+	// set if monitoring:user is
+	monitoringRes *postgres.PostgresMonitoringUserResource
+	// external readers whose access hasn't expired, and their usernames
+	readerRes   []*postgres.PostgresExternalReaderResource
+	readerNames []string
+	// set if pg:defaultPrivileges is
+	defaultPrivilegesRes []*postgres.PostgresDefaultPrivilegesResource
+	// user-supplied passwords, keyed by username
+	passwords map[string]pulumi.StringOutput
 }
 
-type pgConfig struct {
-	Database       string      `json:"database" required:""`
-	Users          []pgUserArg `json:"users"`
-	ExportAsSecret bool        `json:"exportAsSecret"`
-
-	provider pgProviderArg
+// loadConfig reads every namespace of the stack config. Each one is read by
+// its own utils.ExtractConfig call, so configlint can find them.
+func (p *program) loadConfig(ctx *pulumi.Context) error {
+	if err := utils.ExtractConfig(ctx, "pg", &p.pg); err != nil {
+		return err
+	}
+	if err := utils.ExtractConfig(ctx, "creds", &p.creds); err != nil {
+		return err
+	}
+	if err := utils.ExtractConfig(ctx, "rds", &p.rds); err != nil {
+		return err
+	}
+	if err := utils.ExtractConfig(ctx, "replication", &p.replication); err != nil {
+		return err
+	}
+	if err := utils.ExtractConfig(ctx, "citus", &p.citus); err != nil {
+		return err
+	}
+	if err := utils.ExtractConfig(ctx, "timescale", &p.timescale); err != nil {
+		return err
+	}
+	if err := utils.ExtractConfig(ctx, "rls", &p.rls); err != nil {
+		return err
+	}
+	if err := utils.ExtractConfig(ctx, "pgbouncer", &p.pgbouncer); err != nil {
+		return err
+	}
+	if err := utils.ExtractConfig(ctx, "monitoring", &p.monitoring); err != nil {
+		return err
+	}
+	if err := utils.ExtractConfig(ctx, "readers", &p.readers); err != nil {
+		return err
+	}
+	return utils.ExtractConfig(ctx, "provider", &p.provider)
 }
 
-func (cfg *pgConfig) provisionDatabase(ctx *pulumi.Context, provider *postgresql.Provider) (*postgres.PostgresDBResource, error) {
-	dbProps := postgres.PostgresDbProps{
-		Database: cfg.Database,
+// validate checks the namespaces against each other, before any resource is
+// registered
+func (p *program) validate() error {
+	if err := p.validateSecretFormat(); err != nil {
+		return err
 	}
-	res, err := postgres.NewPostgresDatabase(ctx, cfg.Database, dbProps, pulumi.Provider(provider))
-	if err != nil {
-		return res, err
+	if err := p.pg.validateUserPermissions(); err != nil {
+		return err
 	}
-
-	return res, nil
+	for _, user := range p.pg.Users {
+		if err := p.validateUser(user); err != nil {
+			return fmt.Errorf("user %s: %w", user.Username, err)
+		}
+	}
+	return nil
 }
 
-func (cfg *pgConfig) provisionLoginUsers(ctx *pulumi.Context, provider *postgresql.Provider) (*postgres.PostgresUsersResource, error) {
-	userProps := make([]postgres.PostgresUserProps, len(cfg.Users))
-	for i, user := range cfg.Users {
-		userProps[i] = postgres.PostgresUserProps{
-			Username:   user.Username,
-			Login:      user.Login,
-			AssumeRole: pulumi.Sprintf("%s-rw", cfg.Database),
+// plannedResources counts the resources the config is about to create, per quota kind
+func (p *program) plannedResources() map[string]int {
+	secrets := 0
+	if p.exporter.ExportsToAWS() && len(p.pg.Users) > 0 {
+		secrets = len(p.pg.Users)
+		if p.exporter.Mode() == postgres.ExportConsolidated {
+			secrets = 1
+		}
+	}
+	if p.creds.HelmValues != nil && p.creds.HelmValues.ExportAsSecret {
+		secrets += len(p.pg.Users)
+	}
+	if p.creds.ExportAsSecret {
+		secrets += len(p.replication.Publications)
+		if p.pgbouncer.Userlist != nil {
+			secrets++
+		}
+	}
+	users := len(p.pg.Users) + len(p.replication.Publications)
+	if p.pgbouncer.Auth != nil && !p.pgbouncer.Auth.SkipRole {
+		users++
+		if p.creds.ExportAsSecret {
+			secrets++
+		}
+	}
+	if p.monitoring.User != nil {
+		users++
+		if p.creds.ExportAsSecret {
+			secrets++
 		}
 	}
-	res, err := postgres.NewPostgresUsers(ctx, cfg.Database, userProps, pulumi.Provider(provider))
-	if err != nil {
-		return res, err
+	// the secrets of the readers are in the accounts of the teams, but still billed
+	users += len(p.readers.Teams)
+	secrets += len(p.readers.Teams)
+	return map[string]int{
+		utils.QuotaDatabases: 1,
+		utils.QuotaUsers:     users,
+		utils.QuotaSecrets:   secrets,
 	}
-	return res, nil
 }
 
-func (cfg *pgConfig) genCredsMap(usersRes *postgres.PostgresUsersResource, i int) pulumi.StringMap {
-	return pulumi.StringMap{
-		"username": usersRes.Users[i].Name,
-		"password": usersRes.Users[i].Password.Elem().ToStringOutput(),
-		"database": pulumi.String(cfg.Database),
-		"host":     cfg.provider.Host,
-		"port":     pulumi.Sprintf("%d", cfg.provider.Port),
+// provisionUsers creates the login users, and everything exposing or
+// guarding their creds
+func (p *program) provisionUsers(ctx *pulumi.Context, provider *postgresql.Provider, dbRes *postgres.PostgresDBResource) error {
+	if p.rds.ConnectionBudget != nil {
+		if err := p.checkConnectionBudget(ctx); err != nil {
+			return fmt.Errorf("connection budget exceeded: %w", err)
+		}
+	}
+	usersRes, err := p.provisionLoginUsers(ctx, provider)
+	if usersRes == nil {
+		return err
+	}
+	// the failed users are reported, the others are still exported
+	for _, user := range p.pg.Users {
+		if userErr, ok := usersRes.FailedUsers[user.Username]; ok {
+			wrappedErr := fmt.Errorf("failed to create user '%s': %w", user.Username, userErr)
+			ctx.Log.Error(wrappedErr.Error(), &pulumi.LogArgs{Resource: usersRes})
+		}
+	}
+	// creds are stored only once the access is fully provisioned
+	ready := pulumi.All(dbRes.Ready, usersRes.Ready)
+	if err := p.exporter.ExportUsers(ctx, p.exportedUsers(usersRes), ready); err != nil {
+		return err
 	}
+	if p.pgbouncer.Userlist != nil {
+		if err := p.exportPgBouncerConfig(ctx, usersRes, ready); err != nil {
+			return fmt.Errorf("failed to render the pgbouncer config: %w", err)
+		}
+	}
+	if p.creds.HelmValues != nil {
+		if err := p.exportHelmValues(ctx, usersRes); err != nil {
+			return err
+		}
+	}
+	if p.rds.SecurityGroupId != "" {
+		if _, err := p.provisionIngress(ctx); err != nil {
+			return fmt.Errorf("failed to allow ingress from the users' CIDRs: %w", err)
+		}
+	}
+	if p.rds.IamAuthPolicy != nil {
+		if err := p.provisionIamAuthPolicy(ctx); err != nil {
+			return fmt.Errorf("failed to create IAM auth policy: %w", err)
+		}
+	}
+	if p.rds.LoginAlert != nil {
+		if err := p.provisionLoginAlert(ctx); err != nil {
+			return fmt.Errorf("failed to create failed login alert: %w", err)
+		}
+	}
+	return nil
 }
 
 func main() {
 	pulumi.Run(func(ctx *pulumi.Context) error {
-		cfg := &pgConfig{}
-		if err := utils.ExtractConfig(ctx, "pg", cfg); err != nil {
+		if err := utils.CheckPluginVersions(ctx, postgres.PsqlTool); err != nil {
+			return err
+		}
+		p := &program{}
+		if err := p.loadConfig(ctx); err != nil {
+			return err
+		}
+		if len(p.pg.ImportIds) > 0 {
+			if err := ctx.RegisterStackTransformation(utils.ImportAliases(p.pg.ImportIds)); err != nil {
+				return err
+			}
+		}
+		if err := p.loadExporter(); err != nil {
+			return err
+		}
+		if err := p.validate(); err != nil {
 			return err
 		}
-		cfg.provider = pgProviderArg{}
-		if err := utils.ExtractConfig(ctx, "provider", &cfg.provider); err != nil {
+		if err := utils.CheckResourceQuota(ctx, p.plannedResources()); err != nil {
 			return err
 		}
-		providerArgs := &postgresql.ProviderArgs{
-			Host:     cfg.provider.Host,
-			Username: cfg.provider.SuperuserName,
-			Password: cfg.provider.SuperuserPassword,
-			Port:     pulumi.IntPtr(cfg.provider.Port),
+		if err := p.loadPasswords(ctx); err != nil {
+			return err
 		}
-		if cfg.provider.DisableSSL {
-			providerArgs.Sslmode = pulumi.String("disable")
+		if err := p.provider.LoadSuperuser(ctx); err != nil {
+			return err
 		}
-		provider, err := postgresql.NewProvider(ctx, "postgresql", providerArgs)
+		provider, err := postgresql.NewProvider(ctx, "postgresql", p.provider.ProviderArgs())
 		if err != nil {
 			return err
 		}
 		// Provision database
-		dbRes, err := cfg.provisionDatabase(ctx, provider)
+		dbRes, err := p.provisionDatabase(ctx, provider)
 		if err != nil {
+			// invalid names are rejected before the component is registered
+			if dbRes == nil {
+				return err
+			}
 			args := &pulumi.LogArgs{
 				Resource: dbRes,
 			}
@@ -102,38 +241,76 @@ func main() {
 			return err
 		}
 
-		if len(cfg.Users) > 0 {
-			usersRes, err := cfg.provisionLoginUsers(ctx, provider)
-			if err != nil {
-				args := &pulumi.LogArgs{
-					Resource: usersRes,
-				}
-				wrappedErr := fmt.Errorf("failed to create user '%s': %w", usersRes.FailedUser, err)
-				ctx.Log.Error(wrappedErr.Error(), args)
+		if len(p.pg.Users) > 0 {
+			if err := p.provisionUsers(ctx, provider, dbRes); err != nil {
+				return err
 			}
-			// expose each user creds in independent secret
-			if cfg.ExportAsSecret {
-				for i, user := range cfg.Users {
-					secret, err := secret.NewAWSSecret(ctx, secret.AWSSecretProps{
-						Name:         fmt.Sprintf("pg-%s-user-%s", cfg.Database, user.Username),
-						Type:         secret.DBCreds,
-						InitialValue: cfg.genCredsMap(usersRes, i),
-					})
-					if err != nil {
-						return fmt.Errorf("failed to create secret for user %s: %w", user.Username, err)
-					}
-					ctx.Export(fmt.Sprintf("secret-%s", user.Username), pulumi.StringMap{
-						"secretId": secret.Secret.ID(),
-					})
-				}
-			} else {
-				for i, user := range cfg.Users {
-					ctx.Export(user.Username, cfg.genCredsMap(usersRes, i))
-				}
+		}
+		if err := p.provisionPublications(ctx, provider, dbRes); err != nil {
+			return err
+		}
+		if err := p.provisionSubscriptions(ctx, provider, dbRes); err != nil {
+			return err
+		}
+		if err := p.provisionForeignServers(ctx, dbRes); err != nil {
+			return err
+		}
+		if err := p.provisionCitus(ctx, provider, dbRes); err != nil {
+			return err
+		}
+		if err := p.provisionTimescale(ctx, provider, dbRes); err != nil {
+			return err
+		}
+		if err := p.provisionRowSecurity(ctx, dbRes); err != nil {
+			return err
+		}
+		if err := p.provisionMonitoringUser(ctx, provider, dbRes); err != nil {
+			return err
+		}
+		if err := p.provisionExternalReaders(ctx, provider, dbRes); err != nil {
+			return err
+		}
+		if err := p.provisionDefaultPrivileges(ctx, provider, dbRes); err != nil {
+			return err
+		}
+		if p.pgbouncer.Auth != nil {
+			if err := p.exporter.ExportPgBouncerAuth(ctx, dbRes, p.pgbouncer.Auth.Username); err != nil {
+				return err
 			}
 		}
-		ctx.Export("database", pulumi.String(cfg.Database))
+		if p.creds.AbacPolicy {
+			if err := p.provisionAbacPolicy(ctx); err != nil {
+				return fmt.Errorf("failed to create secrets ABAC policy: %w", err)
+			}
+		}
+		if err := p.commentRoleSecrets(ctx); err != nil {
+			return err
+		}
+		if err := utils.Export(ctx, utils.OutputReference, "database", pulumi.String(p.pg.Database)); err != nil {
+			return err
+		}
+		plan := p.plan(dbRes)
+		if err := postgres.ExportPlan(ctx, plan); err != nil {
+			return err
+		}
+		if p.pg.AccessChangelog {
+			if err := postgres.ExportAccessChangelog(ctx, plan); err != nil {
+				return err
+			}
+		}
+		if err := p.exportAccessGraph(ctx, dbRes); err != nil {
+			return err
+		}
+
+		if err := secret.CheckCostGuard(ctx); err != nil {
+			return err
+		}
 
-		return nil
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		metadata.Databases = []string{p.pg.Database}
+		return metadata.Export(ctx)
 	})
 }
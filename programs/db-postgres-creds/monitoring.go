@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+)
+
+// monitoringConfig is the monitoring namespace
+type monitoringConfig struct {
+	// Login role of the monitoring agents, granted pg_monitor
+	User *postgres.PostgresMonitoringUserProps `json:"user"`
+}
+
+// provisionMonitoringUser creates the role of the monitoring agents, its creds
+// are exported as monitoringUser (as a secret with creds:exportAsSecret)
+func (p *program) provisionMonitoringUser(ctx *pulumi.Context, provider *postgresql.Provider, dbRes *postgres.PostgresDBResource) error {
+	if p.monitoring.User == nil {
+		return nil
+	}
+	props := *p.monitoring.User
+	if props.Username == "" {
+		props.Username = postgres.DefaultMonitoringUser
+	}
+	if props.Database == "" {
+		props.Database = p.pg.Database
+	}
+	props.Host = p.provider.Host
+	props.Port = p.provider.Port
+	p.exporter.MonitoringUserStore(&props)
+	res, err := postgres.NewPostgresMonitoringUser(ctx, p.pg.Database, props, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{dbRes}))
+	if err != nil {
+		return fmt.Errorf("failed to create monitoring user of database %s: %w", p.pg.Database, err)
+	}
+	p.monitoringRes = res
+	return p.exporter.ExportMonitoringUser(ctx, res, props)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+)
+
+// pgbouncerConfig is the pgbouncer namespace, for the pooler in front of the
+// database
+type pgbouncerConfig struct {
+	// auth_query function & lookup role
+	Auth *postgres.PgBouncerAuthProps `json:"auth"`
+	// userlist.txt & [databases] entry, for the users of the stack
+	Userlist *postgres.PgBouncerConfigProps `json:"userlist"`
+}
+
+// exportPgBouncerConfig renders the userlist of the users with a password,
+// for the auth_file of the pooler
+func (p *program) exportPgBouncerConfig(ctx *pulumi.Context, usersRes *postgres.PostgresUsersResource, ready pulumi.ArrayOutput) error {
+	props := *p.pgbouncer.Userlist
+	for i, user := range p.pg.Users {
+		if usersRes.Users[i] == nil || user.IamAuth {
+			continue
+		}
+		props.Users = append(props.Users, postgres.PgBouncerUser{
+			Username:         user.Username,
+			Password:         usersRes.Users[i].Password.Elem().ToStringOutput(),
+			PasswordIsHashed: user.PasswordIsHashed,
+		})
+	}
+	return p.exporter.ExportPgBouncerConfig(ctx, props, ready)
+}
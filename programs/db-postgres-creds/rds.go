@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/network"
+	"github.com/shivanshs9/iac-pulumi/components/aws/rds"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+type pgIamAuthPolicyArg struct {
+	InstanceId string `json:"instanceId" required:""`
+}
+
+type pgConnectionBudgetArg struct {
+	// Looked up from the RDS instance class if not set
+	MaxConnections int    `json:"maxConnections"`
+	InstanceId     string `json:"instanceId"`
+	Reserved       int    `json:"reserved"`
+	// Fail the deployment instead of warning about the over-subscription
+	FailOnExceed bool `json:"failOnExceed"`
+}
+
+type pgLoginAlertArg struct {
+	InstanceId           string   `json:"instanceId"`
+	LogGroupName         string   `json:"logGroupName"`
+	ParameterGroupFamily string   `json:"parameterGroupFamily"`
+	Threshold            int      `json:"threshold"`
+	PeriodSeconds        int      `json:"periodSeconds"`
+	AlarmActions         []string `json:"alarmActions"`
+}
+
+// rdsConfig is the rds namespace: the AWS resources around the RDS instance
+// for the login users
+type rdsConfig struct {
+	// Security group of the DB, allowed ingress from the users' CIDRs if set
+	SecurityGroupId string `json:"securityGroupId"`
+	// IAM policy to connect as the iamAuth users is created if set
+	IamAuthPolicy *pgIamAuthPolicyArg `json:"iamAuthPolicy"`
+	LoginAlert    *pgLoginAlertArg    `json:"loginAlert"`
+	// Sum of the users' connection limits is checked against max_connections if set
+	ConnectionBudget *pgConnectionBudgetArg `json:"connectionBudget"`
+}
+
+func (p *program) checkConnectionBudget(ctx *pulumi.Context) error {
+	budget := p.rds.ConnectionBudget
+	if budget.MaxConnections == 0 {
+		if budget.InstanceId == "" {
+			return fmt.Errorf("either maxConnections or instanceId is required to check the connection budget")
+		}
+		maxConnections, err := rds.LookupDefaultMaxConnections(ctx, budget.InstanceId)
+		if err != nil {
+			return err
+		}
+		budget.MaxConnections = maxConnections
+	}
+	err := postgres.CheckConnectionBudget(p.userProps(), budget.MaxConnections, budget.Reserved)
+	if err != nil && !budget.FailOnExceed {
+		ctx.Log.Warn(err.Error(), nil)
+		return nil
+	}
+	return err
+}
+
+func (p *program) provisionIamAuthPolicy(ctx *pulumi.Context) error {
+	usernames := []string{}
+	for _, user := range p.pg.Users {
+		if user.IamAuth {
+			usernames = append(usernames, user.Username)
+		}
+	}
+	if len(usernames) == 0 {
+		return fmt.Errorf("none of the users has iamAuth set")
+	}
+	res, err := rds.NewIamAuthPolicy(ctx, rds.IamAuthPolicyProps{
+		Name:       p.pg.Database,
+		InstanceId: p.rds.IamAuthPolicy.InstanceId,
+		Usernames:  usernames,
+	})
+	if err != nil {
+		return err
+	}
+	return utils.Export(ctx, utils.OutputReference, "iamAuthPolicyArn", res.Policy.Arn)
+}
+
+// provisionLoginAlert creates the alarm on the failed logins of the users,
+// exported as loginAlarmArn
+func (p *program) provisionLoginAlert(ctx *pulumi.Context) error {
+	alert := p.rds.LoginAlert
+	usernames := make([]string, len(p.pg.Users))
+	for i, user := range p.pg.Users {
+		usernames[i] = user.Username
+	}
+	res, err := rds.NewLoginAlert(ctx, rds.LoginAlertProps{
+		Name:                 p.pg.Database,
+		InstanceId:           alert.InstanceId,
+		LogGroupName:         alert.LogGroupName,
+		ParameterGroupFamily: alert.ParameterGroupFamily,
+		Usernames:            usernames,
+		Threshold:            alert.Threshold,
+		PeriodSeconds:        alert.PeriodSeconds,
+		AlarmActions:         alert.AlarmActions,
+	})
+	if err != nil {
+		return err
+	}
+	if err := utils.Export(ctx, utils.OutputReference, "loginAlarmArn", res.Alarm.Arn); err != nil {
+		return err
+	}
+	if res.ParameterGroup == nil {
+		return nil
+	}
+	return utils.Export(ctx, utils.OutputReference, "loginParameterGroup", res.ParameterGroup.Name)
+}
+
+func (p *program) provisionIngress(ctx *pulumi.Context) (*network.IngressResource, error) {
+	rules := make([]network.IngressRuleProps, len(p.pg.Users))
+	for i, user := range p.pg.Users {
+		rules[i] = network.IngressRuleProps{
+			Name:  user.Username,
+			Cidrs: user.AllowedCidrs,
+		}
+	}
+	return network.NewIngress(ctx, network.IngressProps{
+		Name:            p.pg.Database,
+		SecurityGroupId: pulumi.String(p.rds.SecurityGroupId),
+		Port:            p.provider.Port,
+		Rules:           rules,
+	})
+}
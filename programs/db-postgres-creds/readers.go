@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+type pgExternalReaderArg struct {
+	Team      string   `json:"team"`
+	Schemas   []string `json:"schemas"`
+	ExpiresAt string   `json:"expiresAt"`
+	// Role assumed in the account of the team, the secret is created there
+	RoleArn string `json:"roleArn"`
+	// Region of the secret, the one of the aws provider if not set
+	Region string `json:"region"`
+	// KMS key of the secret in the account of the team, aws/secretsmanager if not set
+	KmsKeyId string `json:"kmsKeyId"`
+}
+
+// readersConfig is the readers namespace
+type readersConfig struct {
+	// Time-boxed read-only users of other teams, with the secret in their account
+	Teams []pgExternalReaderArg `json:"teams"`
+}
+
+// activeReaders validates the external readers and drops the expired ones,
+// so their role, grants and secret are removed on the next deploy.
+func (p *program) activeReaders(ctx *pulumi.Context) ([]pgExternalReaderArg, error) {
+	schemas := map[string]bool{"public": true}
+	for _, schema := range p.pg.Schemas {
+		schemas[schema.Name] = true
+	}
+	now := time.Now()
+	seen := map[string]bool{}
+	active := []pgExternalReaderArg{}
+	for _, reader := range p.readers.Teams {
+		if reader.Team == "" || reader.RoleArn == "" {
+			return nil, fmt.Errorf("team and roleArn are required for the external readers")
+		}
+		if seen[reader.Team] {
+			return nil, fmt.Errorf("external reader %s is declared more than once", reader.Team)
+		}
+		seen[reader.Team] = true
+		for _, schema := range reader.Schemas {
+			if !schemas[schema] {
+				return nil, fmt.Errorf("schema %s of external reader %s isn't a schema of database %s", schema, reader.Team, p.pg.Database)
+			}
+		}
+		props := postgres.PostgresExternalReaderProps{Team: reader.Team, ExpiresAt: reader.ExpiresAt}
+		ok, err := props.Active(now)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			ctx.Log.Warn(fmt.Sprintf("access of external reader %s expired at %s, it's removed", reader.Team, reader.ExpiresAt), nil)
+			continue
+		}
+		active = append(active, reader)
+	}
+	return active, nil
+}
+
+// provisionExternalReaders creates the users of the other teams, their
+// creds are only stored in the account of the team, never exported.
+func (p *program) provisionExternalReaders(ctx *pulumi.Context, provider *postgresql.Provider, dbRes *postgres.PostgresDBResource) error {
+	readers, err := p.activeReaders(ctx)
+	if err != nil {
+		return err
+	}
+	database := p.pg.Database
+	refs := pulumi.Map{}
+	for _, reader := range readers {
+		providerArgs := &aws.ProviderArgs{
+			AssumeRole: aws.ProviderAssumeRoleArgs{
+				RoleArn:     pulumi.String(reader.RoleArn),
+				SessionName: pulumi.Sprintf("db-postgres-creds-%s", database),
+			},
+		}
+		if reader.Region != "" {
+			providerArgs.Region = pulumi.String(reader.Region)
+		}
+		awsProvider, err := aws.NewProvider(ctx, fmt.Sprintf("%s-reader-%s", database, reader.Team), providerArgs)
+		if err != nil {
+			return err
+		}
+		store := secret.AWSSecretStore{
+			Tags:     p.exporter.Tags(postgres.ExternalReaderUsername(database, reader.Team)),
+			KmsKeyId: reader.KmsKeyId,
+		}
+		if store.KmsKeyId == "" {
+			// the key of secret:kms_alias is in this account
+			store.KmsKeyId = "alias/aws/secretsmanager"
+		}
+		res, err := postgres.NewPostgresExternalReader(ctx, database, postgres.PostgresExternalReaderProps{
+			Team:       reader.Team,
+			Schemas:    reader.Schemas,
+			ExpiresAt:  reader.ExpiresAt,
+			Host:       p.provider.Host,
+			Port:       p.provider.Port,
+			Store:      store,
+			SecretName: fmt.Sprintf("pg-%s-reader-%s", database, reader.Team),
+		}, pulumi.Providers(provider, awsProvider), pulumi.DependsOn([]pulumi.Resource{dbRes}))
+		if err != nil {
+			return fmt.Errorf("failed to create external reader %s of database %s: %w", reader.Team, database, err)
+		}
+		p.readerRes = append(p.readerRes, res)
+		p.readerNames = append(p.readerNames, postgres.ExternalReaderUsername(database, reader.Team))
+		refs[reader.Team] = pulumi.StringMap{
+			"username":  res.Role.Name,
+			"secretId":  res.SecretId,
+			"expiresAt": pulumi.String(reader.ExpiresAt),
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	return utils.Export(ctx, utils.OutputReference, "externalReaders", refs)
+}
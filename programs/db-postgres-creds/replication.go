@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+)
+
+type pgPublicationArg struct {
+	Name     string   `json:"name"`
+	Tables   []string `json:"tables"`
+	Username string   `json:"username"`
+	Plugin   string   `json:"plugin"`
+	RDS      bool     `json:"rds"`
+}
+
+type pgSubscriptionArg struct {
+	Name           string   `json:"name"`
+	Publications   []string `json:"publications"`
+	SourceSecretId string   `json:"sourceSecretId"`
+	SlotName       string   `json:"slotName"`
+}
+
+type pgForeignServerArg struct {
+	Name           string   `json:"name"`
+	SourceSecretId string   `json:"sourceSecretId"`
+	Users          []string `json:"users"`
+}
+
+// replicationConfig is the replication namespace: the data shared with the
+// other servers
+type replicationConfig struct {
+	// Logical replication sources for CDC pipelines
+	Publications []pgPublicationArg `json:"publications"`
+	// Logical replication from publications of other servers
+	Subscriptions []pgSubscriptionArg `json:"subscriptions"`
+	// Remote databases readable via postgres_fdw
+	ForeignServers []pgForeignServerArg `json:"foreignServers"`
+}
+
+func (p *program) provisionPublications(ctx *pulumi.Context, provider *postgresql.Provider, dbRes *postgres.PostgresDBResource) error {
+	for _, pub := range p.replication.Publications {
+		res, err := postgres.NewPostgresPublication(ctx, pub.Name, postgres.PostgresPublicationProps{
+			Database: p.pg.Database,
+			Tables:   pub.Tables,
+			Username: pub.Username,
+			Plugin:   pub.Plugin,
+			RDS:      pub.RDS,
+		}, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{dbRes}))
+		if err != nil {
+			return fmt.Errorf("failed to create publication %s: %w", pub.Name, err)
+		}
+		if err := p.exporter.ExportPublication(ctx, pub.Name, res, pub.Username); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *program) provisionSubscriptions(ctx *pulumi.Context, provider *postgresql.Provider, dbRes *postgres.PostgresDBResource) error {
+	for _, sub := range p.replication.Subscriptions {
+		if _, err := postgres.NewPostgresSubscription(ctx, sub.Name, postgres.PostgresSubscriptionProps{
+			Database:       p.pg.Database,
+			Publications:   sub.Publications,
+			SourceSecretId: sub.SourceSecretId,
+			SlotName:       sub.SlotName,
+		}, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{dbRes})); err != nil {
+			return fmt.Errorf("failed to create subscription %s: %w", sub.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *program) provisionForeignServers(ctx *pulumi.Context, dbRes *postgres.PostgresDBResource) error {
+	if len(p.replication.ForeignServers) == 0 {
+		return nil
+	}
+	// foreign servers are created in the database the provider connects to
+	providerArgs := p.provider.ProviderArgs()
+	providerArgs.Database = dbRes.DB.Name
+	dbProvider, err := postgresql.NewProvider(ctx, fmt.Sprintf("postgresql-%s", p.pg.Database), providerArgs)
+	if err != nil {
+		return err
+	}
+	for _, server := range p.replication.ForeignServers {
+		if _, err := postgres.NewPostgresFdw(ctx, server.Name, postgres.PostgresFdwProps{
+			Database:       p.pg.Database,
+			ServerName:     server.Name,
+			SourceSecretId: server.SourceSecretId,
+			Users:          server.Users,
+		}, pulumi.Provider(dbProvider), pulumi.DependsOn([]pulumi.Resource{dbRes})); err != nil {
+			return fmt.Errorf("failed to create foreign server %s: %w", server.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+)
+
+// rowSecurityConfig is the rls namespace: the row level security policies,
+// created with psql
+type rowSecurityConfig struct {
+	Tables []postgres.RowSecurityTableProps `json:"tables"`
+}
+
+func (p *program) provisionRowSecurity(ctx *pulumi.Context, dbRes *postgres.PostgresDBResource) error {
+	if len(p.rls.Tables) == 0 {
+		return nil
+	}
+	if _, err := postgres.NewPostgresRowSecurity(ctx, p.pg.Database, postgres.PostgresRowSecurityProps{
+		Database:   p.pg.Database,
+		Tables:     p.rls.Tables,
+		Connection: p.provider.SQLConnection(),
+	}, pulumi.DependsOn([]pulumi.Resource{dbRes})); err != nil {
+		return fmt.Errorf("failed to create row level security of database %s: %w", p.pg.Database, err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+)
+
+// timescaleConfig is the timescale namespace: timescaledb is installed if
+// any of it is set, and the hypertables created
+type timescaleConfig struct {
+	// Installs timescaledb without pinning its version nor any hypertable
+	Enabled     bool                                `json:"enabled"`
+	Version     string                              `json:"version"`
+	Hypertables []postgres.TimescaleHypertableProps `json:"hypertables"`
+}
+
+func (cfg *timescaleConfig) enabled() bool {
+	return cfg.Enabled || cfg.Version != "" || len(cfg.Hypertables) > 0
+}
+
+func (p *program) provisionTimescale(ctx *pulumi.Context, provider *postgresql.Provider, dbRes *postgres.PostgresDBResource) error {
+	if !p.timescale.enabled() {
+		return nil
+	}
+	if _, err := postgres.NewPostgresTimescale(ctx, p.pg.Database, postgres.PostgresTimescaleProps{
+		Database:    p.pg.Database,
+		Version:     p.timescale.Version,
+		Hypertables: p.timescale.Hypertables,
+		Connection:  p.provider.SQLConnection(),
+	}, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{dbRes})); err != nil {
+		return fmt.Errorf("failed to install timescaledb in database %s: %w", p.pg.Database, err)
+	}
+	return nil
+}
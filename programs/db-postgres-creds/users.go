@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-postgresql/sdk/v3/go/postgresql"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"github.com/shivanshs9/iac-pulumi/components/aws/network"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/postgres"
+)
+
+// pgUserArg is a login user of pg:users
+type pgUserArg struct {
+	Username        string `json:"username"`
+	Login           bool   `json:"login"`
+	ConnectionLimit int    `json:"connectionLimit"`
+	CreateDatabase  bool   `json:"createDatabase"`
+	CreateRole      bool   `json:"createRole"`
+	Inherit         *bool  `json:"inherit"`
+	// DB role the user assumes, rw or ro (e.g. analysts), rw if not set
+	Permission postgres.PostgresUserPermission `json:"permission"`
+	// NOINHERIT user which has to SET ROLE to the rw role before writing
+	ExplicitSetRole bool   `json:"explicitSetRole"`
+	ValidUntil      string `json:"validUntil"`
+	TTL             string `json:"ttl"`
+	// Roles granted besides the rw role of the database, e.g. analytics-ro
+	Roles []string `json:"roles"`
+	// Connects with RDS IAM tokens instead of a password
+	IamAuth bool `json:"iamAuth"`
+	// Developers allowed to read the secret of the user for a while, in
+	// perUser export mode
+	ShareWith []secret.SecretShare `json:"shareWith"`
+	// Key of the secret config (in pg namespace) holding the existing
+	// password of the user, a random one is generated if not set
+	Password string `json:"password"`
+	// Bump it to rotate the generated password
+	RotationTrigger string `json:"rotationTrigger"`
+	// Secret Manager secret holding the password, e.g. owned by another team
+	PasswordSecretArn string `json:"passwordSecretArn"`
+	// The password (or passwordSecretArn) is a SCRAM-SHA-256 or md5 verifier
+	PasswordIsHashed bool `json:"passwordIsHashed"`
+	ImportExisting   bool `json:"importExisting"`
+	Protect          bool `json:"protect"`
+	RetainOnDelete   bool `json:"retainOnDelete"`
+	// Clients the creds are meant to be used from
+	AllowedCidrs []string `json:"allowedCidrs"`
+	// Role-level search_path, statement_timeout & idle_in_transaction_session_timeout
+	Settings map[string]string `json:"settings"`
+	// Ownership or team metadata of the role
+	Comment string `json:"comment"`
+	// Secret Manager rotates the password, in the secret of the user
+	Rotation *pgRotationArg `json:"rotation"`
+}
+
+type pgRotationArg struct {
+	// Days between the rotations, unless scheduleExpression is set
+	AutomaticallyAfterDays int    `json:"automaticallyAfterDays"`
+	ScheduleExpression     string `json:"scheduleExpression"`
+	Duration               string `json:"duration"`
+	// Where the rotation function runs, to reach a private database
+	SubnetIds        []string `json:"subnetIds"`
+	SecurityGroupIds []string `json:"securityGroupIds"`
+}
+
+// validateUserPermissions checks the users only assume the rw or ro role
+func (cfg *pgConfig) validateUserPermissions() error {
+	for _, user := range cfg.Users {
+		switch user.Permission {
+		case "", postgres.ReadWrite, postgres.ReadOnly:
+		default:
+			return fmt.Errorf("invalid permission '%s' of user %s, expected %s or %s", user.Permission, user.Username, postgres.ReadWrite, postgres.ReadOnly)
+		}
+	}
+	return nil
+}
+
+// validateUser checks the user against the export of its creds
+func (p *program) validateUser(user pgUserArg) error {
+	if err := network.ValidateCidrs(user.AllowedCidrs); err != nil {
+		return err
+	}
+	// the db secret types need a password, the plaintext of a verifier isn't known
+	if user.PasswordIsHashed && p.creds.ExportAsSecret {
+		return fmt.Errorf("the password is hashed, its creds can't be exported with exportAsSecret")
+	}
+	if len(user.ShareWith) > 0 && (!p.exporter.ExportsToAWS() || p.exporter.Mode() != postgres.ExportPerUser) {
+		return fmt.Errorf("shareWith needs exportAsSecret to aws in perUser export mode")
+	}
+	return p.validateRotation(user)
+}
+
+// loadPasswords reads the existing passwords of the users, for the services
+// which can't rotate their creds yet.
+func (p *program) loadPasswords(ctx *pulumi.Context) error {
+	p.passwords = map[string]pulumi.StringOutput{}
+	for _, user := range p.pg.Users {
+		if user.Password == "" {
+			continue
+		}
+		key := fmt.Sprintf("pg:%s", user.Password)
+		if _, ok := ctx.GetConfig(key); !ok {
+			return fmt.Errorf("password config '%s' of user %s is not set", key, user.Username)
+		}
+		if !ctx.IsConfigSecret(key) {
+			return fmt.Errorf("password config '%s' of user %s needs to be set with --secret", key, user.Username)
+		}
+		p.passwords[user.Username] = config.RequireSecret(ctx, key)
+	}
+	return nil
+}
+
+func (p *program) userProps() []postgres.PostgresUserProps {
+	cfg := &p.pg
+	userProps := make([]postgres.PostgresUserProps, len(cfg.Users))
+	for i, user := range cfg.Users {
+		userProps[i] = postgres.PostgresUserProps{
+			Username:          user.Username,
+			Login:             user.Login,
+			AssumeRole:        pulumi.String(cfg.userRole(user)),
+			Roles:             toStringInputs(user.Roles),
+			ConnectionLimit:   user.ConnectionLimit,
+			CreateDatabase:    user.CreateDatabase,
+			CreateRole:        user.CreateRole,
+			Inherit:           user.Inherit,
+			ExplicitSetRole:   user.ExplicitSetRole,
+			ValidUntil:        user.ValidUntil,
+			TTL:               user.TTL,
+			RotationTrigger:   user.RotationTrigger,
+			PasswordSecretArn: user.PasswordSecretArn,
+			PasswordIsHashed:  user.PasswordIsHashed,
+			ImportExisting:    user.ImportExisting,
+			IamAuth:           user.IamAuth,
+			Protect:           user.Protect,
+			RetainOnDelete:    user.RetainOnDelete,
+			Settings:          user.Settings,
+			Endpoint:          p.provider.Endpoint(cfg.Database),
+			Connection:        p.provider.SQLConnection(),
+			RotatedBySecret:   user.Rotation != nil,
+		}
+		// the comment of the users with a secret links to it too, see commentRoleSecrets
+		if !p.exporter.ExportsToAWS() {
+			userProps[i].Comment = user.Comment
+		}
+		if password, ok := p.passwords[user.Username]; ok {
+			userProps[i].Password = password
+		}
+	}
+	return userProps
+}
+
+func (p *program) provisionLoginUsers(ctx *pulumi.Context, provider *postgresql.Provider) (*postgres.PostgresUsersResource, error) {
+	return postgres.NewPostgresUsers(ctx, p.pg.Database, p.userProps(), pulumi.Provider(provider))
+}
+
+// commentRoleSecrets writes the ARN of the secret of each role into its
+// COMMENT, after the comment of the user if set
+func (p *program) commentRoleSecrets(ctx *pulumi.Context) error {
+	comments := map[string]string{}
+	for _, user := range p.pg.Users {
+		comments[user.Username] = user.Comment
+	}
+	return p.exporter.CommentRoleSecrets(ctx, p.provider.SQLConnection(), comments)
+}
+
+func toStringInputs(values []string) []pulumi.StringInput {
+	inputs := make([]pulumi.StringInput, len(values))
+	for i, v := range values {
+		inputs[i] = pulumi.String(v)
+	}
+	return inputs
+}
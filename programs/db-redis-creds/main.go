@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/elasticache"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const defaultPort = 6379
+
+type redisConfig struct {
+	// Names the ACL users (<name>-<username>) and the secrets
+	Name string `json:"name" required:""`
+	// Primary endpoint of the replication group
+	Host string `json:"host" required:""`
+	Port int    `json:"port"`
+	// User group of the replication group, the services get ACL users of
+	// their own if set, the auth token otherwise
+	UserGroupId string `json:"userGroupId"`
+	// Secret holding the auth token, e.g. redis-<cluster>-auth-token of
+	// NewRedisCluster, required without userGroupId
+	AuthTokenSecretId string                                `json:"authTokenSecretId"`
+	Users             []elasticache.ElastiCacheACLUserProps `json:"users"`
+	// Exports the creds as AWS secrets, stack outputs otherwise
+	ExportAsSecret bool `json:"exportAsSecret"`
+}
+
+func (cfg *redisConfig) validate() error {
+	if cfg.Port == 0 {
+		cfg.Port = defaultPort
+	}
+	if cfg.UserGroupId != "" {
+		return nil
+	}
+	if cfg.AuthTokenSecretId == "" {
+		return fmt.Errorf("redis:authTokenSecretId is required, unless redis:userGroupId is set")
+	}
+	// all the services share the default user of the auth token
+	for _, user := range cfg.Users {
+		if len(user.Keys) > 0 || len(user.Commands) > 0 || len(user.Channels) > 0 {
+			return fmt.Errorf("user %s: keys, commands and channels need redis:userGroupId", user.Username)
+		}
+	}
+	return nil
+}
+
+func (cfg *redisConfig) creds(username string, password pulumi.StringInput) pulumi.StringMap {
+	return pulumi.StringMap{
+		"username": pulumi.String(username),
+		"password": password,
+		"host":     pulumi.String(cfg.Host),
+		"port":     pulumi.Sprintf("%d", cfg.Port),
+		// the auth token and the ACL users of elasticache need it
+		"tls": pulumi.String("true"),
+	}
+}
+
+// passwords provisions the ACL users of the services, or hands them the auth
+// token, keyed by username along with the redis username of each
+func (cfg *redisConfig) passwords(ctx *pulumi.Context) (map[string]pulumi.StringOutput, map[string]string, error) {
+	usernames := map[string]string{}
+	if cfg.UserGroupId == "" {
+		token := secret.LookupPassword(ctx, cfg.AuthTokenSecretId)
+		passwords := map[string]pulumi.StringOutput{}
+		for _, user := range cfg.Users {
+			passwords[user.Username] = token
+			usernames[user.Username] = "default"
+		}
+		return passwords, usernames, nil
+	}
+	aclRes, aclErr := elasticache.NewElastiCacheACL(ctx, cfg.Name, elasticache.ElastiCacheACLProps{
+		UserGroupId: cfg.UserGroupId,
+		Host:        pulumi.String(cfg.Host),
+		Port:        cfg.Port,
+		Users:       cfg.Users,
+		// the creds are exported by the program
+		NoSecrets: true,
+	})
+	if aclRes == nil {
+		return nil, nil, fmt.Errorf("failed to create the ACL users of %s: %w", cfg.Name, aclErr)
+	}
+	// the failed users are reported, the others are still exported
+	for _, user := range cfg.Users {
+		if userErr, ok := aclRes.FailedUsers[user.Username]; ok {
+			wrappedErr := fmt.Errorf("failed to create user '%s': %w", user.Username, userErr)
+			ctx.Log.Error(wrappedErr.Error(), &pulumi.LogArgs{Resource: aclRes})
+			continue
+		}
+		usernames[user.Username] = user.Username
+	}
+	return aclRes.Passwords, usernames, aclErr
+}
+
+// exportCreds exposes each service creds in its own secret, or all of them in
+// the users output
+func (cfg *redisConfig) exportCreds(ctx *pulumi.Context, passwords map[string]pulumi.StringOutput, usernames map[string]string) error {
+	users := pulumi.Map{}
+	for _, user := range cfg.Users {
+		password, ok := passwords[user.Username]
+		if !ok {
+			continue
+		}
+		creds := cfg.creds(usernames[user.Username], password)
+		if !cfg.ExportAsSecret {
+			users[user.Username] = creds
+			continue
+		}
+		res, err := secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+			Name:         fmt.Sprintf("%s-user-%s", cfg.Name, user.Username),
+			Type:         secret.RedisCreds,
+			InitialValue: creds,
+			Tags: map[string]string{
+				"redis:cluster": cfg.Name,
+				"redis:user":    user.Username,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create secret for user %s: %w", user.Username, err)
+		}
+		if err := utils.Export(ctx, utils.OutputReference, fmt.Sprintf("secret-%s", user.Username), pulumi.StringMap{
+			"secretId": res.Secret.ID().ToStringOutput(),
+		}); err != nil {
+			return err
+		}
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	return utils.Export(ctx, utils.OutputCreds, "users", users)
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		if err := utils.CheckPluginVersions(ctx); err != nil {
+			return err
+		}
+		cfg := &redisConfig{}
+		if err := utils.ExtractConfig(ctx, "redis", cfg); err != nil {
+			return err
+		}
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		aclUsers, secrets := 0, 0
+		if cfg.UserGroupId != "" {
+			aclUsers = len(cfg.Users)
+		}
+		if cfg.ExportAsSecret {
+			secrets = len(cfg.Users)
+		}
+		if err := utils.CheckResourceQuota(ctx, map[string]int{
+			utils.QuotaUsers:   aclUsers,
+			utils.QuotaSecrets: secrets,
+		}); err != nil {
+			return err
+		}
+
+		passwords, usernames, usersErr := cfg.passwords(ctx)
+		if passwords == nil {
+			return usersErr
+		}
+		if err := cfg.exportCreds(ctx, passwords, usernames); err != nil {
+			return err
+		}
+		if err := utils.Export(ctx, utils.OutputReference, "host", pulumi.String(cfg.Host)); err != nil {
+			return err
+		}
+		if err := secret.CheckCostGuard(ctx); err != nil {
+			return err
+		}
+
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		if err := metadata.Export(ctx); err != nil {
+			return err
+		}
+		return usersErr
+	})
+}
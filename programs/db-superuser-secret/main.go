@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const defaultKeyDeletionWindow = 30
+
+type superuserConfig struct {
+	// Identifies the server, e.g. the RDS instance id
+	Name     string             `json:"name" required:""`
+	Host     string             `json:"host" required:""`
+	Port     int                `json:"port" required:""`
+	Username string             `json:"username" required:""`
+	Password pulumi.StringInput `required:"" secret:"password"`
+	Database string             `json:"database"`
+	// IAM principals allowed to read the secret, the deployer included
+	ReaderArns        []string `json:"readerArns" required:""`
+	KeyDeletionWindow int      `json:"keyDeletionWindow"`
+}
+
+// provisionKey creates the KMS key dedicated to the superuser secret, so its
+// usage is audited apart from the other secrets.
+func (cfg *superuserConfig) provisionKey(ctx *pulumi.Context) (*kms.Key, error) {
+	key, err := kms.NewKey(ctx, fmt.Sprintf("pg-superuser-%s", cfg.Name), &kms.KeyArgs{
+		Description:          pulumi.Sprintf("Encrypts the superuser creds of %s", cfg.Name),
+		DeletionWindowInDays: pulumi.Int(cfg.KeyDeletionWindow),
+		EnableKeyRotation:    pulumi.Bool(true),
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := kms.NewAlias(ctx, fmt.Sprintf("pg-superuser-%s", cfg.Name), &kms.AliasArgs{
+		Name:        pulumi.Sprintf("alias/pg-superuser-%s", cfg.Name),
+		TargetKeyId: key.KeyId,
+	}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		if err := utils.CheckPluginVersions(ctx); err != nil {
+			return err
+		}
+		cfg := &superuserConfig{}
+		if err := utils.ExtractConfig(ctx, "superuser", cfg); err != nil {
+			return err
+		}
+		if cfg.Database == "" {
+			cfg.Database = "postgres"
+		}
+		if cfg.KeyDeletionWindow == 0 {
+			cfg.KeyDeletionWindow = defaultKeyDeletionWindow
+		}
+		if len(cfg.ReaderArns) == 0 {
+			return fmt.Errorf("at least one reader is required, else nobody could read the secret")
+		}
+		if err := utils.CheckResourceQuota(ctx, map[string]int{utils.QuotaSecrets: 1}); err != nil {
+			return err
+		}
+
+		key, err := cfg.provisionKey(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create KMS key: %w", err)
+		}
+		res, err := secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+			Name: fmt.Sprintf("pg-superuser-%s", cfg.Name),
+			Type: secret.DBCreds,
+			InitialValue: pulumi.StringMap{
+				"username": pulumi.String(cfg.Username),
+				"password": cfg.Password,
+				"database": pulumi.String(cfg.Database),
+				"host":     pulumi.String(cfg.Host),
+				"port":     pulumi.Sprintf("%d", cfg.Port),
+			},
+			KmsKeyId:   key.Arn,
+			ReaderArns: cfg.ReaderArns,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create superuser secret: %w", err)
+		}
+		if err := utils.Export(ctx, utils.OutputReference, "secret", pulumi.StringMap{
+			"secretId": res.Secret.ID(),
+			"keyArn":   key.Arn,
+		}); err != nil {
+			return err
+		}
+
+		if err := secret.CheckCostGuard(ctx); err != nil {
+			return err
+		}
+
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		return metadata.Export(ctx)
+	})
+}
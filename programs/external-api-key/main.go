@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const dateLayout = "2006-01-02"
+
+type apiKeyArg struct {
+	Name            string            `json:"name"`
+	Type            secret.SecretType `json:"type"`
+	Vendor          string            `json:"vendor"`
+	Owner           string            `json:"owner"`
+	IssuedAt        string            `json:"issuedAt"`
+	RotateAfterDays int               `json:"rotateAfterDays"`
+}
+
+type apiKeyConfig struct {
+	Keys []apiKeyArg `json:"keys" required:""`
+	// ExternalSecret manifests are emitted for the keys if set
+	ExternalSecret *secret.ExternalSecretProps `json:"externalSecret"`
+
+	values map[string]map[string]string
+}
+
+func (key *apiKeyArg) validate(payload map[string]string) error {
+	if key.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if key.Type == "" {
+		key.Type = secret.APIKey
+	}
+	if payload == nil {
+		return fmt.Errorf("value not found in secret config 'apikey:values.%s'", key.Name)
+	}
+	if err := key.Type.ValidatePayload(payload); err != nil {
+		return err
+	}
+	if key.RotateAfterDays > 0 && key.IssuedAt == "" {
+		return fmt.Errorf("issuedAt is required to remind about rotation")
+	}
+	return nil
+}
+
+// rotationDueBy returns the date by which the key needs to be rotated, if any.
+func (key *apiKeyArg) rotationDueBy() (time.Time, error) {
+	if key.RotateAfterDays <= 0 {
+		return time.Time{}, nil
+	}
+	issuedAt, err := time.Parse(dateLayout, key.IssuedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid issuedAt '%s', expected format YYYY-MM-DD: %w", key.IssuedAt, err)
+	}
+	return issuedAt.AddDate(0, 0, key.RotateAfterDays), nil
+}
+
+func (key *apiKeyArg) genTags(dueBy time.Time) map[string]string {
+	tags := map[string]string{}
+	if key.Vendor != "" {
+		tags["vendor"] = key.Vendor
+	}
+	if key.Owner != "" {
+		tags["owner"] = key.Owner
+	}
+	if key.IssuedAt != "" {
+		tags["rotation:issuedAt"] = key.IssuedAt
+	}
+	if !dueBy.IsZero() {
+		tags["rotation:dueBy"] = dueBy.Format(dateLayout)
+	}
+	return tags
+}
+
+func (cfg *apiKeyConfig) storeKey(ctx *pulumi.Context, key *apiKeyArg) (*secret.AWSSecret, error) {
+	payload := cfg.values[key.Name]
+	if err := key.validate(payload); err != nil {
+		return nil, err
+	}
+	dueBy, err := key.rotationDueBy()
+	if err != nil {
+		return nil, err
+	}
+	if !dueBy.IsZero() && time.Now().After(dueBy) {
+		ctx.Log.Warn(fmt.Sprintf("API key '%s' was due for rotation on %s", key.Name, dueBy.Format(dateLayout)), nil)
+	}
+	return secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+		Name:         key.Name,
+		Type:         key.Type,
+		InitialValue: pulumi.ToSecret(pulumi.ToStringMap(payload)).(pulumi.StringMapOutput),
+		Tags:         key.genTags(dueBy),
+	})
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		if err := utils.CheckPluginVersions(ctx); err != nil {
+			return err
+		}
+		cfg := &apiKeyConfig{}
+		if err := utils.ExtractConfig(ctx, "apikey", cfg); err != nil {
+			return err
+		}
+		// plaintext is only used to validate the shape, the payload stays secret
+		config.New(ctx, "apikey").RequireSecretObject("values", &cfg.values)
+		if err := utils.CheckResourceQuota(ctx, map[string]int{utils.QuotaSecrets: len(cfg.Keys)}); err != nil {
+			return err
+		}
+
+		for i := range cfg.Keys {
+			key := &cfg.Keys[i]
+			secret, err := cfg.storeKey(ctx, key)
+			if err != nil {
+				return fmt.Errorf("failed to store API key '%s': %w", key.Name, err)
+			}
+			if err := utils.Export(ctx, utils.OutputReference, fmt.Sprintf("secret-%s", key.Name), pulumi.StringMap{
+				"secretId": secret.Secret.ID(),
+			}); err != nil {
+				return err
+			}
+			if cfg.ExternalSecret != nil {
+				props := *cfg.ExternalSecret
+				props.Name = key.Name
+				manifest, err := secret.ExternalSecretManifest(props)
+				if err != nil {
+					return err
+				}
+				if err := utils.Export(ctx, utils.OutputManifest, fmt.Sprintf("manifest-%s", key.Name), manifest); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := secret.CheckCostGuard(ctx); err != nil {
+			return err
+		}
+
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		return metadata.Export(ctx)
+	})
+}
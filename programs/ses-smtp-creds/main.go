@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	awsiam "github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"github.com/shivanshs9/iac-pulumi/components/aws/iam"
+	"github.com/shivanshs9/iac-pulumi/components/aws/secret"
+	"github.com/shivanshs9/iac-pulumi/components/utils"
+)
+
+const (
+	smtpPort = 587
+	// SES only accepts this version of derived SMTP passwords
+	smtpPasswordVersion = 0x04
+)
+
+type smtpConfig struct {
+	Username       string `json:"username" required:""`
+	Region         string `json:"region"`
+	RotationKeeper int    `json:"rotationKeeper"`
+	ExportAsSecret bool   `json:"exportAsSecret"`
+	// ExternalSecret manifest is emitted for the exported secret if set
+	ExternalSecret *secret.ExternalSecretProps `json:"externalSecret"`
+}
+
+func sign(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+// deriveSmtpPassword converts the IAM secret access key into SES SMTP password.
+// Ref: https://docs.aws.amazon.com/ses/latest/dg/smtp-credentials.html#smtp-credentials-convert
+func deriveSmtpPassword(secretAccessKey string, region string) string {
+	signature := []byte("AWS4" + secretAccessKey)
+	for _, msg := range []string{"11111111", region, "ses", "aws4_request", "SendRawEmail"} {
+		signature = sign(signature, msg)
+	}
+	return base64.StdEncoding.EncodeToString(append([]byte{smtpPasswordVersion}, signature...))
+}
+
+func (cfg *smtpConfig) provisionUser(ctx *pulumi.Context) (*awsiam.User, error) {
+	user, err := awsiam.NewUser(ctx, cfg.Username, &awsiam.UserArgs{
+		Name: pulumi.String(cfg.Username),
+		Tags: pulumi.StringMap{
+			"Pulumi": pulumi.String("true"),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := awsiam.NewUserPolicy(ctx, fmt.Sprintf("%s-ses-send", cfg.Username), &awsiam.UserPolicyArgs{
+		User: user.Name,
+		Policy: pulumi.String(`{
+			"Version": "2012-10-17",
+			"Statement": [{
+				"Effect": "Allow",
+				"Action": "ses:SendRawEmail",
+				"Resource": "*"
+			}]
+		}`),
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (cfg *smtpConfig) genCredsMap(user *awsiam.User, keyRes *iam.AccessKeyResource) pulumi.StringMap {
+	region := cfg.Region
+	return pulumi.StringMap{
+		"username": keyRes.ActiveKey.ID().ToStringOutput(),
+		"password": keyRes.ActiveKey.Secret.ApplyT(func(secretKey string) string {
+			return deriveSmtpPassword(secretKey, region)
+		}).(pulumi.StringOutput),
+		"host":    pulumi.Sprintf("email-smtp.%s.amazonaws.com", region),
+		"port":    pulumi.Sprintf("%d", smtpPort),
+		"iamUser": user.Name,
+	}
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		if err := utils.CheckPluginVersions(ctx); err != nil {
+			return err
+		}
+		cfg := &smtpConfig{}
+		if err := utils.ExtractConfig(ctx, "smtp", cfg); err != nil {
+			return err
+		}
+		if cfg.Region == "" {
+			cfg.Region = config.Require(ctx, "aws:region")
+		}
+		planned := map[string]int{utils.QuotaUsers: 1}
+		if cfg.ExportAsSecret {
+			planned[utils.QuotaSecrets] = 1
+		}
+		if err := utils.CheckResourceQuota(ctx, planned); err != nil {
+			return err
+		}
+		user, err := cfg.provisionUser(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create IAM user %s: %w", cfg.Username, err)
+		}
+		keyRes, err := iam.NewAccessKey(ctx, iam.AccessKeyProps{
+			Name:           cfg.Username,
+			User:           user.Name,
+			RotationKeeper: cfg.RotationKeeper,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create access keys for %s: %w", cfg.Username, err)
+		}
+
+		if cfg.ExportAsSecret {
+			secret, err := secret.NewAWSSecret(ctx, secret.AWSSecretProps{
+				Name:         fmt.Sprintf("ses-%s", cfg.Username),
+				Type:         secret.SMTPCreds,
+				InitialValue: cfg.genCredsMap(user, keyRes),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create secret for user %s: %w", cfg.Username, err)
+			}
+			if err := utils.Export(ctx, utils.OutputReference, "secret", pulumi.StringMap{
+				"secretId": secret.Secret.ID(),
+			}); err != nil {
+				return err
+			}
+			if cfg.ExternalSecret != nil {
+				props := *cfg.ExternalSecret
+				props.Name = fmt.Sprintf("ses-%s", cfg.Username)
+				manifest, err := secret.ExternalSecretManifest(props)
+				if err != nil {
+					return err
+				}
+				if err := utils.Export(ctx, utils.OutputManifest, "manifest", manifest); err != nil {
+					return err
+				}
+			}
+		} else {
+			if err := utils.Export(ctx, utils.OutputCreds, "smtp", cfg.genCredsMap(user, keyRes)); err != nil {
+				return err
+			}
+		}
+
+		if err := secret.CheckCostGuard(ctx); err != nil {
+			return err
+		}
+
+		metadata, err := utils.LoadStackMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		return metadata.Export(ctx)
+	})
+}
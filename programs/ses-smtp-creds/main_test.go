@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestDeriveSmtpPassword(t *testing.T) {
+	// expected passwords computed by the conversion script of the SES docs
+	// (smtp-credentials.html#smtp-credentials-convert), with the example
+	// secret key of the AWS docs
+	tests := []struct {
+		name            string
+		secretAccessKey string
+		region          string
+		want            string
+	}{
+		{
+			name:            "us-east-1",
+			secretAccessKey: "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+			region:          "us-east-1",
+			want:            "BLBM/9hSUELfq8Gw+rU1YcBjkOxGbhT2XG763xVLGWL9",
+		},
+		{
+			name:            "the region is part of the signature",
+			secretAccessKey: "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+			region:          "eu-west-1",
+			want:            "BMW5RDrXmmVs0lV7GpI4oLkHXpZ4stDsk6q91z1g38Pk",
+		},
+		{
+			name:            "empty key",
+			secretAccessKey: "",
+			region:          "us-east-1",
+			want:            "BOW79JVhDT89FyiFKOdz7GgFSn54m/kvJC9qQ6Zd5iK0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deriveSmtpPassword(tt.secretAccessKey, tt.region)
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+			raw, err := base64.StdEncoding.DecodeString(got)
+			if err != nil {
+				t.Fatalf("password isn't base64: %v", err)
+			}
+			// version byte followed by the HMAC-SHA256 signature
+			if len(raw) != 33 || raw[0] != smtpPasswordVersion {
+				t.Fatalf("got %d bytes with version %#x, want 33 bytes with version %#x", len(raw), raw[0], smtpPasswordVersion)
+			}
+		})
+	}
+}